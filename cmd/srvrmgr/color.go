@@ -0,0 +1,145 @@
+// cmd/srvrmgr/color.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// colorMode is the choice made by the global --force-color and --no-color
+// flags.
+type colorMode int
+
+const (
+	colorAuto colorMode = iota // color only when stdout is a terminal
+	colorAlways
+	colorNever
+)
+
+// colorEnabled is set once in main; it is off by default so tests and other
+// callers get plain text.
+var colorEnabled bool
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "1"
+	ansiRed    = "31"
+	ansiGreen  = "32"
+	ansiYellow = "33"
+)
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// parseColorFlags removes --force-color and --no-color from args, wherever
+// they appear before a "--" terminator. The last one given wins.
+func parseColorFlags(args []string) ([]string, colorMode) {
+	mode := colorAuto
+	rest := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		switch arg {
+		case "--force-color":
+			mode = colorAlways
+		case "--no-color":
+			mode = colorNever
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, mode
+}
+
+// useColor resolves mode: an explicit flag wins, then a non-empty NO_COLOR
+// (https://no-color.org) turns color off, otherwise color follows whether
+// output is a terminal.
+func useColor(mode colorMode, noColorEnv string, terminal bool) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	return noColorEnv == "" && terminal
+}
+
+// isTerminal reports whether f is a character device such as a terminal,
+// rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in an ANSI color when color is enabled.
+func paint(code, s string) string {
+	if !colorEnabled || s == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + ansiReset
+}
+
+// colorStatus colors a status cell by its first word: green for success,
+// red for failure, yellow for skipped or disabled. Other text is unchanged.
+func colorStatus(s string) string {
+	word, _, _ := strings.Cut(s, " ")
+	switch word {
+	case "ok", "pass", "success", "yes":
+		return paint(ansiGreen, s)
+	case "FAIL", "failure", "timeout", "circuit_open":
+		return paint(ansiRed, s)
+	case "skipped", "cancelled", "no":
+		return paint(ansiYellow, s)
+	}
+	return s
+}
+
+// visibleWidth is the number of characters s takes on screen, ignoring
+// color codes.
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiEscape.ReplaceAllString(s, ""))
+}
+
+// writeTable writes headers and rows as aligned columns separated by two
+// spaces. Widths ignore color codes, so cells may be painted.
+func writeTable(w io.Writer, headers []string, rows [][]string) {
+	var widths []int
+	measure := func(row []string) {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], visibleWidth(cell))
+		}
+	}
+	measure(headers)
+	for _, row := range rows {
+		measure(row)
+	}
+
+	writeRow := func(row []string) {
+		var b strings.Builder
+		for i, cell := range row {
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-visibleWidth(cell)+2))
+			}
+		}
+		fmt.Fprintln(w, b.String())
+	}
+
+	bold := make([]string, len(headers))
+	for i, h := range headers {
+		bold[i] = paint(ansiBold, h)
+	}
+	writeRow(bold)
+	fmt.Fprintln(w, strings.Repeat("─", 60))
+	for _, row := range rows {
+		writeRow(row)
+	}
+}
@@ -0,0 +1,130 @@
+// cmd/srvrmgr/color_test.go
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withColor sets colorEnabled for the duration of a test.
+func withColor(t *testing.T, enabled bool) {
+	t.Helper()
+	prev := colorEnabled
+	colorEnabled = enabled
+	t.Cleanup(func() { colorEnabled = prev })
+}
+
+func TestParseColorFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantArgs []string
+		wantMode colorMode
+	}{
+		{[]string{"status"}, []string{"status"}, colorAuto},
+		{[]string{"--no-color", "status"}, []string{"status"}, colorNever},
+		{[]string{"history", "--force-color", "backup"}, []string{"history", "backup"}, colorAlways},
+		{[]string{"--force-color", "list", "--no-color"}, []string{"list"}, colorNever},
+		{[]string{"run", "x", "--", "--no-color"}, []string{"run", "x", "--", "--no-color"}, colorAuto},
+	}
+	for _, tt := range tests {
+		args, mode := parseColorFlags(tt.args)
+		if !reflect.DeepEqual(args, tt.wantArgs) || mode != tt.wantMode {
+			t.Errorf("parseColorFlags(%q) = %q, %v; want %q, %v", tt.args, args, mode, tt.wantArgs, tt.wantMode)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     colorMode
+		noColor  string
+		terminal bool
+		want     bool
+	}{
+		{"terminal", colorAuto, "", true, true},
+		{"piped", colorAuto, "", false, false},
+		{"NO_COLOR on terminal", colorAuto, "1", true, false},
+		{"--no-color on terminal", colorNever, "", true, false},
+		{"--force-color piped", colorAlways, "", false, true},
+		{"--force-color beats NO_COLOR", colorAlways, "1", false, true},
+	}
+	for _, tt := range tests {
+		if got := useColor(tt.mode, tt.noColor, tt.terminal); got != tt.want {
+			t.Errorf("%s: useColor() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsTerminal_Pipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if isTerminal(w) {
+		t.Error("isTerminal(pipe) = true, want false")
+	}
+}
+
+func TestColorStatus(t *testing.T) {
+	withColor(t, false)
+	if got := colorStatus("FAIL"); got != "FAIL" {
+		t.Errorf("colorStatus with color off = %q, want plain", got)
+	}
+
+	withColor(t, true)
+	for s, code := range map[string]string{
+		"ok":                   ansiGreen,
+		"failure (retry 2)":    ansiRed,
+		"skipped (paused)":     ansiYellow,
+		"success (dry run)":    ansiGreen,
+		"circuit_open":         ansiRed,
+		"no":                   ansiYellow,
+		"something-unexpected": "",
+	} {
+		want := s
+		if code != "" {
+			want = "\x1b[" + code + "m" + s + ansiReset
+		}
+		if got := colorStatus(s); got != want {
+			t.Errorf("colorStatus(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestWriteTable_AlignsPaintedCells(t *testing.T) {
+	rows := func() [][]string {
+		return [][]string{
+			{"backup", colorStatus("ok"), "-"},
+			{"a-much-longer-rule", colorStatus("FAIL"), "bad trigger"},
+		}
+	}
+
+	withColor(t, false)
+	var plain strings.Builder
+	writeTable(&plain, []string{"RULE", "STATUS", "WARNINGS"}, rows())
+	if strings.Contains(plain.String(), "\x1b[") {
+		t.Fatalf("color codes written with color off:\n%q", plain.String())
+	}
+	want := "RULE                STATUS  WARNINGS\n" +
+		strings.Repeat("─", 60) + "\n" +
+		"backup              ok      -\n" +
+		"a-much-longer-rule  FAIL    bad trigger\n"
+	if plain.String() != want {
+		t.Errorf("writeTable() =\n%s\nwant\n%s", plain.String(), want)
+	}
+
+	withColor(t, true)
+	var colored strings.Builder
+	writeTable(&colored, []string{"RULE", "STATUS", "WARNINGS"}, rows())
+	if !strings.Contains(colored.String(), "\x1b["+ansiRed+"mFAIL") {
+		t.Errorf("expected FAIL painted red:\n%q", colored.String())
+	}
+	if got := ansiEscape.ReplaceAllString(colored.String(), ""); got != want {
+		t.Errorf("colored table without codes =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+// cmd/srvrmgr/follow.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// historyPollInterval is how often history --follow polls /api/history when
+// the daemon has no history stream; tests shorten it.
+var historyPollInterval = 2 * time.Second
+
+// errNoHistoryStream means the daemon predates /api/history/stream.
+var errNoHistoryStream = errors.New("daemon has no history stream")
+
+// cmdHistoryFollow prints executions newer than lastID as they're recorded,
+// for rule or every rule, until interrupted.
+func cmdHistoryFollow(w io.Writer, rule string, lastID int64) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg := loadConfig()
+	baseURL := daemonBaseURL(cfg)
+	fmt.Fprintln(w, "\nFollowing new executions (Ctrl-C to stop)...")
+	return followHistory(ctx, w, baseURL, rule, lastID)
+}
+
+// followHistory streams new executions from /api/history/stream, falling
+// back to polling /api/history on daemons without it, until ctx is done.
+func followHistory(ctx context.Context, w io.Writer, baseURL, rule string, lastID int64) error {
+	err := streamHistory(ctx, w, baseURL, rule, lastID)
+	if errors.Is(err, errNoHistoryStream) {
+		err = pollHistory(ctx, w, baseURL, rule, lastID)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// streamHistory prints each record the daemon streams, skipping any at or
+// before lastID. Once subscribed, it first prints what was recorded since
+// lastID but before the subscription, which the stream doesn't replay.
+func streamHistory(ctx context.Context, w io.Writer, baseURL, rule string, lastID int64) error {
+	query := "/api/history/stream"
+	if rule != "" {
+		query += "?rule=" + url.QueryEscape(rule)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+query, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("querying daemon: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNoHistoryStream
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("daemon returned %s", resp.Status)
+	}
+
+	missed, err := fetchHistorySince(ctx, baseURL, rule, lastID)
+	if err != nil {
+		return err
+	}
+	shown := make(map[int64]bool, len(missed))
+	for _, rec := range missed {
+		fmt.Fprintln(w, followLine(rec))
+		shown[rec.ID] = true
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024) // records carry their output
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue // blank separator or keepalive comment
+		}
+		var rec historyRecord
+		if err := json.Unmarshal([]byte(data), &rec); err != nil {
+			return fmt.Errorf("parsing streamed record: %w", err)
+		}
+		if rec.ID > lastID && !shown[rec.ID] {
+			fmt.Fprintln(w, followLine(rec))
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("reading history stream: %w", err)
+	}
+	if ctx.Err() == nil {
+		return errors.New("daemon closed the history stream")
+	}
+	return nil
+}
+
+// pollHistory polls /api/history and prints records newer than the last one
+// printed, oldest first.
+func pollHistory(ctx context.Context, w io.Writer, baseURL, rule string, lastID int64) error {
+	ticker := time.NewTicker(historyPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		records, err := fetchHistorySince(ctx, baseURL, rule, lastID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, rec := range records {
+			fmt.Fprintln(w, followLine(rec))
+			lastID = rec.ID
+		}
+	}
+}
+
+// fetchHistorySince returns the records after lastID from /api/history,
+// oldest first.
+func fetchHistorySince(ctx context.Context, baseURL, rule string, lastID int64) ([]historyRecord, error) {
+	query := "/api/history?limit=500"
+	if rule != "" {
+		query += "&rule=" + url.QueryEscape(rule)
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, statusTimeout)
+	status, body, err := getWithContext(reqCtx, baseURL+query)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("querying daemon: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("daemon returned %d: %s", status, strings.TrimSpace(string(body)))
+	}
+	var records []historyRecord
+	if err := json.Unmarshal(body, &records); err != nil {
+		return nil, fmt.Errorf("parsing history response: %w", err)
+	}
+	slices.Reverse(records) // oldest first
+	return slices.DeleteFunc(records, func(rec historyRecord) bool { return rec.ID <= lastID }), nil
+}
+
+// followLine formats one execution for history --follow, which can't align
+// a table whose rows aren't known yet.
+func followLine(rec historyRecord) string {
+	started := rec.StartedAt
+	if t, err := time.Parse(time.RFC3339, rec.StartedAt); err == nil {
+		started = t.Local().Format("2006-01-02 15:04:05")
+	}
+	line := fmt.Sprintf("%s  #%d  %s  %s  %s  %s", started, rec.ID, rec.RuleName, rec.TriggerType,
+		colorStatus(rec.stateText()), formatDuration(rec.DurationMs))
+	if rec.Error != "" {
+		line += "  " + truncate(rec.Error, 60)
+	}
+	return line
+}
@@ -0,0 +1,190 @@
+// cmd/srvrmgr/follow_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to read while followHistory writes it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitFor polls until out contains want.
+func waitFor(t *testing.T, out *syncBuffer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("output = %q, want %q", out.String(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFollowHistory_PollsWithoutStream(t *testing.T) {
+	defer func(d time.Duration) { historyPollInterval = d }(historyPollInterval)
+	historyPollInterval = 10 * time.Millisecond
+
+	var mu sync.Mutex
+	records := `[{"ID":5,"RuleName":"backup","TriggerType":"scheduled","State":"success"}]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/history/stream":
+			http.NotFound(w, r) // an older daemon
+		case "/api/history":
+			if r.URL.Query().Get("rule") != "backup" {
+				t.Errorf("query = %q, want rule=backup", r.URL.RawQuery)
+			}
+			mu.Lock()
+			w.Write([]byte(records))
+			mu.Unlock()
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() { done <- followHistory(ctx, out, srv.URL, "backup", 5) }()
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	records = `[{"ID":7,"RuleName":"backup","TriggerType":"manual","State":"failure","Error":"disk full"},` +
+		`{"ID":6,"RuleName":"backup","TriggerType":"scheduled","State":"success","RetryAttempt":1},` + records[1:]
+	mu.Unlock()
+
+	waitFor(t, out, "#7")
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("followHistory() error = %v", err)
+	}
+
+	got := out.String()
+	if strings.Contains(got, "#5") {
+		t.Errorf("output = %q, want records up to lastID skipped", got)
+	}
+	if i, j := strings.Index(got, "#6"), strings.Index(got, "#7"); i < 0 || j < i {
+		t.Errorf("output = %q, want #6 then #7", got)
+	}
+	if strings.Count(got, "#7") != 1 {
+		t.Errorf("output = %q, want each record once", got)
+	}
+	if !strings.Contains(got, "success (retry 1)") || !strings.Contains(got, "failure") || !strings.Contains(got, "disk full") {
+		t.Errorf("output = %q, want states and errors", got)
+	}
+}
+
+func TestFollowHistory_Stream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/history" {
+			w.Write([]byte("[]"))
+			return
+		}
+		if r.URL.Path != "/api/history/stream" {
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, rec := range []historyRecord{
+			{ID: 3, RuleName: "backup", State: "success"}, // already shown
+			{ID: 4, RuleName: "backup", TriggerType: "manual", State: "skipped", SkipReason: "paused"},
+		} {
+			data, _ := json.Marshal(rec)
+			fmt.Fprintf(w, ": keepalive\n\ndata: %s\n\n", data)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() { done <- followHistory(ctx, out, srv.URL, "", 3) }()
+
+	waitFor(t, out, "#4")
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("followHistory() error = %v", err)
+	}
+	if got := out.String(); strings.Contains(got, "#3") || !strings.Contains(got, "#4  backup  manual  skipped (paused)") {
+		t.Errorf("output = %q, want only #4 with its skip reason", got)
+	}
+}
+
+// Records stored between the initial table and the subscription are fetched
+// once subscribed, and not printed again if the stream repeats them.
+func TestFollowHistory_StreamCatchesUp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/history" {
+			w.Write([]byte(`[{"ID":4,"RuleName":"backup","State":"success"},{"ID":3,"RuleName":"backup","State":"success"}]`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, rec := range []historyRecord{{ID: 4, RuleName: "backup", State: "success"}, {ID: 5, RuleName: "backup", State: "failure"}} {
+			data, _ := json.Marshal(rec)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() { done <- followHistory(ctx, out, srv.URL, "backup", 3) }()
+
+	waitFor(t, out, "#5")
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("followHistory() error = %v", err)
+	}
+	got := out.String()
+	if strings.Contains(got, "#3") || strings.Count(got, "#4") != 1 {
+		t.Errorf("output = %q, want #4 once and not #3", got)
+	}
+	if i, j := strings.Index(got, "#4"), strings.Index(got, "#5"); i < 0 || j < i {
+		t.Errorf("output = %q, want #4 then #5", got)
+	}
+}
+
+// The daemon's records decode into historyRecord with the fields follow
+// prints.
+func TestFollowLine(t *testing.T) {
+	started := time.Date(2025, 6, 1, 3, 0, 5, 0, time.Local)
+	data := fmt.Sprintf(`{"ID":12,"RuleName":"nightly","TriggerType":"scheduled","State":"failure","StartedAt":%q,"DurationMs":1500,"Error":"exit status 1","DryRun":true}`,
+		started.Format(time.RFC3339Nano))
+	var rec historyRecord
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		t.Fatal(err)
+	}
+	want := "2025-06-01 03:00:05  #12  nightly  scheduled  failure (dry run)  " + formatDuration(1500) + "  exit status 1"
+	if got := followLine(rec); got != want {
+		t.Errorf("followLine() = %q, want %q", got, want)
+	}
+}
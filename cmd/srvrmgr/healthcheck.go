@@ -0,0 +1,114 @@
+// cmd/srvrmgr/healthcheck.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Exit codes of srvrmgr healthcheck, for monitoring scripts.
+const (
+	healthExitOK          = 0
+	healthExitUnhealthy   = 1 // API answered but reported a problem, or hung
+	healthExitUnreachable = 2 // daemon is loaded but its API can't be reached
+	healthExitNotRunning  = 3
+)
+
+// checkHealth probes /health and /ready under baseURL, giving up after
+// timeout. running is only consulted when the API can't be reached, to tell
+// a stopped daemon from one whose API is down. It returns an exit code and a
+// one-line message.
+func checkHealth(baseURL string, timeout time.Duration, running func() bool) (int, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var health struct {
+		Status       string `json:"status"`
+		Uptime       string `json:"uptime"`
+		RulesEnabled int    `json:"rules_enabled"`
+	}
+	status, body, err := getWithContext(ctx, baseURL+"/health")
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return healthExitUnhealthy, fmt.Sprintf("unhealthy: no response from daemon within %s", timeout)
+		}
+		if !running() {
+			return healthExitNotRunning, "not running"
+		}
+		return healthExitUnreachable, fmt.Sprintf("running but API unreachable: %v", err)
+	}
+	if status != http.StatusOK {
+		return healthExitUnhealthy, fmt.Sprintf("unhealthy: /health returned %d", status)
+	}
+	if err := json.Unmarshal(body, &health); err != nil {
+		return healthExitUnhealthy, fmt.Sprintf("unhealthy: parsing /health response: %v", err)
+	}
+	if health.Status != "ok" {
+		return healthExitUnhealthy, fmt.Sprintf("unhealthy: status %q", health.Status)
+	}
+
+	var ready struct {
+		Ready  bool   `json:"ready"`
+		Reason string `json:"reason"`
+	}
+	status, body, err = getWithContext(ctx, baseURL+"/ready")
+	if err != nil {
+		return healthExitUnhealthy, fmt.Sprintf("unhealthy: /ready: %v", err)
+	}
+	json.Unmarshal(body, &ready)
+	if status != http.StatusOK || !ready.Ready {
+		if ready.Reason != "" {
+			return healthExitUnhealthy, "unhealthy: not ready (" + ready.Reason + ")"
+		}
+		return healthExitUnhealthy, fmt.Sprintf("unhealthy: /ready returned %d", status)
+	}
+
+	return healthExitOK, fmt.Sprintf("healthy: up %s, %d rules enabled", health.Uptime, health.RulesEnabled)
+}
+
+func getWithContext(ctx context.Context, url string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+// cmdHealthcheck prints one line about the daemon's health and returns the
+// process exit code (see the healthExit constants).
+func cmdHealthcheck(args []string) (int, error) {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 5*time.Second, "how long to wait for the daemon to answer")
+	if err := fs.Parse(args); err != nil {
+		return 0, err
+	}
+	if *timeout <= 0 {
+		return 0, fmt.Errorf("--timeout must be positive, got %s", *timeout)
+	}
+
+	cfg := loadConfig()
+	baseURL := daemonBaseURL(cfg)
+	code, msg := checkHealth(baseURL, *timeout, isRunning)
+	if code == healthExitOK {
+		fmt.Println(msg)
+	} else {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	return code, nil
+}
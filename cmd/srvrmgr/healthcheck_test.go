@@ -0,0 +1,122 @@
+// cmd/srvrmgr/healthcheck_test.go
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubDaemon serves fixed /health and /ready responses.
+func stubDaemon(t *testing.T, healthStatus int, health string, readyStatus int, ready string) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(healthStatus)
+		w.Write([]byte(health))
+	})
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(readyStatus)
+		w.Write([]byte(ready))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestCheckHealth(t *testing.T) {
+	const okHealth = `{"status":"ok","uptime":"1h0m0s","rules_enabled":3}`
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name     string
+		baseURL  string
+		running  bool
+		wantCode int
+		wantMsg  string
+	}{
+		{
+			name:     "healthy",
+			baseURL:  stubDaemon(t, 200, okHealth, 200, `{"ready":true}`),
+			wantCode: healthExitOK,
+			wantMsg:  "healthy: up 1h0m0s, 3 rules enabled",
+		},
+		{
+			name:     "not running",
+			baseURL:  closed.URL,
+			wantCode: healthExitNotRunning,
+			wantMsg:  "not running",
+		},
+		{
+			name:     "api unreachable",
+			baseURL:  closed.URL,
+			running:  true,
+			wantCode: healthExitUnreachable,
+			wantMsg:  "running but API unreachable",
+		},
+		{
+			name:     "health error status",
+			baseURL:  stubDaemon(t, 500, "boom", 200, `{"ready":true}`),
+			wantCode: healthExitUnhealthy,
+			wantMsg:  "/health returned 500",
+		},
+		{
+			name:     "health status not ok",
+			baseURL:  stubDaemon(t, 200, `{"status":"degraded"}`, 200, `{"ready":true}`),
+			wantCode: healthExitUnhealthy,
+			wantMsg:  `status "degraded"`,
+		},
+		{
+			name:     "not ready",
+			baseURL:  stubDaemon(t, 200, okHealth, 503, `{"ready":false,"reason":"starting"}`),
+			wantCode: healthExitUnhealthy,
+			wantMsg:  "not ready (starting)",
+		},
+		{
+			name:     "ready missing",
+			baseURL:  stubDaemon(t, 200, okHealth, 404, "404 page not found"),
+			wantCode: healthExitUnhealthy,
+			wantMsg:  "/ready returned 404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, msg := checkHealth(tt.baseURL, time.Second, func() bool { return tt.running })
+			if code != tt.wantCode {
+				t.Errorf("code = %d, want %d (msg %q)", code, tt.wantCode, msg)
+			}
+			if !strings.Contains(msg, tt.wantMsg) {
+				t.Errorf("msg = %q, want it to contain %q", msg, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestCheckHealth_TimeoutIsUnhealthy(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	code, msg := checkHealth(srv.URL, 50*time.Millisecond, func() bool {
+		t.Error("running should not be consulted when the API answered")
+		return true
+	})
+	if code != healthExitUnhealthy || !strings.Contains(msg, "no response") {
+		t.Errorf("checkHealth = %d, %q; want unhealthy timeout", code, msg)
+	}
+}
+
+func TestCmdHealthcheck_RejectsBadTimeout(t *testing.T) {
+	if _, err := cmdHealthcheck([]string{"--timeout", "0s"}); err == nil {
+		t.Error("expected error for zero timeout")
+	}
+}
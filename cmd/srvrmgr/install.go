@@ -0,0 +1,244 @@
+// cmd/srvrmgr/install.go
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+)
+
+// defaultDaemonPath is PATH for the daemon and the commands its rules run,
+// including both Homebrew prefixes.
+const defaultDaemonPath = "/opt/homebrew/bin:/usr/local/bin:/usr/bin:/bin:/usr/sbin:/sbin"
+
+// plistParams fills launchdPlistTemplate.
+type plistParams struct {
+	Label      string
+	Binary     string // absolute path to srvrmgrd
+	ConfigPath string
+	Path       string // PATH environment variable
+	StdoutPath string
+	StderrPath string
+}
+
+// launchdPlistTemplate runs srvrmgrd at boot and restarts it if it exits
+// abnormally. The daemon writes its own rotated log; stdout and stderr only
+// catch output from before logging starts, such as panics.
+var launchdPlistTemplate = template.Must(template.New("plist").Funcs(template.FuncMap{
+	"xml": xmlEscape,
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+    <key>Label</key>
+    <string>{{xml .Label}}</string>
+
+    <key>ProgramArguments</key>
+    <array>
+        <string>{{xml .Binary}}</string>
+    </array>
+
+    <key>RunAtLoad</key>
+    <true/>
+
+    <key>KeepAlive</key>
+    <dict>
+        <key>SuccessfulExit</key>
+        <false/>
+    </dict>
+
+    <key>ThrottleInterval</key>
+    <integer>10</integer>
+
+    <key>StandardOutPath</key>
+    <string>{{xml .StdoutPath}}</string>
+
+    <key>StandardErrorPath</key>
+    <string>{{xml .StderrPath}}</string>
+
+    <key>EnvironmentVariables</key>
+    <dict>
+        <key>PATH</key>
+        <string>{{xml .Path}}</string>
+        <key>SRVRMGR_CONFIG</key>
+        <string>{{xml .ConfigPath}}</string>
+    </dict>
+</dict>
+</plist>
+`))
+
+func xmlEscape(s string) (string, error) {
+	var b strings.Builder
+	if err := xml.EscapeText(&b, []byte(s)); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// launchdPlistXML renders the launchd plist for p.
+func launchdPlistXML(p plistParams) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := launchdPlistTemplate.Execute(&buf, p); err != nil {
+		return nil, fmt.Errorf("rendering plist: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// plistPathFor returns where launchd looks for a daemon with the given label.
+func plistPathFor(label string) string {
+	return filepath.Join("/Library/LaunchDaemons", label+".plist")
+}
+
+// defaultDaemonBinary finds srvrmgrd next to this executable, then on PATH.
+func defaultDaemonBinary() string {
+	if exe, err := os.Executable(); err == nil {
+		sibling := filepath.Join(filepath.Dir(exe), "srvrmgrd")
+		if _, err := os.Stat(sibling); err == nil {
+			return sibling
+		}
+	}
+	if path, err := exec.LookPath("srvrmgrd"); err == nil {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+	}
+	return "/usr/local/bin/srvrmgrd"
+}
+
+func cmdInstall(args []string) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	name := fs.String("name", launchdLabel, "launchd label; the plist is written to /Library/LaunchDaemons/<name>.plist")
+	binary := fs.String("binary", defaultDaemonBinary(), "path to the srvrmgrd binary")
+	configPath := fs.String("config", filepath.Join(defaultConfigDir, "config.yaml"), "config file passed to the daemon as SRVRMGR_CONFIG")
+	force := fs.Bool("force", false, "overwrite an existing plist")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *name == "" || strings.ContainsAny(*name, "/ ") {
+		return fmt.Errorf("invalid --name %q: must be a launchd label such as %s", *name, launchdLabel)
+	}
+	if !filepath.IsAbs(*binary) {
+		return fmt.Errorf("--binary must be an absolute path, got %q", *binary)
+	}
+	if _, err := os.Stat(*binary); err != nil {
+		return fmt.Errorf("daemon binary: %w", err)
+	}
+
+	plistPath := plistPathFor(*name)
+	if _, err := os.Stat(plistPath); err == nil && !*force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", plistPath)
+	}
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("install must be run as root (use sudo)")
+	}
+
+	// launchd's output goes beside the daemon's own log
+	_, logDir := config.DataPaths(*configPath)
+	data, err := launchdPlistXML(plistParams{
+		Label:      *name,
+		Binary:     *binary,
+		ConfigPath: *configPath,
+		Path:       defaultDaemonPath,
+		StdoutPath: filepath.Join(logDir, "srvrmgrd.stdout.log"),
+		StderrPath: filepath.Join(logDir, "srvrmgrd.stderr.log"),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writePlist(plistPath, data, lintPlist); err != nil {
+		return err
+	}
+
+	fmt.Println("Wrote", plistPath)
+	if *name == launchdLabel {
+		fmt.Println("Run 'srvrmgr start' to load the daemon.")
+	} else {
+		fmt.Printf("Load it with: sudo launchctl load %s\n", plistPath)
+	}
+	return nil
+}
+
+// plistConfigPath returns the SRVRMGR_CONFIG that the launchd plist at path
+// passes the daemon, or "" if the plist can't be read or sets none.
+func plistConfigPath(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var key string
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || (start.Name.Local != "key" && start.Name.Local != "string") {
+			continue
+		}
+		var text string
+		if err := dec.DecodeElement(&text, &start); err != nil {
+			return ""
+		}
+		switch {
+		case start.Name.Local == "key":
+			key = text
+		case key == "SRVRMGR_CONFIG":
+			return text
+		default:
+			key = ""
+		}
+	}
+}
+
+// writePlist writes data to path through a temporary file beside it, which is
+// only renamed into place once lint accepts it, so a failed install never
+// leaves an invalid plist for launchd to find.
+func writePlist(path string, data []byte, lint func(path string) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("writing plist: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	// launchd refuses plists that are writable by anyone but root
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("writing plist: %w", err)
+	}
+	if err := lint(tmp.Name()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing plist: %w", err)
+	}
+	return nil
+}
+
+// lintPlist checks the plist at path with plutil, where it is available.
+func lintPlist(path string) error {
+	plutil, err := exec.LookPath("plutil")
+	if err != nil {
+		return nil
+	}
+	if out, err := exec.Command(plutil, "-lint", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("plist failed validation: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// cmd/srvrmgr/install_test.go
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLaunchdPlistXML(t *testing.T) {
+	data, err := launchdPlistXML(plistParams{
+		Label:      "com.example.srvrmgr",
+		Binary:     "/opt/srvrmgr & co/bin/srvrmgrd",
+		ConfigPath: "/etc/srvrmgr/config.yaml",
+		Path:       defaultDaemonPath,
+		StdoutPath: "/var/log/srvrmgrd.stdout.log",
+		StderrPath: "/var/log/srvrmgrd.stderr.log",
+	})
+	if err != nil {
+		t.Fatalf("launchdPlistXML() error = %v", err)
+	}
+
+	// Well-formed XML, with special characters escaped
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = true
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("plist is not well-formed XML: %v\n%s", err, data)
+		}
+	}
+
+	got := string(data)
+	for _, want := range []string{
+		"<key>Label</key>\n    <string>com.example.srvrmgr</string>",
+		"<string>/opt/srvrmgr &amp; co/bin/srvrmgrd</string>",
+		"<key>KeepAlive</key>",
+		"<key>RunAtLoad</key>",
+		"<key>StandardOutPath</key>\n    <string>/var/log/srvrmgrd.stdout.log</string>",
+		"<key>StandardErrorPath</key>\n    <string>/var/log/srvrmgrd.stderr.log</string>",
+		"<key>SRVRMGR_CONFIG</key>\n        <string>/etc/srvrmgr/config.yaml</string>",
+		"<key>PATH</key>\n        <string>" + defaultDaemonPath + "</string>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("plist missing %q:\n%s", want, got)
+		}
+	}
+	if !strings.Contains(defaultDaemonPath, "/opt/homebrew/bin") {
+		t.Error("daemon PATH should include Homebrew")
+	}
+}
+
+func TestPlistConfigPath(t *testing.T) {
+	data, err := launchdPlistXML(plistParams{
+		Label:      launchdLabel,
+		Binary:     "/usr/local/bin/srvrmgrd",
+		ConfigPath: "/etc/srvrmgr & co/config.yaml",
+		Path:       defaultDaemonPath,
+		StdoutPath: "/var/log/srvrmgrd.stdout.log",
+		StderrPath: "/var/log/srvrmgrd.stderr.log",
+	})
+	if err != nil {
+		t.Fatalf("launchdPlistXML() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "com.srvrmgr.daemon.plist")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := plistConfigPath(path); got != "/etc/srvrmgr & co/config.yaml" {
+		t.Errorf("plistConfigPath() = %q, want the installed SRVRMGR_CONFIG", got)
+	}
+	if got := plistConfigPath(filepath.Join(t.TempDir(), "missing.plist")); got != "" {
+		t.Errorf("plistConfigPath(missing) = %q, want empty", got)
+	}
+}
+
+func TestPlistPathFor(t *testing.T) {
+	if got := plistPathFor(launchdLabel); got != launchdPlist {
+		t.Errorf("plistPathFor(default) = %q, want %q", got, launchdPlist)
+	}
+}
+
+func TestCmdInstall_RejectsBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--name", "bad/label", "--binary", "/bin/sh"},
+		{"--binary", "relative/srvrmgrd"},
+		{"--binary", "/nonexistent/srvrmgrd"},
+	} {
+		if err := cmdInstall(args); err == nil {
+			t.Errorf("cmdInstall(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestWritePlist(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "com.srvrmgr.daemon.plist")
+
+	// A plist that fails lint is not installed, and nothing is left behind
+	rejected := errors.New("plist failed validation")
+	if err := writePlist(path, []byte("<plist>"), func(string) error { return rejected }); !errors.Is(err, rejected) {
+		t.Fatalf("writePlist() error = %v, want the lint error", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files left after a failed lint: %v", entries)
+	}
+
+	var linted string
+	lint := func(p string) error {
+		linted = p
+		return nil
+	}
+	if err := writePlist(path, []byte("<plist/>"), lint); err != nil {
+		t.Fatalf("writePlist() error = %v", err)
+	}
+	if linted == path {
+		t.Error("lint ran on the final path, want the temporary file")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("plist mode = %v, want 0644", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %v, want only the plist", entries)
+	}
+}
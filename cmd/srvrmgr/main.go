@@ -2,45 +2,60 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
-	"text/tabwriter"
+	"sync"
 	"time"
 
 	"github.com/colebrumley/srvrmgr/internal/config"
 	"github.com/colebrumley/srvrmgr/internal/daemon"
+	"github.com/colebrumley/srvrmgr/internal/logging"
+	"github.com/colebrumley/srvrmgr/internal/state"
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	defaultConfigDir = "/Library/Application Support/srvrmgr"
-	defaultLogsDir   = "/Library/Logs/srvrmgr"
+	defaultConfigDir = config.SystemConfigDir
+	defaultLogsDir   = config.SystemLogDir
 	launchdLabel     = "com.srvrmgr.daemon"
 	launchdPlist     = "/Library/LaunchDaemons/com.srvrmgr.daemon.plist"
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	args, mode := parseColorFlags(os.Args[1:])
+	colorEnabled = useColor(mode, os.Getenv("NO_COLOR"), isTerminal(os.Stdout))
+
+	if len(args) < 1 {
 		printUsage()
 		os.Exit(1)
 	}
 
-	cmd := os.Args[1]
-	args := os.Args[2:]
+	cmd := args[0]
+	args = args[1:]
 
 	var err error
 	switch cmd {
 	case "init":
 		err = cmdInit()
+	case "install":
+		err = cmdInstall(args)
 	case "start":
 		err = cmdStart()
 	case "stop":
@@ -48,17 +63,41 @@ func main() {
 	case "restart":
 		err = cmdRestart()
 	case "status":
-		err = cmdStatus()
+		err = cmdStatus(args)
+	case "healthcheck":
+		var code int
+		code, err = cmdHealthcheck(args)
+		if err == nil && code != healthExitOK {
+			os.Exit(code)
+		}
 	case "list":
 		err = cmdList()
+	case "triggers":
+		err = cmdTriggers()
 	case "validate":
 		err = cmdValidate(args)
+	case "schema":
+		err = cmdSchema(os.Stdout, args)
 	case "run":
 		err = cmdRun(args)
+	case "replay":
+		err = cmdReplay(args)
+	case "show":
+		err = cmdShow(args)
 	case "logs":
 		err = cmdLogs(args)
 	case "history":
 		err = cmdHistory(args)
+	case "reliability":
+		err = cmdReliability(args)
+	case "memory":
+		err = cmdMemory(args)
+	case "pause":
+		err = cmdPause()
+	case "resume":
+		err = cmdResume()
+	case "enable":
+		err = cmdEnable(args)
 	case "uninstall":
 		err = cmdUninstall(args)
 	case "help", "-h", "--help":
@@ -82,22 +121,57 @@ Usage: srvrmgr <command> [options]
 
 Commands:
   init              Initialize configuration directories
+  install           Write the launchd plist for the daemon (--name, --binary, --config)
   start             Start the daemon
   stop              Stop the daemon
   restart           Restart the daemon
-  status            Show daemon status
+  status            Show daemon status (--json for health, readiness and rules in one document)
+  healthcheck       Exit 0 if the daemon is healthy; 1 unhealthy, 2 API unreachable, 3 not running (--timeout)
   list              List all rules
-  validate [rule]   Validate rules
-  run <rule>        Manually run a rule
-  logs [rule]       View logs
-  history [rule]    View execution history
-  uninstall         Uninstall srvrmgr (stop daemon, remove plist)`)
+  triggers          Show the daemon's running triggers, with webhook paths and next scheduled runs
+  validate [rule]   Validate rules (--serial or --parallel N, --json, --watch to re-validate on changes)
+  schema [rule|config] Print a JSON Schema for rule files or config.yaml
+  run <rule>        Manually run a rule, on the daemon if it's running (--explain to show gates without running, --event E to simulate a lifecycle event, --no-deps/--force to skip dependency checks)
+  run --all --type T  Run every enabled rule with trigger type T
+  replay <id>       Re-run a past execution with its original trigger type and event data
+  show <id>         Show one execution's output and stderr
+  logs [rule]       View the daemon log, or a rule's lines of it (--tail N; --grep, --level, -i to filter)
+  history [rule]    View execution history (--group-by rule for per-rule totals, --output csv [--full], --since-boot, --follow/-f for new executions as they happen)
+  reliability [rule] Show success rate and MTBF per rule
+  memory stats      Show memory counts by category, database size and embedding coverage
+  memory purge      Delete memories (--category, --older-than 30d; asks first unless --yes)
+  pause             Pause all rule executions (daemon keeps running)
+  resume            Resume rule executions
+  enable <rule>     Re-arm a rule stopped by the circuit breaker
+  uninstall         Uninstall srvrmgr (stop daemon, remove plist; --dry-run to preview)
+
+Global options:
+  --no-color        Never color output (also set by NO_COLOR)
+  --force-color     Color output even when it isn't a terminal
+
+Environment:
+  SRVRMGR_CONFIG    The daemon's config file (default: the one the installed plist
+                    passes it); its history and logs are read from beside it`)
 }
 
 // --- Helpers ---
 
+// daemonConfigPath returns the daemon's config file, as the daemon finds it:
+// SRVRMGR_CONFIG, else the one the installed launchd plist passes it (see
+// install --config), else the default. Its state database and logs follow
+// it (see config.DataPaths).
+func daemonConfigPath() string {
+	if path := os.Getenv("SRVRMGR_CONFIG"); path != "" {
+		return path
+	}
+	if path := plistConfigPath(launchdPlist); path != "" {
+		return path
+	}
+	return filepath.Join(defaultConfigDir, "config.yaml")
+}
+
 func loadConfig() *config.Global {
-	cfg, err := config.LoadGlobal(filepath.Join(defaultConfigDir, "config.yaml"))
+	cfg, err := config.LoadGlobal(daemonConfigPath())
 	if err != nil {
 		return &config.Global{
 			Daemon: config.DaemonConfig{
@@ -109,9 +183,14 @@ func loadConfig() *config.Global {
 	return cfg
 }
 
+// daemonBaseURL returns the URL of the daemon's HTTP server, bracketing an
+// IPv6 listen address.
+func daemonBaseURL(cfg *config.Global) string {
+	return "http://" + net.JoinHostPort(cfg.Daemon.WebhookListenAddress, strconv.Itoa(cfg.Daemon.WebhookListenPort))
+}
+
 func queryDaemon(path string) ([]byte, error) {
-	cfg := loadConfig()
-	url := fmt.Sprintf("http://%s:%d%s", cfg.Daemon.WebhookListenAddress, cfg.Daemon.WebhookListenPort, path)
+	url := daemonBaseURL(loadConfig()) + path
 	client := &http.Client{Timeout: 2 * time.Second}
 	resp, err := client.Get(url)
 	if err != nil {
@@ -121,14 +200,26 @@ func queryDaemon(path string) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-func printTable(headers []string, rows [][]string) {
-	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(tw, strings.Join(headers, "\t"))
-	fmt.Fprintln(tw, strings.Repeat("─", 60))
-	for _, row := range rows {
-		fmt.Fprintln(tw, strings.Join(row, "\t"))
+func postDaemon(path string) ([]byte, error) {
+	url := daemonBaseURL(loadConfig()) + path
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Post(url, "application/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
 	}
-	tw.Flush()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("daemon returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func printTable(headers []string, rows [][]string) {
+	writeTable(os.Stdout, headers, rows)
 }
 
 func truncate(s string, max int) string {
@@ -193,7 +284,6 @@ func cmdInit() error {
 		defaultConfigDir,
 		rulesDir,
 		defaultLogsDir,
-		filepath.Join(defaultLogsDir, "rules"),
 	}
 
 	for _, dir := range dirs {
@@ -243,6 +333,9 @@ func cmdInit() error {
 	}
 
 	fmt.Println("\nInitialization complete. Add rules to:", filepath.Join(defaultConfigDir, "rules"))
+	if _, err := os.Stat(launchdPlist); os.IsNotExist(err) {
+		fmt.Println("Then run 'sudo srvrmgr install' to register the daemon with launchd.")
+	}
 	return nil
 }
 
@@ -253,6 +346,10 @@ func cmdStart() error {
 		return nil
 	}
 
+	if _, err := os.Stat(launchdPlist); os.IsNotExist(err) {
+		return fmt.Errorf("%s not found; run 'sudo srvrmgr install' first", launchdPlist)
+	}
+
 	// Load the daemon via launchctl
 	cmd := exec.Command("sudo", "launchctl", "load", launchdPlist)
 	cmd.Stdout = os.Stdout
@@ -291,7 +388,16 @@ func cmdRestart() error {
 	return cmdStart()
 }
 
-func cmdStatus() error {
+func cmdStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "print health, readiness and rule states as one JSON document")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *jsonOut {
+		return cmdStatusJSON(os.Stdout)
+	}
+
 	if isRunning() {
 		body, err := queryDaemon("/health")
 		if err != nil {
@@ -311,6 +417,7 @@ func cmdStatus() error {
 			Uptime       string `json:"uptime"`
 			RulesLoaded  int    `json:"rules_loaded"`
 			RulesEnabled int    `json:"rules_enabled"`
+			Paused       bool   `json:"paused"`
 		}
 		if err := json.Unmarshal(body, &health); err != nil {
 			return fmt.Errorf("parsing health response: %w", err)
@@ -319,14 +426,18 @@ func cmdStatus() error {
 		fmt.Println("Daemon:  running")
 		fmt.Printf("Uptime:  %s\n", health.Uptime)
 		fmt.Printf("Rules:   %d loaded, %d enabled\n", health.RulesLoaded, health.RulesEnabled)
+		if health.Paused {
+			fmt.Println("Paused:  yes (events are logged but not executed; run 'srvrmgr resume')")
+		}
 
 		body, err = queryDaemon("/api/rules")
 		if err == nil {
 			var ruleStates []struct {
-				Name      string `json:"name"`
-				Enabled   bool   `json:"enabled"`
-				DryRun    bool   `json:"dry_run"`
-				LastState string `json:"last_state"`
+				Name        string `json:"name"`
+				Enabled     bool   `json:"enabled"`
+				DryRun      bool   `json:"dry_run"`
+				LastState   string `json:"last_state"`
+				CircuitOpen bool   `json:"circuit_open"`
 			}
 			if json.Unmarshal(body, &ruleStates) == nil && len(ruleStates) > 0 {
 				fmt.Println()
@@ -340,7 +451,11 @@ func cmdStatus() error {
 					if lastState == "" {
 						lastState = "-"
 					}
-					rows = append(rows, []string{r.Name, boolYesNo(r.Enabled), dryRun, lastState})
+					lastState = colorStatus(lastState)
+					if r.CircuitOpen {
+						lastState += paint(ansiRed, " (circuit open)")
+					}
+					rows = append(rows, []string{r.Name, colorStatus(boolYesNo(r.Enabled)), dryRun, lastState})
 				}
 				printTable([]string{"NAME", "ENABLED", "DRY RUN", "LAST STATE"}, rows)
 			}
@@ -390,7 +505,7 @@ func cmdList() error {
 		}
 		rows = append(rows, []string{
 			truncate(rule.Name, 30),
-			boolYesNo(rule.Enabled),
+			colorStatus(boolYesNo(rule.Enabled)),
 			rule.Trigger.Type,
 			triggerDetail(rule.Trigger),
 			boolYesNo(rule.DryRun),
@@ -403,35 +518,135 @@ func cmdList() error {
 	return nil
 }
 
+func cmdTriggers() error {
+	if !isRunning() {
+		return fmt.Errorf("daemon is not running")
+	}
+	body, err := queryDaemon("/api/triggers")
+	if err != nil {
+		return fmt.Errorf("querying daemon: %w", err)
+	}
+	return writeTriggers(os.Stdout, body, time.Now())
+}
+
+// writeTriggers renders an /api/triggers response as a table, with each next
+// scheduled run relative to now.
+func writeTriggers(w io.Writer, body []byte, now time.Time) error {
+	var triggers []struct {
+		Rule        string     `json:"rule"`
+		Type        string     `json:"type"`
+		WebhookPath string     `json:"webhook_path"`
+		NextRun     *time.Time `json:"next_run"`
+	}
+	if err := json.Unmarshal(body, &triggers); err != nil {
+		return fmt.Errorf("parsing triggers response: %w", err)
+	}
+	if len(triggers) == 0 {
+		fmt.Fprintln(w, "No triggers running")
+		return nil
+	}
+
+	var rows [][]string
+	for _, t := range triggers {
+		path, next := "-", "-"
+		if t.WebhookPath != "" {
+			path = t.WebhookPath
+		}
+		if t.NextRun != nil {
+			next = fmt.Sprintf("%s (in %s)", t.NextRun.Local().Format("2006-01-02 15:04:05"), t.NextRun.Sub(now).Round(time.Second))
+		}
+		rows = append(rows, []string{truncate(t.Rule, 30), t.Type, path, next})
+	}
+	writeTable(w, []string{"RULE", "TYPE", "WEBHOOK PATH", "NEXT RUN"}, rows)
+	return nil
+}
+
 func cmdValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	parallel := fs.Int("parallel", runtime.NumCPU(), "number of rules to validate concurrently")
+	serial := fs.Bool("serial", false, "validate rules one at a time (same as --parallel 1)")
+	jsonOut := fs.Bool("json", false, "print results as JSON, with an error code and field for each invalid rule")
+	watch := fs.Bool("watch", false, "re-validate all rules whenever a rule file changes, until interrupted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// Allow flags after the rule name too: srvrmgr validate <rule> --json
+	name := fs.Arg(0)
+	if name != "" {
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+	}
+
+	if *watch && (name != "" || *jsonOut) {
+		return fmt.Errorf("--watch validates every rule as a table; it can't be combined with a rule name or --json")
+	}
+
 	dir, err := rulesDir()
 	if err != nil {
 		return err
 	}
 
-	if len(args) > 0 {
-		return cmdValidateOne(dir, args[0])
+	if name != "" {
+		if *jsonOut {
+			return cmdValidateOneJSON(os.Stdout, dir, name)
+		}
+		return cmdValidateOne(os.Stdout, dir, name)
 	}
-	return cmdValidateAll(dir)
+
+	workers := *parallel
+	if *serial || workers < 1 {
+		workers = 1
+	}
+	if *watch {
+		return cmdValidateWatch(os.Stdout, dir, workers)
+	}
+	if *jsonOut {
+		return cmdValidateAllJSON(os.Stdout, dir, workers)
+	}
+	return cmdValidateAll(os.Stdout, dir, workers)
 }
 
-func cmdValidateOne(dir, name string) error {
-	// Try .yaml then .yml
-	rulePath := filepath.Join(dir, name+".yaml")
-	if _, err := os.Stat(rulePath); os.IsNotExist(err) {
-		rulePath = filepath.Join(dir, name+".yml")
-		if _, err := os.Stat(rulePath); os.IsNotExist(err) {
-			return fmt.Errorf("rule file not found: %s.yaml or %s.yml", name, name)
+// findRuleFile returns the rule file dir/name.<ext>, trying each rule file
+// extension in order, or "" if there is none.
+func findRuleFile(dir, name string) string {
+	for _, ext := range config.RuleFileExtensions {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
 		}
 	}
+	return ""
+}
 
-	rule, err := config.LoadRule(rulePath)
-	if err != nil {
-		fmt.Printf("Rule '%s' is INVALID: %v\n", name, err)
-		return err
+// cmdValidateOne validates the rules in the file dir/name.<ext>, printing a
+// summary of each valid rule and each invalid rule's error.
+func cmdValidateOne(w io.Writer, dir, name string) error {
+	rulePath := findRuleFile(dir, name)
+	if rulePath == "" {
+		return fmt.Errorf("rule file not found: %s{%s}", name, strings.Join(config.RuleFileExtensions, ","))
+	}
+
+	rules, loadErr := config.LoadRuleFile(rulePath)
+	for _, err := range splitErrors(loadErr) {
+		fmt.Fprintf(w, "Rule '%s' is INVALID: %v\n", name, err)
+	}
+
+	// Run global validation for warnings
+	global := loadConfig()
+	allRules := dirRules(dir)
+	for i, rule := range rules {
+		if i > 0 || loadErr != nil {
+			fmt.Fprintln(w)
+		}
+		printRuleSummary(w, rule, config.ValidateRuleWithGlobal(rule, global, allRules))
 	}
+	return loadErr
+}
 
-	fmt.Printf("Rule '%s' is valid\n", name)
+// printRuleSummary prints a valid rule's effective settings and warnings.
+func printRuleSummary(w io.Writer, rule *config.Rule, warnings []string) {
+	fmt.Fprintf(w, "Rule '%s' is valid\n", rule.Name)
 
 	model := rule.Claude.Model
 	if model == "" {
@@ -463,104 +678,286 @@ func cmdValidateOne(dir, name string) error {
 		retry = fmt.Sprintf("%d attempts, %ds delay", attempts, delay)
 	}
 
-	fmt.Println()
-	fmt.Printf("  Trigger:      %s (%s)\n", rule.Trigger.Type, triggerDetail(rule.Trigger))
-	fmt.Printf("  Model:        %s\n", model)
-	fmt.Printf("  Dry run:      %s\n", boolYesNo(rule.DryRun))
-	fmt.Printf("  Timeout:      %ds\n", timeout)
-	fmt.Printf("  Max actions:  %d\n", maxActions)
-	fmt.Printf("  Depends on:   %s\n", dependsOn)
-	fmt.Printf("  Triggers:     %s\n", triggers)
-	fmt.Printf("  Retry:        %s\n", retry)
-
-	// Run global validation for warnings
-	global := loadConfig()
-	allRulesSlice, _ := config.LoadRulesDir(dir)
-	allRules := make(map[string]*config.Rule)
-	for _, r := range allRulesSlice {
-		allRules[r.Name] = r
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  Trigger:      %s (%s)\n", rule.Trigger.Type, triggerDetail(rule.Trigger))
+	fmt.Fprintf(w, "  Model:        %s\n", model)
+	fmt.Fprintf(w, "  Dry run:      %s\n", boolYesNo(rule.DryRun))
+	fmt.Fprintf(w, "  Timeout:      %ds\n", timeout)
+	fmt.Fprintf(w, "  Max actions:  %d\n", maxActions)
+	fmt.Fprintf(w, "  Depends on:   %s\n", dependsOn)
+	fmt.Fprintf(w, "  Triggers:     %s\n", triggers)
+	fmt.Fprintf(w, "  Retry:        %s\n", retry)
+	if rule.When != "" {
+		fmt.Fprintf(w, "  When:         %s\n", rule.When)
 	}
-	warnings := config.ValidateRuleWithGlobal(rule, global, allRules)
+
 	if len(warnings) > 0 {
-		fmt.Println()
-		for _, w := range warnings {
-			fmt.Printf("  Warning: %s\n", w)
+		fmt.Fprintln(w)
+		for _, warning := range warnings {
+			fmt.Fprintf(w, "  Warning: %s\n", warning)
+		}
+	}
+}
+
+// dirRules loads the valid rules in dir by name, as context for
+// ValidateRuleWithGlobal.
+func dirRules(dir string) map[string]*config.Rule {
+	rules, _ := config.LoadRulesDir(dir)
+	byName := make(map[string]*config.Rule, len(rules))
+	for _, r := range rules {
+		byName[r.Name] = r
+	}
+	return byName
+}
+
+// ruleResult is the validation result for one rule, as printed by
+// validate --json.
+type ruleResult struct {
+	Rule     string   `json:"rule"` // file name without extension if the rule is invalid
+	Valid    bool     `json:"valid"`
+	Code     string   `json:"code,omitempty"`  // see config.ErrorCode
+	Field    string   `json:"field,omitempty"` // rule key at fault, when known
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// validateReport is the output of validate --json.
+type validateReport struct {
+	Valid   int          `json:"valid"`
+	Invalid int          `json:"invalid"`
+	Rules   []ruleResult `json:"rules"`
+}
+
+func invalidRule(name string, err error) ruleResult {
+	r := ruleResult{Rule: name, Code: config.ErrorCode(err), Error: err.Error()}
+	var verr *config.ValidationError
+	if errors.As(err, &verr) {
+		r.Field = verr.Field
+	}
+	return r
+}
+
+func newValidateReport(results []ruleResult) validateReport {
+	report := validateReport{Rules: results}
+	for _, r := range results {
+		if r.Valid {
+			report.Valid++
+		} else {
+			report.Invalid++
 		}
 	}
+	return report
+}
 
+// writeValidateReport writes report as indented JSON, returning an error if
+// any rule is invalid.
+func writeValidateReport(w io.Writer, report validateReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if report.Invalid > 0 {
+		total := report.Valid + report.Invalid
+		return fmt.Errorf("%d of %d rules are invalid", report.Invalid, total)
+	}
 	return nil
 }
 
-func cmdValidateAll(dir string) error {
-	entries, err := os.ReadDir(dir)
+func cmdValidateOneJSON(w io.Writer, dir, name string) error {
+	rulePath := findRuleFile(dir, name)
+	if rulePath == "" {
+		return fmt.Errorf("rule file not found: %s{%s}", name, strings.Join(config.RuleFileExtensions, ","))
+	}
+	rules, err := config.LoadRuleFile(rulePath)
+	f := ruleFile{name: name, rules: rules, err: err}
+	return writeValidateReport(w, newValidateReport(checkRuleFile(f, loadConfig(), dirRules(dir))))
+}
+
+func cmdValidateAllJSON(w io.Writer, dir string, workers int) error {
+	files, err := loadRuleFiles(dir, workers)
 	if err != nil {
-		return fmt.Errorf("reading rules directory: %w", err)
+		return err
 	}
+	return writeValidateReport(w, newValidateReport(checkRuleFiles(files, loadConfig())))
+}
 
-	global := loadConfig()
+// ruleFile is the result of loading one rule file from the rules directory.
+type ruleFile struct {
+	name  string // file name without extension, used when a rule fails to load
+	rules []*config.Rule
+	err   error // invalid rules in the file; rules still holds the valid ones
+}
 
-	// Load all valid rules for global validation context
-	allRulesSlice, _ := config.LoadRulesDir(dir)
-	allRules := make(map[string]*config.Rule)
-	for _, r := range allRulesSlice {
-		allRules[r.Name] = r
+// loadRuleFiles loads every rule file in dir once, using up to workers
+// goroutines. Results keep directory order regardless of concurrency.
+func loadRuleFiles(dir string, workers int) ([]ruleFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading rules directory: %w", err)
 	}
 
-	var rows [][]string
-	var valid, invalid int
-
+	var paths []string
+	var files []ruleFile
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
-		ext := filepath.Ext(entry.Name())
-		if ext != ".yaml" && ext != ".yml" {
+		if !config.IsRuleFile(entry.Name()) {
 			continue
 		}
+		ext := filepath.Ext(entry.Name())
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+		files = append(files, ruleFile{name: strings.TrimSuffix(entry.Name(), ext)})
+	}
 
-		rulePath := filepath.Join(dir, entry.Name())
-		rule, err := config.LoadRule(rulePath)
-		if err != nil {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(paths)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				files[i].rules, files[i].err = config.LoadRuleFile(paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return files, nil
+}
+
+// checkRuleFiles validates the rules from a single directory load against
+// each other and the global config. A multi-rule file with invalid rules
+// gets one result for each.
+func checkRuleFiles(files []ruleFile, global *config.Global) []ruleResult {
+	// All valid rules provide the global validation context
+	allRules := make(map[string]*config.Rule)
+	for _, f := range files {
+		for _, rule := range f.rules {
+			allRules[rule.Name] = rule
+		}
+	}
+
+	var results []ruleResult
+	for _, f := range files {
+		results = append(results, checkRuleFile(f, global, allRules)...)
+	}
+	return results
+}
+
+// checkRuleFile validates the rules loaded from one file against allRules
+// and the global config, with one result for each invalid rule.
+func checkRuleFile(f ruleFile, global *config.Global, allRules map[string]*config.Rule) []ruleResult {
+	var results []ruleResult
+	for _, err := range splitErrors(f.err) {
+		results = append(results, invalidRule(f.name, err))
+	}
+	for _, rule := range f.rules {
+		warnings := config.ValidateRuleWithGlobal(rule, global, allRules)
+		results = append(results, ruleResult{Rule: rule.Name, Valid: true, Warnings: warnings})
+	}
+	return results
+}
+
+// splitErrors returns the errors joined in err, one per invalid rule of a
+// multi-rule file, or nil if err is nil.
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// validateRuleFiles builds the validate table rows from a single directory load.
+func validateRuleFiles(files []ruleFile, global *config.Global) (rows [][]string, valid, invalid int) {
+	for _, r := range checkRuleFiles(files, global) {
+		if !r.Valid {
 			invalid++
-			rows = append(rows, []string{
-				strings.TrimSuffix(entry.Name(), ext),
-				"FAIL",
-				truncate(err.Error(), 50),
-			})
+			rows = append(rows, []string{r.Rule, colorStatus("FAIL"), truncate(r.Error, 50)})
 			continue
 		}
-
 		valid++
-		warnings := config.ValidateRuleWithGlobal(rule, global, allRules)
 		warnText := "-"
-		if len(warnings) > 0 {
-			warnText = truncate(strings.Join(warnings, "; "), 50)
+		if len(r.Warnings) > 0 {
+			warnText = truncate(strings.Join(r.Warnings, "; "), 50)
 		}
-		rows = append(rows, []string{rule.Name, "ok", warnText})
+		rows = append(rows, []string{r.Rule, colorStatus("ok"), warnText})
 	}
+	return rows, valid, invalid
+}
 
-	total := valid + invalid
-	printTable([]string{"RULE", "STATUS", "WARNINGS"}, rows)
-	fmt.Printf("\n%d valid, %d invalid (total %d)\n", valid, invalid, total)
-
+func cmdValidateAll(w io.Writer, dir string, workers int) error {
+	invalid, total, err := writeValidation(w, dir, workers)
+	if err != nil {
+		return err
+	}
 	if invalid > 0 {
 		return fmt.Errorf("%d of %d rules are invalid", invalid, total)
 	}
 	return nil
 }
 
+// writeValidation validates every rule in dir, writing the validate table
+// and a summary line to w.
+func writeValidation(w io.Writer, dir string, workers int) (invalid, total int, err error) {
+	files, err := loadRuleFiles(dir, workers)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rows, valid, invalid := validateRuleFiles(files, loadConfig())
+
+	total = valid + invalid
+	writeTable(w, []string{"RULE", "STATUS", "WARNINGS"}, rows)
+	fmt.Fprintf(w, "\n%d valid, %d invalid (total %d)\n", valid, invalid, total)
+	return invalid, total, nil
+}
+
 func cmdHistory(args []string) error {
 	fs := flag.NewFlagSet("history", flag.ContinueOnError)
 	limit := fs.Int("limit", 50, "max records to return")
-	state := fs.String("state", "", "filter by state (success, failure, timeout, cancelled)")
+	stateFilter := fs.String("state", "", "filter by state ("+strings.Join(historyStates(), ", ")+")")
+	trigger := fs.String("trigger", "", "filter by trigger type, comma-separated; prefix with - to exclude (e.g. -manual,-triggered)")
+	groupBy := fs.String("group-by", "", "show one row per rule with its totals instead of each execution (rule)")
+	output := fs.String("output", "table", "output format (table, csv)")
+	full := fs.Bool("full", false, "with --output csv, include untruncated errors and each execution's output and stderr")
+	sinceBoot := fs.Bool("since-boot", false, "only show executions since the daemon last started")
+	follow := fs.Bool("follow", false, "after the table, print new executions as they're recorded until interrupted")
+	fs.BoolVar(follow, "f", false, "shorthand for --follow")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if *groupBy != "" && *groupBy != "rule" {
+		return fmt.Errorf("invalid --group-by %q: must be rule", *groupBy)
+	}
+	if *output != "table" && *output != "csv" {
+		return fmt.Errorf("invalid --output %q: must be table or csv", *output)
+	}
+	if *output == "csv" && *groupBy != "" {
+		return fmt.Errorf("--output csv can't be combined with --group-by")
+	}
+	if *full && *output != "csv" {
+		return fmt.Errorf("--full requires --output csv")
+	}
+	if *follow && (*groupBy != "" || *output != "table" || *stateFilter != "" || *trigger != "") {
+		return fmt.Errorf("--follow shows every new execution, or a rule's; it can't be combined with --group-by, --output, --state or --trigger")
+	}
 
-	if *state != "" {
-		validStates := map[string]bool{"success": true, "failure": true, "timeout": true, "cancelled": true}
-		if !validStates[*state] {
-			return fmt.Errorf("invalid state %q: must be one of success, failure, timeout, cancelled", *state)
+	if *stateFilter != "" {
+		valid := false
+		for _, s := range historyStates() {
+			valid = valid || s == *stateFilter
+		}
+		if !valid {
+			return fmt.Errorf("invalid state %q: must be one of %s", *stateFilter, strings.Join(historyStates(), ", "))
 		}
 	}
 
@@ -572,8 +969,21 @@ func cmdHistory(args []string) error {
 	if ruleName := fs.Arg(0); ruleName != "" {
 		query += "&rule=" + ruleName
 	}
-	if *state != "" {
-		query += "&state=" + *state
+	if *stateFilter != "" {
+		query += "&state=" + url.QueryEscape(*stateFilter)
+	}
+	if *trigger != "" {
+		query += "&trigger_type=" + url.QueryEscape(*trigger)
+	}
+	if *groupBy != "" {
+		query += "&group_by=" + *groupBy
+	}
+	if *sinceBoot {
+		boot, err := daemonBootTime()
+		if err != nil {
+			return err
+		}
+		query += "&since=" + url.QueryEscape(boot.Format(time.RFC3339))
 	}
 
 	body, err := queryDaemon(query)
@@ -581,25 +991,47 @@ func cmdHistory(args []string) error {
 		return fmt.Errorf("querying daemon: %w", err)
 	}
 
-	var records []struct {
-		RuleName    string `json:"RuleName"`
-		TriggerType string `json:"TriggerType"`
-		State       string `json:"State"`
-		StartedAt   string `json:"StartedAt"`
-		DurationMs  int64  `json:"DurationMs"`
-		Error       string `json:"Error"`
+	if *groupBy == "rule" {
+		var sums []state.RuleHistorySummary
+		if err := json.Unmarshal(body, &sums); err != nil {
+			return fmt.Errorf("parsing history response: %w", err)
+		}
+		printHistoryByRule(os.Stdout, sums)
+		return nil
 	}
+
+	var records []historyRecord
 	if err := json.Unmarshal(body, &records); err != nil {
 		return fmt.Errorf("parsing history response: %w", err)
 	}
 
+	if *output == "csv" {
+		return writeHistoryCSV(os.Stdout, records, *full)
+	}
+
+	printHistory(records)
+	if !*follow {
+		return nil
+	}
+	var lastID int64
+	if len(records) > 0 {
+		lastID = records[0].ID // newest first
+	}
+	return cmdHistoryFollow(os.Stdout, fs.Arg(0), lastID)
+}
+
+// printHistory prints records as a table with a summary line, followed by
+// the file operations of any dry runs.
+func printHistory(records []historyRecord) {
 	if len(records) == 0 {
 		fmt.Println("No execution history found")
-		return nil
+		return
 	}
 
 	var rows [][]string
+	var states []string
 	for _, rec := range records {
+		states = append(states, rec.State)
 		started := rec.StartedAt
 		if t, err := time.Parse(time.RFC3339, rec.StartedAt); err == nil {
 			started = t.Format("2006-01-02 15:04")
@@ -609,32 +1041,650 @@ func cmdHistory(args []string) error {
 			errMsg = truncate(rec.Error, 40)
 		}
 		rows = append(rows, []string{
+			strconv.FormatInt(rec.ID, 10),
 			rec.RuleName,
 			rec.TriggerType,
-			rec.State,
+			colorStatus(rec.stateText()),
 			started,
 			formatDuration(rec.DurationMs),
 			errMsg,
 		})
 	}
 
-	printTable([]string{"RULE", "TRIGGER", "STATE", "STARTED", "DURATION", "ERROR"}, rows)
+	printTable([]string{"ID", "RULE", "TRIGGER", "STATE", "STARTED", "DURATION", "ERROR"}, rows)
+	fmt.Printf("\n%s\n", historySummary(states))
+
+	// Dry runs list the file operations Claude planned
+	for _, rec := range records {
+		if rec.PlannedOps == "" {
+			continue
+		}
+		fmt.Printf("\nDry run %d (%s) planned:\n", rec.ID, rec.RuleName)
+		for _, line := range plannedOpLines(rec.PlannedOps) {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+}
+
+// stateText is rec's state as shown in history, with its skip reason, retry
+// attempt or dry run noted.
+func (rec historyRecord) stateText() string {
+	text := rec.State
+	if rec.SkipReason != "" {
+		text = fmt.Sprintf("%s (%s)", rec.State, rec.SkipReason)
+	}
+	if rec.RetryAttempt > 0 {
+		text = fmt.Sprintf("%s (retry %d)", rec.State, rec.RetryAttempt)
+	}
+	if rec.DryRun && rec.State != state.StateSkipped {
+		text += " (dry run)"
+	}
+	return text
+}
+
+// historyRecord is an execution as returned by /api/history.
+type historyRecord struct {
+	ID           int64  `json:"ID"`
+	RuleName     string `json:"RuleName"`
+	TriggerType  string `json:"TriggerType"`
+	State        string `json:"State"`
+	SkipReason   string `json:"SkipReason"`
+	RetryAttempt int    `json:"RetryAttempt"`
+	StartedAt    string `json:"StartedAt"`
+	DurationMs   int64  `json:"DurationMs"`
+	Error        string `json:"Error"`
+	Output       string `json:"Output"`
+	Stderr       string `json:"Stderr"`
+	DryRun       bool   `json:"DryRun"`
+	PlannedOps   string `json:"PlannedOps"`
+}
+
+// writeHistoryCSV writes records as RFC 4180 CSV with a header row. Errors are
+// truncated as in the table unless full is set, which also adds the output
+// and stderr columns.
+func writeHistoryCSV(w io.Writer, records []historyRecord, full bool) error {
+	cw := csv.NewWriter(w)
+	header := []string{"id", "rule", "trigger", "state", "skip_reason", "retry_attempt", "dry_run", "started_at", "duration_ms", "error"}
+	if full {
+		header = append(header, "output", "stderr")
+	}
+	cw.Write(header)
+	for _, rec := range records {
+		errMsg := rec.Error
+		if !full {
+			errMsg = truncate(errMsg, 40)
+		}
+		row := []string{
+			strconv.FormatInt(rec.ID, 10),
+			rec.RuleName,
+			rec.TriggerType,
+			rec.State,
+			rec.SkipReason,
+			strconv.Itoa(rec.RetryAttempt),
+			strconv.FormatBool(rec.DryRun),
+			rec.StartedAt,
+			strconv.FormatInt(rec.DurationMs, 10),
+			errMsg,
+		}
+		if full {
+			row = append(row, rec.Output, rec.Stderr)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
+	}
 	return nil
 }
 
+// historySummary totals the states of the listed executions, e.g.
+// "4 executions: 3 success, 1 failure".
+func historySummary(states []string) string {
+	counts := make(map[string]int)
+	for _, st := range states {
+		counts[st]++
+	}
+	noun := "executions"
+	if len(states) == 1 {
+		noun = "execution"
+	}
+	return fmt.Sprintf("%d %s: %s", len(states), noun, formatStateCounts(counts))
+}
+
+// formatStateCounts lists non-zero state counts in historyStates order,
+// followed by any other states alphabetically.
+func formatStateCounts(counts map[string]int) string {
+	var order []string
+	for _, st := range historyStates() {
+		if !strings.Contains(st, ":") {
+			order = append(order, st)
+		}
+	}
+	var others []string
+	for st := range counts {
+		if !slices.Contains(order, st) {
+			others = append(others, st)
+		}
+	}
+	sort.Strings(others)
+
+	var parts []string
+	for _, st := range append(order, others...) {
+		if counts[st] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[st], st))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// daemonBootTime returns when the running daemon started, from the uptime in
+// its /health response.
+func daemonBootTime() (time.Time, error) {
+	body, err := queryDaemon("/health")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("querying daemon: %w", err)
+	}
+	var health struct {
+		Uptime string `json:"uptime"`
+	}
+	if err := json.Unmarshal(body, &health); err != nil {
+		return time.Time{}, fmt.Errorf("parsing health response: %w", err)
+	}
+	return bootTime(health.Uptime, time.Now())
+}
+
+// bootTime is the start time of a daemon that has been up for uptime at now.
+// Uptime is reported in whole seconds, so the result is rounded down a further
+// second to keep executions from the daemon's first moments.
+func bootTime(uptime string, now time.Time) (time.Time, error) {
+	d, err := time.ParseDuration(uptime)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid daemon uptime %q", uptime)
+	}
+	return now.Add(-d - time.Second).Truncate(time.Second), nil
+}
+
+// printHistoryByRule writes one row per rule with its totals and latest run.
+func printHistoryByRule(w io.Writer, sums []state.RuleHistorySummary) {
+	if len(sums) == 0 {
+		fmt.Fprintln(w, "No execution history found")
+		return
+	}
+	var rows [][]string
+	for _, sum := range sums {
+		rows = append(rows, []string{sum.RuleName, strconv.Itoa(sum.Total),
+			formatStateCounts(sum.States), colorStatus(sum.LastState), sum.LastAt.Format("2006-01-02 15:04")})
+	}
+	writeTable(w, []string{"RULE", "TOTAL", "STATES", "LAST STATE", "LAST RUN"}, rows)
+}
+
+// plannedOpLines formats a record's JSON planned operations one per line.
+func plannedOpLines(plannedOps string) []string {
+	var ops []struct {
+		Op      string `json:"op"`
+		Path    string `json:"path"`
+		Command string `json:"command"`
+	}
+	if err := json.Unmarshal([]byte(plannedOps), &ops); err != nil {
+		return []string{plannedOps}
+	}
+	lines := make([]string, len(ops))
+	for i, op := range ops {
+		target := op.Path
+		if op.Command != "" {
+			target = op.Command
+		}
+		lines[i] = fmt.Sprintf("%-6s %s", op.Op, target)
+	}
+	return lines
+}
+
+// historyStates lists the values accepted by history --state: each final
+// state, plus "skipped:<reason>" to narrow skips to one reason code, and
+// "dropped" for "skipped:dropped".
+func historyStates() []string {
+	states := []string{"success", "failure", "timeout", "cancelled", state.StateCircuitOpen, state.StateSkipped}
+	for _, reason := range state.SkipReasons {
+		states = append(states, state.StateSkipped+":"+reason)
+	}
+	return append(states, state.StateDropped)
+}
+
+func cmdReliability(args []string) error {
+	fs := flag.NewFlagSet("reliability", flag.ContinueOnError)
+	days := fs.Int("days", 30, "window in days to compute stats over")
+	trigger := fs.String("trigger", "", "filter by trigger type, comma-separated; prefix with - to exclude (e.g. -manual,-triggered)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *days <= 0 {
+		return fmt.Errorf("--days must be positive")
+	}
+
+	if !isRunning() {
+		return fmt.Errorf("daemon is not running")
+	}
+
+	query := fmt.Sprintf("/api/stats?days=%d", *days)
+	if ruleName := fs.Arg(0); ruleName != "" {
+		query += "&rule=" + ruleName
+	}
+	if *trigger != "" {
+		query += "&trigger_type=" + url.QueryEscape(*trigger)
+	}
+
+	body, err := queryDaemon(query)
+	if err != nil {
+		return fmt.Errorf("querying daemon: %w", err)
+	}
+
+	var stats []struct {
+		RuleName      string    `json:"rule_name"`
+		TotalRuns     int       `json:"total_runs"`
+		Failures      int       `json:"failures"`
+		SuccessRate   float64   `json:"success_rate"`
+		LastFailureAt time.Time `json:"last_failure_at"`
+		MTBFSeconds   float64   `json:"mtbf_seconds"`
+	}
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return fmt.Errorf("parsing stats response: %w", err)
+	}
+
+	if len(stats) == 0 {
+		fmt.Println("No rules found")
+		return nil
+	}
+
+	var rows [][]string
+	for _, st := range stats {
+		rate, lastFailure, mtbf := "-", "-", "-"
+		if st.TotalRuns > 0 {
+			rate = fmt.Sprintf("%.1f%%", st.SuccessRate*100)
+		}
+		if !st.LastFailureAt.IsZero() {
+			lastFailure = st.LastFailureAt.Format("2006-01-02 15:04")
+		}
+		if st.MTBFSeconds > 0 {
+			mtbf = (time.Duration(st.MTBFSeconds) * time.Second).String()
+		}
+		rows = append(rows, []string{
+			st.RuleName,
+			fmt.Sprintf("%d", st.TotalRuns),
+			rate,
+			fmt.Sprintf("%d", st.Failures),
+			lastFailure,
+			mtbf,
+		})
+	}
+
+	printTable([]string{"RULE", "RUNS", "SUCCESS", "FAILURES", "LAST FAILURE", "MTBF"}, rows)
+	return nil
+}
+
+func cmdPause() error {
+	if !isRunning() {
+		return fmt.Errorf("daemon is not running")
+	}
+	if _, err := postDaemon("/api/pause"); err != nil {
+		return fmt.Errorf("pausing daemon: %w", err)
+	}
+	fmt.Println("Rule executions paused")
+	return nil
+}
+
+func cmdResume() error {
+	if !isRunning() {
+		return fmt.Errorf("daemon is not running")
+	}
+	body, err := postDaemon("/api/resume")
+	if err != nil {
+		return fmt.Errorf("resuming daemon: %w", err)
+	}
+
+	var status struct {
+		Paused    bool   `json:"paused"`
+		PauseFile string `json:"pause_file"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return fmt.Errorf("parsing resume response: %w", err)
+	}
+	if status.Paused {
+		return fmt.Errorf("still paused: remove %s to resume", status.PauseFile)
+	}
+	fmt.Println("Rule executions resumed")
+	return nil
+}
+
+func cmdEnable(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: srvrmgr enable <rule>")
+	}
+	ruleName := args[0]
+	if !isRunning() {
+		return fmt.Errorf("daemon is not running")
+	}
+	body, err := postDaemon("/api/enable?rule=" + url.QueryEscape(ruleName))
+	if err != nil {
+		return fmt.Errorf("enabling rule: %w", err)
+	}
+
+	var status struct {
+		Rearmed bool `json:"rearmed"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return fmt.Errorf("parsing enable response: %w", err)
+	}
+	if status.Rearmed {
+		fmt.Printf("Circuit breaker re-armed for %s\n", ruleName)
+	} else {
+		fmt.Printf("Circuit breaker for %s is not open\n", ruleName)
+	}
+	return nil
+}
+
+// cmdSchema prints the JSON Schema for rule files (the default) or
+// config.yaml, for editor completion and validation.
+func cmdSchema(w io.Writer, args []string) error {
+	kind := "rule"
+	if len(args) > 0 {
+		kind = args[0]
+	}
+
+	var schema map[string]any
+	switch kind {
+	case "rule":
+		schema = config.RuleSchema()
+	case "config":
+		schema = config.GlobalSchema()
+	default:
+		return fmt.Errorf("unknown schema %q: must be rule or config", kind)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
 func cmdRun(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	explain := fs.Bool("explain", false, "print why the rule would or wouldn't run, without executing it")
+	all := fs.Bool("all", false, "run every enabled rule with the trigger type given by --type")
+	triggerType := fs.String("type", "", "trigger type of the rules --all runs")
+	eventType := fs.String("event", "", "run a lifecycle rule as if this event fired, e.g. daemon_started")
+	var noDeps bool
+	fs.BoolVar(&noDeps, "no-deps", false, "run even if the rule's depends_on_rules haven't succeeded")
+	fs.BoolVar(&noDeps, "force", false, "same as --no-deps")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// Allow flags after the rule name too: srvrmgr run <rule> --explain
+	ruleName := fs.Arg(0)
+	if ruleName != "" {
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+	}
+
+	configPath := daemonConfigPath()
+	rulesDir := filepath.Join(defaultConfigDir, "rules")
+
+	if *all {
+		if ruleName != "" || *explain || *triggerType == "" || *eventType != "" || noDeps {
+			return fmt.Errorf("usage: srvrmgr run --all --type <trigger-type>")
+		}
+		outcomes, err := daemon.New(configPath, rulesDir).RunRules(context.Background(), *triggerType)
+		if err != nil {
+			return err
+		}
+		return printRunSummary(os.Stdout, *triggerType, outcomes)
+	}
+	if ruleName == "" || *triggerType != "" || (*explain && noDeps) {
+		return fmt.Errorf("usage: srvrmgr run <rule-name> [--explain] [--event <lifecycle-event>] [--no-deps]")
+	}
+
+	d := daemon.New(configPath, rulesDir)
+
+	if *explain {
+		gates, err := d.ExplainRule(ruleName, *eventType, map[string]any{})
+		if err != nil {
+			return err
+		}
+		printExplain(ruleName, gates)
+		return nil
+	}
+
+	if noDeps {
+		fmt.Fprintln(os.Stderr, "warning: dependency checks bypassed; the rule runs even if its depends_on_rules haven't succeeded")
+	}
+	cfg := loadConfig()
+	baseURL := daemonBaseURL(cfg)
+	return runRule(os.Stdout, baseURL, isRunning, ruleName, *eventType, noDeps, func() error {
+		return d.RunRule(context.Background(), ruleName, *eventType, map[string]any{}, noDeps)
+	})
+}
+
+// runRule queues a manual run of ruleName on the running daemon, so it runs
+// with the daemon's state, memory server and concurrency limit. When the
+// daemon isn't running it calls inProcess instead.
+func runRule(w io.Writer, baseURL string, running func() bool, ruleName, eventType string, noDeps bool, inProcess func() error) error {
+	if !running() {
+		return inProcess()
+	}
+
+	query := url.Values{}
+	if eventType != "" {
+		query.Set("event", eventType)
+	}
+	if noDeps {
+		query.Set("no_deps", "true")
+	}
+	target := baseURL + "/api/rules/" + url.PathEscape(ruleName) + "/run"
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Post(target, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("queueing run on daemon: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("daemon returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	fmt.Fprintf(w, "Queued '%s' on the running daemon; see 'srvrmgr history %s' for the result\n", ruleName, ruleName)
+	return nil
+}
+
+// printRunSummary prints the outcome of each rule run by run --all, and
+// returns an error if any of them didn't succeed.
+func printRunSummary(w io.Writer, triggerType string, outcomes []daemon.RunOutcome) error {
+	if len(outcomes) == 0 {
+		fmt.Fprintf(w, "No enabled %s rules\n", triggerType)
+		return nil
+	}
+
+	counts := make(map[string]int)
+	rows := make([][]string, len(outcomes))
+	for i, o := range outcomes {
+		counts[o.State]++
+		detail := o.Detail
+		if detail == "" {
+			detail = "-"
+		}
+		rows[i] = []string{o.Rule, colorStatus(o.State), truncate(detail, 60)}
+	}
+	fmt.Fprintln(w)
+	writeTable(w, []string{"RULE", "RESULT", "DETAIL"}, rows)
+
+	states := make([]string, 0, len(counts))
+	for st := range counts {
+		states = append(states, st)
+	}
+	sort.Strings(states)
+	parts := make([]string, len(states))
+	for i, st := range states {
+		parts[i] = fmt.Sprintf("%d %s", counts[st], st)
+	}
+	fmt.Fprintf(w, "\nRan %d %s rules: %s\n", len(outcomes), triggerType, strings.Join(parts, ", "))
+
+	if failed := len(outcomes) - counts["success"] - counts[state.StateSkipped]; failed > 0 {
+		return fmt.Errorf("%d of %d rules did not succeed", failed, len(outcomes))
+	}
+	return nil
+}
+
+func cmdReplay(args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("usage: srvrmgr run <rule-name>")
+		return fmt.Errorf("usage: srvrmgr replay <execution-id>")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id <= 0 {
+		return fmt.Errorf("invalid execution id %q", args[0])
 	}
 
-	ruleName := args[0]
-	configPath := filepath.Join(defaultConfigDir, "config.yaml")
+	configPath := daemonConfigPath()
 	rulesDir := filepath.Join(defaultConfigDir, "rules")
+	d := daemon.New(configPath, rulesDir)
+
+	rec, err := d.GetExecution(id)
+	if err != nil {
+		return err
+	}
 
+	fmt.Printf("Replaying execution %d of '%s' (%s, %s, started %s)\n",
+		rec.ID, rec.RuleName, rec.TriggerType, rec.State, rec.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintln(os.Stderr, "warning: the rule runs again for real; non-idempotent actions will repeat their side effects")
+
+	return d.Replay(context.Background(), rec)
+}
+
+func cmdShow(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: srvrmgr show <execution-id>")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id <= 0 {
+		return fmt.Errorf("invalid execution id %q", args[0])
+	}
+
+	configPath := daemonConfigPath()
+	rulesDir := filepath.Join(defaultConfigDir, "rules")
 	d := daemon.New(configPath, rulesDir)
 
-	ctx := context.Background()
-	return d.RunRule(ctx, ruleName, map[string]any{})
+	rec, err := d.GetExecution(id)
+	if err != nil {
+		return err
+	}
+	printExecution(os.Stdout, rec)
+	return nil
+}
+
+// printExecution prints one execution's details, with its output and stderr
+// in separate sections.
+func printExecution(w io.Writer, rec *state.ExecutionRecord) {
+	recState := rec.State
+	if rec.SkipReason != "" {
+		recState = fmt.Sprintf("%s (%s)", rec.State, rec.SkipReason)
+	}
+	if rec.DryRun && rec.State != state.StateSkipped {
+		recState += " (dry run)"
+	}
+
+	fmt.Fprintf(w, "Execution %d of '%s'\n", rec.ID, rec.RuleName)
+	fmt.Fprintf(w, "  Trigger:  %s\n", rec.TriggerType)
+	fmt.Fprintf(w, "  State:    %s\n", recState)
+	fmt.Fprintf(w, "  Started:  %s\n", rec.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "  Duration: %s\n", formatDuration(rec.DurationMs))
+	if rec.RetryAttempt > 0 {
+		fmt.Fprintf(w, "  Retry:    %d of execution %d\n", rec.RetryAttempt, rec.TriggeredByExecutionID)
+	} else if rec.TriggeredByExecutionID > 0 {
+		fmt.Fprintf(w, "  Parent:   execution %d\n", rec.TriggeredByExecutionID)
+	}
+	if rec.ExitCode != nil {
+		fmt.Fprintf(w, "  Exit:     %s\n", formatExitCode(*rec.ExitCode))
+	}
+	if rec.Error != "" {
+		fmt.Fprintf(w, "  Error:    %s\n", rec.Error)
+	}
+
+	for _, section := range []struct{ title, text string }{
+		{"Output", rec.Output},
+		{"Stderr", rec.Stderr},
+	} {
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		text := strings.TrimRight(section.text, "\n")
+		if text == "" {
+			fmt.Fprintln(w, "  (none)")
+			continue
+		}
+		for _, line := range strings.Split(text, "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	if rec.ParsedOutput != "" {
+		fmt.Fprintln(w, "\nParsed output:")
+		var buf bytes.Buffer
+		text := rec.ParsedOutput
+		if json.Indent(&buf, []byte(text), "", "  ") == nil {
+			text = buf.String()
+		}
+		for _, line := range strings.Split(text, "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	if rec.PlannedOps != "" {
+		fmt.Fprintln(w, "\nPlanned (dry run):")
+		for _, line := range plannedOpLines(rec.PlannedOps) {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+}
+
+// exitCodeHints explains exit codes with a conventional meaning.
+var exitCodeHints = map[int]string{
+	1:   "general error",
+	2:   "misuse of a shell builtin or bad arguments",
+	124: "timed out",
+	126: "command not executable",
+	127: "command not found",
+	130: "interrupted (SIGINT)",
+	137: "killed (SIGKILL)",
+	143: "terminated (SIGTERM)",
+}
+
+// formatExitCode returns code followed by its hint, if it has one.
+func formatExitCode(code int) string {
+	if hint, ok := exitCodeHints[code]; ok {
+		return fmt.Sprintf("%d (%s)", code, hint)
+	}
+	return strconv.Itoa(code)
+}
+
+// printExplain prints each gate's result and the final run/skip decision.
+func printExplain(ruleName string, gates []daemon.Gate) {
+	fmt.Printf("Rule '%s' (manual run)\n\n", ruleName)
+
+	var rows [][]string
+	var blocking []string
+	for _, g := range gates {
+		result := "pass"
+		if !g.Passed {
+			result = "FAIL"
+			blocking = append(blocking, g.Name)
+		}
+		rows = append(rows, []string{g.Name, colorStatus(result), g.Detail})
+	}
+	printTable([]string{"GATE", "RESULT", "DETAIL"}, rows)
+
+	fmt.Println()
+	if len(blocking) == 0 {
+		fmt.Println("Decision: would run")
+	} else {
+		fmt.Printf("Decision: would skip (%s)\n", strings.Join(blocking, ", "))
+	}
 }
 
 func cmdLogs(args []string) error {
@@ -643,37 +1693,104 @@ func cmdLogs(args []string) error {
 	// FR-10: --follow alias for -f.
 	// Sourced from convention.
 	fs.BoolVar(follow, "follow", false, "follow logs")
+	grep := fs.String("grep", "", "only show lines matching this regex or substring")
+	level := fs.String("level", "", "only show lines at or above this level (debug, info, warn, error)")
+	ignoreCase := fs.Bool("i", false, "case-insensitive --grep")
+	fs.BoolVar(ignoreCase, "ignore-case", false, "case-insensitive --grep")
+	lines := fs.Int("tail", defaultLogLines, "number of trailing lines to show")
+	fs.IntVar(lines, "n", defaultLogLines, "number of trailing lines to show")
 	fs.Parse(args)
 
-	var logPath string
-	if fs.NArg() > 0 {
-		// Specific rule logs
-		logPath = filepath.Join(defaultLogsDir, "rules", fs.Arg(0)+".log")
-	} else {
-		// Daemon logs
-		logPath = filepath.Join(defaultLogsDir, "srvrmgrd.log")
+	if *lines <= 0 {
+		return fmt.Errorf("invalid --tail %d: must be positive", *lines)
+	}
+	filter, err := logging.NewLineFilter(*grep, *level, *ignoreCase)
+	if err != nil {
+		return err
+	}
+	// Rules log to the daemon's log, tagged with their name
+	if rule := fs.Arg(0); rule != "" {
+		filter.SetRule(rule)
+	}
+
+	// Let a running daemon filter its log so large logs aren't pulled whole
+	if filter.Active() && !*follow && isRunning() {
+		query := url.Values{}
+		query.Set("grep", *grep)
+		query.Set("level", *level)
+		query.Set("i", strconv.FormatBool(*ignoreCase))
+		query.Set("rule", fs.Arg(0))
+		query.Set("lines", strconv.Itoa(*lines))
+		body, err := queryDaemon("/api/logs?" + query.Encode())
+		if err != nil {
+			return fmt.Errorf("querying daemon: %w", err)
+		}
+		_, err = os.Stdout.Write(body)
+		return err
 	}
 
+	_, logDir := config.DataPaths(daemonConfigPath())
+	logPath := filepath.Join(logDir, "srvrmgrd.log")
 	if _, err := os.Stat(logPath); os.IsNotExist(err) {
 		return fmt.Errorf("log file not found: %s", logPath)
 	}
 
-	tailArgs := []string{"-n", "50"}
-	if *follow {
-		tailArgs = append(tailArgs, "-f")
+	if filter.Active() {
+		return filterLogFile(logPath, filter, *lines, *follow)
 	}
-	tailArgs = append(tailArgs, logPath)
 
-	cmd := exec.Command("tail", tailArgs...)
+	cmd := exec.Command("tail", tailArgs(logPath, *lines, *follow)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
+// defaultLogLines is how many trailing lines `srvrmgr logs` shows by default.
+const defaultLogLines = 50
+
+// tailArgs returns the tail(1) arguments showing the last lines of logPath.
+func tailArgs(logPath string, lines int, follow bool) []string {
+	args := []string{"-n", strconv.Itoa(lines)}
+	if follow {
+		args = append(args, "-f")
+	}
+	return append(args, logPath)
+}
+
+// filterLogFile prints the last lines of logPath that pass filter, then keeps
+// printing new matching lines if follow is set.
+func filterLogFile(logPath string, filter *logging.LineFilter, lines int, follow bool) error {
+	f, err := os.Open(logPath)
+	if err != nil {
+		return err
+	}
+	err = filter.Filter(f, os.Stdout, lines)
+	f.Close()
+	if err != nil || !follow {
+		return err
+	}
+
+	cmd := exec.Command("tail", "-n", "0", "-f", logPath)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if err := filter.Filter(out, os.Stdout, 0); err != nil {
+		cmd.Process.Kill()
+		return err
+	}
+	return cmd.Wait()
+}
+
 func cmdUninstall(args []string) error {
 	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
 	keepConfig := fs.Bool("keep-config", false, "keep config and rules")
 	removeConfig := fs.Bool("remove-config", false, "remove config and rules without prompting")
+	dryRun := fs.Bool("dry-run", false, "print what would be stopped and removed, without changing anything")
 	fs.Parse(args)
 
 	// Validate flags
@@ -681,49 +1798,121 @@ func cmdUninstall(args []string) error {
 		return fmt.Errorf("cannot specify both --keep-config and --remove-config")
 	}
 
+	configPath := daemonConfigPath()
+	plan := planUninstall(isRunning(), launchdPlist, !*keepConfig, uninstallDataDirs(configPath)...)
+	plan.AskData = !*removeConfig
+	if *dryRun {
+		plan.describe(os.Stdout)
+		return nil
+	}
+
 	// Check for root
 	if os.Geteuid() != 0 {
 		return fmt.Errorf("uninstall must be run as root (use sudo)")
 	}
 
-	// Stop daemon if running
-	if isRunning() {
-		fmt.Println("Stopping daemon...")
-		cmd := exec.Command("launchctl", "unload", launchdPlist)
-		cmd.Run() // Ignore error, plist might not be loaded
+	confirm := func() bool {
+		fmt.Print("Remove config and rules? (y/N): ")
+		var response string
+		fmt.Scanln(&response)
+		return strings.ToLower(response) == "y"
+	}
+	audit := daemon.New(configPath, filepath.Join(filepath.Dir(configPath), "rules"))
+	if err := audit.AuditUninstall(plan.StopDaemon, plan.Plist, plan.DataDirs); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: uninstall not audited: %v\n", err)
+	}
+	if err := plan.run(os.Stdout, confirm); err != nil {
+		return err
 	}
 
-	// Remove plist
-	if _, err := os.Stat(launchdPlist); err == nil {
-		if err := os.Remove(launchdPlist); err != nil {
-			return fmt.Errorf("removing plist: %w", err)
+	fmt.Println("\nUninstall complete. Run 'brew uninstall srvrmgr' to remove binaries.")
+	return nil
+}
+
+// uninstallPlan is what `srvrmgr uninstall` does, in order.
+type uninstallPlan struct {
+	StopDaemon bool     // unload the running daemon
+	Plist      string   // launchd plist to remove, empty when not installed
+	DataDirs   []string // config and log directories to remove
+	AskData    bool     // confirm before removing DataDirs
+}
+
+// planUninstall plans an uninstall, leaving out paths that don't exist.
+// dataDirs are only removed if removeData is set.
+func planUninstall(running bool, plist string, removeData bool, dataDirs ...string) uninstallPlan {
+	plan := uninstallPlan{StopDaemon: running}
+	if _, err := os.Stat(plist); err == nil {
+		plan.Plist = plist
+	}
+	if removeData {
+		for _, dir := range dataDirs {
+			if _, err := os.Stat(dir); err == nil {
+				plan.DataDirs = append(plan.DataDirs, dir)
+			}
 		}
-		fmt.Println("Removed", launchdPlist)
 	}
+	return plan
+}
+
+// uninstallDataDirs lists the directories uninstall removes along with the
+// config: the system config and log directories, or for a daemon using a
+// config file elsewhere, only the state and logs directories beside it (see
+// config.DataPaths), leaving the directory the config lives in alone.
+func uninstallDataDirs(configPath string) []string {
+	stateDB, logDir := config.DataPaths(configPath)
+	if filepath.Dir(configPath) == defaultConfigDir {
+		return []string{defaultConfigDir, logDir}
+	}
+	return []string{filepath.Dir(stateDB), logDir}
+}
 
-	// Handle config removal
-	if !*keepConfig {
-		removeIt := *removeConfig
-		if !removeIt {
-			fmt.Print("Remove config and rules? (y/N): ")
-			var response string
-			fmt.Scanln(&response)
-			removeIt = strings.ToLower(response) == "y"
+// describe prints the plan for --dry-run.
+func (p uninstallPlan) describe(w io.Writer) {
+	fmt.Fprintln(w, "Dry run: nothing will be stopped or removed.")
+	if p.StopDaemon {
+		fmt.Fprintln(w, "Would stop the daemon")
+	} else {
+		fmt.Fprintln(w, "Daemon is not running")
+	}
+	if p.Plist != "" {
+		fmt.Fprintln(w, "Would remove", p.Plist)
+	}
+	for _, dir := range p.DataDirs {
+		if p.AskData {
+			fmt.Fprintf(w, "Would remove %s (after confirmation)\n", dir)
+		} else {
+			fmt.Fprintln(w, "Would remove", dir)
 		}
+	}
+	if p.Plist == "" && len(p.DataDirs) == 0 {
+		fmt.Fprintln(w, "Nothing to remove")
+	}
+}
 
-		if removeIt {
-			if err := os.RemoveAll(defaultConfigDir); err != nil {
-				return fmt.Errorf("removing config dir: %w", err)
-			}
-			fmt.Println("Removed", defaultConfigDir)
+// run carries out the plan. confirm is asked before removing DataDirs when
+// AskData is set.
+func (p uninstallPlan) run(w io.Writer, confirm func() bool) error {
+	if p.StopDaemon {
+		fmt.Fprintln(w, "Stopping daemon...")
+		cmd := exec.Command("launchctl", "unload", launchdPlist)
+		cmd.Run() // Ignore error, plist might not be loaded
+	}
 
-			if err := os.RemoveAll(defaultLogsDir); err != nil {
-				return fmt.Errorf("removing logs dir: %w", err)
-			}
-			fmt.Println("Removed", defaultLogsDir)
+	if p.Plist != "" {
+		if err := os.Remove(p.Plist); err != nil {
+			return fmt.Errorf("removing plist: %w", err)
 		}
+		fmt.Fprintln(w, "Removed", p.Plist)
 	}
 
-	fmt.Println("\nUninstall complete. Run 'brew uninstall srvrmgr' to remove binaries.")
+	if len(p.DataDirs) == 0 || (p.AskData && !confirm()) {
+		return nil
+	}
+	for _, dir := range p.DataDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("removing %s: %w", dir, err)
+		}
+		fmt.Fprintln(w, "Removed", dir)
+	}
 	return nil
 }
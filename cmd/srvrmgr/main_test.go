@@ -0,0 +1,640 @@
+// cmd/srvrmgr/main_test.go
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/daemon"
+	"github.com/colebrumley/srvrmgr/internal/state"
+)
+
+// writeSampleRules writes n valid rules plus a few invalid and non-rule files.
+func writeSampleRules(tb testing.TB, n int) string {
+	tb.Helper()
+	dir := tb.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		write(fmt.Sprintf("rule-%03d.yaml", i), fmt.Sprintf(`name: rule-%03d
+enabled: true
+run_as_user: nobody
+trigger:
+  type: scheduled
+  run_every: 1h
+action:
+  prompt: "do thing %d"
+`, i, i))
+	}
+	// depends_on + triggers_rules overlap produces a warning
+	write("parent.yaml", `name: parent
+trigger:
+  type: manual
+action:
+  prompt: "parent"
+triggers_rules: [child]
+`)
+	write("child.yml", `name: child
+trigger:
+  type: manual
+action:
+  prompt: "child"
+depends_on_rules: [parent]
+`)
+	write("broken.yaml", "name: broken\ntrigger:\n  type: bogus\naction:\n  prompt: x\n")
+	write("garbage.yml", "name: [unterminated\n")
+	write("README.md", "not a rule")
+	if err := os.Mkdir(filepath.Join(dir, "sub.yaml"), 0755); err != nil {
+		tb.Fatal(err)
+	}
+	return dir
+}
+
+// legacyValidateRows reproduces the original validate-all loop, which listed
+// the directory and loaded every rule a second time via LoadRulesDir.
+func legacyValidateRows(dir string, global *config.Global) (rows [][]string, valid, invalid int) {
+	entries, _ := os.ReadDir(dir)
+	allRulesSlice, _ := config.LoadRulesDir(dir)
+	allRules := make(map[string]*config.Rule)
+	for _, r := range allRulesSlice {
+		allRules[r.Name] = r
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(entry.Name())
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		rule, err := config.LoadRule(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			invalid++
+			rows = append(rows, []string{strings.TrimSuffix(entry.Name(), ext), "FAIL", truncate(err.Error(), 50)})
+			continue
+		}
+		valid++
+		warnings := config.ValidateRuleWithGlobal(rule, global, allRules)
+		warnText := "-"
+		if len(warnings) > 0 {
+			warnText = truncate(strings.Join(warnings, "; "), 50)
+		}
+		rows = append(rows, []string{rule.Name, "ok", warnText})
+	}
+	return rows, valid, invalid
+}
+
+func TestValidateRuleFiles_MatchesLegacyOutput(t *testing.T) {
+	dir := writeSampleRules(t, 25)
+	global := &config.Global{Daemon: config.DaemonConfig{AllowedRunAsUsers: []string{"admin"}}}
+
+	wantRows, wantValid, wantInvalid := legacyValidateRows(dir, global)
+	if wantValid != 27 || wantInvalid != 2 {
+		t.Fatalf("legacy counts = %d/%d, want 27/2", wantValid, wantInvalid)
+	}
+
+	for _, workers := range []int{1, 4, 64} {
+		files, err := loadRuleFiles(dir, workers)
+		if err != nil {
+			t.Fatalf("loadRuleFiles(%d) error = %v", workers, err)
+		}
+		rows, valid, invalid := validateRuleFiles(files, global)
+		if valid != wantValid || invalid != wantInvalid {
+			t.Errorf("workers=%d: counts = %d/%d, want %d/%d", workers, valid, invalid, wantValid, wantInvalid)
+		}
+		if !reflect.DeepEqual(rows, wantRows) {
+			t.Errorf("workers=%d: rows differ from legacy output\ngot:  %v\nwant: %v", workers, rows, wantRows)
+		}
+	}
+}
+
+func TestCmdValidateAllJSON(t *testing.T) {
+	dir := writeSampleRules(t, 2)
+	var out strings.Builder
+	if err := cmdValidateAllJSON(&out, dir, 2); err == nil {
+		t.Error("expected error for invalid rules")
+	}
+
+	var report validateReport
+	if err := json.Unmarshal([]byte(out.String()), &report); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if report.Valid != 4 || report.Invalid != 2 {
+		t.Errorf("counts = %d valid, %d invalid; want 4, 2", report.Valid, report.Invalid)
+	}
+	byRule := map[string]ruleResult{}
+	for _, r := range report.Rules {
+		byRule[r.Rule] = r
+	}
+	if got := byRule["broken"]; got.Valid || got.Code != "invalid_trigger_type" || got.Field != "trigger.type" {
+		t.Errorf("broken = %+v, want invalid_trigger_type on trigger.type", got)
+	}
+	if got := byRule["garbage"]; got.Valid || got.Code != "invalid_rule" || got.Error == "" {
+		t.Errorf("garbage = %+v, want invalid_rule with an error", got)
+	}
+	if got := byRule["child"]; !got.Valid || len(got.Warnings) == 0 {
+		t.Errorf("child = %+v, want valid with warnings", got)
+	}
+}
+
+func TestCmdValidateOne_MultiRuleFile(t *testing.T) {
+	dir := t.TempDir()
+	fleet := `
+defaults:
+  trigger: {type: manual}
+rules:
+  - name: disk
+    action: {prompt: check disk}
+  - name: memory
+    action: {prompt: check memory}
+`
+	if err := os.WriteFile(filepath.Join(dir, "fleet.yaml"), []byte(fleet), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := cmdValidateOne(&out, dir, "fleet"); err != nil {
+		t.Fatalf("cmdValidateOne() error = %v\n%s", err, out.String())
+	}
+	for _, want := range []string{"Rule 'disk' is valid", "Rule 'memory' is valid"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := cmdValidateOneJSON(&out, dir, "fleet"); err != nil {
+		t.Fatalf("cmdValidateOneJSON() error = %v", err)
+	}
+	var report validateReport
+	if err := json.Unmarshal([]byte(out.String()), &report); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if report.Valid != 2 || report.Invalid != 0 {
+		t.Errorf("counts = %d valid, %d invalid; want 2, 0", report.Valid, report.Invalid)
+	}
+
+	// An invalid entry is reported without hiding the valid one
+	broken := fleet + "  - name: broken\n    trigger: {type: bogus}\n    action: {prompt: x}\n"
+	if err := os.WriteFile(filepath.Join(dir, "fleet.yaml"), []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := cmdValidateOne(&out, dir, "fleet"); err == nil {
+		t.Error("expected an error for the invalid entry")
+	}
+	if !strings.Contains(out.String(), "is INVALID") || !strings.Contains(out.String(), "Rule 'disk' is valid") {
+		t.Errorf("output should report the invalid entry and the valid ones:\n%s", out.String())
+	}
+}
+
+func TestLoadRuleFiles_MissingDir(t *testing.T) {
+	if _, err := loadRuleFiles(filepath.Join(t.TempDir(), "nope"), 2); err == nil {
+		t.Error("expected error for missing rules directory")
+	}
+}
+
+func BenchmarkValidateAll(b *testing.B) {
+	dir := writeSampleRules(b, 300)
+	global := &config.Global{}
+
+	b.Run("legacy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			legacyValidateRows(dir, global)
+		}
+	})
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				files, _ := loadRuleFiles(dir, workers)
+				validateRuleFiles(files, global)
+			}
+		})
+	}
+}
+
+func TestDaemonBaseURL(t *testing.T) {
+	for addr, want := range map[string]string{
+		"127.0.0.1": "http://127.0.0.1:9876",
+		"::1":       "http://[::1]:9876",
+		"localhost": "http://localhost:9876",
+	} {
+		cfg := &config.Global{Daemon: config.DaemonConfig{WebhookListenAddress: addr, WebhookListenPort: 9876}}
+		if got := daemonBaseURL(cfg); got != want {
+			t.Errorf("daemonBaseURL(%s) = %q, want %q", addr, got, want)
+		}
+	}
+}
+
+func TestPlannedOpLines(t *testing.T) {
+	got := plannedOpLines(`[{"op":"write","path":"/tmp/a"},{"op":"run","command":"make clean"}]`)
+	want := []string{"write  /tmp/a", "run    make clean"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("plannedOpLines() = %q, want %q", got, want)
+	}
+	if got := plannedOpLines("not json"); len(got) != 1 || got[0] != "not json" {
+		t.Errorf("plannedOpLines(invalid) = %q, want raw text", got)
+	}
+}
+
+func TestHistorySummary(t *testing.T) {
+	states := []string{"success", "failure", "skipped", "success", "timeout", "success"}
+	got := historySummary(states)
+	want := "6 executions: 3 success, 1 failure, 1 timeout, 1 skipped"
+	if got != want {
+		t.Errorf("historySummary() = %q, want %q", got, want)
+	}
+	if got := historySummary([]string{"failure"}); got != "1 execution: 1 failure" {
+		t.Errorf("historySummary(one) = %q", got)
+	}
+}
+
+func TestBootTime(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 30, 500_000_000, time.UTC)
+	boot, err := bootTime("1h0m10s", now)
+	if err != nil {
+		t.Fatalf("bootTime() error = %v", err)
+	}
+	if want := time.Date(2026, 3, 1, 11, 0, 19, 0, time.UTC); !boot.Equal(want) {
+		t.Errorf("bootTime() = %v, want %v", boot, want)
+	}
+	for _, bad := range []string{"", "soon", "-5s"} {
+		if _, err := bootTime(bad, now); err == nil {
+			t.Errorf("bootTime(%q) accepted an invalid uptime", bad)
+		}
+	}
+}
+
+func TestPrintHistoryByRule(t *testing.T) {
+	var b strings.Builder
+	printHistoryByRule(&b, []state.RuleHistorySummary{{
+		RuleName: "backup", Total: 5,
+		States:    map[string]int{"success": 4, "failure": 1},
+		LastState: "success", LastAt: time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+	}})
+	got := b.String()
+	for _, want := range []string{"backup", "4 success, 1 failure", "2026-03-01 09:30"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("printHistoryByRule() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestWriteHistoryCSV(t *testing.T) {
+	longErr := "exit status 1: " + strings.Repeat("x", 60)
+	records := []historyRecord{
+		{ID: 7, RuleName: "backup", TriggerType: "scheduled", State: "failure", RetryAttempt: 2,
+			StartedAt: "2026-03-01T09:30:00Z", DurationMs: 1500, Error: longErr,
+			Output: "line one, with a comma\nline \"two\"", Stderr: "warn"},
+		{ID: 8, RuleName: "watch", TriggerType: "filesystem", State: "skipped", SkipReason: "paused", DryRun: true,
+			StartedAt: "2026-03-01T09:31:00Z"},
+	}
+
+	for _, full := range []bool{false, true} {
+		var b strings.Builder
+		if err := writeHistoryCSV(&b, records, full); err != nil {
+			t.Fatalf("writeHistoryCSV(full=%v) error = %v", full, err)
+		}
+		rows, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+		if err != nil {
+			t.Fatalf("full=%v: emitted CSV doesn't parse: %v\n%s", full, err, b.String())
+		}
+		if len(rows) != 3 {
+			t.Fatalf("full=%v: got %d rows, want header + 2", full, len(rows))
+		}
+		wantCols := 10
+		if full {
+			wantCols = 12
+		}
+		for i, row := range rows {
+			if len(row) != wantCols {
+				t.Errorf("full=%v: row %d has %d columns, want %d", full, i, len(row), wantCols)
+			}
+		}
+
+		first, second := rows[1], rows[2]
+		if first[0] != "7" || first[1] != "backup" || first[3] != "failure" || first[5] != "2" || first[8] != "1500" {
+			t.Errorf("full=%v: first row = %q", full, first)
+		}
+		if second[4] != "paused" || second[6] != "true" || second[9] != "" {
+			t.Errorf("full=%v: second row = %q", full, second)
+		}
+		if full {
+			if first[9] != longErr {
+				t.Errorf("full error = %q, want untruncated", first[9])
+			}
+			if first[10] != records[0].Output || first[11] != "warn" {
+				t.Errorf("output/stderr = %q, %q", first[10], first[11])
+			}
+		} else if first[9] != truncate(longErr, 40) {
+			t.Errorf("error = %q, want truncated", first[9])
+		}
+	}
+}
+
+func TestCmdHistory_RejectsBadOutputFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--output", "xml"},
+		{"--output", "csv", "--group-by", "rule"},
+		{"--full"},
+	} {
+		if err := cmdHistory(args); err == nil || strings.Contains(err.Error(), "not running") {
+			t.Errorf("cmdHistory(%q) error = %v, want flag error", args, err)
+		}
+	}
+}
+
+func TestPrintExecution_SeparatesStderr(t *testing.T) {
+	var b strings.Builder
+	printExecution(&b, &state.ExecutionRecord{
+		ID: 7, RuleName: "mounter", TriggerType: "manual", State: "failure", StartedAt: time.Now(),
+		Error: "exit status 1", Output: "partial\n", Stderr: "mount failed\n",
+	})
+	got := b.String()
+	for _, want := range []string{"Execution 7 of 'mounter'", "Error:    exit status 1", "Output:\n  partial\n", "Stderr:\n  mount failed\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("printExecution() = %q, missing %q", got, want)
+		}
+	}
+
+	b.Reset()
+	printExecution(&b, &state.ExecutionRecord{ID: 8, RuleName: "quiet", State: "success", StartedAt: time.Now()})
+	if !strings.Contains(b.String(), "Stderr:\n  (none)") {
+		t.Errorf("printExecution() without stderr = %q", b.String())
+	}
+	if strings.Contains(b.String(), "Exit:") {
+		t.Errorf("printExecution() without exit code = %q, want no Exit line", b.String())
+	}
+}
+
+func TestPrintExecution_ExitCode(t *testing.T) {
+	code := 127
+	var b strings.Builder
+	printExecution(&b, &state.ExecutionRecord{ID: 9, RuleName: "r", State: "failure", StartedAt: time.Now(), ExitCode: &code})
+	if !strings.Contains(b.String(), "Exit:     127 (command not found)") {
+		t.Errorf("printExecution() = %q, want exit code with hint", b.String())
+	}
+}
+
+func TestPrintExecution_ParsedOutput(t *testing.T) {
+	var b strings.Builder
+	printExecution(&b, &state.ExecutionRecord{ID: 10, RuleName: "disk", State: "success", StartedAt: time.Now(), ParsedOutput: `{"pct":"91"}`})
+	if !strings.Contains(b.String(), "Parsed output:\n  {\n    \"pct\": \"91\"\n  }\n") {
+		t.Errorf("printExecution() = %q, want the parsed output indented", b.String())
+	}
+}
+
+func TestFormatExitCode(t *testing.T) {
+	for code, want := range map[int]string{0: "0", 3: "3", 1: "1 (general error)", 137: "137 (killed (SIGKILL))"} {
+		if got := formatExitCode(code); got != want {
+			t.Errorf("formatExitCode(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestTailArgs(t *testing.T) {
+	tests := []struct {
+		lines  int
+		follow bool
+		want   []string
+	}{
+		{defaultLogLines, false, []string{"-n", "50", "/var/log/d.log"}},
+		{200, false, []string{"-n", "200", "/var/log/d.log"}},
+		{10, true, []string{"-n", "10", "-f", "/var/log/d.log"}},
+	}
+	for _, tt := range tests {
+		if got := tailArgs("/var/log/d.log", tt.lines, tt.follow); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tailArgs(%d, %v) = %q, want %q", tt.lines, tt.follow, got, tt.want)
+		}
+	}
+}
+
+func TestCmdLogs_RejectsNonPositiveTail(t *testing.T) {
+	for _, args := range [][]string{{"--tail", "0"}, {"-n", "-5"}} {
+		if err := cmdLogs(args); err == nil || !strings.Contains(err.Error(), "must be positive") {
+			t.Errorf("cmdLogs(%q) error = %v, want positive-count error", args, err)
+		}
+	}
+}
+
+func TestCmdSchema(t *testing.T) {
+	var b strings.Builder
+	if err := cmdSchema(&b, nil); err != nil {
+		t.Fatalf("cmdSchema() error = %v", err)
+	}
+	if !strings.Contains(b.String(), `"title": "srvrmgr rule"`) || !strings.Contains(b.String(), `"scheduled"`) {
+		t.Errorf("cmdSchema() = %s", b.String())
+	}
+
+	b.Reset()
+	if err := cmdSchema(&b, []string{"config"}); err != nil || !strings.Contains(b.String(), `"webhook_listen_port"`) {
+		t.Errorf("cmdSchema(config) = %v, %s", err, b.String())
+	}
+	if err := cmdSchema(&b, []string{"bogus"}); err == nil {
+		t.Error("cmdSchema(bogus) should fail")
+	}
+}
+
+func TestPrintRunSummary(t *testing.T) {
+	var b strings.Builder
+	err := printRunSummary(&b, "scheduled", []daemon.RunOutcome{
+		{Rule: "backup", State: "success"},
+		{Rule: "report", State: "skipped", Detail: "dependency"},
+		{Rule: "sync", State: "failure", Detail: "exit status 1"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 rules did not succeed") {
+		t.Errorf("printRunSummary() error = %v, want one failure", err)
+	}
+	for _, want := range []string{"sync    failure  exit status 1", "Ran 3 scheduled rules: 1 failure, 1 skipped, 1 success"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("printRunSummary() = %q, missing %q", b.String(), want)
+		}
+	}
+
+	b.Reset()
+	if err := printRunSummary(&b, "webhook", nil); err != nil || !strings.Contains(b.String(), "No enabled webhook rules") {
+		t.Errorf("printRunSummary(none) = %q, %v", b.String(), err)
+	}
+}
+
+func TestRunRule_QueuesOnRunningDaemon(t *testing.T) {
+	var gotMethod, gotPath, gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath, gotQuery = r.Method, r.URL.Path, r.URL.RawQuery
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	var b strings.Builder
+	err := runRule(&b, srv.URL, func() bool { return true }, "startup-check", "daemon_started", true, func() error {
+		t.Error("ran in-process although the daemon is running")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runRule() error = %v", err)
+	}
+	if gotMethod != http.MethodPost || gotPath != "/api/rules/startup-check/run" || gotQuery != "event=daemon_started&no_deps=true" {
+		t.Errorf("request = %s %s?%s", gotMethod, gotPath, gotQuery)
+	}
+	if !strings.Contains(b.String(), "Queued 'startup-check' on the running daemon") {
+		t.Errorf("output = %q", b.String())
+	}
+
+	// Errors from the daemon are reported, not retried in-process
+	missing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `rule "nope" not found`, http.StatusNotFound)
+	}))
+	defer missing.Close()
+	err = runRule(&b, missing.URL, func() bool { return true }, "nope", "", false, func() error {
+		t.Error("ran in-process after the daemon rejected the run")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("runRule() error = %v, want the daemon's not found", err)
+	}
+}
+
+func TestRunRule_InProcessWhenDaemonDown(t *testing.T) {
+	ran := false
+	err := runRule(io.Discard, "http://127.0.0.1:0", func() bool { return false }, "job", "", false, func() error {
+		ran = true
+		return nil
+	})
+	if err != nil || !ran {
+		t.Errorf("runRule() = %v, ran in-process %v; want an in-process run", err, ran)
+	}
+}
+
+func TestWriteTriggers(t *testing.T) {
+	now := time.Date(2025, 6, 1, 1, 30, 0, 0, time.Local)
+	body := `[{"rule":"deploy","type":"webhook","webhook_path":"/hooks/deploy"},` +
+		`{"rule":"nightly","type":"scheduled","next_run":"` + now.Add(90*time.Minute).Format(time.RFC3339) + `"}]`
+
+	var buf bytes.Buffer
+	if err := writeTriggers(&buf, []byte(body), now); err != nil {
+		t.Fatalf("writeTriggers() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("output = %q, want a header, rule and two rows", buf.String())
+	}
+	if f := strings.Fields(lines[2]); !reflect.DeepEqual(f, []string{"deploy", "webhook", "/hooks/deploy", "-"}) {
+		t.Errorf("webhook row = %q", lines[2])
+	}
+	if !strings.Contains(lines[3], "2025-06-01 03:00:00 (in 1h30m0s)") || !strings.Contains(lines[3], "scheduled") {
+		t.Errorf("scheduled row = %q, want the next run and time until it", lines[3])
+	}
+
+	buf.Reset()
+	if err := writeTriggers(&buf, []byte(`[]`), now); err != nil || !strings.Contains(buf.String(), "No triggers") {
+		t.Errorf("empty list: %q, %v", buf.String(), err)
+	}
+}
+
+// uninstallFixture creates a plist file and config and log directories.
+func uninstallFixture(t *testing.T) (plist, configDir, logsDir string) {
+	t.Helper()
+	root := t.TempDir()
+	plist = filepath.Join(root, "com.srvrmgr.daemon.plist")
+	configDir = filepath.Join(root, "config")
+	logsDir = filepath.Join(root, "logs")
+	if err := os.WriteFile(plist, []byte("<plist/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{configDir, logsDir} {
+		if err := os.MkdirAll(filepath.Join(dir, "rules"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return plist, configDir, logsDir
+}
+
+func TestUninstallPlan_DryRun(t *testing.T) {
+	plist, configDir, logsDir := uninstallFixture(t)
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	plan := planUninstall(true, plist, true, configDir, logsDir, missing)
+	var out bytes.Buffer
+	plan.describe(&out)
+
+	for _, path := range []string{plist, configDir, logsDir, filepath.Join(configDir, "rules")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s changed by dry run: %v", path, err)
+		}
+	}
+	for _, want := range []string{"Would stop the daemon", "Would remove " + plist, "Would remove " + configDir, "Would remove " + logsDir} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dry run output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), missing) {
+		t.Errorf("dry run lists %s, which doesn't exist:\n%s", missing, out.String())
+	}
+
+	plan = planUninstall(false, plist, false, configDir, logsDir)
+	out.Reset()
+	plan.describe(&out)
+	if !strings.Contains(out.String(), "Daemon is not running") || strings.Contains(out.String(), configDir) {
+		t.Errorf("dry run with config kept:\n%s", out.String())
+	}
+}
+
+func TestUninstallDataDirs(t *testing.T) {
+	got := uninstallDataDirs(filepath.Join(defaultConfigDir, "config.yaml"))
+	if want := []string{defaultConfigDir, defaultLogsDir}; !slices.Equal(got, want) {
+		t.Errorf("uninstallDataDirs(system config) = %v, want %v", got, want)
+	}
+	// A config elsewhere keeps its directory; only what the daemon made beside it goes
+	got = uninstallDataDirs("/etc/srvrmgr/config.yaml")
+	if want := []string{"/etc/srvrmgr/state", "/etc/srvrmgr/logs"}; !slices.Equal(got, want) {
+		t.Errorf("uninstallDataDirs(/etc/srvrmgr/config.yaml) = %v, want %v", got, want)
+	}
+}
+
+func TestUninstallPlan_Run(t *testing.T) {
+	plist, configDir, logsDir := uninstallFixture(t)
+
+	// Declining the prompt keeps config and logs
+	plan := planUninstall(false, plist, true, configDir, logsDir)
+	plan.AskData = true
+	if err := plan.run(io.Discard, func() bool { return false }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(plist); !os.IsNotExist(err) {
+		t.Errorf("plist still exists: %v", err)
+	}
+	for _, dir := range []string{configDir, logsDir} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s removed after declining: %v", dir, err)
+		}
+	}
+
+	plan.AskData = false
+	plan.Plist = ""
+	if err := plan.run(io.Discard, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{configDir, logsDir} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("%s still exists: %v", dir, err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// cmd/srvrmgr/memory.go
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/memory"
+)
+
+// cmdMemory inspects and prunes the memory database shared by memory-enabled
+// rules. It reads the database directly, so the daemon needn't be running.
+func cmdMemory(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: srvrmgr memory stats | purge [--category C] [--older-than AGE] [--yes]")
+	}
+	switch args[0] {
+	case "stats":
+		return cmdMemoryStats(args[1:])
+	case "purge":
+		return cmdMemoryPurge(args[1:], os.Stdin, os.Stdout)
+	}
+	return fmt.Errorf("unknown memory command %q: must be stats or purge", args[0])
+}
+
+// openMemoryDB opens the existing memory database; unlike memory.Open it
+// won't create one.
+func openMemoryDB() (*memory.DB, string, error) {
+	path, err := loadConfig().Memory.DBPath()
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, "", fmt.Errorf("no memory database at %s", path)
+	}
+	db, err := memory.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return db, path, nil
+}
+
+func cmdMemoryStats(args []string) error {
+	fs := flag.NewFlagSet("memory stats", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	db, path, err := openMemoryDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stats, err := db.Stats()
+	if err != nil {
+		return err
+	}
+	printMemoryStats(os.Stdout, path, databaseSize(path), stats)
+	return nil
+}
+
+// databaseSize is the size of a SQLite database on disk, including its
+// write-ahead log.
+func databaseSize(path string) int64 {
+	var size int64
+	for _, p := range []string{path, path + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+func printMemoryStats(w io.Writer, path string, size int64, stats memory.Stats) {
+	fmt.Fprintf(w, "Database:   %s (%s)\n", path, formatBytes(size))
+	fmt.Fprintf(w, "Memories:   %d\n", stats.Total)
+	coverage := 100.0
+	if stats.Total > 0 {
+		coverage = float64(stats.Embedded) / float64(stats.Total) * 100
+	}
+	fmt.Fprintf(w, "Embeddings: %d of %d (%.0f%%)\n", stats.Embedded, stats.Total, coverage)
+	if len(stats.Categories) == 0 {
+		return
+	}
+
+	categories := make([]string, 0, len(stats.Categories))
+	for c := range stats.Categories {
+		categories = append(categories, c)
+	}
+	// Largest first, then by name
+	sort.Slice(categories, func(i, j int) bool {
+		a, b := categories[i], categories[j]
+		if stats.Categories[a] != stats.Categories[b] {
+			return stats.Categories[a] > stats.Categories[b]
+		}
+		return a < b
+	})
+	rows := make([][]string, len(categories))
+	for i, c := range categories {
+		name := c
+		if name == "" {
+			name = "(none)"
+		}
+		rows[i] = []string{name, strconv.Itoa(stats.Categories[c])}
+	}
+	fmt.Fprintln(w)
+	writeTable(w, []string{"CATEGORY", "MEMORIES"}, rows)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+func cmdMemoryPurge(args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("memory purge", flag.ContinueOnError)
+	category := fs.String("category", "", "only delete memories in this category")
+	olderThan := fs.String("older-than", "", "only delete memories older than this, e.g. 30d or 12h")
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var cutoff time.Time
+	if *olderThan != "" {
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+		cutoff = time.Now().Add(-age)
+	}
+
+	db, _, err := openMemoryDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return purgeMemories(db, *category, cutoff, *yes, in, out)
+}
+
+// purgeMemories deletes the memories matching category and cutoff (every
+// memory if both are unset), after asking on in unless yes is set.
+func purgeMemories(db *memory.DB, category string, cutoff time.Time, yes bool, in io.Reader, out io.Writer) error {
+	n, err := db.CountWhere(category, cutoff)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Fprintln(out, "No memories match.")
+		return nil
+	}
+
+	if !yes {
+		prompt := fmt.Sprintf("Delete %d memories? (y/N): ", n)
+		if category == "" && cutoff.IsZero() {
+			prompt = fmt.Sprintf("Delete all %d memories? (y/N): ", n)
+		}
+		fmt.Fprint(out, prompt)
+		response, _ := bufio.NewReader(in).ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(response)) != "y" {
+			fmt.Fprintln(out, "Cancelled.")
+			return nil
+		}
+	}
+
+	var deleted int64
+	if category == "" && cutoff.IsZero() {
+		deleted, err = db.ForgetAll()
+	} else {
+		deleted, err = db.ForgetWhere(category, cutoff)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Deleted %d memories.\n", deleted)
+	return nil
+}
+
+// parseAge parses a Go duration, or a whole number of days such as "30d".
+func parseAge(s string) (time.Duration, error) {
+	var age time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number of days", s)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if age, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("%q must be positive", s)
+	}
+	return age, nil
+}
@@ -0,0 +1,123 @@
+// cmd/srvrmgr/memory_test.go
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/memory"
+)
+
+func openMemoryTestDB(t *testing.T) *memory.DB {
+	t.Helper()
+	db, err := memory.Open(filepath.Join(t.TempDir(), "memory.db"))
+	if err != nil {
+		t.Fatalf("memory.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestPrintMemoryStats(t *testing.T) {
+	withColor(t, false)
+	var out strings.Builder
+	printMemoryStats(&out, "/tmp/memory.db", 1536, memory.Stats{
+		Total:      4,
+		Embedded:   3,
+		Categories: map[string]int{"file-patterns": 1, "": 1, "api-behaviors": 2},
+	})
+	want := "Database:   /tmp/memory.db (1.5 KB)\n" +
+		"Memories:   4\n" +
+		"Embeddings: 3 of 4 (75%)\n" +
+		"\n" +
+		"CATEGORY       MEMORIES\n" +
+		strings.Repeat("─", 60) + "\n" +
+		"api-behaviors  2\n" +
+		"(none)         1\n" +
+		"file-patterns  1\n"
+	if out.String() != want {
+		t.Errorf("printMemoryStats() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestPurgeMemories(t *testing.T) {
+	seed := func(t *testing.T) *memory.DB {
+		db := openMemoryTestDB(t)
+		db.Remember("scratch one", "temp", "")
+		db.Remember("scratch two", "temp", "")
+		db.Remember("invoices are PDFs", "file-patterns", "")
+		return db
+	}
+	remaining := func(t *testing.T, db *memory.DB) int64 {
+		n, err := db.CountWhere("", time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	t.Run("category", func(t *testing.T) {
+		db := seed(t)
+		var out strings.Builder
+		if err := purgeMemories(db, "temp", time.Time{}, false, strings.NewReader("y\n"), &out); err != nil {
+			t.Fatalf("purgeMemories() error = %v", err)
+		}
+		if !strings.Contains(out.String(), "Delete 2 memories?") || !strings.Contains(out.String(), "Deleted 2 memories.") {
+			t.Errorf("output = %q", out.String())
+		}
+		if n := remaining(t, db); n != 1 {
+			t.Errorf("remaining = %d, want 1", n)
+		}
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		db := seed(t)
+		var out strings.Builder
+		if err := purgeMemories(db, "", time.Time{}, false, strings.NewReader("\n"), &out); err != nil {
+			t.Fatalf("purgeMemories() error = %v", err)
+		}
+		if !strings.Contains(out.String(), "Delete all 3 memories?") {
+			t.Errorf("output = %q, want a warning that everything goes", out.String())
+		}
+		if n := remaining(t, db); n != 3 {
+			t.Errorf("remaining = %d after declining, want 3", n)
+		}
+	})
+
+	t.Run("everything with --yes", func(t *testing.T) {
+		db := seed(t)
+		var out strings.Builder
+		if err := purgeMemories(db, "", time.Time{}, true, strings.NewReader(""), &out); err != nil {
+			t.Fatalf("purgeMemories() error = %v", err)
+		}
+		if n := remaining(t, db); n != 0 {
+			t.Errorf("remaining = %d, want 0", n)
+		}
+	})
+
+	t.Run("nothing older", func(t *testing.T) {
+		db := seed(t)
+		var out strings.Builder
+		if err := purgeMemories(db, "", time.Now().AddDate(0, 0, -30), false, strings.NewReader(""), &out); err != nil {
+			t.Fatalf("purgeMemories() error = %v", err)
+		}
+		if out.String() != "No memories match.\n" {
+			t.Errorf("output = %q", out.String())
+		}
+	})
+}
+
+func TestParseAge(t *testing.T) {
+	for in, want := range map[string]time.Duration{"30d": 30 * 24 * time.Hour, "12h": 12 * time.Hour, "1d": 24 * time.Hour} {
+		if got, err := parseAge(in); err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0d", "-1h", "xd", "soon"} {
+		if _, err := parseAge(in); err == nil {
+			t.Errorf("parseAge(%q) should fail", in)
+		}
+	}
+}
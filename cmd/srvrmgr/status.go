@@ -0,0 +1,95 @@
+// cmd/srvrmgr/status.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+)
+
+// statusTimeout bounds the daemon API calls made by srvrmgr status --json.
+const statusTimeout = 2 * time.Second
+
+// daemonStatus is the document printed by srvrmgr status --json. Health and
+// Rules are the daemon's /health and /api/rules responses as-is; they are
+// omitted when the daemon can't be reached, which is reported in the other
+// fields rather than as an error.
+type daemonStatus struct {
+	Running      bool   `json:"running"`
+	APIReachable bool   `json:"api_reachable"`
+	Error        string `json:"error,omitempty"`
+	Ready        bool   `json:"ready"`
+	ReadyReason  string `json:"ready_reason,omitempty"`
+	Health       any    `json:"health,omitempty"`
+	Rules        any    `json:"rules,omitempty"`
+	RulesOnDisk  *int   `json:"rules_on_disk,omitempty"`
+}
+
+// collectStatus queries /health, /ready and /api/rules under baseURL. As in
+// checkHealth, running is only consulted when the API can't be reached.
+// rulesOnDisk counts the rule files, for when the daemon can't.
+func collectStatus(baseURL string, timeout time.Duration, running func() bool, rulesOnDisk func() (int, error)) daemonStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var st daemonStatus
+	status, body, err := getWithContext(ctx, baseURL+"/health")
+	if err != nil {
+		st.Running = running()
+		if st.Running {
+			st.Error = fmt.Sprintf("API unreachable: %v", err)
+		}
+		if n, err := rulesOnDisk(); err == nil {
+			st.RulesOnDisk = &n
+		}
+		return st
+	}
+	st.Running, st.APIReachable = true, true
+	if status != http.StatusOK {
+		st.Error = fmt.Sprintf("/health returned %d", status)
+	}
+	if err := json.Unmarshal(body, &st.Health); err != nil {
+		st.Error = fmt.Sprintf("parsing /health response: %v", err)
+		st.Health = nil
+	}
+
+	var ready struct {
+		Ready  bool   `json:"ready"`
+		Reason string `json:"reason"`
+	}
+	if _, body, err := getWithContext(ctx, baseURL+"/ready"); err != nil {
+		st.ReadyReason = err.Error()
+	} else if json.Unmarshal(body, &ready) == nil {
+		st.Ready, st.ReadyReason = ready.Ready, ready.Reason
+	}
+
+	if status, body, err := getWithContext(ctx, baseURL+"/api/rules"); err == nil && status == http.StatusOK {
+		if json.Unmarshal(body, &st.Rules) != nil {
+			st.Rules = nil
+		}
+	}
+	return st
+}
+
+// cmdStatusJSON writes the daemon's status as one JSON document. A stopped
+// daemon is a normal status, not an error.
+func cmdStatusJSON(w io.Writer) error {
+	cfg := loadConfig()
+	baseURL := daemonBaseURL(cfg)
+	st := collectStatus(baseURL, statusTimeout, isRunning, func() (int, error) {
+		dir, err := rulesDir()
+		if err != nil {
+			return 0, err
+		}
+		rules, err := config.LoadRulesDir(dir)
+		return len(rules), err
+	})
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(st)
+}
@@ -0,0 +1,93 @@
+// cmd/srvrmgr/status_test.go
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// roundTrip encodes st as srvrmgr status --json would and decodes it
+// generically, checking the output is valid JSON.
+func roundTrip(t *testing.T, st daemonStatus) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatalf("encoding status: %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("status is not valid JSON: %v\n%s", err, data)
+	}
+	return doc
+}
+
+func TestCollectStatus_Running(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok","uptime":"1h0m0s","rules_loaded":2,"rules_enabled":1,"paused":false}`))
+	})
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ready":true}`))
+	})
+	mux.HandleFunc("/api/rules", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"backup","enabled":true,"last_state":"success"},{"name":"cleanup","enabled":false}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	st := collectStatus(srv.URL, time.Second, func() bool {
+		t.Error("running() consulted although the API answered")
+		return true
+	}, func() (int, error) {
+		t.Error("rulesOnDisk() consulted although the daemon answered")
+		return 0, nil
+	})
+
+	doc := roundTrip(t, st)
+	if doc["running"] != true || doc["api_reachable"] != true || doc["ready"] != true {
+		t.Errorf("status = %v, want running, reachable and ready", doc)
+	}
+	if _, ok := doc["error"]; ok {
+		t.Errorf("unexpected error in status: %v", doc["error"])
+	}
+	health, _ := doc["health"].(map[string]any)
+	if health["uptime"] != "1h0m0s" || health["rules_enabled"] != 1.0 {
+		t.Errorf("health = %v, want the /health response", doc["health"])
+	}
+	rules, _ := doc["rules"].([]any)
+	var names []string
+	for _, r := range rules {
+		names = append(names, r.(map[string]any)["name"].(string))
+	}
+	if want := []string{"backup", "cleanup"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("rules = %v, want %v", names, want)
+	}
+}
+
+func TestCollectStatus_NotRunning(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	st := collectStatus(closed.URL, time.Second, func() bool { return false }, func() (int, error) { return 3, nil })
+
+	doc := roundTrip(t, st)
+	want := map[string]any{
+		"running":       false,
+		"api_reachable": false,
+		"ready":         false,
+		"rules_on_disk": 3.0,
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("status = %v, want %v", doc, want)
+	}
+
+	// Loaded but with the API down is reported, still without an error exit
+	st = collectStatus(closed.URL, time.Second, func() bool { return true }, func() (int, error) { return 3, nil })
+	if doc := roundTrip(t, st); doc["running"] != true || doc["api_reachable"] != false || doc["error"] == nil {
+		t.Errorf("status = %v, want running with an unreachable API", doc)
+	}
+}
@@ -0,0 +1,83 @@
+// cmd/srvrmgr/watch.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/fsnotify/fsnotify"
+)
+
+// validateWatchDebounce is how long validate --watch waits after the last
+// change to a rule file before re-validating, so an editor's burst of writes
+// is validated once, as the daemon does before reloading; tests shorten it.
+var validateWatchDebounce = 1 * time.Second
+
+// cmdValidateWatch re-validates the rules in dir whenever a rule file
+// changes, until interrupted.
+func cmdValidateWatch(w io.Writer, dir string, workers int) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return watchValidate(ctx, w, dir, workers)
+}
+
+// watchValidate validates every rule in dir, then again after each burst of
+// changes to rule files, until ctx is done. Invalid rules are reported and
+// don't end the loop.
+func watchValidate(ctx context.Context, w io.Writer, dir string, workers int) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("creating rules watcher: %w", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("watching rules directory: %w", err)
+	}
+
+	validate := func() {
+		fmt.Fprintf(w, "\n[%s] Validating %s\n\n", time.Now().Format("15:04:05"), dir)
+		invalid, total, err := writeValidation(w, dir, workers)
+		switch {
+		case err != nil:
+			fmt.Fprintf(w, "%s: %v\n", colorStatus("FAIL"), err)
+		case invalid > 0:
+			fmt.Fprintf(w, "%s: %d of %d rules are invalid\n", colorStatus("FAIL"), invalid, total)
+		default:
+			fmt.Fprintf(w, "%s: all %d rules are valid\n", colorStatus("ok"), total)
+		}
+		fmt.Fprintln(w, "Watching for changes (Ctrl-C to stop)...")
+	}
+	validate()
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if config.IsRuleFile(event.Name) {
+				debounce = time.After(validateWatchDebounce)
+			}
+
+		case <-debounce:
+			debounce = nil
+			validate()
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(w, "watch error: %v\n", err)
+		}
+	}
+}
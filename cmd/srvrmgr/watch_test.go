@@ -0,0 +1,72 @@
+// cmd/srvrmgr/watch_test.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchValidate_RevalidatesOnChange(t *testing.T) {
+	prev := validateWatchDebounce
+	validateWatchDebounce = 50 * time.Millisecond
+	t.Cleanup(func() { validateWatchDebounce = prev })
+
+	dir := writeSampleRules(t, 1)
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watchValidate(ctx, pw, dir, 2) }()
+	t.Cleanup(func() {
+		cancel()
+		pr.Close()
+		<-done
+	})
+
+	// Summary lines, one per validation run
+	summaries := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.Contains(line, " valid, ") {
+				summaries <- line
+			}
+		}
+	}()
+	next := func() string {
+		t.Helper()
+		select {
+		case line := <-summaries:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for validation")
+			return ""
+		}
+	}
+
+	if got, want := next(), "3 valid, 2 invalid (total 5)"; got != want {
+		t.Errorf("initial validation = %q, want %q", got, want)
+	}
+
+	// Break a valid rule; a burst of writes is validated once
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(filepath.Join(dir, "rule-000.yaml"), []byte("name: rule-000\ntrigger:\n  type: manual\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := next(), "2 valid, 3 invalid (total 5)"; got != want {
+		t.Errorf("after breaking a rule = %q, want %q", got, want)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "garbage.yml")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := next(), "2 valid, 2 invalid (total 4)"; got != want {
+		t.Errorf("after removing a rule = %q, want %q", got, want)
+	}
+}
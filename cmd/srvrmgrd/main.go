@@ -3,22 +3,56 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 
+	"github.com/colebrumley/srvrmgr/internal/config"
 	"github.com/colebrumley/srvrmgr/internal/daemon"
 	"github.com/colebrumley/srvrmgr/internal/mcp"
+	"github.com/colebrumley/srvrmgr/internal/state"
 )
 
 const (
-	defaultConfigPath = "/Library/Application Support/srvrmgr/config.yaml"
-	defaultRulesDir   = "/Library/Application Support/srvrmgr/rules"
+	defaultConfigPath = config.SystemConfigDir + "/config.yaml"
+	defaultRulesDir   = config.SystemConfigDir + "/rules"
 )
 
-const defaultMCPPort = "9877"
+// daemonPaths are the daemon's default file locations.
+type daemonPaths struct {
+	configPath string
+	rulesDir   string
+	stateDB    string
+	logDir     string
+	userMode   bool // not root: user-scoped paths, no run_as_user
+}
+
+// defaultPaths returns the system-wide paths for root (euid 0) and paths under
+// home's ~/Library for anyone else.
+func defaultPaths(euid int, home string) daemonPaths {
+	if euid == 0 {
+		return daemonPaths{
+			configPath: defaultConfigPath,
+			rulesDir:   defaultRulesDir,
+			stateDB:    config.SystemStateDB,
+			logDir:     config.SystemLogDir,
+		}
+	}
+	base := filepath.Join(home, "Library", "Application Support", "srvrmgr")
+	return daemonPaths{
+		configPath: filepath.Join(base, "config.yaml"),
+		rulesDir:   filepath.Join(base, "rules"),
+		stateDB:    filepath.Join(base, "state", "history.db"),
+		logDir:     filepath.Join(home, "Library", "Logs", "srvrmgr"),
+		userMode:   true,
+	}
+}
 
 func main() {
 	if len(os.Args) > 1 {
@@ -29,21 +63,26 @@ func main() {
 		case "mcp-http-server":
 			runMCPHTTPServer()
 			return
+		case "mcp-tools":
+			runMCPTools()
+			return
 		}
 	}
 
-	runDaemon()
+	runDaemon(os.Args[1:])
 }
 
 func runMCPServer() {
-	dbPath := os.Getenv("SRVRMGR_MEMORY_DB")
-	if dbPath == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error getting home directory: %v\n", err)
-			os.Exit(1)
-		}
-		dbPath = filepath.Join(homeDir, "Library/Application Support/srvrmgr/memory.db")
+	// The daemon has already validated its config; if it can't be read here,
+	// memory still works with the defaults
+	memCfg, err := memoryConfig(daemonConfigPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	dbPath, err := memCfg.DBPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error getting home directory: %v\n", err)
+		os.Exit(1)
 	}
 
 	server, err := mcp.NewServer(dbPath)
@@ -52,6 +91,9 @@ func runMCPServer() {
 		os.Exit(1)
 	}
 	defer server.Close()
+	// Set by the executor so memories are tagged with the rule that spawned us
+	server.SetRule(os.Getenv(mcp.RuleEnv))
+	server.SetUnknownCategory(memCfg.UnknownCategory)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -70,21 +112,26 @@ func runMCPServer() {
 }
 
 func runMCPHTTPServer() {
-	dbPath := os.Getenv("SRVRMGR_MEMORY_DB")
-	if dbPath == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error getting home directory: %v\n", err)
-			os.Exit(1)
-		}
-		dbPath = filepath.Join(homeDir, "Library/Application Support/srvrmgr/memory.db")
+	memCfg, err := memoryConfig(daemonConfigPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
 	}
-
-	port := os.Getenv("SRVRMGR_MCP_PORT")
-	if port == "" {
-		port = defaultMCPPort
+	dbPath, err := memCfg.DBPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error getting home directory: %v\n", err)
+		os.Exit(1)
+	}
+	addr, err := memCfg.ListenAddr(os.Getenv("SRVRMGR_MCP_PORT"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	ln, err := mcp.Listen(addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
 	}
-	addr := "127.0.0.1:" + port
 
 	fmt.Fprintf(os.Stderr, "Loading embedding model...\n")
 	server, err := mcp.NewServer(dbPath)
@@ -93,6 +140,7 @@ func runMCPHTTPServer() {
 		os.Exit(1)
 	}
 	defer server.Close()
+	server.SetUnknownCategory(memCfg.UnknownCategory)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -105,40 +153,183 @@ func runMCPHTTPServer() {
 		cancel()
 	}()
 
-	fmt.Fprintf(os.Stderr, "MCP HTTP server listening on %s\n", addr)
-	if err := server.RunHTTP(ctx, addr); err != nil {
+	fmt.Fprintf(os.Stderr, "MCP HTTP server listening on %s\n", ln.Addr())
+	if err := server.RunHTTP(ctx, ln); err != nil {
 		fmt.Fprintf(os.Stderr, "MCP HTTP server error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func runDaemon() {
+// daemonConfigPath returns SRVRMGR_CONFIG, or the daemon's default config
+// path for the current user.
+func daemonConfigPath() string {
+	if configPath := os.Getenv("SRVRMGR_CONFIG"); configPath != "" {
+		return configPath
+	}
+	homeDir, _ := os.UserHomeDir()
+	return defaultPaths(os.Geteuid(), homeDir).configPath
+}
+
+// memoryConfig reads the memory section of the daemon config at configPath,
+// which the standalone memory servers share with the daemon. Without a
+// config the defaults apply.
+func memoryConfig(configPath string) (config.MemoryConfig, error) {
+	if _, err := os.Stat(configPath); err != nil {
+		return config.MemoryConfig{}, nil
+	}
+	cfg, err := config.LoadGlobal(configPath)
+	if err != nil {
+		return config.MemoryConfig{}, err
+	}
+	return cfg.Memory, nil
+}
+
+// runMCPTools prints the tools the memory server offers, for checking what
+// Claude will see when memory is enabled.
+func runMCPTools() {
+	memCfg, err := memoryConfig(daemonConfigPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	dbPath, err := memCfg.DBPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error getting home directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	server, err := mcp.NewServer(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating MCP server: %v\n", err)
+		os.Exit(1)
+	}
+	defer server.Close()
+	// The daemon's shared server reads its history; listing needs none
+	server.SetHistory(noHistory{})
+
+	if err := printMCPTools(context.Background(), os.Stdout, server); err != nil {
+		fmt.Fprintf(os.Stderr, "error listing MCP tools: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// noHistory is an empty execution history, so a server that only lists its
+// tools offers recall_executions like the daemon's.
+type noHistory struct{}
+
+func (noHistory) GetHistory(string, string, []string, int) ([]state.ExecutionRecord, error) {
+	return nil, nil
+}
+
+// printMCPTools writes each tool's name, description and input schema.
+func printMCPTools(ctx context.Context, w io.Writer, server *mcp.Server) error {
+	tools, err := server.Tools(ctx)
+	if err != nil {
+		return err
+	}
+	for i, tool := range tools {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		schema, err := json.MarshalIndent(tool.InputSchema, "    ", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding %s schema: %w", tool.Name, err)
+		}
+		fmt.Fprintln(w, tool.Name)
+		fmt.Fprintf(w, "  %s\n", tool.Description)
+		fmt.Fprintf(w, "  Input schema:\n    %s\n", schema)
+	}
+	return nil
+}
+
+// concurrencyOverride returns the rule_execution.max_concurrent override
+// given by --concurrency, else by SRVRMGR_MAX_CONCURRENT (env), or 0 for none.
+func concurrencyOverride(args []string, env string) (int, error) {
+	fs := flag.NewFlagSet("srvrmgrd", flag.ContinueOnError)
+	concurrency := fs.Int("concurrency", 0, "max rules executing at once, overriding rule_execution.max_concurrent")
+	if err := fs.Parse(args); err != nil {
+		return 0, err
+	}
+
+	set := false
+	fs.Visit(func(f *flag.Flag) { set = set || f.Name == "concurrency" })
+
+	var source string
+	var n int
+	switch {
+	case set:
+		source, n = "--concurrency", *concurrency
+	case env != "":
+		v, err := strconv.Atoi(env)
+		if err != nil {
+			return 0, fmt.Errorf("SRVRMGR_MAX_CONCURRENT: %q is not a number", env)
+		}
+		source, n = "SRVRMGR_MAX_CONCURRENT", v
+	default:
+		return 0, nil
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got %d", source, n)
+	}
+	return n, nil
+}
+
+func runDaemon(args []string) {
+	concurrency, err := concurrencyOverride(args, os.Getenv("SRVRMGR_MAX_CONCURRENT"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+
+	euid := os.Geteuid()
+	homeDir, err := os.UserHomeDir()
+	if err != nil && euid != 0 {
+		fmt.Fprintf(os.Stderr, "error getting home directory: %v\n", err)
+		os.Exit(1)
+	}
+	paths := defaultPaths(euid, homeDir)
+
 	configPath := os.Getenv("SRVRMGR_CONFIG")
 	if configPath == "" {
-		configPath = defaultConfigPath
+		configPath = paths.configPath
 	}
 
 	rulesDir := os.Getenv("SRVRMGR_RULES_DIR")
 	if rulesDir == "" {
-		rulesDir = defaultRulesDir
+		rulesDir = paths.rulesDir
 	}
 
 	d := daemon.New(configPath, rulesDir)
+	if paths.userMode {
+		fmt.Fprintf(os.Stderr, "Not running as root: using %s and %s; rules with run_as_user are disabled\n", filepath.Dir(paths.stateDB), paths.logDir)
+		d.SetUserMode(paths.stateDB, paths.logDir)
+	}
+	if concurrency > 0 {
+		d.SetMaxConcurrent(concurrency)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		<-sigCh
-		fmt.Println("\nReceived shutdown signal")
-		cancel()
-	}()
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go handleSignals(sigCh, d.Reload, cancel)
 
 	if err := d.Run(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "daemon error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// handleSignals calls reload on SIGHUP and cancels the daemon on any other
+// signal.
+func handleSignals(sigCh <-chan os.Signal, reload func(), cancel context.CancelFunc) {
+	for sig := range sigCh {
+		if sig == syscall.SIGHUP {
+			reload()
+			continue
+		}
+		fmt.Println("\nReceived shutdown signal")
+		cancel()
+		return
+	}
+}
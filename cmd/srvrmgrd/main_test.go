@@ -0,0 +1,168 @@
+// cmd/srvrmgrd/main_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/mcp"
+)
+
+func TestDefaultPaths_Root(t *testing.T) {
+	p := defaultPaths(0, "/var/root")
+	if p.userMode {
+		t.Error("root should not run in user mode")
+	}
+	if p.configPath != defaultConfigPath || p.rulesDir != defaultRulesDir || p.stateDB != config.SystemStateDB || p.logDir != config.SystemLogDir {
+		t.Errorf("root paths = %+v, want system-wide defaults", p)
+	}
+}
+
+func TestDefaultPaths_User(t *testing.T) {
+	p := defaultPaths(501, "/Users/alice")
+	if !p.userMode {
+		t.Error("non-root should run in user mode")
+	}
+	want := daemonPaths{
+		configPath: "/Users/alice/Library/Application Support/srvrmgr/config.yaml",
+		rulesDir:   "/Users/alice/Library/Application Support/srvrmgr/rules",
+		stateDB:    "/Users/alice/Library/Application Support/srvrmgr/state/history.db",
+		logDir:     "/Users/alice/Library/Logs/srvrmgr",
+		userMode:   true,
+	}
+	if p != want {
+		t.Errorf("user paths = %+v, want %+v", p, want)
+	}
+}
+
+func TestHandleSignals(t *testing.T) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	reloaded := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		handleSignals(sigCh, func() { reloaded <- struct{}{} }, cancel)
+		close(done)
+	}()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGHUP did not trigger a reload")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("SIGHUP cancelled the daemon")
+	}
+
+	sigCh <- syscall.SIGTERM
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGTERM did not stop the signal handler")
+	}
+	if ctx.Err() == nil {
+		t.Error("SIGTERM did not cancel the daemon")
+	}
+}
+
+func TestConcurrencyOverride(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		want    int
+		wantErr bool
+	}{
+		{"none", nil, "", 0, false},
+		{"flag", []string{"--concurrency", "4"}, "", 4, false},
+		{"env", nil, "2", 2, false},
+		{"flag beats env", []string{"--concurrency=8"}, "2", 8, false},
+		{"zero flag", []string{"--concurrency", "0"}, "", 0, true},
+		{"negative env", nil, "-1", 0, true},
+		{"non-numeric env", nil, "lots", 0, true},
+		{"unknown flag", []string{"--verbose"}, "", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := concurrencyOverride(tt.args, tt.env)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: concurrencyOverride() = %d, %v; want %d, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPrintMCPTools(t *testing.T) {
+	server, err := mcp.NewServer(filepath.Join(t.TempDir(), "memory.db"))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+
+	var out strings.Builder
+	server.SetHistory(noHistory{})
+	if err := printMCPTools(context.Background(), &out, server); err != nil {
+		t.Fatalf("printMCPTools() error = %v", err)
+	}
+	for _, want := range []string{
+		"remember\n  Store domain knowledge",
+		"recall_executions\n",
+		"recall\n  Search stored memories",
+		"forget\n  Remove a memory",
+		`"query"`, // from recall's input schema
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestMemoryConfig(t *testing.T) {
+	dir := t.TempDir()
+	memCfg, err := memoryConfig(filepath.Join(dir, "missing.yaml"))
+	if err != nil {
+		t.Fatalf("memoryConfig(no config) error = %v", err)
+	}
+	if got, err := memCfg.ListenAddr(""); err != nil || got != "127.0.0.1:9877" {
+		t.Errorf("ListenAddr(no config) = %q, %v; want default", got, err)
+	}
+
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("memory:\n  listen_address: 127.0.0.2\n  listen_port: 9000\n  unknown_category: all\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if memCfg, err = memoryConfig(configPath); err != nil {
+		t.Fatalf("memoryConfig() error = %v", err)
+	}
+	if memCfg.UnknownCategory != config.UnknownCategoryAll {
+		t.Errorf("UnknownCategory = %q, want %q", memCfg.UnknownCategory, config.UnknownCategoryAll)
+	}
+	if got, err := memCfg.ListenAddr(""); err != nil || got != "127.0.0.2:9000" {
+		t.Errorf("ListenAddr(config) = %q, %v; want 127.0.0.2:9000", got, err)
+	}
+	if got, err := memCfg.ListenAddr("9100"); err != nil || got != "127.0.0.2:9100" {
+		t.Errorf("ListenAddr(config, env) = %q, %v; want env port", got, err)
+	}
+	if _, err := memCfg.ListenAddr("0"); err == nil {
+		t.Error("expected error for out-of-range SRVRMGR_MCP_PORT")
+	}
+
+	if err := os.WriteFile(configPath, []byte("memory:\n  unknown_category: guess\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := memoryConfig(configPath); err == nil {
+		t.Error("expected error for invalid unknown_category")
+	}
+}
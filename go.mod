@@ -3,8 +3,10 @@ module github.com/colebrumley/srvrmgr
 go 1.25.5
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/fsnotify/fsevents v0.2.0
 	github.com/fsnotify/fsnotify v1.9.0
+	github.com/google/jsonschema-go v0.3.0
 	github.com/knights-analytics/hugot v0.6.2
 	github.com/modelcontextprotocol/go-sdk v1.2.0
 	github.com/robfig/cron/v3 v3.0.1
@@ -24,7 +26,6 @@ require (
 	github.com/gomlx/go-xla v0.1.4 // indirect
 	github.com/gomlx/gomlx v0.26.0 // indirect
 	github.com/gomlx/onnx-gomlx v0.3.4 // indirect
-	github.com/google/jsonschema-go v0.3.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/knights-analytics/ortgenai v0.0.3 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
@@ -0,0 +1,548 @@
+// internal/condition/condition.go
+// Rule guard expressions (`when:`) evaluated before execution.
+// The language is deliberately tiny: literals, event data identifiers,
+// comparisons, && / || / !, parentheses and a fixed set of built-ins.
+// There is no way to call arbitrary code.
+package condition
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// now is the clock used by time built-ins; tests override it.
+var now = time.Now
+
+// Expr is a parsed condition expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// String returns the source text of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Parse parses a condition expression, reporting syntax errors, unknown
+// built-ins and wrong argument counts.
+func Parse(src string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Eval evaluates the expression against event data. Identifiers resolve to
+// data values; a missing key evaluates to the empty string.
+func (e *Expr) Eval(data map[string]any) (bool, error) {
+	v, err := e.root.eval(data)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition must evaluate to a boolean, got %s", typeName(v))
+	}
+	return b, nil
+}
+
+// Evaluate parses and evaluates src in one step.
+func Evaluate(src string, data map[string]any) (bool, error) {
+	e, err := Parse(src)
+	if err != nil {
+		return false, err
+	}
+	return e.Eval(data)
+}
+
+// ===== Lexer =====
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			toks = append(toks, token{tokComma, ",", i})
+			i++
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var b strings.Builder
+			for i < len(src) && src[i] != c {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				b.WriteByte(src[i])
+				i++
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			toks = append(toks, token{tokString, b.String(), start})
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start := i
+			i++
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			toks = append(toks, token{tokNumber, src[start:i], start})
+		case isIdentByte(c):
+			start := i
+			for i < len(src) && (isIdentByte(src[i]) || src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			toks = append(toks, token{tokIdent, src[start:i], start})
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			toks = append(toks, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(toks, token{tokEOF, "end of expression", len(src)}), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// ===== Parser =====
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	tok := p.toks[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "!" {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	if tok.kind == tokOp {
+		switch tok.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+			p.next()
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return compareNode{op: tok.text, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("expected ) at position %d, got %q", closing.pos, closing.text)
+		}
+		return inner, nil
+	case tokString:
+		return literalNode{value: tok.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return literalNode{value: f}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		}
+		if p.peek().kind == tokLParen {
+			return p.parseCall(tok)
+		}
+		return identNode{name: tok.text}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := builtins[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+	}
+	p.next() // (
+
+	var args []node
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if closing := p.next(); closing.kind != tokRParen {
+		return nil, fmt.Errorf("expected ) at position %d, got %q", closing.pos, closing.text)
+	}
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("%s() takes %d argument(s), got %d", name.text, fn.arity, len(args))
+	}
+	return callNode{name: name.text, fn: fn, args: args}, nil
+}
+
+// ===== Evaluation =====
+
+type node interface {
+	eval(data map[string]any) (any, error)
+}
+
+type literalNode struct{ value any }
+
+func (n literalNode) eval(map[string]any) (any, error) { return n.value, nil }
+
+type identNode struct{ name string }
+
+func (n identNode) eval(data map[string]any) (any, error) {
+	v, ok := data[n.name]
+	if !ok || v == nil {
+		return "", nil
+	}
+	return normalize(v), nil
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(data map[string]any) (any, error) {
+	v, err := n.operand.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("! requires a boolean, got %s", typeName(v))
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n logicalNode) eval(data map[string]any) (any, error) {
+	l, err := evalBool(n.left, data, n.op)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit
+	if n.op == "&&" && !l || n.op == "||" && l {
+		return l, nil
+	}
+	return evalBool(n.right, data, n.op)
+}
+
+func evalBool(n node, data map[string]any, op string) (bool, error) {
+	v, err := n.eval(data)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s requires booleans, got %s", op, typeName(v))
+	}
+	return b, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(data map[string]any) (any, error) {
+	l, err := n.left.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers compare numerically, including numeric strings from event data
+	lf, lNum := asNumber(l)
+	rf, rNum := asNumber(r)
+	if lNum && rNum {
+		switch n.op {
+		case "==":
+			return lf == rf, nil
+		case "!=":
+			return lf != rf, nil
+		case "<":
+			return lf < rf, nil
+		case "<=":
+			return lf <= rf, nil
+		case ">":
+			return lf > rf, nil
+		case ">=":
+			return lf >= rf, nil
+		}
+	}
+
+	switch n.op {
+	case "==":
+		return fmt.Sprint(l) == fmt.Sprint(r), nil
+	case "!=":
+		return fmt.Sprint(l) != fmt.Sprint(r), nil
+	}
+
+	ls, lStr := l.(string)
+	rs, rStr := r.(string)
+	if !lStr || !rStr {
+		return nil, fmt.Errorf("%s requires two numbers or two strings, got %s and %s", n.op, typeName(l), typeName(r))
+	}
+	switch n.op {
+	case "<":
+		return ls < rs, nil
+	case "<=":
+		return ls <= rs, nil
+	case ">":
+		return ls > rs, nil
+	default:
+		return ls >= rs, nil
+	}
+}
+
+type callNode struct {
+	name string
+	fn   builtin
+	args []node
+}
+
+func (n callNode) eval(data map[string]any) (any, error) {
+	args := make([]string, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(data)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = fmt.Sprint(v)
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", n.name, err)
+	}
+	return v, nil
+}
+
+// ===== Built-ins =====
+
+type builtin struct {
+	arity int
+	call  func(args []string) (any, error)
+}
+
+var builtins = map[string]builtin{
+	// file_exists(path) reports whether path exists
+	"file_exists": {1, func(args []string) (any, error) {
+		_, err := os.Stat(args[0])
+		return err == nil, nil
+	}},
+	// env(name) returns the daemon's environment variable, or ""
+	"env": {1, func(args []string) (any, error) {
+		return os.Getenv(args[0]), nil
+	}},
+	// time_between("HH:MM", "HH:MM") reports whether the local time of day falls
+	// in [start, end); ranges may wrap past midnight, e.g. ("22:00", "06:00")
+	"time_between": {2, func(args []string) (any, error) {
+		start, err := parseClock(args[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(args[1])
+		if err != nil {
+			return nil, err
+		}
+		t := now()
+		cur := t.Hour()*60 + t.Minute()
+		if start <= end {
+			return cur >= start && cur < end, nil
+		}
+		return cur >= start || cur < end, nil
+	}},
+	// hour() returns the current local hour (0-23)
+	"hour": {0, func([]string) (any, error) {
+		return float64(now().Hour()), nil
+	}},
+	// weekday() returns the current local weekday in lower case, e.g. "monday"
+	"weekday": {0, func([]string) (any, error) {
+		return strings.ToLower(now().Weekday().String()), nil
+	}},
+}
+
+// parseClock parses "HH:MM" into minutes past midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// normalize converts event data values to the evaluator's string/float64/bool types.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case bool, string, float64:
+		return x
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case float32:
+		return float64(x)
+	case uint:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	case uint32:
+		return float64(x)
+	}
+	return fmt.Sprint(v)
+}
+
+func asNumber(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	}
+	return fmt.Sprintf("%T", v)
+}
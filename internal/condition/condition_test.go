@@ -0,0 +1,119 @@
+// internal/condition/condition_test.go
+package condition
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "present")
+	if err := os.WriteFile(existing, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SRVRMGR_TEST_POWER", "ac")
+
+	data := map[string]any{
+		"event_type": "file_created",
+		"file_name":  "report.pdf",
+		"size":       2048,
+		"present":    existing,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`event_type == "file_created"`, true},
+		{`event_type != 'file_created'`, false},
+		{`size > 1024 && size <= 4096`, true},
+		{`size < 10 || file_name == "report.pdf"`, true},
+		{`!(size > 1024)`, false},
+		{`missing == ""`, true},
+		{`file_exists(present)`, true},
+		{`file_exists("` + filepath.Join(dir, "absent") + `")`, false},
+		{`env("SRVRMGR_TEST_POWER") == "ac"`, true},
+		{`env("SRVRMGR_TEST_UNSET") == ""`, true},
+		{`true && !false`, true},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(tt.expr, data)
+		if err != nil {
+			t.Errorf("Evaluate(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluate_TimeBuiltins(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2025, 3, 3, 23, 30, 0, 0, time.Local) } // Monday
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`time_between("22:00", "06:00")`, true},
+		{`time_between("09:00", "17:00")`, false},
+		{`time_between("23:30", "23:31")`, true},
+		{`hour() >= 23`, true},
+		{`weekday() == "monday"`, true},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(tt.expr, nil)
+		if err != nil {
+			t.Errorf("Evaluate(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{`size >`, "unexpected"},
+		{`(a == "b"`, "expected )"},
+		{`a == "unterminated`, "unterminated string"},
+		{`exec("rm -rf /")`, "unknown function"},
+		{`file_exists()`, "takes 1 argument"},
+		{`a == b c`, "unexpected"},
+		{`a $ b`, "unexpected character"},
+		{``, "unexpected"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if err == nil {
+			t.Errorf("Parse(%q) expected error", tt.expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Parse(%q) error = %v, want containing %q", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEval_TypeErrors(t *testing.T) {
+	tests := []string{
+		`file_name`,              // not a boolean result
+		`file_name && true`,      // string in boolean context
+		`file_name < 3`,          // string vs number ordering
+		`time_between("x", "y")`, // bad clock argument
+	}
+	for _, expr := range tests {
+		if _, err := Evaluate(expr, map[string]any{"file_name": "a.txt"}); err == nil {
+			t.Errorf("Evaluate(%q) expected error", expr)
+		}
+	}
+}
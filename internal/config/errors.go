@@ -0,0 +1,73 @@
+// internal/config/errors.go
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of rule validation failure. Errors from ValidateRule wrap one of
+// these, so callers can tell them apart with errors.Is rather than matching
+// message text.
+var (
+	ErrMissingField         = errors.New("missing required field")
+	ErrInvalidValue         = errors.New("invalid value")
+	ErrInvalidTriggerType   = errors.New("invalid trigger type")
+	ErrConflictingFields    = errors.New("conflicting fields")
+	ErrUnsafePermissionMode = errors.New("unsafe permission mode")
+	ErrUnsafeRunAsUser      = errors.New("unsafe run_as_user")
+	ErrUnsupportedVersion   = errors.New("unsupported rule version")
+)
+
+// errorCodes are the machine-readable names of the error kinds, as reported
+// by ErrorCode.
+var errorCodes = []struct {
+	kind error
+	code string
+}{
+	{ErrMissingField, "missing_field"},
+	{ErrInvalidValue, "invalid_value"},
+	{ErrInvalidTriggerType, "invalid_trigger_type"},
+	{ErrConflictingFields, "conflicting_fields"},
+	{ErrUnsafePermissionMode, "unsafe_permission_mode"},
+	{ErrUnsafeRunAsUser, "unsafe_run_as_user"},
+	{ErrUnsupportedVersion, "unsupported_version"},
+}
+
+// ErrorCode returns a stable code for the kind of err, e.g.
+// "missing_field", or "invalid_rule" if it isn't one of the kinds above
+// (such as a YAML syntax error).
+func ErrorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.kind) {
+			return c.code
+		}
+	}
+	return "invalid_rule"
+}
+
+// ValidationError is a rule that failed validation. Use errors.As to get
+// the offending field.
+type ValidationError struct {
+	Field string // rule key, dotted for nested keys, e.g. "trigger.watch_paths"
+	Kind  error  // one of the Err* kinds
+	Msg   string
+	Err   error // underlying cause, if any
+}
+
+func (e *ValidationError) Error() string {
+	return e.Msg
+}
+
+func (e *ValidationError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// fieldError returns a *ValidationError of kind for field with a formatted
+// message.
+func fieldError(kind error, field, format string, args ...any) error {
+	return &ValidationError{Field: field, Kind: kind, Msg: fmt.Sprintf(format, args...)}
+}
@@ -2,12 +2,24 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
+	"github.com/colebrumley/srvrmgr/internal/condition"
+	"github.com/google/jsonschema-go/jsonschema"
 	"gopkg.in/yaml.v3"
 )
 
@@ -24,100 +36,457 @@ func LoadGlobal(path string) (*Global, error) {
 	}
 
 	applyGlobalDefaults(&cfg)
+	if err := loadPromptFiles(&cfg.ClaudeDefaults, filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("claude_defaults: %w", err)
+	}
+	if err := validatePermissionMode(cfg.ClaudeDefaults.PermissionMode); err != nil {
+		return nil, fmt.Errorf("claude_defaults: %w", err)
+	}
+	if tool, ok := conflictingTool(cfg.ClaudeDefaults.AllowedTools, cfg.ClaudeDefaults.DisallowedTools); ok {
+		return nil, fmt.Errorf("claude_defaults: tool %q is in both allowed_tools and disallowed_tools", tool)
+	}
+	if cfg.Daemon.CanaryFailure != "" && !slices.Contains(CanaryFailureModes, cfg.Daemon.CanaryFailure) {
+		return nil, fmt.Errorf("daemon: invalid canary_failure %q: must be one of %s", cfg.Daemon.CanaryFailure, strings.Join(CanaryFailureModes, ", "))
+	}
+	if err := cfg.Daemon.RateLimits.validate(); err != nil {
+		return nil, fmt.Errorf("daemon.rate_limits: %w", err)
+	}
+	if err := cfg.RuleExecution.validate(); err != nil {
+		return nil, fmt.Errorf("rule_execution: %w", err)
+	}
+	if err := cfg.Memory.validate(); err != nil {
+		return nil, fmt.Errorf("memory: %w", err)
+	}
 	return &cfg, nil
 }
 
-// LoadRule loads a rule configuration from a YAML file
+// RuleFileExtensions are the file extensions loaded as rule files. The format
+// follows the extension; anything else is YAML.
+var RuleFileExtensions = []string{".yaml", ".yml", ".json", ".toml"}
+
+// IsRuleFile reports whether name has a rule file extension.
+func IsRuleFile(name string) bool {
+	return slices.Contains(RuleFileExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+// LoadRule loads a rule configuration from a file containing a single rule.
 func LoadRule(path string) (*Rule, error) {
+	rules, err := LoadRuleFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) != 1 {
+		return nil, fmt.Errorf("%s defines %d rules, expected one", filepath.Base(path), len(rules))
+	}
+	return rules[0], nil
+}
+
+// LoadRuleFile loads every rule in a YAML, JSON or TOML file. A file holds
+// either one rule, or a top-level rules: list whose entries are deep-merged
+// over an optional defaults: map. Valid rules are returned even when others in
+// the file fail validation; the error then describes each invalid rule.
+func LoadRuleFile(path string) ([]*Rule, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("reading rule file: %w", err)
 	}
+	data, err = ruleFileYAML(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing rule file: %w", err)
+	}
 
+	var doc struct {
+		Version  int              `yaml:"version"`
+		Defaults map[string]any   `yaml:"defaults"`
+		Rules    []map[string]any `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil || doc.Rules == nil {
+		// Not a multi-rule file (or not valid YAML): parse as a single rule
+		var rule Rule
+		if err := yaml.Unmarshal(data, &rule); err != nil {
+			return nil, fmt.Errorf("parsing rule file: %w", err)
+		}
+		applyRuleDefaults(&rule)
+		err := resolveRuleFiles(&rule, filepath.Dir(path))
+		if err == nil {
+			err = ValidateRule(&rule)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("validating rule in %s: %w", filepath.Base(path), err)
+		}
+		rule.Hash = rule.ContentHash()
+		return []*Rule{&rule}, nil
+	}
+
+	// A top-level version applies to every rule that doesn't set its own
+	if doc.Version != 0 {
+		doc.Defaults = mergeMaps(map[string]any{"version": doc.Version}, doc.Defaults)
+	}
+
+	var rules []*Rule
+	var errs []error
+	for i, entry := range doc.Rules {
+		rule, err := expandRule(doc.Defaults, entry)
+		if err == nil {
+			err = resolveRuleFiles(rule, filepath.Dir(path))
+		}
+		if err == nil {
+			err = ValidateRule(rule)
+		}
+		if err != nil {
+			label := fmt.Sprintf("rules[%d]", i)
+			if name, ok := entry["name"].(string); ok && name != "" {
+				label = fmt.Sprintf("%q (%s)", name, label)
+			}
+			errs = append(errs, fmt.Errorf("validating rule %s in %s: %w", label, filepath.Base(path), err))
+			continue
+		}
+		rule.Hash = rule.ContentHash()
+		rules = append(rules, rule)
+	}
+	return rules, errors.Join(errs...)
+}
+
+// ContentHash returns a stable hash of every field of the rule after
+// defaults are applied and prompt and schema files read, so two loads of an
+// unchanged rule hash the same. The Hash field itself isn't encoded.
+func (r *Rule) ContentHash() string {
+	// yaml.v3 sorts map keys, so the encoding is deterministic
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	h.Write(data)
+	h.Write(r.Trigger.bodySchemaData)
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// resolveRuleFiles resolves the files a rule refers to against dir, the
+// directory of its rule file, and reads its prompt and body schema files.
+func resolveRuleFiles(rule *Rule, dir string) error {
+	if p := rule.Trigger.BodySchema; p != "" {
+		if !filepath.IsAbs(p) {
+			rule.Trigger.BodySchema = filepath.Join(dir, p)
+		}
+		data, resolved, err := readBodySchema(rule.Trigger.BodySchema)
+		if err != nil {
+			return &ValidationError{Field: "trigger.body_schema", Kind: ErrInvalidValue, Msg: err.Error(), Err: err}
+		}
+		rule.Trigger.ResolvedBodySchema, rule.Trigger.bodySchemaData = resolved, data
+	}
+	return loadPromptFiles(&rule.Claude, dir)
+}
+
+// LoadBodySchema reads and resolves the JSON Schema at path.
+func LoadBodySchema(path string) (*jsonschema.Resolved, error) {
+	_, resolved, err := readBodySchema(path)
+	return resolved, err
+}
+
+// readBodySchema reads and resolves the JSON Schema at path, returning the
+// file's contents too.
+func readBodySchema(path string) ([]byte, *jsonschema.Resolved, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading body_schema: %w", err)
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, nil, fmt.Errorf("parsing body_schema %s: %w", path, err)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving body_schema %s: %w", path, err)
+	}
+	return data, resolved, nil
+}
+
+// loadPromptFiles fills in cfg's system prompts from system_prompt_file and
+// append_system_prompt_file, resolving relative paths against dir. An inline
+// prompt wins over its file, which is then not read.
+func loadPromptFiles(cfg *ClaudeConfig, dir string) error {
+	for _, p := range []struct {
+		field  string
+		file   string
+		prompt *string
+	}{
+		{"system_prompt_file", cfg.SystemPromptFile, &cfg.SystemPrompt},
+		{"append_system_prompt_file", cfg.AppendSystemPromptFile, &cfg.AppendSystemPrompt},
+	} {
+		if p.file == "" || *p.prompt != "" {
+			continue
+		}
+		path := p.file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return &ValidationError{Field: "claude." + p.field, Kind: ErrInvalidValue, Msg: fmt.Sprintf("reading %s: %v", p.field, err), Err: err}
+		}
+		*p.prompt = strings.TrimRight(string(data), "\n")
+	}
+	return nil
+}
+
+// ruleFileYAML returns a rule file's contents as YAML. JSON and TOML files are
+// decoded generically and re-encoded, so every format goes through the same
+// yaml tags and multi-rule handling.
+func ruleFileYAML(path string, data []byte) ([]byte, error) {
+	var doc map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid TOML: %w", err)
+		}
+	default:
+		return data, nil
+	}
+	return yaml.Marshal(doc)
+}
+
+// expandRule deep-merges a rules: entry over the file's defaults and decodes
+// the result as a Rule.
+func expandRule(defaults, entry map[string]any) (*Rule, error) {
+	data, err := yaml.Marshal(mergeMaps(defaults, entry))
+	if err != nil {
+		return nil, fmt.Errorf("encoding merged rule: %w", err)
+	}
 	var rule Rule
 	if err := yaml.Unmarshal(data, &rule); err != nil {
-		return nil, fmt.Errorf("parsing rule file: %w", err)
+		return nil, fmt.Errorf("parsing merged rule: %w", err)
 	}
+	applyRuleDefaults(&rule)
+	return &rule, nil
+}
 
-	if err := ValidateRule(&rule); err != nil {
-		return nil, fmt.Errorf("validating rule in %s: %w", filepath.Base(path), err)
+// applyRuleDefaults fills in what a parsed rule may leave out: a missing
+// version is the current RuleVersion, and a rule without a trigger block is a
+// manual rule, run only by srvrmgr run. A trigger block without a type is
+// still an error.
+func applyRuleDefaults(rule *Rule) {
+	if rule.Version == 0 {
+		rule.Version = RuleVersion
 	}
+	if reflect.ValueOf(rule.Trigger).IsZero() {
+		rule.Trigger.Type = "manual"
+	}
+}
 
-	return &rule, nil
+// Supported rule format versions. Bump RuleVersion when the format changes
+// incompatibly, and MinRuleVersion when older rules can no longer be loaded.
+const (
+	RuleVersion    = 1
+	MinRuleVersion = 1
+)
+
+// checkRuleVersion rejects versions outside the supported range. An unset
+// version is accepted; the loader defaults it to RuleVersion.
+func checkRuleVersion(rule *Rule) error {
+	switch {
+	case rule.Version == 0:
+	case rule.Version > RuleVersion:
+		return fieldError(ErrUnsupportedVersion, "version", "rule format version %d is newer than this srvrmgr supports (%d); upgrade srvrmgr", rule.Version, RuleVersion)
+	case rule.Version < MinRuleVersion:
+		return fieldError(ErrUnsupportedVersion, "version", "rule format version %d is no longer supported (supported: %d to %d); update the rule", rule.Version, MinRuleVersion, RuleVersion)
+	}
+	return nil
+}
+
+// mergeMaps returns base overlaid with override. Nested maps are merged
+// recursively; any other value in override (including lists) replaces base's.
+func mergeMaps(base, override map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		baseMap, baseOK := merged[k].(map[string]any)
+		overMap, overOK := v.(map[string]any)
+		if baseOK && overOK {
+			merged[k] = mergeMaps(baseMap, overMap)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
 }
 
 // ValidateRule checks that a rule has all required fields and valid configuration.
+// Failures are *ValidationError values wrapping one of the Err* kinds.
 func ValidateRule(rule *Rule) error {
+	// Checked first: a rule for another format may fail the checks below in
+	// confusing ways
+	if err := checkRuleVersion(rule); err != nil {
+		return err
+	}
 	if rule.Name == "" {
-		return fmt.Errorf("rule name is required")
+		return fieldError(ErrMissingField, "name", "rule name is required")
 	}
 	if rule.Trigger.Type == "" {
-		return fmt.Errorf("trigger type is required")
+		return fieldError(ErrMissingField, "trigger.type", "trigger type is required")
 	}
-	if rule.Action.Prompt == "" {
-		return fmt.Errorf("action prompt is required")
+	if rule.Action.Prompt == "" && rule.Action.Script == "" {
+		return fieldError(ErrMissingField, "action.prompt", "action prompt is required (or action script for non-Claude rules)")
 	}
-
-	validTypes := map[string]bool{
-		"filesystem": true,
-		"scheduled":  true,
-		"webhook":    true,
-		"lifecycle":  true,
-		"manual":     true,
+	if rule.Action.Prompt != "" && rule.Action.Script != "" {
+		return fieldError(ErrConflictingFields, "action.script", "action prompt and action script are mutually exclusive")
 	}
-	if !validTypes[rule.Trigger.Type] {
-		return fmt.Errorf("invalid trigger type %q: must be one of filesystem, scheduled, webhook, lifecycle, manual", rule.Trigger.Type)
+	if err := validateOutputParser(rule.Action); err != nil {
+		return err
+	}
+
+	if !slices.Contains(TriggerTypes, rule.Trigger.Type) {
+		return fieldError(ErrInvalidTriggerType, "trigger.type", "invalid trigger type %q: must be one of %s", rule.Trigger.Type, strings.Join(TriggerTypes, ", "))
 	}
 
 	switch rule.Trigger.Type {
 	case "filesystem":
 		if len(rule.Trigger.WatchPaths) == 0 {
-			return fmt.Errorf("filesystem trigger requires at least one watch_paths entry")
+			return fieldError(ErrMissingField, "trigger.watch_paths", "filesystem trigger requires at least one watch_paths entry")
+		}
+		if c := rule.Trigger.CoalesceSeconds; c < 0 || c > 60 {
+			return fieldError(ErrInvalidValue, "trigger.coalesce_seconds", "coalesce_seconds must be between 0 and 60, got %g", c)
+		}
+		if w := rule.Trigger.DedupeWindowMs; w < 0 || w > 10000 {
+			return fieldError(ErrInvalidValue, "trigger.dedupe_window_ms", "dedupe_window_ms must be between 0 and 10000, got %d", w)
 		}
 	case "scheduled":
 		if rule.Trigger.CronExpression == "" && rule.Trigger.RunEvery == "" && rule.Trigger.RunAt == "" {
-			return fmt.Errorf("scheduled trigger requires at least one of cron_expression, run_every, or run_at")
+			return fieldError(ErrMissingField, "trigger.cron_expression", "scheduled trigger requires at least one of cron_expression, run_every, or run_at")
 		}
 	case "webhook":
 		if rule.Trigger.ListenPath == "" {
-			return fmt.Errorf("webhook trigger requires listen_path")
+			return fieldError(ErrMissingField, "trigger.listen_path", "webhook trigger requires listen_path")
 		}
 		if !strings.HasPrefix(rule.Trigger.ListenPath, "/") {
-			return fmt.Errorf("webhook listen_path must start with \"/\"")
+			return fieldError(ErrInvalidValue, "trigger.listen_path", "webhook listen_path must start with \"/\"")
 		}
 	case "lifecycle":
 		if len(rule.Trigger.OnEvents) == 0 {
-			return fmt.Errorf("lifecycle trigger requires at least one on_events entry")
+			return fieldError(ErrMissingField, "trigger.on_events", "lifecycle trigger requires at least one on_events entry")
 		}
 	}
+	if rule.Trigger.BodySchema != "" && rule.Trigger.Type != "webhook" {
+		return fieldError(ErrConflictingFields, "trigger.body_schema", "body_schema is only valid for webhook triggers")
+	}
 
 	if rule.OnFailure.Retry && rule.OnFailure.RetryAttempts <= 0 {
 		rule.OnFailure.RetryAttempts = 3
 	}
+	if len(rule.OnFailure.RetryIf) > 0 && !rule.OnFailure.Retry {
+		return fieldError(ErrMissingField, "on_failure.retry", "on_failure retry_if requires retry: true")
+	}
+	rule.OnFailure.RetryIfPatterns = nil
+	for _, pattern := range rule.OnFailure.RetryIf {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fieldError(ErrInvalidValue, "on_failure.retry_if", "invalid retry_if pattern %q: %v", pattern, err)
+		}
+		rule.OnFailure.RetryIfPatterns = append(rule.OnFailure.RetryIfPatterns, re)
+	}
+	if hook := rule.OnSuccess.Webhook; hook != "" {
+		if u, err := url.Parse(hook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fieldError(ErrInvalidValue, "on_success.webhook", "on_success webhook must be an http or https URL, got %q", hook)
+		}
+	}
 
 	// FR-3: Validate max_timeout_seconds range
 	if rule.MaxTimeoutSeconds < 0 {
-		return fmt.Errorf("max_timeout_seconds must be >= 0, got %d", rule.MaxTimeoutSeconds)
+		return fieldError(ErrInvalidValue, "max_timeout_seconds", "max_timeout_seconds must be >= 0, got %d", rule.MaxTimeoutSeconds)
 	}
 	if rule.MaxTimeoutSeconds > 3600 {
-		return fmt.Errorf("max_timeout_seconds must be <= 3600 (1 hour), got %d", rule.MaxTimeoutSeconds)
+		return fieldError(ErrInvalidValue, "max_timeout_seconds", "max_timeout_seconds must be <= 3600 (1 hour), got %d", rule.MaxTimeoutSeconds)
+	}
+
+	if rule.DependsOnMode != "" && !slices.Contains(DependsOnModes, rule.DependsOnMode) {
+		return fieldError(ErrInvalidValue, "depends_on_mode", "invalid depends_on_mode %q: must be one of %s", rule.DependsOnMode, strings.Join(DependsOnModes, ", "))
+	}
+
+	if rule.DependsOnMaxAge != "" {
+		age, err := time.ParseDuration(rule.DependsOnMaxAge)
+		if err != nil || age <= 0 {
+			return fieldError(ErrInvalidValue, "depends_on_max_age", "invalid depends_on_max_age %q: must be a positive duration such as 6h or 90m", rule.DependsOnMaxAge)
+		}
+		if len(rule.DependsOn) == 0 {
+			return fieldError(ErrMissingField, "depends_on_rules", "depends_on_max_age requires depends_on_rules")
+		}
+	}
+
+	if rule.DependsOnWait && len(rule.DependsOn) == 0 {
+		return fieldError(ErrMissingField, "depends_on_rules", "depends_on_wait requires depends_on_rules")
+	}
+	if rule.DependsOnWaitTimeout != "" {
+		timeout, err := time.ParseDuration(rule.DependsOnWaitTimeout)
+		if err != nil || timeout <= 0 {
+			return fieldError(ErrInvalidValue, "depends_on_wait_timeout", "invalid depends_on_wait_timeout %q: must be a positive duration such as 30m", rule.DependsOnWaitTimeout)
+		}
+		if !rule.DependsOnWait {
+			return fieldError(ErrMissingField, "depends_on_wait", "depends_on_wait_timeout requires depends_on_wait")
+		}
+	}
+
+	if rule.RetentionDays < 0 {
+		return fieldError(ErrInvalidValue, "retention_days", "retention_days must be >= 0, got %d", rule.RetentionDays)
 	}
 
 	// FR-15: Reject run_as_user: root
 	if rule.RunAsUser == "root" {
-		return fmt.Errorf("run_as_user cannot be \"root\" — rules must never run as root")
+		return fieldError(ErrUnsafeRunAsUser, "run_as_user", "run_as_user cannot be \"root\" — rules must never run as root")
 	}
 
-	// FR-15: Reject bypassPermissions mode
-	if rule.Claude.PermissionMode == "bypassPermissions" {
-		return fmt.Errorf("permission_mode \"bypassPermissions\" is not allowed for daemon rules")
+	if rule.When != "" {
+		if _, err := condition.Parse(rule.When); err != nil {
+			return &ValidationError{Field: "when", Kind: ErrInvalidValue, Msg: "invalid when condition: " + err.Error(), Err: err}
+		}
+	}
+
+	if tool, ok := conflictingTool(rule.Claude.AllowedTools, rule.Claude.DisallowedTools); ok {
+		return fieldError(ErrConflictingFields, "claude.disallowed_tools", "tool %q is in both allowed_tools and disallowed_tools", tool)
+	}
+
+	// FR-15: Reject bypassPermissions and misspelled permission modes
+	if err := validatePermissionMode(rule.Claude.PermissionMode); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// validateOutputParser checks action.output_parser and, for the regex
+// parser, that output_pattern compiles and names at least one group.
+func validateOutputParser(action Action) error {
+	if action.OutputParser != "" && !slices.Contains(OutputParsers, action.OutputParser) {
+		return fieldError(ErrInvalidValue, "action.output_parser", "invalid output_parser %q: must be one of %s", action.OutputParser, strings.Join(OutputParsers, ", "))
+	}
+	if action.OutputParser != OutputParserRegex {
+		if action.OutputPattern != "" {
+			return fieldError(ErrConflictingFields, "action.output_pattern", "output_pattern is only valid with output_parser: regex")
+		}
+		return nil
+	}
+	if action.OutputPattern == "" {
+		return fieldError(ErrMissingField, "action.output_pattern", "output_parser regex requires output_pattern")
+	}
+	re, err := regexp.Compile(action.OutputPattern)
+	if err != nil {
+		return fieldError(ErrInvalidValue, "action.output_pattern", "invalid output_pattern %q: %v", action.OutputPattern, err)
+	}
+	if !slices.ContainsFunc(re.SubexpNames(), func(name string) bool { return name != "" }) {
+		return fieldError(ErrInvalidValue, "action.output_pattern", "output_pattern %q has no named capture groups, e.g. (?P<name>...)", action.OutputPattern)
+	}
+	return nil
+}
+
 // ValidateRuleWithGlobal performs additional validation that requires global config context.
 // FR-15: Checks run_as_user against the allowed_run_as_users allowlist.
+// Warns about tool conflicts introduced by claude_defaults, and about
+// high_risk_tools allowed for rules with run_as_user.
 // FR-19: Warns about triggers_rules / depends_on overlap.
 // Sourced from architect — clean separation of global-context validation.
 func ValidateRuleWithGlobal(rule *Rule, global *Global, allRules map[string]*Rule) []string {
@@ -137,6 +506,50 @@ func ValidateRuleWithGlobal(rule *Rule, global *Global, allRules map[string]*Rul
 		}
 	}
 
+	// Tool permissions, as merged with claude_defaults at execution time
+	if rule.Action.Prompt != "" {
+		allowed, disallowed := rule.Claude.AllowedTools, rule.Claude.DisallowedTools
+		if len(allowed) == 0 {
+			allowed = global.ClaudeDefaults.AllowedTools
+		}
+		if len(disallowed) == 0 {
+			disallowed = global.ClaudeDefaults.DisallowedTools
+		}
+		inherited := len(rule.Claude.AllowedTools) == 0 || len(rule.Claude.DisallowedTools) == 0
+		if tool, ok := conflictingTool(allowed, disallowed); ok && inherited {
+			warnings = append(warnings, fmt.Sprintf("rule %q: tool %q is both allowed and disallowed once merged with claude_defaults (disallowed wins)", rule.Name, tool))
+		}
+		model := rule.Claude.Model
+		if model == "" {
+			model = global.ClaudeDefaults.Model
+		}
+		for _, w := range modelWarnings(model, global.Daemon.KnownModels) {
+			warnings = append(warnings, fmt.Sprintf("rule %q: %s", rule.Name, w))
+		}
+		if rule.RunAsUser != "" {
+			why := ""
+			if len(allowed) == 0 {
+				why = " (allowed_tools is empty, so every tool is)"
+			}
+			for _, tool := range riskyTools(allowed, disallowed, global.Daemon.HighRiskTools) {
+				warnings = append(warnings, fmt.Sprintf("rule %q: high-risk tool %q is allowed while running as %q%s", rule.Name, tool, rule.RunAsUser, why))
+			}
+		}
+	}
+
+	// Warn about triggers_rules entries that can never fire
+	if allRules != nil {
+		for _, name := range rule.Triggers {
+			target, ok := allRules[name]
+			switch {
+			case !ok:
+				warnings = append(warnings, fmt.Sprintf("rule %q: triggers_rules entry %q does not match any loaded rule", rule.Name, name))
+			case !target.Enabled:
+				warnings = append(warnings, fmt.Sprintf("rule %q: triggers_rules entry %q is disabled and will not fire", rule.Name, name))
+			}
+		}
+	}
+
 	// FR-19: Warn about triggers_rules / depends_on overlap
 	if len(rule.DependsOn) > 0 && allRules != nil {
 		for _, dep := range rule.DependsOn {
@@ -169,18 +582,16 @@ func LoadRulesDir(dir string) ([]*Rule, error) {
 		if entry.IsDir() {
 			continue
 		}
-		ext := filepath.Ext(entry.Name())
-		if ext != ".yaml" && ext != ".yml" {
+		if !IsRuleFile(entry.Name()) {
 			continue
 		}
 
-		rule, err := LoadRule(filepath.Join(dir, entry.Name()))
+		fileRules, err := LoadRuleFile(filepath.Join(dir, entry.Name()))
 		if err != nil {
 			// FR-8: Use slog for warnings about invalid rules (not log.Printf or fmt.Fprintf)
 			slog.Warn("skipping invalid rule", "file", entry.Name(), "error", err)
-			continue
 		}
-		rules = append(rules, rule)
+		rules = append(rules, fileRules...)
 	}
 
 	return rules, nil
@@ -196,6 +607,27 @@ func applyGlobalDefaults(cfg *Global) {
 	if cfg.Daemon.WebhookListenAddress == "" {
 		cfg.Daemon.WebhookListenAddress = "127.0.0.1"
 	}
+	if cfg.Daemon.MaintenanceIntervalMinutes == 0 {
+		cfg.Daemon.MaintenanceIntervalMinutes = 60
+	}
+	if cfg.Daemon.CircuitBreakerThreshold == 0 {
+		cfg.Daemon.CircuitBreakerThreshold = 5
+	}
+	if cfg.Daemon.CircuitBreakerCooldownMinutes <= 0 {
+		cfg.Daemon.CircuitBreakerCooldownMinutes = 60
+	}
+	if cfg.Daemon.ShutdownGraceSeconds <= 0 {
+		cfg.Daemon.ShutdownGraceSeconds = 15
+	}
+	if cfg.Daemon.TriggerMarker == "" {
+		cfg.Daemon.TriggerMarker = "TRIGGER:"
+	}
+	if cfg.Daemon.HighRiskTools == nil {
+		cfg.Daemon.HighRiskTools = DefaultHighRiskTools
+	}
+	if cfg.Daemon.HistoryRetentionDays == 0 {
+		cfg.Daemon.HistoryRetentionDays = 90
+	}
 	if cfg.ClaudeDefaults.Model == "" {
 		cfg.ClaudeDefaults.Model = "sonnet"
 	}
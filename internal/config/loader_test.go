@@ -2,8 +2,11 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -44,6 +47,21 @@ rule_execution:
 	if cfg.ClaudeDefaults.Model != "sonnet" {
 		t.Errorf("expected model sonnet, got %s", cfg.ClaudeDefaults.Model)
 	}
+	if cfg.Daemon.CircuitBreakerThreshold != 5 || cfg.Daemon.CircuitBreakerCooldownMinutes != 60 {
+		t.Errorf("expected circuit breaker defaults 5/60, got %d/%d",
+			cfg.Daemon.CircuitBreakerThreshold, cfg.Daemon.CircuitBreakerCooldownMinutes)
+	}
+	if cfg.Daemon.ShutdownGraceSeconds != 15 {
+		t.Errorf("expected shutdown_grace_seconds default 15, got %d", cfg.Daemon.ShutdownGraceSeconds)
+	}
+	if cfg.Daemon.TriggerMarker != "TRIGGER:" {
+		t.Errorf("expected trigger_marker default TRIGGER:, got %q", cfg.Daemon.TriggerMarker)
+	}
+	// The row limits are opt-in: defaulting them would delete existing history
+	if cfg.Daemon.HistoryRetentionDays != 90 || cfg.Daemon.HistoryMaxRowsPerRule != 0 || cfg.Daemon.HistoryMaxRows != 0 {
+		t.Errorf("expected history limits 90/0/0, got %d/%d/%d",
+			cfg.Daemon.HistoryRetentionDays, cfg.Daemon.HistoryMaxRowsPerRule, cfg.Daemon.HistoryMaxRows)
+	}
 }
 
 func TestLoadRule(t *testing.T) {
@@ -111,6 +129,17 @@ func TestValidateRule_Valid(t *testing.T) {
 	}
 }
 
+func TestValidateRule_DoesNotModify(t *testing.T) {
+	rule := validRule()
+	before := rule
+	if err := ValidateRule(&rule); err != nil {
+		t.Fatalf("ValidateRule() error: %v", err)
+	}
+	if !reflect.DeepEqual(rule, before) {
+		t.Errorf("ValidateRule() modified the rule: got %+v, want %+v", rule, before)
+	}
+}
+
 func TestValidateRule_MissingName(t *testing.T) {
 	rule := validRule()
 	rule.Name = ""
@@ -148,6 +177,128 @@ func TestValidateRule_FilesystemNoWatchPaths(t *testing.T) {
 	}
 }
 
+func TestValidateRule_CoalesceSeconds(t *testing.T) {
+	for _, tc := range []struct {
+		value float64
+		ok    bool
+	}{
+		{0, true},
+		{0.5, true},
+		{60, true},
+		{-1, false},
+		{61, false},
+	} {
+		rule := validRule()
+		rule.Trigger.Type = "filesystem"
+		rule.Trigger.WatchPaths = []string{"/tmp"}
+		rule.Trigger.CoalesceSeconds = tc.value
+		err := ValidateRule(&rule)
+		if tc.ok && err != nil {
+			t.Errorf("coalesce_seconds=%g: unexpected error: %v", tc.value, err)
+		}
+		if !tc.ok && (err == nil || !strings.Contains(err.Error(), "coalesce_seconds")) {
+			t.Errorf("coalesce_seconds=%g: expected coalesce_seconds error, got %v", tc.value, err)
+		}
+	}
+}
+
+func TestValidateRule_RetentionDays(t *testing.T) {
+	rule := validRule()
+	rule.RetentionDays = 365
+	if err := ValidateRule(&rule); err != nil {
+		t.Errorf("retention_days=365: unexpected error: %v", err)
+	}
+	rule.RetentionDays = -1
+	if err := ValidateRule(&rule); err == nil || !strings.Contains(err.Error(), "retention_days") {
+		t.Errorf("retention_days=-1: expected retention_days error, got %v", err)
+	}
+}
+
+func TestValidateRule_DependsOnMaxAge(t *testing.T) {
+	for _, tc := range []struct {
+		maxAge    string
+		dependsOn []string
+		wantErr   string
+	}{
+		{"6h", []string{"parent"}, ""},
+		{"90m", []string{"parent"}, ""},
+		{"1d", []string{"parent"}, "invalid depends_on_max_age"},
+		{"-1h", []string{"parent"}, "invalid depends_on_max_age"},
+		{"6h", nil, "requires depends_on_rules"},
+	} {
+		rule := validRule()
+		rule.DependsOn = tc.dependsOn
+		rule.DependsOnMaxAge = tc.maxAge
+		err := ValidateRule(&rule)
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("depends_on_max_age=%q: unexpected error: %v", tc.maxAge, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("depends_on_max_age=%q: error = %v, want %q", tc.maxAge, err, tc.wantErr)
+		}
+	}
+}
+
+func TestValidateRule_DependsOnMode(t *testing.T) {
+	for mode, ok := range map[string]bool{"": true, "all": true, "any": true, "ANY": false, "some": false} {
+		rule := validRule()
+		rule.DependsOn = []string{"parent"}
+		rule.DependsOnMode = mode
+		err := ValidateRule(&rule)
+		if ok && err != nil {
+			t.Errorf("depends_on_mode=%q: unexpected error: %v", mode, err)
+		}
+		if !ok && (err == nil || !strings.Contains(err.Error(), "depends_on_mode")) {
+			t.Errorf("depends_on_mode=%q: expected depends_on_mode error, got %v", mode, err)
+		}
+	}
+}
+
+func TestValidateRule_DependsOnWait(t *testing.T) {
+	tests := []struct {
+		name      string
+		dependsOn []string
+		wait      bool
+		timeout   string
+		wantErr   string
+	}{
+		{"wait", []string{"parent"}, true, "", ""},
+		{"wait with timeout", []string{"parent"}, true, "2h", ""},
+		{"no dependencies", nil, true, "", "depends_on_wait requires depends_on_rules"},
+		{"bad timeout", []string{"parent"}, true, "soon", "invalid depends_on_wait_timeout"},
+		{"timeout without wait", []string{"parent"}, false, "10m", "depends_on_wait_timeout requires depends_on_wait"},
+	}
+	for _, tt := range tests {
+		rule := validRule()
+		rule.DependsOn = tt.dependsOn
+		rule.DependsOnWait = tt.wait
+		rule.DependsOnWaitTimeout = tt.timeout
+		err := ValidateRule(&rule)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateRule_DedupeWindowMs(t *testing.T) {
+	rule := validRule()
+	rule.Trigger.Type = "filesystem"
+	rule.Trigger.WatchPaths = []string{"/tmp"}
+	rule.Trigger.DedupeWindowMs = 250
+	if err := ValidateRule(&rule); err != nil {
+		t.Fatalf("expected valid rule with dedupe_window_ms=250, got error: %v", err)
+	}
+
+	rule.Trigger.DedupeWindowMs = -1
+	err := ValidateRule(&rule)
+	if err == nil || !strings.Contains(err.Error(), "dedupe_window_ms") {
+		t.Errorf("expected dedupe_window_ms error, got %v", err)
+	}
+}
+
 func TestValidateRule_ScheduledNoExpression(t *testing.T) {
 	rule := validRule()
 	rule.Trigger.Type = "scheduled"
@@ -187,6 +338,139 @@ func TestValidateRule_WebhookBadPath(t *testing.T) {
 	}
 }
 
+func TestValidateRule_BodySchemaRequiresWebhook(t *testing.T) {
+	rule := validRule()
+	rule.Trigger.BodySchema = "schema.json"
+	if err := ValidateRule(&rule); !errors.Is(err, ErrConflictingFields) {
+		t.Errorf("expected conflicting fields error for body_schema on a %s trigger, got %v", rule.Trigger.Type, err)
+	}
+}
+
+func TestLoadRule_BodySchemaRelativeToRuleFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deploy.yaml")
+	content := "name: deploy\ntrigger:\n  type: webhook\n  listen_path: /hooks/deploy\n  body_schema: schemas/deploy.json\naction:\n  prompt: Deploy\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing schema fails validation rather than only the webhook's setup
+	_, err := LoadRule(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Field != "trigger.body_schema" {
+		t.Fatalf("LoadRule with a missing schema error = %v, want a trigger.body_schema error", err)
+	}
+
+	schemaPath := filepath.Join(dir, "schemas", "deploy.json")
+	if err := os.Mkdir(filepath.Dir(schemaPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(schemaPath, []byte(`{"type": 5}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRule(path); err == nil {
+		t.Error("LoadRule accepted an invalid schema")
+	}
+
+	if err := os.WriteFile(schemaPath, []byte(`{"type": "object"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rule, err := LoadRule(path)
+	if err != nil {
+		t.Fatalf("LoadRule failed: %v", err)
+	}
+	if rule.Trigger.BodySchema != schemaPath {
+		t.Errorf("BodySchema = %q, want %q", rule.Trigger.BodySchema, schemaPath)
+	}
+	if rule.Trigger.ResolvedBodySchema == nil {
+		t.Error("ResolvedBodySchema not set")
+	}
+
+	// Editing only the schema changes the rule's hash, so reload rebuilds the trigger
+	if err := os.WriteFile(schemaPath, []byte(`{"type": "object", "required": ["service"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	edited, err := LoadRule(path)
+	if err != nil {
+		t.Fatalf("LoadRule failed: %v", err)
+	}
+	if edited.Hash == rule.Hash {
+		t.Error("hash unchanged after editing the body schema")
+	}
+}
+
+func TestValidateRule_RetryIf(t *testing.T) {
+	rule := validRule()
+	rule.OnFailure = OnFailure{Retry: true, RetryIf: []string{"rate limit", "connection (refused|reset)"}}
+	if err := ValidateRule(&rule); err != nil {
+		t.Errorf("unexpected error for valid retry_if: %v", err)
+	}
+	if len(rule.OnFailure.RetryIfPatterns) != 2 || !rule.OnFailure.RetryIfPatterns[1].MatchString("connection reset") {
+		t.Errorf("RetryIfPatterns = %v, want both patterns compiled", rule.OnFailure.RetryIfPatterns)
+	}
+
+	rule.OnFailure.RetryIf = []string{"rate limit", "(unclosed"}
+	var verr *ValidationError
+	if err := ValidateRule(&rule); !errors.As(err, &verr) || verr.Field != "on_failure.retry_if" || !strings.Contains(err.Error(), "(unclosed") {
+		t.Errorf("expected error naming the bad retry_if pattern, got %v", err)
+	}
+
+	rule.OnFailure = OnFailure{RetryIf: []string{"rate limit"}}
+	if err := ValidateRule(&rule); !errors.Is(err, ErrMissingField) {
+		t.Errorf("expected retry_if without retry to be rejected, got %v", err)
+	}
+}
+
+func TestValidateRule_OutputParser(t *testing.T) {
+	for _, action := range []Action{
+		{Prompt: "p", OutputParser: "json"},
+		{Script: "s", OutputParser: "lines"},
+		{Prompt: "p", OutputParser: "regex", OutputPattern: `(?P<volume>/Volumes/\w+) (?P<pct>\d+)%`},
+	} {
+		rule := validRule()
+		rule.Action = action
+		if err := ValidateRule(&rule); err != nil {
+			t.Errorf("%+v: unexpected error %v", action, err)
+		}
+	}
+
+	tests := []struct {
+		action Action
+		field  string
+		kind   error
+	}{
+		{Action{Prompt: "p", OutputParser: "xml"}, "action.output_parser", ErrInvalidValue},
+		{Action{Prompt: "p", OutputParser: "regex"}, "action.output_pattern", ErrMissingField},
+		{Action{Prompt: "p", OutputParser: "regex", OutputPattern: "(unclosed"}, "action.output_pattern", ErrInvalidValue},
+		{Action{Prompt: "p", OutputParser: "regex", OutputPattern: `(\d+)%`}, "action.output_pattern", ErrInvalidValue},
+		{Action{Prompt: "p", OutputParser: "json", OutputPattern: `(?P<x>.)`}, "action.output_pattern", ErrConflictingFields},
+	}
+	for _, tt := range tests {
+		rule := validRule()
+		rule.Action = tt.action
+		var verr *ValidationError
+		if err := ValidateRule(&rule); !errors.As(err, &verr) || verr.Field != tt.field || !errors.Is(err, tt.kind) {
+			t.Errorf("%+v: error = %v, want %v on %s", tt.action, err, tt.kind, tt.field)
+		}
+	}
+}
+
+func TestValidateRule_OnSuccessWebhook(t *testing.T) {
+	rule := validRule()
+	rule.OnSuccess.Webhook = "https://hooks.slack.com/services/T000/B000/XXX"
+	if err := ValidateRule(&rule); err != nil {
+		t.Errorf("unexpected error for valid on_success webhook: %v", err)
+	}
+
+	for _, hook := range []string{"hooks.slack.com/services/x", "ftp://example.com/hook", "https://", "http://[::1"} {
+		rule.OnSuccess.Webhook = hook
+		var verr *ValidationError
+		if err := ValidateRule(&rule); !errors.As(err, &verr) || verr.Field != "on_success.webhook" {
+			t.Errorf("webhook %q: expected on_success.webhook error, got %v", hook, err)
+		}
+	}
+}
+
 func TestValidateRule_RetryDefaultsAttempts(t *testing.T) {
 	rule := validRule()
 	rule.OnFailure.Retry = true
@@ -225,6 +509,43 @@ func TestValidateRule_MissingPrompt(t *testing.T) {
 	}
 }
 
+func TestValidateRule_ScriptInsteadOfPrompt(t *testing.T) {
+	rule := validRule()
+	rule.Action = Action{Script: "rsync -a ~/src /Volumes/backup"}
+	if err := ValidateRule(&rule); err != nil {
+		t.Errorf("expected script-only rule to be valid, got %v", err)
+	}
+}
+
+func TestValidateRule_PromptAndScriptExclusive(t *testing.T) {
+	rule := validRule()
+	rule.Action.Script = "echo hi"
+	err := ValidateRule(&rule)
+	if err == nil {
+		t.Fatal("expected error when both prompt and script are set")
+	}
+	if !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestValidateRule_WhenCondition(t *testing.T) {
+	rule := validRule()
+	rule.When = `file_exists("/Volumes/backup") && time_between("01:00", "05:00")`
+	if err := ValidateRule(&rule); err != nil {
+		t.Errorf("expected valid when condition, got %v", err)
+	}
+
+	rule.When = `file_exists("/Volumes/backup" &&`
+	err := ValidateRule(&rule)
+	if err == nil {
+		t.Fatal("expected error for malformed when condition")
+	}
+	if !strings.Contains(err.Error(), "invalid when condition") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
 func TestValidateRule_MissingTriggerType(t *testing.T) {
 	rule := validRule()
 	rule.Trigger.Type = ""
@@ -237,6 +558,101 @@ func TestValidateRule_MissingTriggerType(t *testing.T) {
 	}
 }
 
+func TestLoadRule_Version(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rule, err := LoadRule(write("absent.yaml", "name: absent\naction:\n  script: uptime\n"))
+	if err != nil || rule.Version != RuleVersion {
+		t.Errorf("LoadRule() without version = %+v, %v; want version %d", rule, err, RuleVersion)
+	}
+
+	rule, err = LoadRule(write("current.yaml", fmt.Sprintf("version: %d\nname: current\naction:\n  script: uptime\n", RuleVersion)))
+	if err != nil || rule.Version != RuleVersion {
+		t.Errorf("LoadRule() with version %d = %+v, %v", RuleVersion, rule, err)
+	}
+
+	// A future rule fails on its version, not on fields this build doesn't know
+	_, err = LoadRule(write("future.yaml", fmt.Sprintf("version: %d\nname: future\naction:\n  run: uptime\n", RuleVersion+1)))
+	if err == nil || !strings.Contains(err.Error(), "newer than this srvrmgr supports") {
+		t.Errorf("LoadRule() with a future version error = %v, want version error", err)
+	}
+
+	_, err = LoadRule(write("ancient.yaml", "version: -1\nname: ancient\naction:\n  script: uptime\n"))
+	if err == nil || !strings.Contains(err.Error(), "no longer supported") {
+		t.Errorf("LoadRule() with an old version error = %v, want version error", err)
+	}
+
+	// A multi-rule file's top-level version applies to rules without their own
+	rules, err := LoadRuleFile(write("fleet.yaml", fmt.Sprintf(`
+version: %d
+rules:
+  - name: a
+    action: {script: uptime}
+  - name: b
+    version: %d
+    action: {script: uptime}
+`, RuleVersion+1, RuleVersion)))
+	if len(rules) != 1 || rules[0].Name != "b" {
+		t.Errorf("LoadRuleFile() rules = %+v, want only b", rules)
+	}
+	if err == nil || !strings.Contains(err.Error(), `"a"`) || !strings.Contains(err.Error(), "newer than this srvrmgr supports") {
+		t.Errorf("LoadRuleFile() error = %v, want a rejected for its version", err)
+	}
+}
+
+func TestLoadRule_DefaultsToManualTrigger(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rule, err := LoadRule(write("on-demand.yaml", "name: on-demand\naction:\n  script: \"uptime\"\n"))
+	if err != nil {
+		t.Fatalf("LoadRule() without a trigger error = %v", err)
+	}
+	if rule.Trigger.Type != "manual" {
+		t.Errorf("trigger type = %q, want manual", rule.Trigger.Type)
+	}
+
+	// Explicit triggers are kept, and a trigger block without a type is still invalid
+	rule, err = LoadRule(write("hourly.yaml", "name: hourly\ntrigger:\n  type: scheduled\n  run_every: 1h\naction:\n  prompt: x\n"))
+	if err != nil || rule.Trigger.Type != "scheduled" {
+		t.Errorf("LoadRule() with a scheduled trigger = %+v, %v", rule, err)
+	}
+	if _, err := LoadRule(write("typeless.yaml", "name: typeless\ntrigger:\n  run_every: 1h\naction:\n  prompt: x\n")); err == nil ||
+		!strings.Contains(err.Error(), "trigger type is required") {
+		t.Errorf("LoadRule() with a typeless trigger error = %v, want trigger type is required", err)
+	}
+
+	// Multi-rule entries default the same way unless defaults: sets a trigger
+	rules, err := LoadRuleFile(write("fleet.yaml", `
+rules:
+  - name: a
+    action:
+      prompt: x
+  - name: b
+    trigger:
+      type: lifecycle
+      on_events: [daemon_started]
+    action:
+      prompt: y
+`))
+	if err != nil || len(rules) != 2 || rules[0].Trigger.Type != "manual" || rules[1].Trigger.Type != "lifecycle" {
+		t.Errorf("LoadRuleFile() = %+v, %v; want a manual, b lifecycle", rules, err)
+	}
+}
+
 // ===== FR-2: mergeClaudeConfig tests =====
 // These test the mergeClaudeConfig function indirectly through config merge behavior.
 // mergeClaudeConfig is on the daemon, so we test the merge logic expectations here
@@ -511,6 +927,51 @@ func TestValidateRule_RejectsBypassPermissions(t *testing.T) {
 	}
 }
 
+func TestValidateRule_TypedErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(r *Rule)
+		wantKind  error
+		wantField string
+		wantCode  string
+	}{
+		{"missing name", func(r *Rule) { r.Name = "" }, ErrMissingField, "name", "missing_field"},
+		{"bad trigger type", func(r *Rule) { r.Trigger.Type = "bogus" }, ErrInvalidTriggerType, "trigger.type", "invalid_trigger_type"},
+		{"no watch paths", func(r *Rule) { r.Trigger.Type = "filesystem"; r.Trigger.WatchPaths = nil }, ErrMissingField, "trigger.watch_paths", "missing_field"},
+		{"bypass permissions", func(r *Rule) { r.Claude.PermissionMode = "bypassPermissions" }, ErrUnsafePermissionMode, "claude.permission_mode", "unsafe_permission_mode"},
+		{"misspelled permission mode", func(r *Rule) { r.Claude.PermissionMode = "Plan" }, ErrInvalidValue, "claude.permission_mode", "invalid_value"},
+		{"root", func(r *Rule) { r.RunAsUser = "root" }, ErrUnsafeRunAsUser, "run_as_user", "unsafe_run_as_user"},
+		{"prompt and script", func(r *Rule) { r.Action.Script = "true" }, ErrConflictingFields, "action.script", "conflicting_fields"},
+		{"future version", func(r *Rule) { r.Version = RuleVersion + 1 }, ErrUnsupportedVersion, "version", "unsupported_version"},
+		{"bad when", func(r *Rule) { r.When = "((" }, ErrInvalidValue, "when", "invalid_value"},
+	}
+	for _, tt := range tests {
+		rule := validRule()
+		tt.modify(&rule)
+		// Wrapped as LoadRuleFile does
+		err := fmt.Errorf("validating rule in x.yaml: %w", ValidateRule(&rule))
+
+		if !errors.Is(err, tt.wantKind) {
+			t.Errorf("%s: error %v is not %v", tt.name, err, tt.wantKind)
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("%s: error %v is not a *ValidationError", tt.name, err)
+			continue
+		}
+		if verr.Field != tt.wantField {
+			t.Errorf("%s: Field = %q, want %q", tt.name, verr.Field, tt.wantField)
+		}
+		if got := ErrorCode(err); got != tt.wantCode {
+			t.Errorf("%s: ErrorCode() = %q, want %q", tt.name, got, tt.wantCode)
+		}
+	}
+
+	if got := ErrorCode(errors.New("parsing rule file: bad yaml")); got != "invalid_rule" {
+		t.Errorf("ErrorCode(untyped) = %q, want invalid_rule", got)
+	}
+}
+
 func TestValidateRule_RejectsRunAsUserNotInAllowlist(t *testing.T) {
 	// FR-15: run_as_user must be in the allowed_run_as_users list.
 	// This requires passing global config context to ValidateRule or
@@ -588,6 +1049,28 @@ func TestValidateRule_TriggersRulesDependsOnOverlap(t *testing.T) {
 	}
 }
 
+func TestValidateRuleWithGlobal_TriggersRulesTargets(t *testing.T) {
+	rule := validRule()
+	rule.Triggers = []string{"child", "off", "missing"}
+	child := validRule()
+	child.Name = "child"
+	child.Enabled = true
+	off := validRule()
+	off.Name = "off"
+	all := map[string]*Rule{rule.Name: &rule, "child": &child, "off": &off}
+
+	warnings := ValidateRuleWithGlobal(&rule, &Global{}, all)
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if !strings.Contains(warnings[0], `"off" is disabled`) {
+		t.Errorf("warning[0] = %q, want disabled target", warnings[0])
+	}
+	if !strings.Contains(warnings[1], `"missing" does not match any loaded rule`) {
+		t.Errorf("warning[1] = %q, want missing target", warnings[1])
+	}
+}
+
 // ===== FR-2: Config merge via YAML loading =====
 
 func TestLoadGlobal_ClaudeDefaultsAllFields(t *testing.T) {
@@ -719,3 +1202,696 @@ claude:
 		t.Errorf("expected PLEX_TOKEN=${PLEX_TOKEN}, got %q", rule.Claude.EnvVars["PLEX_TOKEN"])
 	}
 }
+
+// ===== Multi-rule files with shared defaults =====
+
+const multiRuleFile = `
+defaults: &defaults
+  enabled: true
+  run_as_user: ops
+  trigger:
+    type: scheduled
+    run_every: "1h"
+  claude:
+    model: haiku
+    allowed_tools: [Bash, Read]
+  on_failure:
+    retry: true
+rules:
+  - name: disk-check
+    action:
+      prompt: "Check disk usage"
+  - name: log-rotate
+    trigger:
+      run_every: "24h"
+    claude:
+      allowed_tools: [Bash]
+    action:
+      prompt: "Rotate logs"
+  - name: broken
+    trigger:
+      type: bogus
+    action:
+      prompt: "never loads"
+`
+
+func TestLoadRuleFile_MultiRuleDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fleet.yaml")
+	if err := os.WriteFile(path, []byte(multiRuleFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := LoadRuleFile(path)
+	if err == nil || !strings.Contains(err.Error(), `"broken" (rules[2])`) {
+		t.Errorf("expected error naming the invalid rule, got %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 valid rules, got %d", len(rules))
+	}
+
+	disk, rotate := rules[0], rules[1]
+	if disk.Name != "disk-check" || !disk.Enabled || disk.RunAsUser != "ops" {
+		t.Errorf("disk-check did not inherit defaults: %+v", disk)
+	}
+	if disk.Trigger.Type != "scheduled" || disk.Trigger.RunEvery != "1h" {
+		t.Errorf("disk-check trigger = %+v", disk.Trigger)
+	}
+	if disk.Claude.Model != "haiku" || len(disk.Claude.AllowedTools) != 2 {
+		t.Errorf("disk-check claude = %+v", disk.Claude)
+	}
+	if !disk.OnFailure.Retry || disk.OnFailure.RetryAttempts != 3 {
+		t.Errorf("disk-check on_failure = %+v, want retry with defaulted attempts", disk.OnFailure)
+	}
+
+	// Nested maps merge; scalars and lists override
+	if rotate.Trigger.Type != "scheduled" || rotate.Trigger.RunEvery != "24h" {
+		t.Errorf("log-rotate trigger = %+v, want scheduled every 24h", rotate.Trigger)
+	}
+	if rotate.Claude.Model != "haiku" || len(rotate.Claude.AllowedTools) != 1 {
+		t.Errorf("log-rotate claude = %+v, want haiku with [Bash]", rotate.Claude)
+	}
+	if rotate.Action.Prompt != "Rotate logs" {
+		t.Errorf("log-rotate prompt = %q", rotate.Action.Prompt)
+	}
+}
+
+func TestLoadRule_RejectsMultiRuleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fleet.yaml")
+	if err := os.WriteFile(path, []byte(multiRuleFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRule(path); err == nil {
+		t.Error("LoadRule should reject a file with several rules")
+	}
+}
+
+func TestLoadRulesDir_MixesFileShapes(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "fleet.yaml"), []byte(multiRuleFile), 0644)
+	os.WriteFile(filepath.Join(dir, "single.yaml"), []byte(`
+name: single
+trigger:
+  type: manual
+action:
+  prompt: "one rule"
+`), 0644)
+
+	rules, err := LoadRulesDir(dir)
+	if err != nil {
+		t.Fatalf("LoadRulesDir() error = %v", err)
+	}
+	var names []string
+	for _, r := range rules {
+		names = append(names, r.Name)
+	}
+	if got := strings.Join(names, ","); got != "disk-check,log-rotate,single" {
+		t.Errorf("loaded rules = %s, want disk-check,log-rotate,single", got)
+	}
+}
+
+// The same rule in each supported format.
+var equivalentRuleFiles = map[string]string{
+	"watch.yaml": `
+name: watch-downloads
+enabled: true
+trigger:
+  type: filesystem
+  watch_paths: [/tmp/downloads]
+  on_events: [file_created]
+  debounce_seconds: 2
+  coalesce_seconds: 0.5
+action:
+  prompt: "Sort {{file_path}}"
+claude:
+  model: haiku
+  env_vars:
+    LANG: C
+on_failure:
+  retry: true
+  retry_attempts: 2
+max_timeout_seconds: 120
+`,
+	"watch.json": `{
+	"name": "watch-downloads",
+	"enabled": true,
+	"trigger": {
+		"type": "filesystem",
+		"watch_paths": ["/tmp/downloads"],
+		"on_events": ["file_created"],
+		"debounce_seconds": 2,
+		"coalesce_seconds": 0.5
+	},
+	"action": {"prompt": "Sort {{file_path}}"},
+	"claude": {"model": "haiku", "env_vars": {"LANG": "C"}},
+	"on_failure": {"retry": true, "retry_attempts": 2},
+	"max_timeout_seconds": 120
+}`,
+	"watch.toml": `
+name = "watch-downloads"
+enabled = true
+max_timeout_seconds = 120
+
+[trigger]
+type = "filesystem"
+watch_paths = ["/tmp/downloads"]
+on_events = ["file_created"]
+debounce_seconds = 2
+coalesce_seconds = 0.5
+
+[action]
+prompt = "Sort {{file_path}}"
+
+[claude]
+model = "haiku"
+env_vars = { LANG = "C" }
+
+[on_failure]
+retry = true
+retry_attempts = 2
+`,
+}
+
+func TestLoadRule_FormatsAreEquivalent(t *testing.T) {
+	dir := t.TempDir()
+	rules := map[string]*Rule{}
+	for name, content := range equivalentRuleFiles {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		rule, err := LoadRule(path)
+		if err != nil {
+			t.Fatalf("LoadRule(%s) error = %v", name, err)
+		}
+		rules[name] = rule
+	}
+
+	want := rules["watch.yaml"]
+	if want.Trigger.CoalesceSeconds != 0.5 || want.Claude.EnvVars["LANG"] != "C" || want.OnFailure.RetryAttempts != 2 {
+		t.Fatalf("YAML rule not parsed as expected: %+v", want)
+	}
+	for _, name := range []string{"watch.json", "watch.toml"} {
+		if !reflect.DeepEqual(rules[name], want) {
+			t.Errorf("%s parsed as\n%+v\nwant\n%+v", name, rules[name], want)
+		}
+	}
+}
+
+func TestLoadRuleFile_MultiRuleTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fleet.toml")
+	content := `
+[defaults]
+enabled = true
+trigger = { type = "scheduled", run_every = "1h" }
+
+[[rules]]
+name = "disk-check"
+action = { prompt = "Check disk" }
+
+[[rules]]
+name = "log-rotate"
+trigger = { run_every = "24h" }
+action = { prompt = "Rotate logs" }
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadRuleFile(path)
+	if err != nil {
+		t.Fatalf("LoadRuleFile() error = %v", err)
+	}
+	if len(rules) != 2 || !rules[0].Enabled || rules[1].Trigger.RunEvery != "24h" || rules[1].Trigger.Type != "scheduled" {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+}
+
+func TestLoadRule_InvalidJSONAndTOML(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"bad.json": `{"name": "x",}`,
+		"bad.toml": `name = `,
+	} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0644)
+		_, err := LoadRule(path)
+		if err == nil || !strings.Contains(err.Error(), "parsing rule file") {
+			t.Errorf("LoadRule(%s) error = %v, want parse error", name, err)
+		}
+	}
+}
+
+func TestLoadRulesDir_LoadsAllFormats(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range equivalentRuleFiles {
+		renamed := strings.Replace(name, "watch", "watch-"+filepath.Ext(name)[1:], 1)
+		content = strings.Replace(content, "watch-downloads", "watch-"+filepath.Ext(name)[1:], 1)
+		os.WriteFile(filepath.Join(dir, renamed), []byte(content), 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a rule"), 0644)
+
+	rules, err := LoadRulesDir(dir)
+	if err != nil {
+		t.Fatalf("LoadRulesDir() error = %v", err)
+	}
+	var names []string
+	for _, r := range rules {
+		names = append(names, r.Name)
+	}
+	if got := strings.Join(names, ","); got != "watch-json,watch-toml,watch-yaml" {
+		t.Errorf("loaded rules = %s, want watch-json,watch-toml,watch-yaml", got)
+	}
+}
+
+func TestIsRuleFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"a.yaml": true, "a.yml": true, "a.json": true, "a.toml": true, "A.JSON": true,
+		"a.txt": false, "a.yaml.bak": false, "README": false,
+	} {
+		if got := IsRuleFile(name); got != want {
+			t.Errorf("IsRuleFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// ===== Tool permission validation =====
+
+func TestValidateRule_ToolInAllowedAndDisallowed(t *testing.T) {
+	rule := validRule()
+	rule.Claude.AllowedTools = []string{"Read", "Bash(git log:*)"}
+	rule.Claude.DisallowedTools = []string{"Bash(git log:*)"}
+	err := ValidateRule(&rule)
+	if err == nil || !strings.Contains(err.Error(), `"Bash(git log:*)" is in both`) {
+		t.Fatalf("expected allowed/disallowed conflict error, got %v", err)
+	}
+
+	// A narrower deny entry for the same tool is not a conflict
+	rule.Claude.DisallowedTools = []string{"Bash(rm:*)"}
+	if err := ValidateRule(&rule); err != nil {
+		t.Errorf("expected valid rule, got %v", err)
+	}
+}
+
+func TestLoadGlobal_ToolConflictInDefaults(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+claude_defaults:
+  allowed_tools: [Read, WebFetch]
+  disallowed_tools: [WebFetch]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGlobal(configPath); err == nil || !strings.Contains(err.Error(), "claude_defaults") {
+		t.Errorf("expected claude_defaults conflict error, got %v", err)
+	}
+}
+
+func TestValidateRuleWithGlobal_HighRiskTools(t *testing.T) {
+	global := &Global{}
+	applyGlobalDefaults(global)
+
+	rule := validRule()
+	rule.Claude.AllowedTools = []string{"Read", "Bash(rsync:*)"}
+
+	// Without run_as_user the daemon's own user runs it: no warning
+	if warnings := ValidateRuleWithGlobal(&rule, global, nil); len(warnings) != 0 {
+		t.Errorf("expected no warnings without run_as_user, got %v", warnings)
+	}
+
+	rule.RunAsUser = "media"
+	warnings := ValidateRuleWithGlobal(&rule, global, nil)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `high-risk tool "Bash(rsync:*)"`) {
+		t.Errorf("expected one Bash warning, got %v", warnings)
+	}
+
+	// Inherited allowed_tools count too, and the list is configurable
+	rule.Claude.AllowedTools = nil
+	global.ClaudeDefaults.AllowedTools = []string{"Read", "Glob"}
+	global.Daemon.HighRiskTools = []string{"Glob"}
+	warnings = ValidateRuleWithGlobal(&rule, global, nil)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `"Glob"`) {
+		t.Errorf("expected one Glob warning, got %v", warnings)
+	}
+
+	global.Daemon.HighRiskTools = []string{}
+	if warnings := ValidateRuleWithGlobal(&rule, global, nil); len(warnings) != 0 {
+		t.Errorf("empty high_risk_tools should disable the warning, got %v", warnings)
+	}
+
+	// No allowed_tools at all allows every tool, except those disallowed
+	global.ClaudeDefaults.AllowedTools = nil
+	global.Daemon.HighRiskTools = []string{"Bash", "Write"}
+	rule.Claude.DisallowedTools = []string{"Write"}
+	warnings = ValidateRuleWithGlobal(&rule, global, nil)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `high-risk tool "Bash"`) || !strings.Contains(warnings[0], "allowed_tools is empty") {
+		t.Errorf("expected one Bash warning for an empty allowed_tools, got %v", warnings)
+	}
+}
+
+func TestValidateRuleWithGlobal_ToolConflictWithDefaults(t *testing.T) {
+	global := &Global{}
+	global.ClaudeDefaults.DisallowedTools = []string{"WebFetch"}
+
+	rule := validRule()
+	rule.Claude.AllowedTools = []string{"WebFetch"}
+	warnings := ValidateRuleWithGlobal(&rule, global, nil)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `"WebFetch" is both allowed and disallowed`) {
+		t.Errorf("expected merged conflict warning, got %v", warnings)
+	}
+}
+
+func TestValidateRule_RejectsMisspelledPermissionMode(t *testing.T) {
+	rule := validRule()
+	rule.Claude.PermissionMode = "plann"
+	err := ValidateRule(&rule)
+	if err == nil || !strings.Contains(err.Error(), `invalid permission_mode "plann"`) {
+		t.Errorf("expected invalid permission_mode error, got %v", err)
+	}
+
+	for _, mode := range append([]string{""}, PermissionModes...) {
+		rule.Claude.PermissionMode = mode
+		if err := ValidateRule(&rule); err != nil {
+			t.Errorf("permission_mode %q: unexpected error %v", mode, err)
+		}
+	}
+}
+
+func TestLoadGlobal_RejectsMisspelledPermissionMode(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("claude_defaults:\n  permission_mode: acceptedits\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGlobal(configPath); err == nil || !strings.Contains(err.Error(), "claude_defaults: invalid permission_mode") {
+		t.Errorf("expected claude_defaults permission_mode error, got %v", err)
+	}
+}
+
+func TestValidateRuleWithGlobal_UnknownModel(t *testing.T) {
+	global := &Global{}
+	applyGlobalDefaults(global)
+
+	rule := validRule()
+	for _, model := range []string{"", "opus", "sonnet[1m]", "claude-sonnet-4-5-20250929"} {
+		rule.Claude.Model = model
+		if warnings := ValidateRuleWithGlobal(&rule, global, nil); len(warnings) != 0 {
+			t.Errorf("model %q: unexpected warnings %v", model, warnings)
+		}
+	}
+
+	rule.Claude.Model = "sonet"
+	warnings := ValidateRuleWithGlobal(&rule, global, nil)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `model "sonet" is not a known alias`) {
+		t.Errorf("expected typo warning, got %v", warnings)
+	}
+
+	// known_models is checked separately from the typo check, including for
+	// the inherited default model
+	global.Daemon.KnownModels = []string{"opus"}
+	rule.Claude.Model = ""
+	warnings = ValidateRuleWithGlobal(&rule, global, nil)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `model "sonnet" is not in known_models`) {
+		t.Errorf("expected known_models warning, got %v", warnings)
+	}
+}
+
+func TestLoadGlobal_RateLimits(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+daemon:
+  rate_limits:
+    health: 600
+    routes:
+      /api/history: 120
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadGlobal(configPath)
+	if err != nil {
+		t.Fatalf("LoadGlobal() error = %v", err)
+	}
+	limits := cfg.Daemon.RateLimits
+	for route, want := range map[string]int{
+		"/health":      600,
+		"/ready":       600,
+		"/api/history": 120,
+		"/api/rules":   DefaultAPIRateLimit,
+		"/":            DefaultWebhookRateLimit,
+	} {
+		if got := limits.Limit(route); got != want {
+			t.Errorf("Limit(%q) = %d, want %d", route, got, want)
+		}
+	}
+}
+
+func TestLoadGlobal_RejectsBadRateLimits(t *testing.T) {
+	for _, content := range []string{
+		"daemon:\n  rate_limits:\n    api: -1\n",
+		"daemon:\n  rate_limits:\n    routes:\n      /health: 0\n",
+		"daemon:\n  rate_limits:\n    routes:\n      health: 10\n",
+	} {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadGlobal(configPath); err == nil || !strings.Contains(err.Error(), "daemon.rate_limits") {
+			t.Errorf("LoadGlobal(%q) error = %v, want rate_limits error", content, err)
+		}
+	}
+}
+
+func TestLoadGlobal_RuleExecutionLimits(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("rule_execution:\n  max_output_bytes: 65536\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadGlobal(configPath)
+	if err != nil {
+		t.Fatalf("LoadGlobal() error = %v", err)
+	}
+	if got := cfg.RuleExecution.OutputLimit(); got != 65536 {
+		t.Errorf("OutputLimit() = %d, want 65536", got)
+	}
+	if got := cfg.RuleExecution.EventDataLimit(); got != DefaultMaxEventDataBytes {
+		t.Errorf("EventDataLimit() = %d, want default %d", got, DefaultMaxEventDataBytes)
+	}
+
+	for _, content := range []string{
+		"rule_execution:\n  max_output_bytes: -1\n",
+		"rule_execution:\n  max_output_bytes: 100\n",
+		"rule_execution:\n  max_output_bytes: 1073741824\n",
+		"rule_execution:\n  max_event_data_bytes: 10\n",
+		"rule_execution:\n  max_event_data_bytes: 2097152\n",
+	} {
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadGlobal(configPath); err == nil || !strings.Contains(err.Error(), "rule_execution: max_") {
+			t.Errorf("LoadGlobal(%q) error = %v, want rule_execution error", content, err)
+		}
+	}
+}
+
+func TestParsePort(t *testing.T) {
+	for in, want := range map[string]int{"1": 1, "9877": 9877, "65535": 65535} {
+		if got, err := ParsePort(in); err != nil || got != want {
+			t.Errorf("ParsePort(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0", "-1", "65536", "http"} {
+		if _, err := ParsePort(in); err == nil {
+			t.Errorf("ParsePort(%q) should fail", in)
+		}
+	}
+}
+
+func TestMemoryConfig_ListenAddr(t *testing.T) {
+	tests := []struct {
+		cfg      MemoryConfig
+		override string
+		want     string
+	}{
+		{MemoryConfig{}, "", "127.0.0.1:9877"},
+		{MemoryConfig{ListenAddress: "127.0.0.2", ListenPort: 9000}, "", "127.0.0.2:9000"},
+		{MemoryConfig{ListenPort: 9000}, "9100", "127.0.0.1:9100"},
+		{MemoryConfig{ListenAddress: "::1"}, "", "[::1]:9877"},
+	}
+	for _, tt := range tests {
+		got, err := tt.cfg.ListenAddr(tt.override)
+		if err != nil || got != tt.want {
+			t.Errorf("%+v.ListenAddr(%q) = %q, %v; want %q", tt.cfg, tt.override, got, err, tt.want)
+		}
+	}
+	if _, err := (MemoryConfig{}).ListenAddr("99999"); err == nil || !strings.Contains(err.Error(), "SRVRMGR_MCP_PORT") {
+		t.Errorf("ListenAddr with bad override error = %v, want SRVRMGR_MCP_PORT error", err)
+	}
+}
+
+func TestMemoryConfig_DBPath(t *testing.T) {
+	t.Setenv("SRVRMGR_MEMORY_DB", "/tmp/env-memory.db")
+	if got, err := (MemoryConfig{Path: "/srv/memory.db"}).DBPath(); err != nil || got != "/srv/memory.db" {
+		t.Errorf("DBPath(memory.path) = %q, %v; want the configured path", got, err)
+	}
+	if got, err := (MemoryConfig{}).DBPath(); err != nil || got != "/tmp/env-memory.db" {
+		t.Errorf("DBPath(no path) = %q, %v; want SRVRMGR_MEMORY_DB", got, err)
+	}
+	t.Setenv("SRVRMGR_MEMORY_DB", "")
+	if got, err := (MemoryConfig{}).DBPath(); err != nil || !strings.HasSuffix(got, "/Library/Application Support/srvrmgr/memory.db") {
+		t.Errorf("DBPath(default) = %q, %v; want the Library default", got, err)
+	}
+}
+
+func TestLoadGlobal_RejectsBadMemoryListen(t *testing.T) {
+	for _, content := range []string{
+		"memory:\n  listen_port: 70000\n",
+		"memory:\n  listen_port: -1\n",
+		"memory:\n  listen_address: nas.local\n",
+		"memory:\n  listen_address: 0.0.0.0\n",
+		"memory:\n  listen_address: 192.168.1.5\n",
+		"memory:\n  listen_address: \"::\"\n",
+	} {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadGlobal(configPath); err == nil || !strings.Contains(err.Error(), "memory: listen_") {
+			t.Errorf("LoadGlobal(%q) error = %v, want memory listen error", content, err)
+		}
+	}
+}
+
+func TestLoadGlobal_CanaryFailure(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	for content, wantErr := range map[string]bool{
+		"daemon:\n  canary_rule: canary\n":                           false,
+		"daemon:\n  canary_rule: canary\n  canary_failure: pause\n":  false,
+		"daemon:\n  canary_rule: canary\n  canary_failure: ignore\n": true,
+	} {
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadGlobal(configPath)
+		if (err != nil) != wantErr {
+			t.Errorf("LoadGlobal(%q) error = %v, wantErr %v", content, err, wantErr)
+		}
+		if wantErr && err != nil && !strings.Contains(err.Error(), "invalid canary_failure") {
+			t.Errorf("LoadGlobal(%q) error = %v, want canary_failure error", content, err)
+		}
+	}
+}
+
+func TestLoadRule_SystemPromptFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "prompts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "prompts", "system.md"), []byte("You manage backups.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	appendPath := filepath.Join(dir, "append.md")
+	if err := os.WriteFile(appendPath, []byte("Never delete snapshots."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writeRule := func(name, claude string) string {
+		path := filepath.Join(dir, name+".yaml")
+		content := "name: " + name + "\naction:\n  prompt: go\nclaude:\n" + claude
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// Relative paths resolve against the rule file; absolute paths as given
+	rule, err := LoadRule(writeRule("from-files", "  system_prompt_file: prompts/system.md\n  append_system_prompt_file: "+appendPath+"\n"))
+	if err != nil {
+		t.Fatalf("LoadRule failed: %v", err)
+	}
+	if rule.Claude.SystemPrompt != "You manage backups." {
+		t.Errorf("SystemPrompt = %q, want contents of system_prompt_file", rule.Claude.SystemPrompt)
+	}
+	if rule.Claude.AppendSystemPrompt != "Never delete snapshots." {
+		t.Errorf("AppendSystemPrompt = %q, want contents of append_system_prompt_file", rule.Claude.AppendSystemPrompt)
+	}
+
+	// An inline prompt wins; its file isn't even read
+	rule, err = LoadRule(writeRule("inline", "  system_prompt: Inline prompt\n  system_prompt_file: missing.md\n  append_system_prompt_file: "+appendPath+"\n"))
+	if err != nil {
+		t.Fatalf("LoadRule failed: %v", err)
+	}
+	if rule.Claude.SystemPrompt != "Inline prompt" {
+		t.Errorf("SystemPrompt = %q, want inline value to take precedence", rule.Claude.SystemPrompt)
+	}
+	if rule.Claude.AppendSystemPrompt != "Never delete snapshots." {
+		t.Errorf("AppendSystemPrompt = %q, want contents of append_system_prompt_file", rule.Claude.AppendSystemPrompt)
+	}
+
+	_, err = LoadRule(writeRule("missing", "  system_prompt_file: missing.md\n"))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Field != "claude.system_prompt_file" || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected claude.system_prompt_file error wrapping ErrNotExist, got %v", err)
+	}
+}
+
+func TestLoadGlobal_SystemPromptFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "default-prompt.md"), []byte("Be careful."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("claude_defaults:\n  system_prompt_file: default-prompt.md\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadGlobal(path)
+	if err != nil {
+		t.Fatalf("LoadGlobal failed: %v", err)
+	}
+	if cfg.ClaudeDefaults.SystemPrompt != "Be careful." {
+		t.Errorf("ClaudeDefaults.SystemPrompt = %q, want contents of system_prompt_file", cfg.ClaudeDefaults.SystemPrompt)
+	}
+}
+
+func TestLoadRule_ContentHash(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hash.yaml")
+	load := func(content string) *Rule {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		rule, err := LoadRule(path)
+		if err != nil {
+			t.Fatalf("LoadRule failed: %v", err)
+		}
+		return rule
+	}
+
+	content := "name: hash\naction:\n  prompt: Check disks\nclaude:\n  env_vars:\n    A: \"1\"\n    B: \"2\"\n"
+	first, second := load(content), load(content)
+	if first.Hash == "" || first.Hash != second.Hash {
+		t.Errorf("hashes of the same rule = %q, %q; want equal and non-empty", first.Hash, second.Hash)
+	}
+	if first.Hash != first.ContentHash() {
+		t.Errorf("Hash = %q, want ContentHash() %q", first.Hash, first.ContentHash())
+	}
+
+	// Formatting that doesn't change the rule doesn't change the hash
+	if reordered := load("action: {prompt: Check disks}\nclaude:\n  env_vars: {B: \"2\", A: \"1\"}\nname: hash\n"); reordered.Hash != first.Hash {
+		t.Errorf("reordered rule hash = %q, want %q", reordered.Hash, first.Hash)
+	}
+	if changed := load(strings.Replace(content, "Check disks", "Check disks twice", 1)); changed.Hash == first.Hash {
+		t.Error("changing the prompt didn't change the hash")
+	}
+}
+
+func TestDataPaths(t *testing.T) {
+	stateDB, logDir := DataPaths(filepath.Join(SystemConfigDir, "config.yaml"))
+	if stateDB != SystemStateDB || logDir != SystemLogDir {
+		t.Errorf("DataPaths(system config) = %q, %q, want the system paths", stateDB, logDir)
+	}
+	stateDB, logDir = DataPaths("/etc/srvrmgr/config.yaml")
+	if stateDB != "/etc/srvrmgr/state/history.db" || logDir != "/etc/srvrmgr/logs" {
+		t.Errorf("DataPaths(/etc/srvrmgr/config.yaml) = %q, %q, want paths beside the config", stateDB, logDir)
+	}
+}
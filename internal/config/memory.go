@@ -0,0 +1,101 @@
+// internal/config/memory.go
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Default address of the shared memory MCP server. The server has no
+// authentication and serves past rule output, so it only ever listens on a
+// loopback address.
+const (
+	DefaultMemoryListenAddress = "127.0.0.1"
+	DefaultMemoryListenPort    = 9877
+)
+
+// Values of memory.unknown_category
+const (
+	UnknownCategoryEmpty = "empty" // recall returns nothing, noting the known categories
+	UnknownCategoryAll   = "all"   // recall searches every category instead
+)
+
+// ParsePort parses a TCP port number in the range 1-65535.
+func ParsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("port %q is not a number", s)
+	}
+	if err := checkPort(port); err != nil {
+		return 0, err
+	}
+	return port, nil
+}
+
+func checkPort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("port %d is out of range 1-65535", port)
+	}
+	return nil
+}
+
+// ListenAddr returns the host:port the shared memory server listens on. A
+// non-empty portOverride (SRVRMGR_MCP_PORT) replaces the configured port.
+func (c MemoryConfig) ListenAddr(portOverride string) (string, error) {
+	port := orDefault(c.ListenPort, DefaultMemoryListenPort)
+	if portOverride != "" {
+		p, err := ParsePort(portOverride)
+		if err != nil {
+			return "", fmt.Errorf("SRVRMGR_MCP_PORT: %w", err)
+		}
+		port = p
+	}
+	host := c.ListenAddress
+	if host == "" {
+		host = DefaultMemoryListenAddress
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// DBPath returns the memory database: Path, else SRVRMGR_MEMORY_DB, else the
+// default in the user's Library. The daemon and the CLI both use it, so
+// `srvrmgr memory` reads the database the daemon writes.
+func (c MemoryConfig) DBPath() (string, error) {
+	if c.Path != "" {
+		return c.Path, nil
+	}
+	if path := os.Getenv("SRVRMGR_MEMORY_DB"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "Application Support", "srvrmgr", "memory.db"), nil
+}
+
+func (c MemoryConfig) validate() error {
+	if c.ListenPort != 0 {
+		if err := checkPort(c.ListenPort); err != nil {
+			return fmt.Errorf("listen_port: %w", err)
+		}
+	}
+	if c.ListenAddress != "" && c.ListenAddress != "localhost" {
+		ip := net.ParseIP(c.ListenAddress)
+		if ip == nil {
+			return fmt.Errorf("listen_address: %q is not an IP address", c.ListenAddress)
+		}
+		if !ip.IsLoopback() {
+			return fmt.Errorf("listen_address: %q is not a loopback address; the memory server has no authentication", c.ListenAddress)
+		}
+	}
+	switch c.UnknownCategory {
+	case "", UnknownCategoryEmpty, UnknownCategoryAll:
+	default:
+		return fmt.Errorf("unknown_category: must be %q or %q, got %q", UnknownCategoryEmpty, UnknownCategoryAll, c.UnknownCategory)
+	}
+	return nil
+}
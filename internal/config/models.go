@@ -0,0 +1,51 @@
+// internal/config/models.go
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PermissionModes are the valid values of permission_mode. Claude also has
+// bypassPermissions, which is never allowed for daemon rules.
+var PermissionModes = []string{"default", "acceptEdits", "plan"}
+
+// modelAliases are the model names claude accepts besides full model IDs.
+var modelAliases = []string{"default", "sonnet", "opus", "haiku", "opusplan"}
+
+// plausibleModel reports whether model is a claude alias (optionally with a
+// context suffix such as "sonnet[1m]") or looks like a full model ID.
+func plausibleModel(model string) bool {
+	alias, _, _ := strings.Cut(model, "[")
+	return slices.Contains(modelAliases, alias) || strings.HasPrefix(model, "claude-")
+}
+
+// validatePermissionMode rejects permission modes claude doesn't have, and
+// bypassPermissions. An empty mode inherits the default.
+func validatePermissionMode(mode string) error {
+	if mode == "" || slices.Contains(PermissionModes, mode) {
+		return nil
+	}
+	if mode == "bypassPermissions" {
+		return fieldError(ErrUnsafePermissionMode, "claude.permission_mode", "permission_mode \"bypassPermissions\" is not allowed for daemon rules")
+	}
+	return fieldError(ErrInvalidValue, "claude.permission_mode", "invalid permission_mode %q: must be one of %s", mode, strings.Join(PermissionModes, ", "))
+}
+
+// modelWarnings returns warnings about a model name: one that is neither an
+// alias nor a claude- model ID is probably a typo, and one missing from
+// knownModels (when set) is outside the models the operator expects.
+func modelWarnings(model string, knownModels []string) []string {
+	if model == "" {
+		return nil
+	}
+	var warnings []string
+	if !plausibleModel(model) {
+		warnings = append(warnings, fmt.Sprintf("model %q is not a known alias (%s) or claude- model ID", model, strings.Join(modelAliases, ", ")))
+	}
+	if len(knownModels) > 0 && !slices.Contains(knownModels, model) {
+		warnings = append(warnings, fmt.Sprintf("model %q is not in known_models", model))
+	}
+	return warnings
+}
@@ -0,0 +1,25 @@
+// internal/config/paths.go
+package config
+
+import "path/filepath"
+
+// System-wide locations of the config directory, execution history database
+// and logs, used by a root daemon and by the CLI.
+const (
+	SystemConfigDir = "/Library/Application Support/srvrmgr"
+	SystemStateDB   = SystemConfigDir + "/state/history.db"
+	SystemLogDir    = "/Library/Logs/srvrmgr"
+)
+
+// DataPaths returns where a daemon using the config file at configPath keeps
+// its execution history database and logs: the system paths for the system
+// config, otherwise state/history.db and logs/ beside the config file, so
+// alternate installs and tests don't write to system paths. The daemon and
+// the CLI both resolve them here, so the CLI reads what the daemon writes.
+func DataPaths(configPath string) (stateDB, logDir string) {
+	dir := filepath.Dir(configPath)
+	if dir == SystemConfigDir {
+		return SystemStateDB, SystemLogDir
+	}
+	return filepath.Join(dir, "state", "history.db"), filepath.Join(dir, "logs")
+}
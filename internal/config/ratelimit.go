@@ -0,0 +1,56 @@
+// internal/config/ratelimit.go
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Default management server rate limits, in requests per minute.
+const (
+	DefaultHealthRateLimit  = 60
+	DefaultAPIRateLimit     = 30
+	DefaultWebhookRateLimit = 10
+)
+
+// Limit returns the requests per minute allowed on route: its entry in
+// Routes, else the limit of its group.
+func (c RateLimitConfig) Limit(route string) int {
+	if n, ok := c.Routes[route]; ok {
+		return n
+	}
+	switch {
+	case route == "/health" || route == "/ready":
+		return orDefault(c.Health, DefaultHealthRateLimit)
+	case strings.HasPrefix(route, "/api/"):
+		return orDefault(c.API, DefaultAPIRateLimit)
+	}
+	return orDefault(c.Webhooks, DefaultWebhookRateLimit)
+}
+
+func orDefault(n, def int) int {
+	if n == 0 {
+		return def
+	}
+	return n
+}
+
+func (c RateLimitConfig) validate() error {
+	for _, group := range []struct {
+		name  string
+		limit int
+	}{{"health", c.Health}, {"api", c.API}, {"webhooks", c.Webhooks}} {
+		if group.limit < 0 {
+			return fmt.Errorf("%s must be positive, got %d", group.name, group.limit)
+		}
+	}
+	for route, n := range c.Routes {
+		if !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("route %q must start with /", route)
+		}
+		if n <= 0 {
+			return fmt.Errorf("route %q must be positive, got %d", route, n)
+		}
+	}
+	return nil
+}
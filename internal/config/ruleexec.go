@@ -0,0 +1,44 @@
+// internal/config/ruleexec.go
+package config
+
+import "fmt"
+
+// Default and allowed sizes, in bytes, of the output and event data kept in
+// the execution history.
+const (
+	DefaultMaxOutputBytes    = 10 * 1024
+	DefaultMaxEventDataBytes = 1024
+
+	minOutputBytes    = 1024
+	maxOutputBytes    = 16 * 1024 * 1024
+	minEventDataBytes = 128
+	maxEventDataBytes = 1024 * 1024
+)
+
+// OutputLimit returns how many bytes of output, stderr and parsed output the
+// history keeps.
+func (c RuleExecConfig) OutputLimit() int {
+	return orDefault(c.MaxOutputBytes, DefaultMaxOutputBytes)
+}
+
+// EventDataLimit returns how many bytes of serialized event data the history
+// keeps.
+func (c RuleExecConfig) EventDataLimit() int {
+	return orDefault(c.MaxEventDataBytes, DefaultMaxEventDataBytes)
+}
+
+func (c RuleExecConfig) validate() error {
+	for _, limit := range []struct {
+		name     string
+		n        int
+		min, max int
+	}{
+		{"max_output_bytes", c.MaxOutputBytes, minOutputBytes, maxOutputBytes},
+		{"max_event_data_bytes", c.MaxEventDataBytes, minEventDataBytes, maxEventDataBytes},
+	} {
+		if limit.n != 0 && (limit.n < limit.min || limit.n > limit.max) {
+			return fmt.Errorf("%s must be between %d and %d, got %d", limit.name, limit.min, limit.max, limit.n)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// internal/config/schema.go
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// TriggerTypes are the valid values of trigger.type.
+var TriggerTypes = []string{"filesystem", "scheduled", "webhook", "lifecycle", "manual"}
+
+// Values of depends_on_mode; empty means DependsOnAll.
+const (
+	DependsOnAll = "all"
+	DependsOnAny = "any"
+)
+
+// DependsOnModes are the valid values of depends_on_mode.
+var DependsOnModes = []string{DependsOnAll, DependsOnAny}
+
+// Values of daemon.canary_failure; empty means CanaryExit.
+const (
+	CanaryExit  = "exit"
+	CanaryPause = "pause"
+)
+
+// CanaryFailureModes are the valid values of daemon.canary_failure.
+var CanaryFailureModes = []string{CanaryExit, CanaryPause}
+
+// Values of action.output_parser; empty means no parsing.
+const (
+	OutputParserJSON  = "json"
+	OutputParserLines = "lines"
+	OutputParserRegex = "regex"
+)
+
+// OutputParsers are the valid values of action.output_parser.
+var OutputParsers = []string{OutputParserJSON, OutputParserLines, OutputParserRegex}
+
+// schemaEnums lists the allowed values of enumerated fields, keyed by
+// "<struct>.<yaml key>".
+var schemaEnums = map[string][]string{
+	"Trigger.type":                 TriggerTypes,
+	"ClaudeConfig.permission_mode": PermissionModes,
+	"Rule.depends_on_mode":         DependsOnModes,
+	"Action.output_parser":         OutputParsers,
+	"DaemonConfig.log_level":       {"debug", "info", "warn", "error"},
+	"DaemonConfig.canary_failure":  CanaryFailureModes,
+	"LoggingConfig.format":         {"json", "text"},
+}
+
+// schemaRequired lists the keys each struct must set, by struct name.
+var schemaRequired = map[string][]string{
+	"Rule":    {"name", "action"}, // no trigger means a manual rule
+	"Trigger": {"type"},
+}
+
+// RuleSchema returns a JSON Schema for rule files, derived from Rule. A file
+// holds either one rule or a multi-rule document (see LoadRuleFile), whose
+// defaults and entries are checked as partial rules since either may supply
+// the other's required keys.
+func RuleSchema() map[string]any {
+	multi := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"version":  map[string]any{"type": "integer"},
+			"defaults": map[string]any{"$ref": "#/$defs/partialRule"},
+			"rules":    map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/partialRule"}},
+		},
+		"required":             []string{"rules"},
+		"additionalProperties": false,
+	}
+	return map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "srvrmgr rule",
+		"oneOf":   []any{map[string]any{"$ref": "#/$defs/rule"}, multi},
+		"$defs": map[string]any{
+			"rule":        typeSchema(reflect.TypeOf(Rule{})),
+			"partialRule": withoutRequired(typeSchema(reflect.TypeOf(Rule{}))),
+		},
+	}
+}
+
+// withoutRequired removes every required list from s and its nested schemas.
+func withoutRequired(s map[string]any) map[string]any {
+	delete(s, "required")
+	if props, ok := s["properties"].(map[string]any); ok {
+		for _, p := range props {
+			withoutRequired(p.(map[string]any))
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		if sub, ok := s[key].(map[string]any); ok {
+			withoutRequired(sub)
+		}
+	}
+	return s
+}
+
+// GlobalSchema returns a JSON Schema for config.yaml, derived from Global.
+func GlobalSchema() map[string]any {
+	return newSchema("srvrmgr config", reflect.TypeOf(Global{}))
+}
+
+func newSchema(title string, t reflect.Type) map[string]any {
+	s := typeSchema(t)
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = title
+	return s
+}
+
+// typeSchema maps a Go type to its JSON Schema, following yaml struct tags.
+func typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	return map[string]any{}
+}
+
+func structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || key == "" || key == "-" {
+			continue
+		}
+		prop := typeSchema(f.Type)
+		if enum, ok := schemaEnums[t.Name()+"."+key]; ok {
+			prop["enum"] = enum
+		}
+		props[key] = prop
+	}
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if required, ok := schemaRequired[t.Name()]; ok {
+		s["required"] = required
+	}
+	return s
+}
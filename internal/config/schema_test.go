@@ -0,0 +1,147 @@
+// internal/config/schema_test.go
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// prop walks nested properties of a schema.
+func prop(t *testing.T, schema map[string]any, path ...string) map[string]any {
+	t.Helper()
+	s := schema
+	for _, key := range path {
+		props, ok := s["properties"].(map[string]any)
+		if !ok {
+			t.Fatalf("schema at %v has no properties", path)
+		}
+		if s, ok = props[key].(map[string]any); !ok {
+			t.Fatalf("schema has no property %v", path)
+		}
+	}
+	return s
+}
+
+// ruleDef returns the single-rule schema from RuleSchema.
+func ruleDef(t *testing.T, name string) map[string]any {
+	t.Helper()
+	def, ok := RuleSchema()["$defs"].(map[string]any)[name].(map[string]any)
+	if !ok {
+		t.Fatalf("rule schema has no $defs/%s", name)
+	}
+	return def
+}
+
+func TestRuleSchema(t *testing.T) {
+	s := ruleDef(t, "rule")
+
+	typ := prop(t, s, "trigger", "type")
+	if !reflect.DeepEqual(typ["enum"], TriggerTypes) {
+		t.Errorf("trigger.type enum = %v, want %v", typ["enum"], TriggerTypes)
+	}
+	// A rule without a trigger defaults to manual
+	if required := s["required"].([]string); !slices.Contains(required, "action") || slices.Contains(required, "trigger") {
+		t.Errorf("required = %v, want action but not trigger", required)
+	}
+
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{[]string{"name"}, "string"},
+		{[]string{"max_timeout_seconds"}, "integer"},
+		{[]string{"dry_run"}, "boolean"},
+		{[]string{"depends_on_rules"}, "array"},
+		{[]string{"claude", "memory"}, "boolean"},
+		{[]string{"claude", "max_budget_usd"}, "number"},
+		{[]string{"claude", "env_vars"}, "object"},
+		{[]string{"on_failure", "retry_attempts"}, "integer"},
+	}
+	for _, tt := range tests {
+		if got := prop(t, s, tt.path...)["type"]; got != tt.want {
+			t.Errorf("%v type = %v, want %s", tt.path, got, tt.want)
+		}
+	}
+	if s["additionalProperties"] != false {
+		t.Error("rule schema should reject unknown keys")
+	}
+}
+
+// Both single-rule and multi-rule files validate against the rule schema.
+func TestRuleSchema_Documents(t *testing.T) {
+	data, err := json.Marshal(RuleSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		doc   string
+		valid bool
+	}{
+		{`{"name": "a", "action": {"prompt": "x"}}`, true},
+		{`{"name": "a", "action": {"prompt": "x"}, "bogus": 1}`, false},
+		{`{"name": "a"}`, false},
+		{`{"version": 1, "defaults": {"action": {"prompt": "x"}}, "rules": [{"name": "a"}, {"name": "b"}]}`, true},
+		{`{"defaults": {"trigger": {"type": "manual"}}, "rules": [{"name": "a", "trigger": {"cron_expression": "0 3 * * *"}}]}`, true},
+		{`{"rules": [{"name": "a", "bogus": 1}]}`, false},
+		{`{"defaults": {"name": "a"}}`, false},
+	} {
+		var instance any
+		if err := json.Unmarshal([]byte(tt.doc), &instance); err != nil {
+			t.Fatal(err)
+		}
+		if err := resolved.Validate(instance); (err == nil) != tt.valid {
+			t.Errorf("Validate(%s) = %v, want valid %v", tt.doc, err, tt.valid)
+		}
+	}
+}
+
+func TestGlobalSchema(t *testing.T) {
+	s := GlobalSchema()
+	if got := prop(t, s, "daemon", "webhook_listen_port")["type"]; got != "integer" {
+		t.Errorf("daemon.webhook_listen_port type = %v", got)
+	}
+	if got := prop(t, s, "logging", "format")["enum"]; !reflect.DeepEqual(got, []string{"json", "text"}) {
+		t.Errorf("logging.format enum = %v", got)
+	}
+	if _, err := json.Marshal(s); err != nil {
+		t.Errorf("schema is not serializable: %v", err)
+	}
+}
+
+// Every yaml key of the config structs must appear in the schema.
+func TestSchema_CoversAllFields(t *testing.T) {
+	var check func(rt reflect.Type, s map[string]any)
+	check = func(rt reflect.Type, s map[string]any) {
+		props := s["properties"].(map[string]any)
+		for i := 0; i < rt.NumField(); i++ {
+			f := rt.Field(i)
+			key := f.Tag.Get("yaml")
+			if key == "-" {
+				continue
+			}
+			p, ok := props[key].(map[string]any)
+			if !ok {
+				t.Errorf("%s.%s (%s) missing from schema", rt.Name(), f.Name, key)
+				continue
+			}
+			if f.Type.Kind() == reflect.Struct {
+				check(f.Type, p)
+			}
+		}
+	}
+	check(reflect.TypeOf(Rule{}), ruleDef(t, "rule"))
+	check(reflect.TypeOf(Global{}), GlobalSchema())
+}
@@ -0,0 +1,53 @@
+// internal/config/tools.go
+package config
+
+import "strings"
+
+// DefaultHighRiskTools are the tools validation warns about allowing for rules
+// that run as another user (daemon.high_risk_tools overrides the list).
+var DefaultHighRiskTools = []string{"Bash", "Write", "Edit", "NotebookEdit", "WebFetch"}
+
+// toolName returns the tool a permission entry applies to, e.g. "Bash" for
+// "Bash(git log:*)".
+func toolName(entry string) string {
+	name, _, _ := strings.Cut(strings.TrimSpace(entry), "(")
+	return strings.TrimSpace(name)
+}
+
+// conflictingTool returns the first entry that appears in both allowed and
+// disallowed.
+func conflictingTool(allowed, disallowed []string) (string, bool) {
+	denied := make(map[string]bool, len(disallowed))
+	for _, t := range disallowed {
+		denied[strings.TrimSpace(t)] = true
+	}
+	for _, t := range allowed {
+		if denied[strings.TrimSpace(t)] {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// riskyTools returns the allowed entries whose tool is in highRisk, skipping
+// any that are also disallowed. An empty allowed list allows every tool, so
+// then it returns each high-risk tool that isn't disallowed.
+func riskyTools(allowed, disallowed, highRisk []string) []string {
+	if len(allowed) == 0 {
+		allowed = highRisk
+	}
+	risky := make(map[string]bool, len(highRisk))
+	for _, t := range highRisk {
+		risky[toolName(t)] = true
+	}
+	var found []string
+	for _, t := range allowed {
+		if _, denied := conflictingTool([]string{t}, disallowed); denied {
+			continue
+		}
+		if risky[toolName(t)] {
+			found = append(found, t)
+		}
+	}
+	return found
+}
@@ -1,6 +1,12 @@
 // internal/config/types.go
 package config
 
+import (
+	"regexp"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
 // Global configuration loaded from config.yaml
 type Global struct {
 	Daemon         DaemonConfig   `yaml:"daemon"`
@@ -11,10 +17,74 @@ type Global struct {
 }
 
 type DaemonConfig struct {
-	LogLevel             string   `yaml:"log_level"`
-	WebhookListenPort    int      `yaml:"webhook_listen_port"`
-	WebhookListenAddress string   `yaml:"webhook_listen_address"`
-	AllowedRunAsUsers    []string `yaml:"allowed_run_as_users"` // FR-15: allowlist for run_as_user
+	LogLevel                   string   `yaml:"log_level"`
+	WebhookListenPort          int      `yaml:"webhook_listen_port"`
+	WebhookListenAddress       string   `yaml:"webhook_listen_address"`
+	AllowedRunAsUsers          []string `yaml:"allowed_run_as_users"`         // FR-15: allowlist for run_as_user
+	MaintenanceIntervalMinutes int      `yaml:"maintenance_interval_minutes"` // history health digest interval (default 60, negative disables)
+	// Circuit breaker: stop a rule's trigger after this many consecutive failures
+	// (default 5, negative disables), re-arming it after the cooldown (default 60).
+	CircuitBreakerThreshold       int `yaml:"circuit_breaker_threshold"`
+	CircuitBreakerCooldownMinutes int `yaml:"circuit_breaker_cooldown_minutes"`
+	// EnablePprof serves net/http/pprof under /debug/pprof/ on the management
+	// server. Debugging only: off by default, and ignored unless
+	// webhook_listen_address is a loopback address.
+	EnablePprof bool `yaml:"enable_pprof"`
+	// ShutdownGraceSeconds is how long shutdown waits for in-flight executions
+	// before cancelling them (default 15, under launchd's 20s exit timeout).
+	ShutdownGraceSeconds int `yaml:"shutdown_grace_seconds"`
+	// TriggerMarker is the line prefix that fires a triggers_rules entry from
+	// a parent's output: "<marker><rule>" or "<marker><rule>{json}" (default "TRIGGER:").
+	TriggerMarker string `yaml:"trigger_marker"`
+	// HighRiskTools are warned about when a rule with run_as_user allows them
+	// (default DefaultHighRiskTools; an empty list disables the warning).
+	HighRiskTools []string `yaml:"high_risk_tools"`
+	// Execution history limits, applied at startup and on each maintenance run:
+	// records older than history_retention_days (default 90), beyond the newest
+	// history_max_rows_per_rule of a rule or beyond the newest history_max_rows
+	// overall are deleted. The row limits are off unless set, so upgrading
+	// never deletes history beyond the age limit. Negative disables a limit.
+	// A rule's retention_days overrides history_retention_days for that rule.
+	HistoryRetentionDays  int `yaml:"history_retention_days"`
+	HistoryMaxRowsPerRule int `yaml:"history_max_rows_per_rule"`
+	HistoryMaxRows        int `yaml:"history_max_rows"`
+	// CompressHistory gzips the output and event data of new history records.
+	// Existing rows are read either way.
+	CompressHistory bool `yaml:"compress_history"`
+	// AuditLogPath is the append-only log of privileged actions (executions
+	// with their run_as_user, API actions, rule reloads). Defaults to
+	// audit.log in the daemon's log directory.
+	AuditLogPath string `yaml:"audit_log_path"`
+	// KnownModels, when set, makes validation warn about rules whose model is
+	// not in the list. Misspelled model names are warned about either way.
+	KnownModels []string `yaml:"known_models"`
+	// RateLimits caps requests per minute to the management server, per
+	// client IP.
+	RateLimits RateLimitConfig `yaml:"rate_limits"`
+	// CanaryRule names a rule run once at startup, before the daemon accepts
+	// events, so a broken environment (such as a claude CLI that isn't logged
+	// in) shows up at boot. It runs even if disabled. If it fails, or doesn't
+	// exist, canary_failure decides what happens: "exit" (default) stops the
+	// daemon, "pause" starts it with executions paused.
+	CanaryRule    string `yaml:"canary_rule"`
+	CanaryFailure string `yaml:"canary_failure"`
+	// RunStartupRulesSync runs the daemon_started lifecycle rules to
+	// completion (within 10 minutes) before starting any other trigger, so
+	// setup rules such as mounting volumes finish first; /health answers
+	// meanwhile and /ready reports "running startup rules". Otherwise they
+	// run alongside the first events.
+	RunStartupRulesSync bool `yaml:"run_startup_rules_sync"`
+}
+
+// RateLimitConfig sets requests per minute for each group of management
+// server routes. Unset values use the defaults (see RateLimitConfig.Limit).
+type RateLimitConfig struct {
+	Health   int `yaml:"health"`   // /health and /ready (default 60)
+	API      int `yaml:"api"`      // /api/* (default 30)
+	Webhooks int `yaml:"webhooks"` // webhook triggers (default 10)
+	// Routes overrides the limit of single routes, e.g. "/api/history": 120.
+	// Webhooks share the "/" route.
+	Routes map[string]int `yaml:"routes"`
 }
 
 type ClaudeConfig struct {
@@ -29,6 +99,10 @@ type ClaudeConfig struct {
 	MCPConfig          []string          `yaml:"mcp_config"`
 	Memory             *bool             `yaml:"memory"`   // nil = inherit, true = enable, false = disable
 	EnvVars            map[string]string `yaml:"env_vars"` // FR-18: environment variables for subprocess
+	// SystemPromptFile and AppendSystemPromptFile load the prompts from files,
+	// relative to the rule (or config) file. An inline prompt takes precedence.
+	SystemPromptFile       string `yaml:"system_prompt_file"`
+	AppendSystemPromptFile string `yaml:"append_system_prompt_file"`
 }
 
 type LoggingConfig struct {
@@ -38,15 +112,31 @@ type LoggingConfig struct {
 
 type RuleExecConfig struct {
 	MaxConcurrent int `yaml:"max_concurrent"`
+	// How much of each execution the history keeps: output and stderr are
+	// truncated to MaxOutputBytes, event data to MaxEventDataBytes, and
+	// parsed output larger than MaxOutputBytes is not kept. Zero uses the
+	// defaults; see OutputLimit and EventDataLimit.
+	MaxOutputBytes    int `yaml:"max_output_bytes"`
+	MaxEventDataBytes int `yaml:"max_event_data_bytes"`
 }
 
 type MemoryConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	Path    string `yaml:"path"`
+	// Where the daemon's shared memory MCP server listens; see ListenAddr.
+	// The address must be loopback. SRVRMGR_MCP_PORT overrides the port.
+	ListenAddress string `yaml:"listen_address"`
+	ListenPort    int    `yaml:"listen_port"`
+	// What recall does when asked for a category no memory is stored
+	// under: UnknownCategoryEmpty (default) or UnknownCategoryAll.
+	UnknownCategory string `yaml:"unknown_category"`
 }
 
 // Rule configuration loaded from individual YAML files
 type Rule struct {
+	// Version is the rule format the rule was written for (see RuleVersion).
+	// Rules without one are taken to be the current version.
+	Version           int          `yaml:"version"`
 	Name              string       `yaml:"name"`
 	Description       string       `yaml:"description"`
 	Enabled           bool         `yaml:"enabled"`
@@ -56,10 +146,25 @@ type Rule struct {
 	Claude            ClaudeConfig `yaml:"claude"`
 	DryRun            bool         `yaml:"dry_run"`
 	DependsOn         []string     `yaml:"depends_on_rules"`
+	DependsOnMaxAge   string       `yaml:"depends_on_max_age"` // dependencies must have succeeded within this duration (e.g. "6h")
+	DependsOnMode     string       `yaml:"depends_on_mode"`    // all (default): every dependency must succeed; any: one is enough
 	Triggers          []string     `yaml:"triggers_rules"`
 	OnFailure         OnFailure    `yaml:"on_failure"`
+	OnSuccess         OnSuccess    `yaml:"on_success"`
 	MaxTimeoutSeconds int          `yaml:"max_timeout_seconds"` // FR-3: per-rule timeout (default 300)
 	MaxActions        int          `yaml:"max_actions"`         // FR-17: max tool calls per execution (default 50)
+	When              string       `yaml:"when"`                // guard expression; execution is skipped when false
+	// RetentionDays keeps this rule's history for this many days instead of
+	// daemon.history_retention_days. 0 uses the global setting.
+	RetentionDays int `yaml:"retention_days"`
+	// DependsOnWait holds an event whose dependencies aren't met yet,
+	// rechecking with backoff, instead of skipping it. The event is skipped
+	// once DependsOnWaitTimeout (default "30m") passes without them being met.
+	DependsOnWait        bool   `yaml:"depends_on_wait"`
+	DependsOnWaitTimeout string `yaml:"depends_on_wait_timeout"`
+	// Hash is the ContentHash of the rule as loaded, used to tell whether a
+	// reload changed it. It isn't part of the rule file.
+	Hash string `yaml:"-"`
 }
 
 type Trigger struct {
@@ -70,6 +175,13 @@ type Trigger struct {
 	IgnorePatterns  []string `yaml:"ignore_patterns"`
 	DebounceSeconds int      `yaml:"debounce_seconds"`
 	Recursive       bool     `yaml:"recursive"`
+	// CoalesceSeconds is the FSEvents latency on macOS: the kernel batches
+	// changes for this long before delivering them. Ignored elsewhere.
+	CoalesceSeconds float64 `yaml:"coalesce_seconds"`
+	// DedupeWindowMs collapses events for the same path within this window
+	// into one (file_created wins over file_modified), even without
+	// debounce_seconds. 0 disables it.
+	DedupeWindowMs int `yaml:"dedupe_window_ms"`
 	// Scheduled
 	CronExpression string `yaml:"cron_expression"`
 	RunEvery       string `yaml:"run_every"`
@@ -80,16 +192,44 @@ type Trigger struct {
 	RequireSecret  bool     `yaml:"require_secret"`
 	SecretHeader   string   `yaml:"secret_header"`
 	SecretEnvVar   string   `yaml:"secret_env_var"`
+	// BodySchema is a JSON Schema file, relative to the rule file, that
+	// request bodies must match. Requests that don't are rejected with 400.
+	BodySchema string `yaml:"body_schema"`
+	// ResolvedBodySchema is BodySchema as read when the rule was loaded.
+	// Its contents are part of the rule's ContentHash.
+	ResolvedBodySchema *jsonschema.Resolved `yaml:"-"`
+	bodySchemaData     []byte               `yaml:"-"`
 	// Lifecycle
 	// (uses OnEvents)
 }
 
 type Action struct {
 	Prompt string `yaml:"prompt"`
+	Script string `yaml:"script"` // shell command run instead of Claude; mutually exclusive with prompt
+	// OutputParser extracts structured data from a successful run's output
+	// (one of OutputParsers). It is stored with the execution and merged into
+	// the event data of rules fired through triggers_rules.
+	OutputParser string `yaml:"output_parser"`
+	// OutputPattern is the regular expression for the regex parser; its
+	// named capture groups become keys.
+	OutputPattern string `yaml:"output_pattern"`
+}
+
+// OnSuccess is what happens after an execution succeeds, including on a
+// retry.
+type OnSuccess struct {
+	// Webhook is an http(s) URL that is POSTed a JSON summary of the
+	// execution, e.g. a Slack incoming webhook.
+	Webhook string `yaml:"webhook"`
 }
 
 type OnFailure struct {
 	Retry             bool `yaml:"retry"`
 	RetryAttempts     int  `yaml:"retry_attempts"`
 	RetryDelaySeconds int  `yaml:"retry_delay_seconds"`
+	// RetryIf limits retries to failures whose error or output matches one
+	// of these regular expressions. Empty retries every failure.
+	RetryIf []string `yaml:"retry_if"`
+	// RetryIfPatterns is RetryIf as compiled when the rule was validated.
+	RetryIfPatterns []*regexp.Regexp `yaml:"-"`
 }
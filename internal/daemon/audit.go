@@ -0,0 +1,149 @@
+// internal/daemon/audit.go
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/logging"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// Audit log actions. The audit log is separate from the operational log so
+// privileged actions can be reviewed on their own.
+const (
+	auditDaemonStarted = "daemon_started"
+	auditDaemonStopped = "daemon_stopped"
+	auditExecution     = "rule_execution"
+	auditRulesReloaded = "rules_reloaded"
+	auditConfigReload  = "config_reloaded"
+	auditPause         = "pause"
+	auditResume        = "resume"
+	auditEnable        = "enable"
+	auditRun           = "run"
+	auditUninstall     = "uninstall"
+)
+
+// auditLogPath returns the configured audit log path, defaulting to audit.log
+// in the daemon's log directory.
+func (d *Daemon) auditLogPath() string {
+	if cfg := d.config(); cfg != nil && cfg.Daemon.AuditLogPath != "" {
+		return cfg.Daemon.AuditLogPath
+	}
+	return filepath.Join(d.logDir, "audit.log")
+}
+
+// initAuditLog opens the append-only audit log. Entries are JSON lines.
+// Only the daemon rotates the file; CLI commands auditing their own actions
+// pass rotate false and just append, so the two processes never race to
+// rename it.
+func (d *Daemon) initAuditLog(rotate bool) error {
+	path := d.auditLogPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating audit log directory: %w", err)
+	}
+	var w io.WriteCloser
+	var err error
+	if rotate {
+		w, err = logging.NewRotatingWriter(path, 50*1024*1024) // 50MB
+	} else {
+		w, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	d.auditWriter = w
+	d.auditLog = newAuditLogger(w)
+	return nil
+}
+
+// closeAuditLog closes the audit log; later audit calls are no-ops.
+func (d *Daemon) closeAuditLog() {
+	if d.auditWriter != nil {
+		d.auditWriter.Close()
+	}
+	d.auditLog, d.auditWriter = nil, nil
+}
+
+// newAuditLogger returns a JSON logger for audit entries. Every entry is
+// written regardless of log_level, so the level attribute is dropped.
+func newAuditLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.LevelKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+// audit records a privileged action and who caused it. It is a no-op when
+// the audit log isn't open.
+func (d *Daemon) audit(action, actor string, attrs ...any) {
+	if d.auditLog == nil {
+		return
+	}
+	attrs = append([]any{"actor", actor, "uid", os.Geteuid()}, attrs...)
+	d.auditLog.Log(context.Background(), slog.LevelInfo, action, attrs...)
+}
+
+// auditExecutionStart records that rule is about to run, and as which user.
+func (d *Daemon) auditExecutionStart(rule *config.Rule, event trigger.Event) {
+	actor := "trigger:" + event.Type
+	if d.cliUser != "" {
+		actor = "cli:" + d.cliUser
+	}
+	runAs := rule.RunAsUser
+	if runAs == "" {
+		runAs = "(daemon user)"
+	}
+	d.audit(auditExecution, actor,
+		"rule", rule.Name,
+		"run_as_user", runAs,
+		"event_type", event.Type,
+		"dry_run", rule.DryRun,
+	)
+}
+
+// AuditUninstall records in the audit log (for CLI use) that the invoking
+// user is uninstalling the daemon, with what the uninstall will stop and
+// remove. It is written first, as the daemon is stopped by the uninstall; if
+// the log directory is among dataDirs, the entry goes with it.
+func (d *Daemon) AuditUninstall(stopDaemon bool, plist string, dataDirs []string) error {
+	// Without a readable config the entry goes to the default path
+	_ = d.loadConfig()
+	if err := d.initAuditLog(false); err != nil {
+		return err
+	}
+	defer d.closeAuditLog()
+	d.audit(auditUninstall, "cli:"+invokingUser(),
+		"stop_daemon", stopDaemon,
+		"plist", plist,
+		"data_dirs", dataDirs,
+	)
+	return nil
+}
+
+// apiActor identifies the client of an API request.
+func apiActor(r *http.Request) string {
+	return "api:" + r.RemoteAddr
+}
+
+// invokingUser returns the user running a CLI command, looking through sudo.
+func invokingUser() string {
+	if u := os.Getenv("SUDO_USER"); u != "" {
+		return u
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return fmt.Sprintf("uid %d", os.Getuid())
+}
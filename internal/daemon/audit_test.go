@@ -0,0 +1,156 @@
+// internal/daemon/audit_test.go
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// auditDaemon returns a test daemon writing its audit log to a temp file.
+func auditDaemon(t *testing.T, rules ...*config.Rule) (*Daemon, string) {
+	t.Helper()
+	d := newTestDaemon(t, rules...)
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	d.config().Daemon.AuditLogPath = path
+	if err := d.initAuditLog(true); err != nil {
+		t.Fatalf("initAuditLog() error = %v", err)
+	}
+	t.Cleanup(d.closeAuditLog)
+	return d, path
+}
+
+// readAudit returns the audit log entries in order.
+func readAudit(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("audit line %q is not JSON: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestAudit_RunAsUserExecution(t *testing.T) {
+	// Stand-in sudo that drops "-u <user>" and runs the command
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "sudo"), []byte("#!/bin/sh\nshift 2\nexec \"$@\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	rule := scriptRule("backup", "true")
+	rule.RunAsUser = "nobody"
+	d, path := auditDaemon(t, rule)
+	d.config().Daemon.AllowedRunAsUsers = []string{"nobody"}
+
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "backup", Type: "scheduled", Timestamp: time.Now()})
+
+	entries := readAudit(t, path)
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %v, want one", entries)
+	}
+	e := entries[0]
+	if e["msg"] != auditExecution || e["rule"] != "backup" || e["run_as_user"] != "nobody" || e["actor"] != "trigger:scheduled" {
+		t.Errorf("audit entry = %v", e)
+	}
+	if _, ok := e["time"]; !ok {
+		t.Error("audit entry has no timestamp")
+	}
+	if _, ok := e["level"]; ok {
+		t.Error("audit entry should not carry a log level")
+	}
+}
+
+func TestAudit_SkippedExecutionNotAudited(t *testing.T) {
+	rule := scriptRule("backup", "true")
+	rule.RunAsUser = "root"
+	d, path := auditDaemon(t, rule)
+	d.config().Daemon.AllowedRunAsUsers = []string{"alice"}
+
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "backup", Type: "manual", Timestamp: time.Now()})
+
+	if entries := readAudit(t, path); len(entries) != 0 {
+		t.Errorf("audit entries = %v, want none for a rule blocked by the allowlist", entries)
+	}
+}
+
+func TestAudit_APIActions(t *testing.T) {
+	d, path := auditDaemon(t)
+
+	d.handleAPIPause(httptest.NewRecorder(), localRequest(http.MethodPost, "/api/pause", nil))
+	d.handleAPIResume(httptest.NewRecorder(), localRequest(http.MethodPost, "/api/resume", nil))
+	// Rejected requests are not audited
+	d.handleAPIPause(httptest.NewRecorder(), localRequest(http.MethodGet, "/api/pause", nil))
+	d.handleAPIPause(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/pause", nil)) // remote
+
+	entries := readAudit(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("audit entries = %v, want pause and resume", entries)
+	}
+	if entries[0]["msg"] != auditPause || entries[0]["actor"] != "api:127.0.0.1:50000" {
+		t.Errorf("pause entry = %v", entries[0])
+	}
+	if entries[1]["msg"] != auditResume {
+		t.Errorf("resume entry = %v", entries[1])
+	}
+}
+
+// CLI processes append to the log the daemon rotates, without rotating it.
+func TestAudit_Uninstall(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	auditPath := filepath.Join(dir, "audit.log")
+	if err := os.WriteFile(configPath, []byte("daemon:\n  audit_log_path: "+auditPath+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(auditPath, []byte(`{"msg":"daemon_started"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := New(configPath, filepath.Join(dir, "rules"))
+	if err := d.AuditUninstall(true, "/Library/LaunchDaemons/com.srvrmgr.daemon.plist", []string{"/Library/Application Support/srvrmgr"}); err != nil {
+		t.Fatalf("AuditUninstall() error = %v", err)
+	}
+
+	entries := readAudit(t, auditPath)
+	if len(entries) != 2 || entries[0]["msg"] != auditDaemonStarted {
+		t.Fatalf("audit entries = %v, want the uninstall appended", entries)
+	}
+	e := entries[1]
+	if e["msg"] != auditUninstall || e["stop_daemon"] != true || !strings.HasPrefix(e["actor"].(string), "cli:") {
+		t.Errorf("uninstall entry = %v", e)
+	}
+}
+
+func TestAudit_DefaultPath(t *testing.T) {
+	d := newTestDaemon(t)
+	d.logDir = "/var/log/srvrmgr"
+	if got := d.auditLogPath(); got != "/var/log/srvrmgr/audit.log" {
+		t.Errorf("auditLogPath() = %q", got)
+	}
+}
+
+func TestAudit_DisabledIsNoop(t *testing.T) {
+	d := newTestDaemon(t)
+	d.audit(auditPause, "api:test") // must not panic without an audit log
+}
@@ -0,0 +1,65 @@
+// internal/daemon/canary.go
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/security"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// canaryTriggerType is the trigger type history records canary runs under.
+const canaryTriggerType = "canary"
+
+// runCanary runs the daemon.canary_rule, if one is set, before the daemon
+// accepts events. On failure it returns an error when canary_failure is
+// "exit", or pauses executions when it is "pause".
+func (d *Daemon) runCanary(ctx context.Context) error {
+	name := d.config().Daemon.CanaryRule
+	if name == "" {
+		return nil
+	}
+
+	err := d.checkCanary(ctx, name)
+	if err == nil {
+		d.logger.Info("canary rule succeeded", "rule", name)
+		return nil
+	}
+
+	if d.config().Daemon.CanaryFailure == config.CanaryPause {
+		d.logger.Error("CRITICAL: canary rule failed, starting with executions paused; resume via the API once fixed",
+			"rule", name, "error", err)
+		d.setPaused(true)
+		return nil
+	}
+	d.logger.Error("CRITICAL: canary rule failed, refusing to start", "rule", name, "error", err)
+	return fmt.Errorf("canary rule %q: %w", name, err)
+}
+
+// checkCanary executes the named rule once, recording it in history, and
+// returns why it didn't succeed.
+func (d *Daemon) checkCanary(ctx context.Context, name string) error {
+	d.mu.RLock()
+	rule, ok := d.rules[name]
+	d.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("rule not found")
+	}
+
+	d.logger.Info("running canary rule", "rule", name)
+	event := trigger.Event{RuleName: name, Type: canaryTriggerType, Timestamp: time.Now()}
+	startedAt := time.Now()
+	result, err := d.executeRule(ctx, rule, event, 0)
+	if err != nil {
+		d.recordExecution(rule, event, "failure", startedAt, "", err.Error())
+		return err
+	}
+	d.recordResult(rule, event, result, startedAt, security.ScrubOutput(result.Output), d.parseResult(rule, result))
+	if result.State != "success" {
+		return fmt.Errorf("%s: %s", result.State, result.Error)
+	}
+	return nil
+}
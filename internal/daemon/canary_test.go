@@ -0,0 +1,81 @@
+// internal/daemon/canary_test.go
+package daemon
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/executor"
+)
+
+func canaryDaemon(t *testing.T, failure string, result *executor.Result) (*Daemon, *fakeExecutor) {
+	t.Helper()
+	canary := scriptRule("canary", "claude --version")
+	canary.Enabled = false // canaries run even when disabled
+	d := newTestDaemon(t, canary)
+	d.config().Daemon.CanaryRule = "canary"
+	d.config().Daemon.CanaryFailure = failure
+	fake := &fakeExecutor{respond: func(int, *config.Rule) (*executor.Result, error) { return result, nil }}
+	d.SetExecutor(fake)
+	return d, fake
+}
+
+func TestRunCanary_Success(t *testing.T) {
+	d, fake := canaryDaemon(t, "", &executor.Result{State: "success"})
+
+	if err := d.runCanary(context.Background()); err != nil {
+		t.Fatalf("runCanary() error = %v", err)
+	}
+	if len(fake.calls) != 1 || fake.calls[0].Event.Type != canaryTriggerType {
+		t.Errorf("executor calls = %+v, want one canary run", fake.calls)
+	}
+	if d.isPaused() {
+		t.Error("daemon paused after a successful canary")
+	}
+	if records := historyFor(t, d, "canary"); len(records) != 1 || records[0].State != "success" {
+		t.Errorf("history = %+v, want the canary run recorded", records)
+	}
+}
+
+func TestRunCanary_FailureExits(t *testing.T) {
+	d, _ := canaryDaemon(t, config.CanaryExit, &executor.Result{State: "failure", Error: "claude: not logged in"})
+
+	err := d.runCanary(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not logged in") {
+		t.Errorf("runCanary() error = %v, want the canary failure", err)
+	}
+}
+
+func TestRunCanary_FailurePauses(t *testing.T) {
+	d, _ := canaryDaemon(t, config.CanaryPause, &executor.Result{State: "failure", Error: "claude: not logged in"})
+
+	if err := d.runCanary(context.Background()); err != nil {
+		t.Fatalf("runCanary() error = %v, want startup to continue", err)
+	}
+	if !d.isPaused() {
+		t.Error("daemon not paused after a failed canary with canary_failure: pause")
+	}
+}
+
+func TestRunCanary_MissingRule(t *testing.T) {
+	d, fake := canaryDaemon(t, "", &executor.Result{State: "success"})
+	d.config().Daemon.CanaryRule = "no-such-rule"
+
+	if err := d.runCanary(context.Background()); err == nil || !strings.Contains(err.Error(), "rule not found") {
+		t.Errorf("runCanary() error = %v, want rule not found", err)
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("executor called %d times, want 0", len(fake.calls))
+	}
+}
+
+func TestRunCanary_Unset(t *testing.T) {
+	d, fake := canaryDaemon(t, "", nil)
+	d.config().Daemon.CanaryRule = ""
+
+	if err := d.runCanary(context.Background()); err != nil || len(fake.calls) != 0 {
+		t.Errorf("runCanary() = %v with %d calls, want no-op", err, len(fake.calls))
+	}
+}
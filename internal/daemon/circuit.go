@@ -0,0 +1,158 @@
+// internal/daemon/circuit.go
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/state"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// circuitCooldownUnit is the unit of circuit_breaker_cooldown_minutes; tests shorten it.
+var circuitCooldownUnit = time.Minute
+
+// checkCircuit opens rule's circuit breaker once its failure streak in history
+// reaches circuit_breaker_threshold. A threshold <= 0 disables the breaker.
+func (d *Daemon) checkCircuit(ctx context.Context, rule *config.Rule) {
+	threshold := d.config().Daemon.CircuitBreakerThreshold
+	if threshold <= 0 || d.stateDB == nil {
+		return
+	}
+	act, err := d.stateDB.RuleActivity(rule.Name)
+	if err != nil {
+		d.logger.Warn("circuit breaker: could not read rule activity", "rule", rule.Name, "error", err)
+		return
+	}
+	if act.ConsecutiveFailures >= threshold {
+		d.openCircuit(ctx, rule, act.ConsecutiveFailures)
+	}
+}
+
+// openCircuit stops rule's trigger and re-arms it after the cooldown. Manual
+// runs and triggers_rules chains still execute. The failure streak only resets
+// on success, so a failure after re-arming trips the breaker again.
+func (d *Daemon) openCircuit(ctx context.Context, rule *config.Rule, failures int) {
+	cooldown := time.Duration(d.config().Daemon.CircuitBreakerCooldownMinutes) * circuitCooldownUnit
+	if cooldown <= 0 {
+		cooldown = 60 * circuitCooldownUnit
+	}
+
+	d.mu.Lock()
+	if _, open := d.circuitOpen[rule.Name]; open {
+		d.mu.Unlock()
+		return
+	}
+	if d.circuitOpen == nil {
+		d.circuitOpen = make(map[string]time.Time)
+	}
+	openedAt := time.Now()
+	d.circuitOpen[rule.Name] = openedAt
+	t := d.unregisterTrigger(rule.Name)
+	d.mu.Unlock()
+	// Stopped outside d.mu, as a trigger's Stop may wait on a handler that
+	// takes it
+	if t != nil {
+		t.Stop()
+	}
+
+	d.logger.Error("CIRCUIT OPEN: rule trigger stopped after repeated failures",
+		"rule", rule.Name,
+		"consecutive_failures", failures,
+		"cooldown", cooldown,
+	)
+	detail := fmt.Sprintf("%d consecutive failures; trigger stopped until %s (or 'srvrmgr enable %s')",
+		failures, openedAt.Add(cooldown).Format(time.RFC3339), rule.Name)
+	event := trigger.Event{RuleName: rule.Name, Type: rule.Trigger.Type, Timestamp: openedAt}
+	d.recordExecution(rule, event, state.StateCircuitOpen, openedAt, "", detail)
+
+	time.AfterFunc(cooldown, func() {
+		if ctx.Err() != nil {
+			return
+		}
+		if d.rearmCircuit(ctx, rule.Name, openedAt) {
+			d.logger.Info("circuit breaker re-armed after cooldown", "rule", rule.Name)
+		}
+	})
+}
+
+// rearmCircuit closes rule's circuit breaker and restarts its trigger if the
+// rule is still enabled. A non-zero openedAt only re-arms the breaker opened at
+// that time, so a stale cooldown timer can't cut short a newer one. It reports
+// whether the breaker was open.
+func (d *Daemon) rearmCircuit(ctx context.Context, ruleName string, openedAt time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	opened, open := d.circuitOpen[ruleName]
+	if !open || (!openedAt.IsZero() && !opened.Equal(openedAt)) {
+		return false
+	}
+	delete(d.circuitOpen, ruleName)
+
+	rule, ok := d.rules[ruleName]
+	if !ok || !rule.Enabled {
+		return true
+	}
+	if _, running := d.triggers[ruleName]; !running {
+		if err := d.startTrigger(ctx, rule); err != nil {
+			d.logger.Error("failed to restart trigger after circuit breaker", "rule", ruleName, "error", err)
+		}
+	}
+	return true
+}
+
+// unregisterTrigger removes ruleName's trigger from the daemon and returns it,
+// or nil if it has none, for the caller to stop. Callers must hold d.mu.
+func (d *Daemon) unregisterTrigger(ruleName string) trigger.Trigger {
+	t, ok := d.triggers[ruleName]
+	if !ok {
+		return nil
+	}
+	delete(d.triggers, ruleName)
+	for path, wh := range d.webhooks {
+		if wh.RuleName() == ruleName {
+			delete(d.webhooks, path)
+		}
+	}
+	return t
+}
+
+// handleAPIEnable re-arms a rule's circuit breaker (POST /api/enable?rule=NAME),
+// for local clients only. ctx is the daemon's lifetime context, which the
+// restarted trigger runs under.
+func (d *Daemon) handleAPIEnable(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !requireLocal(w, r) {
+			return
+		}
+		ruleName := r.URL.Query().Get("rule")
+		if ruleName == "" {
+			http.Error(w, "rule parameter is required", http.StatusBadRequest)
+			return
+		}
+		d.mu.RLock()
+		_, ok := d.rules[ruleName]
+		d.mu.RUnlock()
+		if !ok {
+			http.Error(w, fmt.Sprintf("rule %q not found", ruleName), http.StatusNotFound)
+			return
+		}
+
+		rearmed := d.rearmCircuit(ctx, ruleName, time.Time{})
+		if rearmed {
+			d.logger.Info("circuit breaker re-armed via API", "rule", ruleName)
+		}
+		d.audit(auditEnable, apiActor(r), "rule", ruleName, "rearmed", rearmed)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"rule": ruleName, "rearmed": rearmed})
+	}
+}
@@ -0,0 +1,227 @@
+// internal/daemon/circuit_test.go
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/state"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// stubTrigger records whether it was stopped, calling onStop if set.
+type stubTrigger struct {
+	name    string
+	stopped bool
+	onStop  func()
+}
+
+func (s *stubTrigger) Start(ctx context.Context, _ chan<- trigger.Event) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+func (s *stubTrigger) Stop() error {
+	s.stopped = true
+	if s.onStop != nil {
+		s.onStop()
+	}
+	return nil
+}
+func (s *stubTrigger) RuleName() string { return s.name }
+
+// seedFailures records n failed executions of rule.
+func seedFailures(t *testing.T, d *Daemon, rule string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		now := time.Now().Add(-time.Duration(n-i) * time.Minute)
+		if _, err := d.stateDB.RecordExecution(state.ExecutionRecord{
+			RuleName: rule, TriggerType: "manual", State: "failure", StartedAt: now, FinishedAt: now,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func circuitDaemon(t *testing.T, threshold int) (*Daemon, *stubTrigger) {
+	t.Helper()
+	d := newTestDaemon(t, scriptRule("broken", "exit 1"))
+	d.config().Daemon.CircuitBreakerThreshold = threshold
+	d.config().Daemon.CircuitBreakerCooldownMinutes = 60
+	d.events = make(chan trigger.Event, 1)
+	d.triggers = map[string]trigger.Trigger{}
+	d.webhooks = map[string]*trigger.Webhook{}
+	stub := &stubTrigger{name: "broken"}
+	d.triggers["broken"] = stub
+	return d, stub
+}
+
+func TestCircuit_OpensAfterConsecutiveFailures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, stub := circuitDaemon(t, 3)
+
+	seedFailures(t, d, "broken", 1)
+	d.handleEvent(ctx, trigger.Event{RuleName: "broken", Type: "manual", Timestamp: time.Now()})
+	if stub.stopped {
+		t.Fatal("circuit opened below threshold")
+	}
+
+	d.handleEvent(ctx, trigger.Event{RuleName: "broken", Type: "manual", Timestamp: time.Now()})
+	if !stub.stopped {
+		t.Fatal("trigger should be stopped after 3 consecutive failures")
+	}
+	if _, ok := d.triggers["broken"]; ok {
+		t.Error("stopped trigger should be unregistered")
+	}
+	if _, ok := d.circuitOpen["broken"]; !ok {
+		t.Error("circuit should be open")
+	}
+
+	records, _ := d.stateDB.GetHistory("broken", state.StateCircuitOpen, nil, 10)
+	if len(records) != 1 || !strings.Contains(records[0].Error, "3 consecutive failures") {
+		t.Errorf("expected one circuit_open record, got %+v", records)
+	}
+	if d.lastRunState["broken"] != "failure" {
+		t.Errorf("lastRunState = %q, want failure", d.lastRunState["broken"])
+	}
+}
+
+func TestCircuit_SuccessResetsStreak(t *testing.T) {
+	d, stub := circuitDaemon(t, 3)
+	d.rules["broken"].Action.Script = "true"
+
+	seedFailures(t, d, "broken", 5)
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "broken", Type: "manual", Timestamp: time.Now()})
+	if stub.stopped {
+		t.Error("a successful run should not open the circuit")
+	}
+}
+
+func TestCircuit_Disabled(t *testing.T) {
+	d, stub := circuitDaemon(t, -1)
+
+	seedFailures(t, d, "broken", 10)
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "broken", Type: "manual", Timestamp: time.Now()})
+	if stub.stopped {
+		t.Error("negative threshold should disable the circuit breaker")
+	}
+}
+
+func TestCircuit_RearmsAfterCooldown(t *testing.T) {
+	defer func(u time.Duration) { circuitCooldownUnit = u }(circuitCooldownUnit)
+	circuitCooldownUnit = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, stub := circuitDaemon(t, 2)
+
+	seedFailures(t, d, "broken", 2)
+	d.checkCircuit(ctx, d.rules["broken"])
+	if !stub.stopped {
+		t.Fatal("circuit should open")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		d.mu.RLock()
+		_, open := d.circuitOpen["broken"]
+		_, running := d.triggers["broken"]
+		d.mu.RUnlock()
+		if !open && running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("circuit not re-armed after cooldown (open=%v, trigger running=%v)", open, running)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCircuit_StopsTriggerWithoutLock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, stub := circuitDaemon(t, 2)
+	// A Stop that waits on something needing d.mu, such as an in-flight handler
+	stub.onStop = func() {
+		d.mu.RLock()
+		d.mu.RUnlock()
+	}
+	seedFailures(t, d, "broken", 2)
+
+	done := make(chan struct{})
+	go func() {
+		d.checkCircuit(ctx, d.rules["broken"])
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("opening the circuit deadlocked stopping the trigger")
+	}
+	if !stub.stopped {
+		t.Error("trigger not stopped")
+	}
+}
+
+func TestCircuit_ManualEnable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, _ := circuitDaemon(t, 2)
+	handler := d.handleAPIEnable(ctx)
+
+	// Not open yet
+	rec := httptest.NewRecorder()
+	handler(rec, localRequest(http.MethodPost, "/api/enable?rule=broken", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"rearmed":false`) {
+		t.Fatalf("enable closed circuit = %d %s", rec.Code, rec.Body.String())
+	}
+
+	seedFailures(t, d, "broken", 2)
+	d.checkCircuit(ctx, d.rules["broken"])
+
+	rec = httptest.NewRecorder()
+	handler(rec, localRequest(http.MethodPost, "/api/enable?rule=broken", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"rearmed":true`) {
+		t.Fatalf("enable open circuit = %d %s", rec.Code, rec.Body.String())
+	}
+	if _, ok := d.triggers["broken"]; !ok {
+		t.Error("enable should restart the rule's trigger")
+	}
+
+	// The stale cooldown timer must not affect a later breaker
+	if d.rearmCircuit(ctx, "broken", time.Now().Add(-time.Hour)) {
+		t.Error("rearm with a stale openedAt should be a no-op")
+	}
+}
+
+func TestCircuit_EnableErrors(t *testing.T) {
+	d, _ := circuitDaemon(t, 2)
+	handler := d.handleAPIEnable(context.Background())
+
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodGet, "/api/enable?rule=broken", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/enable", http.StatusBadRequest},
+		{http.MethodPost, "/api/enable?rule=missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler(rec, localRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.target, rec.Code, tt.want)
+		}
+	}
+
+	// Remote clients can't re-arm a tripped breaker
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/enable?rule=broken", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("remote POST /api/enable = %d, want 403", rec.Code)
+	}
+}
@@ -4,22 +4,28 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/colebrumley/srvrmgr/internal/config"
 	"github.com/colebrumley/srvrmgr/internal/executor"
 	"github.com/colebrumley/srvrmgr/internal/logging"
+	"github.com/colebrumley/srvrmgr/internal/mcp"
 	"github.com/colebrumley/srvrmgr/internal/security"
 	"github.com/colebrumley/srvrmgr/internal/state"
-	"github.com/colebrumley/srvrmgr/internal/template"
 	"github.com/colebrumley/srvrmgr/internal/trigger"
 	"github.com/fsnotify/fsnotify"
 )
@@ -28,40 +34,100 @@ import (
 type Daemon struct {
 	configPath   string
 	rulesDir     string
-	config       *config.Global
+	stateDBPath  string
+	logDir       string
+	userMode     bool // running without root: run_as_user rules are rejected
+	concurrency  int  // overrides rule_execution.max_concurrent when positive
+	cfg          atomic.Pointer[config.Global]
 	rules        map[string]*config.Rule
 	triggers     map[string]trigger.Trigger
 	events       chan trigger.Event
 	logger       *slog.Logger
 	webhooks     map[string]*trigger.Webhook
 	httpServer   *http.Server
-	daemonPath   string           // Path to daemon executable for MCP stdio transport
-	lastRunState map[string]string // tracks last execution state per rule name
-	stateDB      *state.DB        // FR-5: execution history persistence
-	startTime    time.Time        // FR-7: daemon start time for uptime
+	daemonPath   string                      // Path to daemon executable for MCP stdio transport
+	memoryServer *mcp.Server                 // shared memory MCP server, nil when not running
+	mcpURL       string                      // URL of the shared memory MCP server, empty when unavailable
+	lastRunState map[string]string           // tracks last execution state per rule name
+	lastSuccess  map[string]time.Time        // when each rule last succeeded, for depends_on_max_age
+	stateDB      *state.DB                   // FR-5: execution history persistence
+	startTime    time.Time                   // FR-7: daemon start time for uptime
+	paused       bool                        // kill switch set via /api/pause
+	ready        bool                        // event loop has started, reported by /ready
+	startupRules bool                        // synchronous daemon_started rules are running
+	circuitOpen  map[string]time.Time        // rules whose circuit breaker tripped, by time opened
+	depWaiting   map[string]bool             // rules with an event held by depends_on_wait
+	draining     chan struct{}               // closed when shutdown starts; pending retries are abandoned
+	auditLog     *slog.Logger                // append-only audit log of privileged actions, nil when not open
+	auditWriter  io.Closer                   // file behind auditLog
+	cliUser      string                      // user running a one-off CLI command, empty in the daemon
+	executor     Executor                    // runs rule actions; nil means claudeExecutor
+	reloadCh     chan struct{}               // Reload requests, handled by the event loop
+	historyFeed  feed[state.ExecutionRecord] // new history records, for /api/history/stream
+	execFeed     feed[ExecutionEvent]        // execution lifecycle events, for /api/events
 	mu           sync.RWMutex
-	sem          chan struct{}   // concurrency limiter
+	sem          chan struct{}  // concurrency limiter
 	wg           sync.WaitGroup // tracks in-flight event handlers
+	notifyWG     sync.WaitGroup // tracks in-flight on_success webhook POSTs
 }
 
-// New creates a new daemon instance
+// New creates a new daemon instance. The state database and log directory
+// follow configPath (see config.DataPaths).
 func New(configPath, rulesDir string) *Daemon {
+	stateDBPath, logDir := config.DataPaths(configPath)
 	return &Daemon{
 		configPath:   configPath,
 		rulesDir:     rulesDir,
+		stateDBPath:  stateDBPath,
+		logDir:       logDir,
 		rules:        make(map[string]*config.Rule),
 		triggers:     make(map[string]trigger.Trigger),
 		events:       make(chan trigger.Event, 100),
 		webhooks:     make(map[string]*trigger.Webhook),
 		lastRunState: make(map[string]string),
+		lastSuccess:  make(map[string]time.Time),
+		circuitOpen:  make(map[string]time.Time),
+		depWaiting:   make(map[string]bool),
+		reloadCh:     make(chan struct{}, 1),
 	}
 }
 
+// SetUserMode configures the daemon to run without root: the state database
+// and log live at the given user-scoped paths, and rules with run_as_user are
+// rejected because the daemon can't switch users. Call it before Run.
+func (d *Daemon) SetUserMode(stateDBPath, logDir string) {
+	d.userMode = true
+	d.stateDBPath = stateDBPath
+	d.logDir = logDir
+}
+
+// SetMaxConcurrent overrides rule_execution.max_concurrent, e.g. from a flag
+// or environment variable. Call it before Run; n must be positive.
+func (d *Daemon) SetMaxConcurrent(n int) {
+	d.concurrency = n
+}
+
+// maxConcurrent is how many rules may execute at once: the override set by
+// SetMaxConcurrent, else rule_execution.max_concurrent.
+func (d *Daemon) maxConcurrent() int {
+	if d.concurrency > 0 {
+		return d.concurrency
+	}
+	return d.config().RuleExecution.MaxConcurrent
+}
+
 // Run starts the daemon and blocks until context is cancelled
 func (d *Daemon) Run(ctx context.Context) error {
 	// Sourced from architect — startTime set in Run(), not New()
 	d.startTime = time.Now()
 
+	// Refuse to share the config's databases, port and logs with another daemon
+	lock, err := acquireLock(filepath.Dir(d.configPath))
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
 	// Load configuration
 	if err := d.loadConfig(); err != nil {
 		return fmt.Errorf("loading config: %w", err)
@@ -71,28 +137,38 @@ func (d *Daemon) Run(ctx context.Context) error {
 	// Sourced from architect — RotatingWriter with stdout fallback.
 	logWriter, err := d.initLogWriter()
 	if err != nil {
-		d.logger = logging.NewLogger(d.config.Logging.Format, d.config.Daemon.LogLevel, os.Stdout)
+		d.logger = logging.NewLogger(d.config().Logging.Format, d.config().Daemon.LogLevel, os.Stdout)
 		d.logger.Warn("failed to initialize rotating log writer, using stdout", "error", err)
 	} else {
-		d.logger = logging.NewLogger(d.config.Logging.Format, d.config.Daemon.LogLevel, logWriter)
+		d.logger = logging.NewLogger(d.config().Logging.Format, d.config().Daemon.LogLevel, logWriter)
 	}
 
 	d.logger.Info("starting daemon", "config", d.configPath, "rules_dir", d.rulesDir)
 
+	if err := d.initAuditLog(true); err != nil {
+		d.logger.Warn("failed to open audit log, privileged actions will not be audited", "error", err)
+	}
+	d.audit(auditDaemonStarted, "daemon", "config", d.configPath, "rules_dir", d.rulesDir, "user_mode", d.userMode)
+
+	// FR-5: Initialize state database (before the memory server, which reads it).
+	// Sourced from architect — separate initStateDB with NFR-1 cleanup goroutine.
+	d.warnRelocatedHistory()
+	if err := d.initStateDB(); err != nil {
+		d.logger.Warn("failed to initialize state database, history will not be recorded", "error", err)
+	}
+
 	// Get daemon path for MCP stdio transport
-	if d.config.Memory.Enabled {
+	if d.config().Memory.Enabled {
 		daemonPath, err := os.Executable()
 		if err != nil {
 			d.logger.Warn("could not determine daemon path, memory disabled", "error", err)
 		} else {
 			d.daemonPath = daemonPath
 		}
-	}
 
-	// FR-5: Initialize state database.
-	// Sourced from architect — separate initStateDB with NFR-1 cleanup goroutine.
-	if err := d.initStateDB(); err != nil {
-		d.logger.Warn("failed to initialize state database, history will not be recorded", "error", err)
+		// Share one embedder and memory DB across all rule executions;
+		// executions fall back to the stdio server if this fails.
+		d.startMemoryServer(ctx)
 	}
 
 	// FR-14: Validate rules directory permissions before loading.
@@ -107,29 +183,54 @@ func (d *Daemon) Run(ctx context.Context) error {
 		return fmt.Errorf("loading rules: %w", err)
 	}
 
+	// NFR-1: Clean up old and excess records, once the rules' own retention
+	// periods are known.
+	go d.cleanupHistory()
+
 	// FR-5: Initialize lastRunState from DB.
 	// Sourced from convention — bulk GetHistory is more efficient than per-rule GetLastState.
 	d.initLastRunStateFromDB()
 
-	// Initialize triggers
-	if err := d.initTriggers(ctx); err != nil {
-		return fmt.Errorf("initializing triggers: %w", err)
+	// Surface a broken environment before any trigger fires
+	if err := d.runCanary(ctx); err != nil {
+		return err
 	}
 
-	// FR-7: Always start HTTP server (not conditional on webhooks)
+	// FR-7: Always start HTTP server (not conditional on webhooks). It starts
+	// before the triggers so /health answers while synchronous startup rules
+	// run; /ready stays 503 until the event loop starts.
 	go d.startHTTPServer(ctx)
 
+	syncStartup := d.config().Daemon.RunStartupRulesSync
+	if err := d.startTriggers(ctx, syncStartup); err != nil {
+		return fmt.Errorf("initializing triggers: %w", err)
+	}
+
 	// FR-4: Start hot-reload watcher.
 	// Sourced from convention — debounce channel pattern.
 	go d.startHotReload(ctx)
 
+	// Periodic history health digest (never-succeeded, failing and stale rules)
+	if d.stateDB != nil && d.config().Daemon.MaintenanceIntervalMinutes > 0 {
+		go d.startMaintenance(ctx, time.Duration(d.config().Daemon.MaintenanceIntervalMinutes)*time.Minute)
+	}
+
 	// Fire lifecycle:daemon_started
-	d.fireLifecycleEvent("daemon_started")
+	if !syncStartup {
+		d.fireLifecycleEvent("daemon_started")
+	}
 
 	d.logger.Info("daemon started", "rules_loaded", len(d.rules))
 
 	// Initialize concurrency limiter
-	d.sem = make(chan struct{}, d.config.RuleExecution.MaxConcurrent)
+	d.sem = make(chan struct{}, d.maxConcurrent())
+
+	// Executions outlive ctx by up to the shutdown grace period, so in-flight
+	// rules can finish before being cancelled.
+	execCtx, cancelExec := context.WithCancel(context.Background())
+	defer cancelExec()
+	d.draining = make(chan struct{})
+	d.setReady(true)
 
 	// Main event loop
 	for {
@@ -142,52 +243,184 @@ func (d *Daemon) Run(ctx context.Context) error {
 					<-d.sem // release semaphore
 					d.wg.Done()
 				}()
-				d.handleEvent(ctx, event)
+				d.handleEvent(execCtx, event)
 			}()
+		case <-d.reloadCh:
+			d.reload(ctx)
 		case <-ctx.Done():
-			d.logger.Info("daemon stopping, waiting for in-flight handlers")
-			d.wg.Wait() // wait for in-flight handlers to finish
+			grace := time.Duration(d.config().Daemon.ShutdownGraceSeconds) * time.Second
+			d.drain(grace, cancelExec)
 			// Use a fresh context for shutdown lifecycle events since parent is cancelled
-			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
-			d.handleLifecycleShutdown(shutdownCtx)
-			shutdownCancel()
+			d.handleLifecycleShutdown(context.Background())
 			return d.shutdown()
 		}
 	}
 }
 
+// drain waits up to grace for in-flight handlers, then cancels their executions
+// (recorded as cancelled) and waits briefly for the killed processes to exit.
+// Pending retries are abandoned as soon as draining starts.
+func (d *Daemon) drain(grace time.Duration, cancelExec context.CancelFunc) {
+	if d.draining != nil {
+		close(d.draining)
+	}
+	d.logger.Info("daemon stopping, waiting for in-flight handlers", "grace", grace)
+	if d.waitHandlers(grace) {
+		return
+	}
+
+	d.logger.Warn("shutdown grace period elapsed, cancelling in-flight executions")
+	cancelExec()
+	if !d.waitHandlers(forceStopWait) {
+		d.logger.Error("in-flight handlers did not exit after cancellation, shutting down anyway")
+	}
+}
+
+// forceStopWait is how long drain waits after cancelling executions. It covers
+// the executor's kill and output drain (killWaitDelay) plus history writes.
+const forceStopWait = 5 * time.Second
+
+// waitHandlers waits for in-flight handlers to finish, reporting false if they
+// are still running after timeout.
+func (d *Daemon) waitHandlers(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
 // initLogWriter creates a rotating log writer (FR-6).
 // Sourced from architect — clean separation into helper.
 func (d *Daemon) initLogWriter() (*logging.RotatingWriter, error) {
-	logDir := "/Library/Logs/srvrmgr"
-	if err := os.MkdirAll(logDir, 0755); err != nil {
+	if err := os.MkdirAll(d.logDir, 0755); err != nil {
 		return nil, fmt.Errorf("creating log directory: %w", err)
 	}
-	logPath := filepath.Join(logDir, "srvrmgrd.log")
+	logPath := filepath.Join(d.logDir, "srvrmgrd.log")
 	return logging.NewRotatingWriter(logPath, 50*1024*1024) // 50MB
 }
 
 // initStateDB opens the state database (FR-5).
 // Sourced from architect — separate method with NFR-1 cleanup goroutine.
 func (d *Daemon) initStateDB() error {
-	dbPath := filepath.Join("/Library/Application Support/srvrmgr/state/history.db")
-	db, err := state.Open(dbPath)
+	db, err := state.Open(d.stateDBPath)
 	if err != nil {
 		return fmt.Errorf("opening state database: %w", err)
 	}
+	db.SetCompression(d.config().Daemon.CompressHistory)
 	d.stateDB = db
+	return nil
+}
+
+// warnRelocatedHistory points out history an older srvrmgr left at the
+// system path for a config file elsewhere, before the history database
+// followed the config (see config.DataPaths).
+func (d *Daemon) warnRelocatedHistory() {
+	if d.userMode || d.stateDBPath == config.SystemStateDB {
+		return
+	}
+	if _, err := os.Stat(d.stateDBPath); err == nil {
+		return
+	}
+	if _, err := os.Stat(config.SystemStateDB); err != nil {
+		return
+	}
+	d.logger.Warn("execution history now lives beside the config file; move the old database there to keep it",
+		"old", config.SystemStateDB, "new", d.stateDBPath)
+}
+
+// startMemoryServer starts the memory MCP server over HTTP (on localhost
+// unless configured otherwise) so rule executions can connect to it instead
+// of each spawning `srvrmgrd mcp-server`.
+func (d *Daemon) startMemoryServer(ctx context.Context) {
+	addr, err := d.config().Memory.ListenAddr(os.Getenv("SRVRMGR_MCP_PORT"))
+	if err != nil {
+		d.logger.Warn("invalid shared memory server address, using stdio per execution", "error", err)
+		return
+	}
+
+	srv, err := mcp.NewServer(d.config().Memory.Path)
+	if err != nil {
+		d.logger.Warn("could not start shared memory server, using stdio per execution", "error", err)
+		return
+	}
+	if d.stateDB != nil {
+		srv.SetHistory(d.stateDB)
+	}
+	srv.SetUnknownCategory(d.config().Memory.UnknownCategory)
+
+	ln, err := mcp.Listen(addr)
+	if err != nil {
+		srv.Close()
+		d.logger.Warn("could not listen for shared memory server, using stdio per execution", "error", err, "address", addr)
+		return
+	}
+
+	httpServer := &http.Server{Handler: srv.Handler()}
+
+	d.mu.Lock()
+	d.memoryServer = srv
+	d.mcpURL = "http://" + ln.Addr().String() + "/mcp"
+	d.mu.Unlock()
 
-	// NFR-1: Run cleanup of old records (90-day retention).
-	// Sourced from architect.
+	d.logger.Info("shared memory server started", "address", ln.Addr().String())
+
+	// Load the embedding model now rather than during the first execution
+	// that uses memory, then repair memories stored without embeddings so
+	// they are semantically recallable
 	go func() {
-		if deleted, err := db.Cleanup(90); err != nil {
-			d.logger.Warn("state cleanup failed", "error", err)
-		} else if deleted > 0 {
-			d.logger.Info("cleaned up old execution records", "deleted", deleted)
+		start := time.Now()
+		if err := srv.WarmUp(); err != nil {
+			d.logger.Warn("could not load memory embedding model, recall falls back to keyword search", "error", err)
+			return
+		}
+		d.logger.Info("memory embedding model loaded", "duration", time.Since(start).Round(time.Millisecond))
+
+		if n, err := srv.BackfillEmbeddings(); err != nil {
+			d.logger.Warn("memory embedding backfill failed", "error", err, "updated", n)
+		} else if n > 0 {
+			d.logger.Info("backfilled memory embeddings", "updated", n)
 		}
 	}()
 
-	return nil
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		if err := httpServer.Serve(ln); err != http.ErrServerClosed {
+			d.logger.Error("shared memory server error, falling back to stdio", "error", err)
+		}
+		d.mu.Lock()
+		d.mcpURL = ""
+		d.mu.Unlock()
+	}()
+}
+
+// memoryEndpoint returns the shared memory server URL when it is running,
+// otherwise the daemon path for the stdio transport.
+func (d *Daemon) memoryEndpoint() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.mcpURL != "" {
+		return d.mcpURL
+	}
+	return d.daemonPath
+}
+
+// config returns the global config in effect. Reload replaces it, so
+// callers reading several settings that belong together should call it once.
+func (d *Daemon) config() *config.Global {
+	return d.cfg.Load()
 }
 
 func (d *Daemon) loadConfig() error {
@@ -195,7 +428,7 @@ func (d *Daemon) loadConfig() error {
 	if err != nil {
 		return err
 	}
-	d.config = cfg
+	d.cfg.Store(cfg)
 	return nil
 }
 
@@ -209,35 +442,35 @@ func (d *Daemon) loadRules() error {
 	defer d.mu.Unlock()
 
 	for _, rule := range rules {
+		if d.userMode && rule.RunAsUser != "" {
+			if d.logger != nil {
+				d.logger.Error("rule sets run_as_user, which needs a root daemon, skipping",
+					"rule", rule.Name,
+					"run_as_user", rule.RunAsUser,
+				)
+			}
+			continue
+		}
 		// FR-15: Validate run_as_user against allowlist.
 		// Sourced from convention — enforce by skipping disallowed rules.
-		if rule.RunAsUser != "" && len(d.config.Daemon.AllowedRunAsUsers) > 0 {
-			allowed := false
-			for _, u := range d.config.Daemon.AllowedRunAsUsers {
-				if u == rule.RunAsUser {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				if d.logger != nil {
-					d.logger.Error("rule run_as_user not in allowlist, skipping",
-						"rule", rule.Name,
-						"run_as_user", rule.RunAsUser,
-						"allowed", d.config.Daemon.AllowedRunAsUsers,
-					)
-				}
-				continue
+		if !d.runAsUserAllowed(rule) {
+			if d.logger != nil {
+				d.logger.Error("rule run_as_user not in allowlist, skipping",
+					"rule", rule.Name,
+					"run_as_user", rule.RunAsUser,
+					"allowed", d.config().Daemon.AllowedRunAsUsers,
+				)
 			}
+			continue
 		}
 		d.rules[rule.Name] = rule
 	}
 
 	// FR-15/FR-19: Run global-context validation for warnings.
 	// Sourced from architect — ValidateRuleWithGlobal returns warnings for overlap detection.
-	if d.config != nil {
+	if cfg := d.config(); cfg != nil {
 		for _, rule := range d.rules {
-			warnings := config.ValidateRuleWithGlobal(rule, d.config, d.rules)
+			warnings := config.ValidateRuleWithGlobal(rule, cfg, d.rules)
 			for _, w := range warnings {
 				if d.logger != nil {
 					d.logger.Warn(w)
@@ -249,6 +482,32 @@ func (d *Daemon) loadRules() error {
 	return nil
 }
 
+// runAsUserAllowed reports whether rule's run_as_user passes the FR-15 allowlist.
+// An empty run_as_user or an empty allowlist always passes.
+func (d *Daemon) runAsUserAllowed(rule *config.Rule) bool {
+	cfg := d.config()
+	if rule.RunAsUser == "" || cfg == nil || len(cfg.Daemon.AllowedRunAsUsers) == 0 {
+		return true
+	}
+	for _, u := range cfg.Daemon.AllowedRunAsUsers {
+		if u == rule.RunAsUser {
+			return true
+		}
+	}
+	return false
+}
+
+// startTriggers starts every enabled rule's trigger. With syncStartup, the
+// daemon_started rules (e.g. mounting volumes) run to completion first, so
+// they finish before any other trigger can fire; otherwise they race with the
+// first events.
+func (d *Daemon) startTriggers(ctx context.Context, syncStartup bool) error {
+	if syncStartup {
+		d.runStartupRules(ctx)
+	}
+	return d.initTriggers(ctx)
+}
+
 func (d *Daemon) initTriggers(ctx context.Context) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -259,29 +518,38 @@ func (d *Daemon) initTriggers(ctx context.Context) error {
 			continue
 		}
 
-		// FR-12: Pass runAsUser to trigger factory.
-		// Sourced from convention — 3-param New() avoids filesystem special-casing.
-		t, err := trigger.New(rule.Name, rule.Trigger, rule.RunAsUser)
-		if err != nil {
+		if err := d.startTrigger(ctx, rule); err != nil {
 			d.logger.Error("failed to create trigger", "rule", rule.Name, "error", err)
-			continue
 		}
+	}
 
-		d.triggers[rule.Name] = t
+	return nil
+}
 
-		// Track webhook triggers separately for HTTP routing
-		if wh, ok := t.(*trigger.Webhook); ok {
-			d.webhooks[wh.ListenPath()] = wh
-		}
+// startTrigger creates, registers and starts rule's trigger. Callers must hold d.mu.
+func (d *Daemon) startTrigger(ctx context.Context, rule *config.Rule) error {
+	// FR-12: Pass runAsUser to trigger factory.
+	// Sourced from convention — 3-param New() avoids filesystem special-casing.
+	t, err := trigger.New(rule.Name, rule.Trigger, rule.RunAsUser)
+	if err != nil {
+		return err
+	}
 
-		// Start the trigger
-		go func(t trigger.Trigger) {
-			if err := t.Start(ctx, d.events); err != nil && err != context.Canceled {
-				d.logger.Error("trigger error", "rule", t.RuleName(), "error", err)
-			}
-		}(t)
+	d.triggers[rule.Name] = t
+	if dr, ok := t.(trigger.DropReporter); ok {
+		dr.SetDropHandler(d.recordDropped)
+	}
+
+	// Track webhook triggers separately for HTTP routing
+	if wh, ok := t.(*trigger.Webhook); ok {
+		d.webhooks[wh.ListenPath()] = wh
 	}
 
+	go func() {
+		if err := t.Start(ctx, d.events); err != nil && err != context.Canceled {
+			d.logger.Error("trigger error", "rule", t.RuleName(), "error", err)
+		}
+	}()
 	return nil
 }
 
@@ -289,21 +557,49 @@ func (d *Daemon) initTriggers(ctx context.Context) error {
 // Combines architect's method guards with convention's typed ruleStatus and inline rate limiter.
 func (d *Daemon) startHTTPServer(ctx context.Context) {
 	addr := fmt.Sprintf("%s:%d",
-		d.config.Daemon.WebhookListenAddress,
-		d.config.Daemon.WebhookListenPort,
+		d.config().Daemon.WebhookListenAddress,
+		d.config().Daemon.WebhookListenPort,
 	)
 
+	d.httpServer = &http.Server{Addr: addr, Handler: d.newMux(ctx)}
+
+	d.logger.Info("starting HTTP server", "address", addr)
+
+	go func() {
+		if err := d.httpServer.ListenAndServe(); err != http.ErrServerClosed {
+			d.logger.Error("HTTP server error", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	d.httpServer.Shutdown(shutdownCtx)
+}
+
+// newMux builds the management server routes.
+func (d *Daemon) newMux(ctx context.Context) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	// FR-7: Health check endpoint
-	mux.HandleFunc("/health", rateLimitHandler(60, d.handleHealth))
+	mux.HandleFunc("/health", d.rateLimited("/health", d.handleHealth))
+	mux.HandleFunc("/ready", d.rateLimited("/ready", d.handleReady(ctx)))
 
 	// FR-7: API endpoints
-	mux.HandleFunc("/api/rules", rateLimitHandler(30, d.handleAPIRules))
-	mux.HandleFunc("/api/history", rateLimitHandler(30, d.handleAPIHistory))
+	mux.HandleFunc("/api/rules", d.rateLimited("/api/rules", d.handleAPIRules))
+	mux.HandleFunc("/api/rules/{name}/run", d.rateLimited("/api/rules/{name}/run", d.handleAPIRunRule))
+	mux.HandleFunc("/api/triggers", d.rateLimited("/api/triggers", d.handleAPITriggers))
+	mux.HandleFunc("/api/history", d.rateLimited("/api/history", d.handleAPIHistory))
+	mux.HandleFunc("/api/history/stream", d.rateLimited("/api/history/stream", d.handleAPIHistoryStream(ctx)))
+	mux.HandleFunc("/api/events", d.rateLimited("/api/events", d.handleAPIEvents(ctx)))
+	mux.HandleFunc("/api/logs", d.rateLimited("/api/logs", d.handleAPILogs))
+	mux.HandleFunc("/api/stats", d.rateLimited("/api/stats", d.handleAPIStats))
+	mux.HandleFunc("/api/pause", d.rateLimited("/api/pause", d.handleAPIPause))
+	mux.HandleFunc("/api/resume", d.rateLimited("/api/resume", d.handleAPIResume))
+	mux.HandleFunc("/api/enable", d.rateLimited("/api/enable", d.handleAPIEnable(ctx)))
 
 	// Webhook handler (catch-all)
-	mux.HandleFunc("/", rateLimitHandler(10, func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/", d.rateLimited("/", func(w http.ResponseWriter, r *http.Request) {
 		d.mu.RLock()
 		wh, ok := d.webhooks[r.URL.Path]
 		d.mu.RUnlock()
@@ -313,28 +609,23 @@ func (d *Daemon) startHTTPServer(ctx context.Context) {
 			return
 		}
 
-		if wh.HandleRequest(r, d.events) {
+		err := wh.HandleRequest(r, d.events)
+		var bodyErr *trigger.InvalidBodyError
+		switch {
+		case err == nil:
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte("OK"))
-		} else {
+		case errors.As(err, &bodyErr):
+			d.recordInvalidBody(bodyErr)
+			http.Error(w, bodyErr.Error(), http.StatusBadRequest)
+		default:
 			http.Error(w, "Forbidden", http.StatusForbidden)
 		}
 	}))
 
-	d.httpServer = &http.Server{Addr: addr, Handler: mux}
-
-	d.logger.Info("starting HTTP server", "address", addr)
-
-	go func() {
-		if err := d.httpServer.ListenAndServe(); err != http.ErrServerClosed {
-			d.logger.Error("HTTP server error", "error", err)
-		}
-	}()
+	d.mountPprof(mux)
 
-	<-ctx.Done()
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	d.httpServer.Shutdown(shutdownCtx)
+	return mux
 }
 
 // handleHealth returns daemon health status.
@@ -361,12 +652,59 @@ func (d *Daemon) handleHealth(w http.ResponseWriter, r *http.Request) {
 		"uptime":        uptime,
 		"rules_loaded":  rulesLoaded,
 		"rules_enabled": rulesEnabled,
+		"paused":        d.isPaused(),
 	}
+	d.mu.RLock()
+	if d.memoryServer != nil {
+		resp["memory_embedder"] = d.memoryServer.EmbedderState()
+	}
+	d.mu.RUnlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// setReady records that the event loop is accepting events.
+func (d *Daemon) setReady(ready bool) {
+	d.mu.Lock()
+	d.ready = ready
+	d.mu.Unlock()
+}
+
+// handleReady reports whether the daemon is processing events: 200 once the
+// event loop is running, 503 while starting up (including while synchronous
+// startup rules run) or once ctx is cancelled for shutdown.
+func (d *Daemon) handleReady(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		d.mu.RLock()
+		ready, startupRules := d.ready, d.startupRules
+		d.mu.RUnlock()
+
+		resp := map[string]any{"ready": false}
+		switch {
+		case ctx.Err() != nil:
+			resp["reason"] = "shutting down"
+		case !ready && startupRules:
+			resp["reason"] = "running startup rules"
+		case !ready:
+			resp["reason"] = "starting"
+		default:
+			resp["ready"] = true
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if resp["ready"] != true {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
 // handleAPIRules returns all rules with their current state.
 // Combines architect's method guard with convention's typed ruleStatus struct.
 func (d *Daemon) handleAPIRules(w http.ResponseWriter, r *http.Request) {
@@ -380,18 +718,21 @@ func (d *Daemon) handleAPIRules(w http.ResponseWriter, r *http.Request) {
 
 	// Sourced from convention — typed struct with JSON tags for stable API contract.
 	type ruleStatus struct {
-		Name      string `json:"name"`
-		Enabled   bool   `json:"enabled"`
-		DryRun    bool   `json:"dry_run"`
-		LastState string `json:"last_state,omitempty"`
+		Name        string `json:"name"`
+		Enabled     bool   `json:"enabled"`
+		DryRun      bool   `json:"dry_run"`
+		LastState   string `json:"last_state,omitempty"`
+		CircuitOpen bool   `json:"circuit_open,omitempty"`
 	}
 
 	var rules []ruleStatus
 	for _, rule := range d.rules {
+		_, open := d.circuitOpen[rule.Name]
 		rs := ruleStatus{
-			Name:    rule.Name,
-			Enabled: rule.Enabled,
-			DryRun:  rule.DryRun,
+			Name:        rule.Name,
+			Enabled:     rule.Enabled,
+			DryRun:      rule.DryRun,
+			CircuitOpen: open,
 		}
 		if st, ok := d.lastRunState[rule.Name]; ok {
 			rs.LastState = st
@@ -403,6 +744,92 @@ func (d *Daemon) handleAPIRules(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(rules)
 }
 
+// triggerStatus is one running trigger, as listed by /api/triggers.
+type triggerStatus struct {
+	Rule        string     `json:"rule"`
+	Type        string     `json:"type"`
+	WebhookPath string     `json:"webhook_path,omitempty"`
+	NextRun     *time.Time `json:"next_run,omitempty"` // scheduled triggers only
+}
+
+// handleAPITriggers lists the triggers currently running, sorted by rule,
+// with the webhook path or next fire time where the trigger has one.
+func (d *Daemon) handleAPITriggers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	d.mu.RLock()
+	triggers := make([]triggerStatus, 0, len(d.triggers))
+	for name, t := range d.triggers {
+		ts := triggerStatus{Rule: name}
+		if rule, ok := d.rules[name]; ok {
+			ts.Type = rule.Trigger.Type
+		}
+		if wh, ok := t.(*trigger.Webhook); ok {
+			ts.WebhookPath = wh.ListenPath()
+		}
+		if nr, ok := t.(trigger.NextRunner); ok {
+			if next := nr.NextRun(); !next.IsZero() {
+				ts.NextRun = &next
+			}
+		}
+		triggers = append(triggers, ts)
+	}
+	d.mu.RUnlock()
+	slices.SortFunc(triggers, func(a, b triggerStatus) int { return strings.Compare(a.Rule, b.Rule) })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(triggers)
+}
+
+// handleAPIRunRule queues a manual run of a rule (POST
+// /api/rules/{name}/run), as srvrmgr run does in-process. The optional
+// event parameter simulates a lifecycle event (see runEvent) and no_deps=true
+// skips the dependency gate. The run goes through the event loop, so it
+// shares the daemon's concurrency limit, memory server and history.
+//
+// The endpoint has no authentication, so it only accepts requests from the
+// local host (see isLocalRequest), even when webhook_listen_address exposes
+// the API.
+func (d *Daemon) handleAPIRunRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireLocal(w, r) {
+		return
+	}
+	ruleName := r.PathValue("name")
+	d.mu.RLock()
+	rule, ok := d.rules[ruleName]
+	d.mu.RUnlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("rule %q not found", ruleName), http.StatusNotFound)
+		return
+	}
+
+	event, err := runEvent(rule, r.URL.Query().Get("event"), map[string]any{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	event.NoDeps = r.URL.Query().Get("no_deps") == "true"
+
+	select {
+	case d.events <- event:
+	default:
+		d.recordDropped(event)
+		http.Error(w, "event queue full", http.StatusServiceUnavailable)
+		return
+	}
+	d.audit(auditRun, apiActor(r), "rule", ruleName, "event_type", event.Type, "no_deps", event.NoDeps)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{"rule": ruleName, "queued": true})
+}
+
 // handleAPIHistory returns execution history from the state DB.
 // Sourced from architect — includes method guard.
 func (d *Daemon) handleAPIHistory(w http.ResponseWriter, r *http.Request) {
@@ -427,7 +854,35 @@ func (d *Daemon) handleAPIHistory(w http.ResponseWriter, r *http.Request) {
 		limit = 500
 	}
 
-	records, err := d.stateDB.GetHistory(ruleName, stateFilter, limit)
+	triggerTypes := parseTriggerTypes(r.URL.Query().Get("trigger_type"))
+
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since %q: must be an RFC 3339 time", s), http.StatusBadRequest)
+			return
+		}
+		since = t.Local()
+	}
+
+	switch groupBy := r.URL.Query().Get("group_by"); groupBy {
+	case "":
+	case "rule":
+		sums, err := d.stateDB.HistoryByRule(ruleName, stateFilter, triggerTypes, since)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("summarizing history: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sums)
+		return
+	default:
+		http.Error(w, fmt.Sprintf("invalid group_by %q: must be rule", groupBy), http.StatusBadRequest)
+		return
+	}
+
+	records, err := d.stateDB.GetHistorySince(ruleName, stateFilter, triggerTypes, since, limit)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("querying history: %v", err), http.StatusInternalServerError)
 		return
@@ -437,37 +892,70 @@ func (d *Daemon) handleAPIHistory(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(records)
 }
 
-// rateLimitHandler wraps an HTTP handler with a simple token-bucket rate limiter (FR-7).
-// Sourced from convention — standalone function with closure state avoids sync.Map issues.
-func rateLimitHandler(requestsPerMinute int, handler http.HandlerFunc) http.HandlerFunc {
-	var mu sync.Mutex
-	tokens := requestsPerMinute
-	lastRefill := time.Now()
+// handleAPIStats returns per-rule reliability computed from the state DB.
+// Query params: rule (optional, defaults to all loaded rules), days (window, default 30)
+// and trigger_type (comma list, "-" prefix excludes).
+func (d *Daemon) handleAPIStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-	return func(w http.ResponseWriter, r *http.Request) {
-		mu.Lock()
-		now := time.Now()
-		elapsed := now.Sub(lastRefill)
-		refill := int(elapsed.Minutes() * float64(requestsPerMinute))
-		if refill > 0 {
-			tokens += refill
-			if tokens > requestsPerMinute {
-				tokens = requestsPerMinute
-			}
-			lastRefill = now
+	if d.stateDB == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]any{})
+		return
+	}
+
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		if _, err := fmt.Sscanf(v, "%d", &days); err != nil || days <= 0 {
+			http.Error(w, "days must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	triggerTypes := parseTriggerTypes(r.URL.Query().Get("trigger_type"))
+
+	var names []string
+	if rule := r.URL.Query().Get("rule"); rule != "" {
+		names = []string{rule}
+	} else {
+		d.mu.RLock()
+		for _, rule := range d.rules {
+			names = append(names, rule.Name)
 		}
+		d.mu.RUnlock()
+		sort.Strings(names)
+	}
 
-		if tokens <= 0 {
-			mu.Unlock()
-			w.Header().Set("Retry-After", "60")
-			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	stats := make([]state.RuleReliability, 0, len(names))
+	for _, name := range names {
+		rel, err := d.stateDB.RuleReliability(name, since, triggerTypes)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("computing stats: %v", err), http.StatusInternalServerError)
 			return
 		}
-		tokens--
-		mu.Unlock()
+		stats = append(stats, rel)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
 
-		handler(w, r)
+// parseTriggerTypes splits a comma-separated trigger_type query value.
+// Values prefixed with "-" are exclusions, e.g. "-manual,-triggered".
+func parseTriggerTypes(v string) []string {
+	if v == "" {
+		return nil
 	}
+	var types []string
+	for _, t := range strings.Split(v, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
 }
 
 func (d *Daemon) fireLifecycleEvent(eventType string) {
@@ -481,132 +969,266 @@ func (d *Daemon) fireLifecycleEvent(eventType string) {
 	}
 }
 
+// Limits on daemon_stopped rules, which run after triggers have stopped and
+// must finish before launchd kills the daemon. Tests shorten them.
+var (
+	// shutdownRulesBudget bounds all daemon_stopped rules together; rules
+	// not started within it are skipped
+	shutdownRulesBudget = 30 * time.Second
+	// shutdownRuleMaxTimeout caps each rule's max_timeout_seconds
+	shutdownRuleMaxTimeout = 20 * time.Second
+)
+
+// startupRulesBudget bounds all daemon_started rules together when
+// run_startup_rules_sync holds back the other triggers until they finish.
+// Each rule still has its own max_timeout_seconds. Tests shorten it.
+var startupRulesBudget = 10 * time.Minute
+
+// lifecycleRulesConcurrency is how many daemon_started or daemon_stopped
+// rules run at once when the daemon waits for them.
+const lifecycleRulesConcurrency = 4
+
+// runStartupRules runs the enabled daemon_started rules to completion, within
+// startupRulesBudget, before triggers start (see run_startup_rules_sync).
+func (d *Daemon) runStartupRules(ctx context.Context) {
+	d.mu.RLock()
+	var rules []*config.Rule
+	for _, rule := range d.rules {
+		if rule.Enabled && rule.Trigger.Type == "lifecycle" && slices.Contains(rule.Trigger.OnEvents, "daemon_started") {
+			rules = append(rules, rule)
+		}
+	}
+	d.mu.RUnlock()
+	if len(rules) == 0 {
+		return
+	}
+
+	d.logger.Info("running daemon_started rules before starting triggers", "rules", len(rules))
+	d.mu.Lock()
+	d.startupRules = true
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		d.startupRules = false
+		d.mu.Unlock()
+	}()
+	start := time.Now()
+	d.runLifecycleRules(ctx, "daemon_started", rules, startupRulesBudget, 0)
+	d.logger.Info("daemon_started rules finished", "duration", time.Since(start).Round(time.Millisecond))
+}
+
 // handleLifecycleShutdown directly handles daemon_stopped events with the given context,
 // bypassing the event channel which is no longer being read after ctx cancellation.
+// The rules run within shutdownRulesBudget, each with its timeout capped at
+// shutdownRuleMaxTimeout.
 func (d *Daemon) handleLifecycleShutdown(ctx context.Context) {
 	d.mu.RLock()
-	var lifecycleRules []string
+	var lifecycleRules []*config.Rule
 	for _, t := range d.triggers {
 		if lt, ok := t.(*trigger.Lifecycle); ok && lt.ShouldFireOn("daemon_stopped") {
-			lifecycleRules = append(lifecycleRules, lt.RuleName())
+			if rule, ok := d.rules[lt.RuleName()]; ok {
+				lifecycleRules = append(lifecycleRules, rule)
+			}
 		}
 	}
 	d.mu.RUnlock()
 
-	for _, ruleName := range lifecycleRules {
-		event := trigger.Event{
-			RuleName:  ruleName,
-			Type:      "daemon_stopped",
-			Timestamp: time.Now(),
-			Data:      map[string]any{},
+	d.runLifecycleRules(ctx, "daemon_stopped", lifecycleRules, shutdownRulesBudget, shutdownRuleMaxTimeout)
+}
+
+// runLifecycleRules runs rules for the lifecycle event eventType and waits
+// for them, up to lifecycleRulesConcurrency at a time, all within budget.
+// maxTimeout, if set, caps each rule's own timeout. Rules not started
+// within the budget are skipped.
+func (d *Daemon) runLifecycleRules(ctx context.Context, eventType string, rules []*config.Rule, budget, maxTimeout time.Duration) {
+	if len(rules) == 0 {
+		return
+	}
+	// Start in a stable order, so the same rules miss out if the budget runs out
+	rules = slices.Clone(rules)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	sem := make(chan struct{}, lifecycleRulesConcurrency)
+	var wg sync.WaitGroup
+	for _, rule := range rules {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			d.logger.Warn("skipping "+eventType+" rule, time budget used up", "rule", rule.Name, "budget", budget)
+			continue
 		}
-		d.handleEvent(ctx, event)
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			timeout := ruleTimeout(rule)
+			if maxTimeout > 0 {
+				timeout = min(timeout, maxTimeout)
+			}
+			ruleCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			d.handleEvent(ruleCtx, trigger.Event{
+				RuleName:  rule.Name,
+				Type:      eventType,
+				Timestamp: time.Now(),
+				Data:      map[string]any{},
+			})
+		}()
 	}
+	wg.Wait()
 }
 
-func (d *Daemon) handleEvent(ctx context.Context, event trigger.Event) {
+// handleEvent runs event's rule through the pre-execution gates and the
+// executor, retrying per on_failure, and returns how it ended.
+func (d *Daemon) handleEvent(ctx context.Context, event trigger.Event) RunOutcome {
 	d.mu.RLock()
 	rule, ok := d.rules[event.RuleName]
 	d.mu.RUnlock()
 
 	if !ok {
 		d.logger.Error("rule not found for event", "rule", event.RuleName)
-		return
+		return RunOutcome{Rule: event.RuleName, State: "failure", Detail: "rule not found"}
 	}
 
 	logger := logging.WithRule(d.logger, rule.Name)
 	logger.Info("handling event", "type", event.Type)
 
 	// FR-1: Inject default event_type and timestamp if not present
-	if event.Data == nil {
-		event.Data = map[string]any{}
-	}
-	if _, ok := event.Data["event_type"]; !ok {
-		event.Data["event_type"] = event.Type
-	}
-	if _, ok := event.Data["timestamp"]; !ok {
-		event.Data["timestamp"] = event.Timestamp.Format(time.RFC3339)
-	}
-
-	// Check dependencies before execution
-	if !d.checkDependencies(rule) {
-		logger.Warn("skipping rule, dependencies not met", "depends_on", rule.DependsOn)
-		return
-	}
+	injectEventDefaults(&event)
 
 	// FR-5: Record start time
 	startedAt := time.Now()
 
+	// Pre-execution gates (kill switch, dependencies, when condition, ...).
+	// Skips are recorded with a reason code so history shows why a rule didn't run.
+	if g, failed := firstFailedGate(d.checkGates(rule, event, false)); failed {
+		if g.Name == gateDependencies && d.holdForDependencies(rule, event, g) {
+			return RunOutcome{Rule: rule.Name, State: "waiting", Detail: g.Detail}
+		}
+		logger.Warn("skipping rule", "reason", g.Reason, "detail", g.Detail)
+		if g.Name != gateEnabled {
+			d.recordSkip(rule, event, g.Reason, g.Detail)
+		}
+		return RunOutcome{Rule: rule.Name, State: state.StateSkipped, Detail: g.Reason}
+	}
+
 	// Execute rule
-	result, err := d.executeRule(ctx, rule, event)
+	result, err := d.executeRule(ctx, rule, event, 0)
 	if err != nil {
 		logger.Error("execution error", "error", err)
 		// FR-5: Record failed execution
-		d.recordExecution(rule, event, "failure", startedAt, "", err.Error())
-		d.handleFailure(ctx, rule, event, err)
-		return
+		execID := d.recordExecution(rule, event, "failure", startedAt, "", err.Error())
+		return failureOutcome(rule.Name, "failure", err.Error(), d.handleFailure(ctx, rule, event, execID, err, ""))
 	}
 
 	logger.Info("execution complete",
 		"state", result.State,
 		"duration", result.Duration,
 	)
-
 	// FR-18: Scrub output before storage
 	scrubbedOutput := security.ScrubOutput(result.Output)
 
+	parsed := d.parseResult(rule, result)
+
 	// FR-5: Record execution
-	d.recordExecution(rule, event, result.State, startedAt, scrubbedOutput, result.Error)
+	execID := d.recordResult(rule, event, result, startedAt, scrubbedOutput, parsed)
 
 	// Track execution state
 	d.recordExecutionState(rule.Name, result.State)
 
 	switch result.State {
 	case "success":
+		d.notifySuccess(rule, event, execID, 0, result.Output)
 		// FR-13: Conditional trigger chains
-		d.fireTriggeredRules(ctx, rule, event, result.Output)
+		d.fireTriggeredRules(ctx, rule, event, result.Output, parsed)
 	case "cancelled":
 		logger.Info("execution cancelled (shutdown)")
 	default:
-		d.handleFailure(ctx, rule, event, fmt.Errorf("execution failed: %s", result.Error))
+		recovered := d.handleFailure(ctx, rule, event, execID, fmt.Errorf("execution failed: %s", result.Error), failureOutput(result))
+		return failureOutcome(rule.Name, result.State, result.Error, recovered)
 	}
+	return RunOutcome{Rule: rule.Name, State: result.State, Detail: result.Error}
 }
 
-// executeRule performs the actual rule execution (template expand, config merge, Claude call)
-func (d *Daemon) executeRule(ctx context.Context, rule *config.Rule, event trigger.Event) (*executor.Result, error) {
-	prompt := template.Expand(rule.Action.Prompt, event.Data)
-	claudeCfg := d.mergeClaudeConfig(rule.Claude)
+// RunOutcome is how one event for a rule ended.
+type RunOutcome struct {
+	Rule   string
+	State  string // success, failure, timeout, cancelled, skipped, or waiting (held by depends_on_wait)
+	Detail string // the skip reason or error, if any
+}
 
-	if rule.DryRun {
-		claudeCfg.PermissionMode = "plan"
+// failureOutcome is the outcome of an execution that ended in resultState,
+// or succeeded after all if a retry recovered it.
+func failureOutcome(rule, resultState, errMsg string, recovered bool) RunOutcome {
+	if recovered {
+		return RunOutcome{Rule: rule, State: "success", Detail: "after retry"}
 	}
+	return RunOutcome{Rule: rule, State: resultState, Detail: errMsg}
+}
 
-	// FR-12: Expand ~ in add_dirs using run_as_user's home directory.
-	// Sourced from architect — expand ALL AddDirs, not just the first.
-	workDir := ""
-	if len(claudeCfg.AddDirs) > 0 {
-		workDir = expandHomeForUser(claudeCfg.AddDirs[0], rule.RunAsUser)
+// injectEventDefaults sets event_type and timestamp in event.Data unless the
+// trigger already provided them (FR-1).
+func injectEventDefaults(event *trigger.Event) {
+	if event.Data == nil {
+		event.Data = map[string]any{}
 	}
-	for i, dir := range claudeCfg.AddDirs {
-		claudeCfg.AddDirs[i] = expandHomeForUser(dir, rule.RunAsUser)
+	if _, ok := event.Data["event_type"]; !ok {
+		event.Data["event_type"] = event.Type
 	}
+	if _, ok := event.Data["timestamp"]; !ok {
+		event.Data["timestamp"] = event.Timestamp.Format(time.RFC3339)
+	}
+}
 
-	// FR-3: Per-rule timeout configuration
-	timeout := 5 * time.Minute
-	if rule.MaxTimeoutSeconds > 0 {
-		timeout = time.Duration(rule.MaxTimeoutSeconds) * time.Second
+// executeRule prepares a rule for execution (config merge, dry-run plan mode,
+// ~ expansion, timeout) and runs it with the daemon's Executor. attempt is the
+// retry attempt number, 0 for the original run.
+func (d *Daemon) executeRule(ctx context.Context, rule *config.Rule, event trigger.Event, attempt int) (*executor.Result, error) {
+	effective := *rule
+	effective.Claude = d.mergeClaudeConfig(rule.Claude)
+
+	if rule.DryRun {
+		effective.Claude.PermissionMode = "plan"
+	}
+
+	d.auditExecutionStart(rule, event)
+	d.publishStarted(rule, event, attempt)
+
+	// FR-12: Expand ~ in add_dirs using run_as_user's home directory.
+	// Sourced from architect — expand ALL AddDirs, not just the first.
+	addDirs := make([]string, len(effective.Claude.AddDirs))
+	for i, dir := range effective.Claude.AddDirs {
+		addDirs[i] = expandHomeForUser(dir, rule.RunAsUser)
 	}
-	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	effective.Claude.AddDirs = addDirs
+
+	execCtx, cancel := context.WithTimeout(ctx, ruleTimeout(rule))
 	defer cancel()
 
-	memoryEnabled := d.isMemoryEnabled(rule)
-	return executor.ExecuteWithMemory(execCtx, prompt, claudeCfg, rule.RunAsUser, d.config.Logging.Debug, workDir, memoryEnabled, d.daemonPath)
+	return d.ruleExecutor().Execute(execCtx, &effective, event)
+}
+
+// FR-3: ruleTimeout is how long one execution of rule may run:
+// max_timeout_seconds, or 5 minutes if unset.
+func ruleTimeout(rule *config.Rule) time.Duration {
+	if rule.MaxTimeoutSeconds > 0 {
+		return time.Duration(rule.MaxTimeoutSeconds) * time.Second
+	}
+	return 5 * time.Minute
 }
 
 // FR-2: mergeClaudeConfig merges all 9 ClaudeConfig fields.
 // Both implementations have identical logic here.
 func (d *Daemon) mergeClaudeConfig(ruleCfg config.ClaudeConfig) config.ClaudeConfig {
-	defaults := d.config.ClaudeDefaults
+	defaults := d.config().ClaudeDefaults
 
 	result := ruleCfg
 	if result.Model == "" {
@@ -631,7 +1253,8 @@ func (d *Daemon) mergeClaudeConfig(ruleCfg config.ClaudeConfig) config.ClaudeCon
 	if len(result.MCPConfig) == 0 {
 		result.MCPConfig = defaults.MCPConfig
 	}
-	// FR-2: Merge string fields
+	// FR-2: Merge string fields. The loader has already read any
+	// system_prompt_file into SystemPrompt, so inline > file > default.
 	if result.SystemPrompt == "" {
 		result.SystemPrompt = defaults.SystemPrompt
 	}
@@ -642,21 +1265,33 @@ func (d *Daemon) mergeClaudeConfig(ruleCfg config.ClaudeConfig) config.ClaudeCon
 	return result
 }
 
-func (d *Daemon) handleFailure(ctx context.Context, rule *config.Rule, event trigger.Event, err error) {
+// retryDelayUnit is the unit of on_failure.retry_delay_seconds; tests shorten it.
+var retryDelayUnit = time.Second
+
+// handleFailure retries a failed execution per the rule's on_failure policy.
+// Each attempt is recorded in history with its attempt number, linked to the
+// original execution execID. output is the failed run's output, matched with
+// err against on_failure.retry_if. It reports whether a retry succeeded.
+func (d *Daemon) handleFailure(ctx context.Context, rule *config.Rule, event trigger.Event, execID int64, err error, output string) bool {
 	logger := logging.WithRule(d.logger, rule.Name)
 
 	if !rule.OnFailure.Retry {
 		logger.Error("rule failed, no retry configured", "error", err)
-		return
+		d.checkCircuit(ctx, rule)
+		return false
+	}
+	if !retryable(rule, err, output) {
+		d.giveUpNotRetryable(ctx, rule, err, 0)
+		return false
 	}
 
 	maxAttempts := rule.OnFailure.RetryAttempts
 	if maxAttempts <= 0 {
 		maxAttempts = 3
 	}
-	delay := time.Duration(rule.OnFailure.RetryDelaySeconds) * time.Second
+	delay := time.Duration(rule.OnFailure.RetryDelaySeconds) * retryDelayUnit
 	if delay <= 0 {
-		delay = 30 * time.Second
+		delay = 30 * retryDelayUnit
 	}
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
@@ -667,31 +1302,48 @@ func (d *Daemon) handleFailure(ctx context.Context, rule *config.Rule, event tri
 			"previous_error", err,
 		)
 
-		// Wait before retry, respecting context cancellation
+		// Wait before retry, respecting context cancellation and shutdown
 		select {
 		case <-time.After(delay):
 		case <-ctx.Done():
 			logger.Info("retry cancelled (shutdown)", "attempt", attempt)
-			return
+			return false
+		case <-d.draining:
+			logger.Info("retry abandoned (shutdown)", "attempt", attempt)
+			return false
 		}
 
 		// Re-execute the rule
-		result, execErr := d.executeRule(ctx, rule, event)
+		startedAt := time.Now()
+		result, execErr := d.executeRule(ctx, rule, event, attempt)
 		if execErr != nil {
+			d.recordRetry(rule, event, execID, attempt, "failure", startedAt, "", "", execErr.Error(), nil, nil)
 			err = execErr
+			if !retryable(rule, err, "") {
+				d.giveUpNotRetryable(ctx, rule, err, attempt)
+				return false
+			}
 			continue
 		}
+		parsed := d.parseResult(rule, result)
+		d.recordRetry(rule, event, execID, attempt, result.State, startedAt,
+			security.ScrubOutput(result.Output), security.ScrubOutput(result.Stderr), result.Error, result, parsed)
 		if result.State == "success" {
 			logger.Info("retry succeeded", "attempt", attempt)
 			d.recordExecutionState(rule.Name, "success")
-			d.fireTriggeredRules(ctx, rule, event, result.Output)
-			return
+			d.notifySuccess(rule, event, execID, attempt, result.Output)
+			d.fireTriggeredRules(ctx, rule, event, result.Output, parsed)
+			return true
 		}
 		if result.State == "cancelled" {
 			logger.Info("retry cancelled (shutdown)", "attempt", attempt)
-			return
+			return false
 		}
 		err = fmt.Errorf("execution failed: %s", result.Error)
+		if !retryable(rule, err, failureOutput(result)) {
+			d.giveUpNotRetryable(ctx, rule, err, attempt)
+			return false
+		}
 	}
 
 	logger.Error("rule failed after all retries",
@@ -699,6 +1351,39 @@ func (d *Daemon) handleFailure(ctx context.Context, rule *config.Rule, event tri
 		"last_error", err,
 	)
 	d.recordExecutionState(rule.Name, "failure")
+	d.checkCircuit(ctx, rule)
+	return false
+}
+
+// retryable reports whether a failure with err and output should be retried:
+// always, unless on_failure.retry_if is set and none of its patterns match.
+func retryable(rule *config.Rule, err error, output string) bool {
+	if len(rule.OnFailure.RetryIf) == 0 {
+		return true
+	}
+	for _, re := range rule.OnFailure.RetryIfPatterns {
+		if (err != nil && re.MatchString(err.Error())) || re.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// failureOutput is the output of a failed run that retry_if is matched
+// against: stdout and stderr.
+func failureOutput(result *executor.Result) string {
+	return result.Output + "\n" + result.Stderr
+}
+
+// giveUpNotRetryable ends retries for a failure that on_failure.retry_if
+// doesn't match, after attempts retries.
+func (d *Daemon) giveUpNotRetryable(ctx context.Context, rule *config.Rule, err error, attempts int) {
+	logging.WithRule(d.logger, rule.Name).Error("rule failed with an error not matching retry_if, not retrying",
+		"attempts", attempts,
+		"error", err,
+	)
+	d.recordExecutionState(rule.Name, "failure")
+	d.checkCircuit(ctx, rule)
 }
 
 // recordExecutionState tracks the last execution state for a rule.
@@ -706,32 +1391,120 @@ func (d *Daemon) recordExecutionState(ruleName, state string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.lastRunState[ruleName] = state
+	if state == "success" {
+		d.lastSuccess[ruleName] = time.Now()
+	}
 }
 
 // FR-5: recordExecution stores an execution record in the state DB.
 // Sourced from convention — cleaner parameter list without separate finishedAt.
-func (d *Daemon) recordExecution(rule *config.Rule, event trigger.Event, resultState string, startedAt time.Time, output, errMsg string) {
+// It returns the new record's ID, or 0 if nothing was recorded.
+func (d *Daemon) recordExecution(rule *config.Rule, event trigger.Event, resultState string, startedAt time.Time, output, errMsg string) int64 {
+	if d.stateDB == nil {
+		return 0
+	}
+	return d.saveRecord(d.newExecutionRecord(rule, event, resultState, startedAt, output, errMsg))
+}
+
+// recordResult stores a finished execution, including its stderr, the file
+// operations a dry run planned and any parsed output. output is the scrubbed
+// result output.
+func (d *Daemon) recordResult(rule *config.Rule, event trigger.Event, result *executor.Result, startedAt time.Time, output string, parsed map[string]any) int64 {
+	if d.stateDB == nil {
+		return 0
+	}
+	rec := d.newExecutionRecord(rule, event, result.State, startedAt, output, result.Error)
+	// FR-18: stderr is scrubbed and truncated like output
+	rec.Stderr = d.truncateOutput(security.ScrubOutput(result.Stderr))
+	rec.ExitCode = exitCode(result)
+	rec.ParsedOutput = d.parsedOutputJSON(parsed)
+	if len(result.PlannedOps) > 0 {
+		ops := make([]executor.PlannedOp, len(result.PlannedOps))
+		for i, op := range result.PlannedOps {
+			ops[i] = executor.PlannedOp{Op: op.Op, Path: op.Path, Command: security.ScrubOutput(op.Command)}
+		}
+		if data, err := json.Marshal(ops); err == nil {
+			rec.PlannedOps = string(data)
+		}
+	}
+	return d.saveRecord(rec)
+}
+
+// recordRetry stores retry attempt number attempt of the execution execID.
+// output and stderr are scrubbed. result is nil if the attempt couldn't run.
+func (d *Daemon) recordRetry(rule *config.Rule, event trigger.Event, execID int64, attempt int, resultState string, startedAt time.Time, output, stderr, errMsg string, result *executor.Result, parsed map[string]any) {
+	if d.stateDB == nil {
+		return
+	}
+	rec := d.newExecutionRecord(rule, event, resultState, startedAt, output, errMsg)
+	rec.Stderr = d.truncateOutput(stderr)
+	if result != nil {
+		rec.ExitCode = exitCode(result)
+	}
+	rec.ParsedOutput = d.parsedOutputJSON(parsed)
+	rec.RetryAttempt = attempt
+	rec.TriggeredByExecutionID = execID
+	d.saveRecord(rec)
+}
+
+// recordSkip stores an event that was accepted but not executed, with a reason
+// code (state.Skip*) and a human-readable explanation in the error column.
+func (d *Daemon) recordSkip(rule *config.Rule, event trigger.Event, reason, detail string) {
 	if d.stateDB == nil {
 		return
 	}
+	rec := d.newExecutionRecord(rule, event, state.StateSkipped, time.Now(), "", detail)
+	rec.SkipReason = reason
+	d.saveRecord(rec)
+}
+
+// recordDropped records an event a trigger dropped because the event channel
+// was full, as skipped with reason dropped (see state.StateDropped). It is
+// called from trigger goroutines, so the history write happens in the
+// background.
+func (d *Daemon) recordDropped(event trigger.Event) {
+	d.logger.Warn("event channel full, dropping event", "rule", event.RuleName, "trigger_type", event.Type)
+	go func() {
+		d.mu.RLock()
+		rule, ok := d.rules[event.RuleName]
+		d.mu.RUnlock()
+		if !ok {
+			rule = &config.Rule{Name: event.RuleName}
+		}
+		d.recordSkip(rule, event, state.SkipDropped, "event channel full")
+	}()
+}
 
-	// Truncate output to 10KB
-	if len(output) > 10240 {
-		output = output[:10240]
+// recordInvalidBody records a webhook request rejected by its trigger's
+// body_schema.
+func (d *Daemon) recordInvalidBody(bodyErr *trigger.InvalidBodyError) {
+	event := bodyErr.Event
+	d.logger.Warn("webhook body rejected", "rule", event.RuleName, "error", bodyErr.Err)
+	d.mu.RLock()
+	rule, ok := d.rules[event.RuleName]
+	d.mu.RUnlock()
+	if !ok {
+		rule = &config.Rule{Name: event.RuleName}
 	}
+	d.recordSkip(rule, event, state.SkipInvalidBody, bodyErr.Error())
+}
+
+// newExecutionRecord builds a history record, truncating output and event
+// data to the rule_execution limits.
+func (d *Daemon) newExecutionRecord(rule *config.Rule, event trigger.Event, resultState string, startedAt time.Time, output, errMsg string) state.ExecutionRecord {
+	output = d.truncateOutput(output)
 
-	// Serialize event data (truncate to 1KB)
 	eventData := ""
 	if event.Data != nil {
 		if data, err := json.Marshal(event.Data); err == nil {
 			eventData = string(data)
-			if len(eventData) > 1024 {
-				eventData = eventData[:1024]
+			if limit := d.ruleExecConfig().EventDataLimit(); len(eventData) > limit {
+				eventData = eventData[:limit]
 			}
 		}
 	}
 
-	rec := state.ExecutionRecord{
+	return state.ExecutionRecord{
 		RuleName:    rule.Name,
 		TriggerType: event.Type,
 		State:       resultState,
@@ -743,12 +1516,50 @@ func (d *Daemon) recordExecution(rule *config.Rule, event trigger.Event, resultS
 		Output:      output,
 		DryRun:      rule.DryRun,
 	}
+}
+
+// exitCode returns result's exit code for the history, or nil if the process
+// was killed by a signal or never started.
+func exitCode(result *executor.Result) *int {
+	if result.ExitCode < 0 {
+		return nil
+	}
+	code := result.ExitCode
+	return &code
+}
+
+// truncateOutput caps recorded output and stderr at
+// rule_execution.max_output_bytes.
+func (d *Daemon) truncateOutput(s string) string {
+	if limit := d.ruleExecConfig().OutputLimit(); len(s) > limit {
+		return s[:limit]
+	}
+	return s
+}
+
+// ruleExecConfig returns the rule_execution settings, or the zero value
+// (meaning the defaults) if the daemon has no config.
+func (d *Daemon) ruleExecConfig() config.RuleExecConfig {
+	cfg := d.config()
+	if cfg == nil {
+		return config.RuleExecConfig{}
+	}
+	return cfg.RuleExecution
+}
 
-	if _, err := d.stateDB.RecordExecution(rec); err != nil {
+// saveRecord writes rec to the state DB, publishes it to stream clients and
+// returns its ID (0 on failure).
+func (d *Daemon) saveRecord(rec state.ExecutionRecord) int64 {
+	id, err := d.stateDB.RecordExecution(rec)
+	if err != nil {
 		if d.logger != nil {
-			d.logger.Warn("failed to record execution", "rule", rule.Name, "error", err)
+			d.logger.Warn("failed to record execution", "rule", rec.RuleName, "error", err)
 		}
+		return 0
 	}
+	rec.ID = id
+	d.publishRecord(rec)
+	return id
 }
 
 // FR-5: initLastRunStateFromDB populates lastRunState from the state DB on startup.
@@ -762,7 +1573,7 @@ func (d *Daemon) initLastRunStateFromDB() {
 	defer d.mu.Unlock()
 
 	// Get recent history to populate lastRunState
-	records, err := d.stateDB.GetHistory("", "", 100)
+	records, err := d.stateDB.GetHistory("", "", nil, 100)
 	if err != nil {
 		if d.logger != nil {
 			d.logger.Warn("could not load state from DB", "error", err)
@@ -770,106 +1581,228 @@ func (d *Daemon) initLastRunStateFromDB() {
 		return
 	}
 
-	// Records are ordered newest-first; only keep the first (most recent) per rule
+	// Records are ordered newest-first; only keep the first (most recent) per rule.
+	// Skipped records never executed, so they don't count as a last state.
 	for _, rec := range records {
+		if rec.State == state.StateSkipped || rec.State == state.StateCircuitOpen {
+			continue
+		}
 		if _, ok := d.lastRunState[rec.RuleName]; !ok {
 			d.lastRunState[rec.RuleName] = rec.State
 		}
 	}
+
+	// Success times come from the whole history: a dependency's last success
+	// may be older than the records above.
+	last, err := d.stateDB.LastSuccesses()
+	if err != nil {
+		if d.logger != nil {
+			d.logger.Warn("could not load last success times from DB", "error", err)
+		}
+		return
+	}
+	for name, at := range last {
+		if at.After(d.lastSuccess[name]) {
+			d.lastSuccess[name] = at
+		}
+	}
 }
 
-// checkDependencies checks if all depends_on_rules have completed successfully.
-// Returns true if there are no dependencies or all dependencies have succeeded.
-func (d *Daemon) checkDependencies(rule *config.Rule) bool {
+// checkDependencies reports whether rule's depends_on_rules are satisfied at
+// now: every entry (depends_on_mode all, the default) or at least one (any)
+// must have last succeeded, within depends_on_max_age when that is set. When
+// they are not, reason is the skip reason and detail says which failed.
+func (d *Daemon) checkDependencies(rule *config.Rule, now time.Time) (ok bool, reason, detail string) {
 	if len(rule.DependsOn) == 0 {
-		return true
+		return true, "", "no dependencies"
 	}
+	var maxAge time.Duration
+	if rule.DependsOnMaxAge != "" {
+		maxAge, _ = time.ParseDuration(rule.DependsOnMaxAge) // validated by ValidateRule
+	}
+	anyMode := rule.DependsOnMode == config.DependsOnAny
 
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
+	var unmet []string
+	reason = state.SkipDependency
 	for _, dep := range rule.DependsOn {
-		state, ok := d.lastRunState[dep]
-		if !ok {
-			return false // dependency hasn't run yet
+		depReason, depDetail := d.unmetDependency(dep, maxAge, now)
+		if depReason == "" {
+			if anyMode {
+				return true, "", fmt.Sprintf("dependency %q succeeded (depends_on_mode any)", dep)
+			}
+			continue
 		}
-		if state != "success" {
-			return false // dependency didn't succeed
+		if !anyMode {
+			return false, depReason, depDetail
 		}
+		// Stale only when every dependency that succeeded is too old
+		if depReason == state.SkipDependencyStale {
+			reason = state.SkipDependencyStale
+		}
+		unmet = append(unmet, depDetail)
 	}
-	return true
+	if anyMode {
+		return false, reason, "no dependency succeeded: " + strings.Join(unmet, "; ")
+	}
+
+	detail = "all dependencies succeeded: " + strings.Join(rule.DependsOn, ", ")
+	if maxAge > 0 {
+		detail += " (within " + rule.DependsOnMaxAge + ")"
+	}
+	return true, "", detail
+}
+
+// unmetDependency checks one depends_on_rules entry, returning an empty
+// reason when it last succeeded (within maxAge, if positive). Callers must
+// hold d.mu.
+func (d *Daemon) unmetDependency(dep string, maxAge time.Duration, now time.Time) (reason, detail string) {
+	switch st, ok := d.lastRunState[dep]; {
+	case !ok:
+		return state.SkipDependency, fmt.Sprintf("dependency %q has not run yet", dep)
+	case st != "success":
+		return state.SkipDependency, fmt.Sprintf("dependency %q last finished with %s", dep, st)
+	}
+	if maxAge <= 0 {
+		return "", ""
+	}
+	last, ok := d.lastSuccess[dep]
+	switch {
+	case !ok:
+		return state.SkipDependencyStale, fmt.Sprintf("dependency %q has no recorded success time", dep)
+	case now.Sub(last) > maxAge:
+		return state.SkipDependencyStale, fmt.Sprintf("dependency %q last succeeded %s ago, more than depends_on_max_age %s",
+			dep, now.Sub(last).Truncate(time.Second), maxAge)
+	}
+	return "", ""
 }
 
 // FR-13: fireTriggeredRules fires triggered rules based on output content.
-// If output contains TRIGGER:<rule-name> markers, only those specific rules fire.
-// If no markers are found, all triggers_rules fire (backward compatible).
-// Both implementations have the same logic.
-func (d *Daemon) fireTriggeredRules(ctx context.Context, rule *config.Rule, event trigger.Event, output string) {
+// If output contains TRIGGER:<rule-name> markers, only those specific rules fire;
+// a TRIGGER:<rule-name>{json} marker also merges the object into the child's
+// event data. If no markers are found, all triggers_rules fire (backward
+// compatible). Targets that are not loaded or are disabled are warned about
+// and not fired. parsed is the output_parser's data, passed to every target.
+func (d *Daemon) fireTriggeredRules(ctx context.Context, rule *config.Rule, event trigger.Event, output string, parsed map[string]any) {
 	if len(rule.Triggers) == 0 {
 		return
 	}
 
 	logger := logging.WithRule(d.logger, rule.Name)
 
-	// FR-13: Parse output for TRIGGER: markers
-	triggered := parseTriggeredRules(output)
+	targets := rule.Triggers
+	var payloads map[string]map[string]any
 
-	if len(triggered) > 0 {
+	// FR-13: Parse output for TRIGGER: markers
+	if markers := parseTriggeredRules(output, d.config().Daemon.TriggerMarker); len(markers) > 0 {
 		// Only fire rules that appear in both triggers_rules and TRIGGER: markers
-		triggerSet := make(map[string]bool)
-		for _, name := range triggered {
-			triggerSet[name] = true
+		payloads = make(map[string]map[string]any)
+		for _, m := range markers {
+			switch {
+			case !slices.Contains(rule.Triggers, m.Name):
+				logger.Warn("TRIGGER: marker names a rule not in triggers_rules, ignoring", "marker", m.Name)
+			case m.Err != nil:
+				logger.Warn("TRIGGER: marker has an invalid JSON payload, ignoring", "marker", m.Name, "error", m.Err)
+			default:
+				if _, dup := payloads[m.Name]; dup {
+					logger.Warn("duplicate TRIGGER: marker, using the first", "marker", m.Name)
+					continue
+				}
+				payloads[m.Name] = m.Data
+			}
 		}
 
+		targets = nil
 		for _, triggerName := range rule.Triggers {
-			if triggerSet[triggerName] {
-				logger.Info("conditional trigger fired", "triggered_rule", triggerName)
-				select {
-				case d.events <- trigger.Event{
-					RuleName:  triggerName,
-					Type:      "triggered",
-					Timestamp: time.Now(),
-					Data:      event.Data,
-				}:
-				default:
-					logger.Warn("event channel full, dropping triggered rule", "rule", triggerName)
-				}
+			if _, ok := payloads[triggerName]; ok {
+				targets = append(targets, triggerName)
 			} else {
 				logger.Debug("conditional trigger suppressed", "triggered_rule", triggerName)
 			}
 		}
-	} else {
-		// No markers: fire all triggers_rules (backward compatible)
-		for _, triggerName := range rule.Triggers {
-			select {
-			case d.events <- trigger.Event{
-				RuleName:  triggerName,
-				Type:      "triggered",
-				Timestamp: time.Now(),
-				Data:      event.Data,
-			}:
-			default:
-				logger.Warn("event channel full, dropping triggered rule", "rule", triggerName)
-			}
-		}
 	}
+
+	for _, triggerName := range targets {
+		d.fireTriggered(logger, rule, event, triggerName, parsed, payloads[triggerName])
+	}
+}
+
+// fireTriggered queues a triggered event for ruleName carrying the parent's
+// event data, overlaid with its parsed output and then the marker's payload,
+// if any. Disabled targets and dropped events are recorded as skipped.
+func (d *Daemon) fireTriggered(logger *slog.Logger, parent *config.Rule, event trigger.Event, ruleName string, parsed, payload map[string]any) {
+	d.mu.RLock()
+	target, ok := d.rules[ruleName]
+	d.mu.RUnlock()
+
+	data := event.Data
+	if len(parsed) > 0 || len(payload) > 0 {
+		data = make(map[string]any, len(event.Data)+len(parsed)+len(payload))
+		maps.Copy(data, event.Data)
+		maps.Copy(data, parsed)
+		maps.Copy(data, payload)
+	}
+	child := trigger.Event{
+		RuleName:  ruleName,
+		Type:      "triggered",
+		Timestamp: time.Now(),
+		Data:      data,
+	}
+
+	switch {
+	case !ok:
+		logger.Warn("triggered rule not found, not firing", "triggered_rule", ruleName)
+		return
+	case !target.Enabled:
+		logger.Warn("triggered rule is disabled, not firing", "triggered_rule", ruleName)
+		d.recordSkip(target, child, state.SkipDisabled, "rule is disabled; triggered by "+parent.Name)
+		return
+	}
+
+	logger.Info("conditional trigger fired", "triggered_rule", ruleName, "payload_keys", len(payload))
+	select {
+	case d.events <- child:
+	default:
+		logger.Warn("event channel full, dropping triggered rule", "rule", ruleName)
+		d.recordSkip(target, child, state.SkipDropped, "event channel full")
+	}
+}
+
+// triggerMarkerLine is one parsed TRIGGER: line. Err is set when the line
+// carries a payload that isn't a JSON object.
+type triggerMarkerLine struct {
+	Name string
+	Data map[string]any
+	Err  error
 }
 
-// FR-13: parseTriggeredRules scans output for TRIGGER:<rule-name> markers.
-func parseTriggeredRules(output string) []string {
-	if output == "" {
+// FR-13: parseTriggeredRules scans output for lines of the form
+// <marker><rule-name> or <marker><rule-name>{json}.
+func parseTriggeredRules(output, marker string) []triggerMarkerLine {
+	if output == "" || marker == "" {
 		return nil
 	}
 
-	var triggered []string
+	var triggered []triggerMarkerLine
 	for _, line := range strings.Split(output, "\n") {
 		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "TRIGGER:") {
-			name := strings.TrimSpace(strings.TrimPrefix(line, "TRIGGER:"))
-			if name != "" {
-				triggered = append(triggered, name)
+		if !strings.HasPrefix(line, marker) {
+			continue
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line, marker))
+		name, payload, hasPayload := strings.Cut(rest, "{")
+		m := triggerMarkerLine{Name: strings.TrimSpace(name)}
+		if m.Name == "" {
+			continue
+		}
+		if hasPayload {
+			if err := json.Unmarshal([]byte("{"+payload), &m.Data); err != nil {
+				m.Err = err
 			}
 		}
+		triggered = append(triggered, m)
 	}
 	return triggered
 }
@@ -890,19 +1823,52 @@ func (d *Daemon) startHotReload(ctx context.Context) {
 	}
 
 	d.logger.Info("hot-reload watcher started", "dir", d.rulesDir)
+	d.watchRules(ctx, watcher)
+}
+
+// rulesDirPollInterval is how often a removed rules directory is checked for;
+// tests shorten it.
+var rulesDirPollInterval = 5 * time.Second
 
+// watchRules reloads rules on changes reported by watcher, which watches the
+// rules directory. If the directory itself is removed or renamed, all rules
+// are unloaded until it reappears, when the watch is re-established and the
+// rules reloaded.
+func (d *Daemon) watchRules(ctx context.Context, watcher *fsnotify.Watcher) {
 	// Debounce: wait 1 second after last event before reloading
 	var debounceTimer *time.Timer
 	debounceCh := make(chan struct{}, 1)
 
+	// Set while the rules directory is missing
+	var poll *time.Ticker
+	var pollC <-chan time.Time
+	defer func() {
+		if poll != nil {
+			poll.Stop()
+		}
+	}()
+
 	for {
 		select {
 		case event, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
-			ext := filepath.Ext(event.Name)
-			if ext != ".yaml" && ext != ".yml" {
+			if filepath.Clean(event.Name) == filepath.Clean(d.rulesDir) && event.Has(fsnotify.Remove|fsnotify.Rename) {
+				if pollC != nil {
+					continue
+				}
+				d.logger.Error("rules directory removed, stopping all triggers until it reappears", "dir", d.rulesDir)
+				watcher.Remove(d.rulesDir) // already gone on most platforms
+				if debounceTimer != nil {
+					debounceTimer.Stop()
+				}
+				d.unloadRules()
+				poll = time.NewTicker(rulesDirPollInterval)
+				pollC = poll.C
+				continue
+			}
+			if !config.IsRuleFile(event.Name) {
 				continue
 			}
 
@@ -918,9 +1884,25 @@ func (d *Daemon) startHotReload(ctx context.Context) {
 			})
 
 		case <-debounceCh:
+			if pollC != nil {
+				continue
+			}
 			d.logger.Info("reloading rules (hot-reload)")
 			d.reloadRules(ctx)
 
+		case <-pollC:
+			if info, err := os.Stat(d.rulesDir); err != nil || !info.IsDir() {
+				continue
+			}
+			if err := watcher.Add(d.rulesDir); err != nil {
+				d.logger.Warn("rules directory reappeared but could not be watched, retrying", "error", err, "dir", d.rulesDir)
+				continue
+			}
+			poll.Stop()
+			poll, pollC = nil, nil
+			d.logger.Info("rules directory restored, reloading rules", "dir", d.rulesDir)
+			d.reloadRules(ctx)
+
 		case err, ok := <-watcher.Errors:
 			if !ok {
 				return
@@ -933,6 +1915,29 @@ func (d *Daemon) startHotReload(ctx context.Context) {
 	}
 }
 
+// unloadRules stops every trigger and forgets all rules, as when the rules
+// directory is removed.
+func (d *Daemon) unloadRules() {
+	d.mu.Lock()
+	names := make([]string, 0, len(d.rules))
+	for name := range d.rules {
+		names = append(names, name)
+	}
+	for name, t := range d.triggers {
+		t.Stop()
+		delete(d.triggers, name)
+	}
+	clear(d.webhooks)
+	clear(d.rules)
+	d.mu.Unlock()
+
+	sort.Strings(names)
+	d.logger.Warn("all rules unloaded", "removed", names)
+	if len(names) > 0 {
+		d.audit(auditRulesReloaded, "hot-reload", "changes", len(names))
+	}
+}
+
 // reloadRules re-validates and reloads rules from the rules directory.
 // Sourced from convention — includes change detection and FR-15 re-validation.
 func (d *Daemon) reloadRules(ctx context.Context) {
@@ -949,26 +1954,20 @@ func (d *Daemon) reloadRules(ctx context.Context) {
 	}
 
 	newRules := make(map[string]*config.Rule)
+	var rejected []string
 	for _, rule := range rules {
 		// FR-15: Validate run_as_user against allowlist during reload too
-		if rule.RunAsUser != "" && len(d.config.Daemon.AllowedRunAsUsers) > 0 {
-			allowed := false
-			for _, u := range d.config.Daemon.AllowedRunAsUsers {
-				if u == rule.RunAsUser {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				d.logger.Error("rule run_as_user not in allowlist, skipping",
-					"rule", rule.Name, "run_as_user", rule.RunAsUser)
-				continue
-			}
+		if !d.runAsUserAllowed(rule) {
+			d.logger.Error("rule run_as_user not in allowlist, skipping",
+				"rule", rule.Name, "run_as_user", rule.RunAsUser)
+			rejected = append(rejected, rule.Name)
+			continue
 		}
 		newRules[rule.Name] = rule
 	}
 
 	d.mu.Lock()
+	changes := ruleChanges(d.rules, newRules)
 	// Stop triggers for removed rules
 	for name, t := range d.triggers {
 		if _, exists := newRules[name]; !exists {
@@ -982,6 +1981,10 @@ func (d *Daemon) reloadRules(ctx context.Context) {
 	// Add/update rules — with change detection from convention
 	for name, rule := range newRules {
 		oldRule, existed := d.rules[name]
+		if existed && oldRule != nil && rule.Hash != "" && oldRule.Hash == rule.Hash {
+			// Unchanged: keep the loaded rule and its trigger as they are
+			continue
+		}
 		d.rules[name] = rule
 
 		if !rule.Enabled {
@@ -1009,31 +2012,33 @@ func (d *Daemon) reloadRules(ctx context.Context) {
 				t.Stop()
 				delete(d.triggers, name)
 			}
+			// A changed trigger re-arms an open circuit breaker
+			delete(d.circuitOpen, name)
 
 			// Create and start new trigger
-			t, err := trigger.New(rule.Name, rule.Trigger, rule.RunAsUser)
-			if err != nil {
+			if err := d.startTrigger(ctx, rule); err != nil {
 				d.logger.Error("failed to create trigger during reload", "rule", rule.Name, "error", err)
 				continue
 			}
-			d.triggers[name] = t
-
-			if wh, ok := t.(*trigger.Webhook); ok {
-				d.webhooks[wh.ListenPath()] = wh
-			}
-
-			go func(t trigger.Trigger) {
-				if err := t.Start(ctx, d.events); err != nil && err != context.Canceled {
-					d.logger.Error("trigger error after reload", "rule", t.RuleName(), "error", err)
-				}
-			}(t)
 
 			d.logger.Info("reloaded trigger", "rule", name)
 		}
 	}
 	d.mu.Unlock()
 
-	d.logger.Info("rules reloaded", "rules_loaded", len(newRules))
+	for _, c := range changes {
+		d.logger.Info("rule changed on reload", "rule", c.Rule, "change", c.Change, "detail", c.Detail)
+	}
+	if len(changes)+len(rejected) == 0 {
+		d.logger.Info("rules reload was a no-op", "rules_loaded", len(newRules))
+	} else {
+		d.logger.Info("rules reloaded", "rules_loaded", len(newRules), "changes", len(changes))
+	}
+	// What changed is in the log above; the audit log records that the
+	// rules changed, and any rule the allowlist kept out
+	if len(changes)+len(rejected) > 0 {
+		d.audit(auditRulesReloaded, "hot-reload", "changes", len(changes), "rejected", rejected)
+	}
 }
 
 // sliceEqual compares two string slices for equality.
@@ -1051,6 +2056,8 @@ func sliceEqual(a, b []string) bool {
 }
 
 func (d *Daemon) shutdown() error {
+	d.waitNotifications()
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -1063,6 +2070,13 @@ func (d *Daemon) shutdown() error {
 		d.stateDB.Close()
 	}
 
+	if d.memoryServer != nil {
+		d.memoryServer.Close()
+	}
+
+	d.audit(auditDaemonStopped, "daemon")
+	d.closeAuditLog()
+
 	return nil
 }
 
@@ -1073,19 +2087,75 @@ func (d *Daemon) isMemoryEnabled(rule *config.Rule) bool {
 		return *rule.Claude.Memory
 	}
 	// Fall back to global config
-	return d.config.Memory.Enabled
+	return d.config().Memory.Enabled
+}
+
+// RunRule manually runs a specific rule (for CLI use). A non-empty
+// eventType runs a lifecycle rule as if that event fired; see runEvent.
+// noDeps runs it even if its depends_on_rules haven't succeeded.
+func (d *Daemon) RunRule(ctx context.Context, ruleName, eventType string, data map[string]any, noDeps bool) error {
+	if err := d.initManualRun(); err != nil {
+		return err
+	}
+	defer d.closeAuditLog()
+
+	rule, ok := d.rules[ruleName]
+	if !ok {
+		return fmt.Errorf("rule not found: %s", ruleName)
+	}
+
+	event, err := runEvent(rule, eventType, data)
+	if err != nil {
+		return err
+	}
+	event.NoDeps = noDeps
+	d.handleEvent(ctx, event)
+	d.waitNotifications()
+	return nil
+}
+
+// runEvent builds the event for a manual run of rule. With an eventType it
+// simulates that lifecycle event instead, so a rule can be tested with the
+// event_type it sees when fired: the rule must have a lifecycle trigger with
+// eventType in its on_events, and the event is otherwise handled like the
+// real one (a disabled rule is skipped, for example).
+func runEvent(rule *config.Rule, eventType string, data map[string]any) (trigger.Event, error) {
+	event := trigger.Event{
+		RuleName:  rule.Name,
+		Type:      "manual",
+		Timestamp: time.Now(),
+		Data:      data,
+	}
+	if eventType == "" {
+		return event, nil
+	}
+
+	if rule.Trigger.Type != "lifecycle" {
+		return trigger.Event{}, fmt.Errorf("can't simulate event %q: rule %s has a %s trigger, not lifecycle", eventType, rule.Name, rule.Trigger.Type)
+	}
+	if !slices.Contains(rule.Trigger.OnEvents, eventType) {
+		return trigger.Event{}, fmt.Errorf("rule %s doesn't fire on %q: its on_events are %s", rule.Name, eventType, strings.Join(rule.Trigger.OnEvents, ", "))
+	}
+	event.Type = eventType
+	event.Data = maps.Clone(data)
+	if event.Data == nil {
+		event.Data = map[string]any{}
+	}
+	event.Data["event_type"] = eventType
+	return event, nil
 }
 
-// RunRule manually runs a specific rule (for CLI use)
-func (d *Daemon) RunRule(ctx context.Context, ruleName string, data map[string]any) error {
+// initManualRun loads config and rules for running rules from the
+// CLI. Callers close the audit log it opens.
+func (d *Daemon) initManualRun() error {
 	if err := d.loadConfig(); err != nil {
 		return err
 	}
 
-	d.logger = logging.NewLogger(d.config.Logging.Format, d.config.Daemon.LogLevel, os.Stdout)
+	d.logger = logging.NewLogger(d.config().Logging.Format, d.config().Daemon.LogLevel, os.Stdout)
 
 	// Set daemon path for memory MCP injection
-	if d.config.Memory.Enabled {
+	if d.config().Memory.Enabled {
 		if daemonPath, err := os.Executable(); err == nil {
 			d.daemonPath = daemonPath
 		}
@@ -1095,22 +2165,120 @@ func (d *Daemon) RunRule(ctx context.Context, ruleName string, data map[string]a
 		return err
 	}
 
-	_, ok := d.rules[ruleName]
+	// Manual runs are audited under the invoking user
+	d.cliUser = invokingUser()
+	if err := d.initAuditLog(false); err != nil {
+		d.logger.Warn("failed to open audit log, this run will not be audited", "error", err)
+	}
+	return nil
+}
+
+// ExplainRule reports every pre-execution gate for a manual run of ruleName
+// without executing it. It loads config and rules the same way RunRule does, so the decision matches what `srvrmgr run` would do with the
+// same eventType.
+func (d *Daemon) ExplainRule(ruleName, eventType string, data map[string]any) ([]Gate, error) {
+	if err := d.loadConfig(); err != nil {
+		return nil, err
+	}
+
+	// Only errors: explain output should not be interleaved with info logs
+	d.logger = logging.NewLogger(d.config().Logging.Format, "error", os.Stderr)
+
+	if err := d.loadRules(); err != nil {
+		return nil, err
+	}
+
+	rule, ok := d.rules[ruleName]
 	if !ok {
-		return fmt.Errorf("rule not found: %s", ruleName)
+		// loadRules drops rules that fail the allowlist; find it so the gate can say so
+		all, err := config.LoadRulesDir(d.rulesDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range all {
+			if r.Name == ruleName {
+				rule = r
+			}
+		}
+		if rule == nil {
+			return nil, fmt.Errorf("rule not found: %s", ruleName)
+		}
 	}
 
-	event := trigger.Event{
-		RuleName:  ruleName,
-		Type:      "manual",
-		Timestamp: time.Now(),
-		Data:      data,
+	event, err := runEvent(rule, eventType, data)
+	if err != nil {
+		return nil, err
+	}
+	injectEventDefaults(&event)
+
+	return d.checkGates(rule, event, true), nil
+}
+
+// GetExecution looks up a recorded execution in the state database, opening
+// the daemon's database when called outside the daemon process.
+func (d *Daemon) GetExecution(id int64) (*state.ExecutionRecord, error) {
+	db := d.stateDB
+	if db == nil {
+		var err error
+		if db, err = state.Open(d.stateDBPath); err != nil {
+			return nil, fmt.Errorf("opening state database: %w", err)
+		}
+		defer db.Close()
+	}
+	rec, err := db.GetExecution(id)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, fmt.Errorf("execution %d not found", id)
+	}
+	return rec, nil
+}
+
+// Replay re-runs a recorded execution's rule, as a manual run from the CLI
+// would, but with the trigger type and event data stored for it. The rule's
+// side effects happen again.
+func (d *Daemon) Replay(ctx context.Context, rec *state.ExecutionRecord) error {
+	event, err := replayEvent(rec)
+	if err != nil {
+		return err
+	}
+	if err := d.initManualRun(); err != nil {
+		return err
 	}
+	defer d.closeAuditLog()
 
+	if _, ok := d.rules[rec.RuleName]; !ok {
+		return fmt.Errorf("rule not found: %s", rec.RuleName)
+	}
 	d.handleEvent(ctx, event)
+	d.waitNotifications()
 	return nil
 }
 
+// replayEvent reconstructs the event of a recorded execution. The stored data
+// already holds the original event_type and timestamp (FR-1), so templates see
+// the same values as the original run.
+func replayEvent(rec *state.ExecutionRecord) (trigger.Event, error) {
+	event := trigger.Event{
+		RuleName:  rec.RuleName,
+		Type:      rec.TriggerType,
+		Timestamp: rec.StartedAt,
+		Data:      map[string]any{},
+	}
+	if rec.EventData != "" {
+		if err := json.NewDecoder(strings.NewReader(rec.EventData)).Decode(&event.Data); err != nil {
+			// Event data over rule_execution.max_event_data_bytes is cut when
+			// recorded, which leaves JSON that ends early
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return event, fmt.Errorf("execution %d can't be replayed: its event data was truncated to %d bytes when recorded (see rule_execution.max_event_data_bytes)", rec.ID, len(rec.EventData))
+			}
+			return event, fmt.Errorf("decoding event data of execution %d: %w", rec.ID, err)
+		}
+	}
+	return event, nil
+}
+
 // FR-12: expandHomeForUser resolves ~ using the specified user's home directory.
 // Falls back to os.UserHomeDir() if username is empty or lookup fails.
 func expandHomeForUser(path, username string) string {
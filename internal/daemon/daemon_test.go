@@ -2,11 +2,28 @@
 package daemon
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/embedder"
+	"github.com/colebrumley/srvrmgr/internal/executor"
+	"github.com/colebrumley/srvrmgr/internal/mcp"
+	"github.com/colebrumley/srvrmgr/internal/state"
 	"github.com/colebrumley/srvrmgr/internal/trigger"
 )
 
@@ -82,6 +99,45 @@ func TestHandleEvent_InjectsTimestampForLifecycle(t *testing.T) {
 	}
 }
 
+func TestRunEvent_SimulatesLifecycleEvent(t *testing.T) {
+	rule := scriptRule("on-start", "true")
+	rule.Trigger = config.Trigger{Type: "lifecycle", OnEvents: []string{"daemon_started"}}
+	d := newTestDaemon(t, rule)
+	fake := &fakeExecutor{}
+	d.SetExecutor(fake)
+
+	data := map[string]any{"note": "from the CLI"}
+	event, err := runEvent(rule, "daemon_started", data)
+	if err != nil {
+		t.Fatalf("runEvent() error = %v", err)
+	}
+	if _, ok := data["event_type"]; ok {
+		t.Error("runEvent() modified the caller's data")
+	}
+	d.handleEvent(context.Background(), event)
+
+	if len(fake.calls) != 1 {
+		t.Fatalf("executor called %d times, want 1", len(fake.calls))
+	}
+	got := fake.calls[0].Event
+	if got.Type != "daemon_started" || got.Data["event_type"] != "daemon_started" {
+		t.Errorf("event type = %q, event_type = %v; want daemon_started", got.Type, got.Data["event_type"])
+	}
+	if got.Data["note"] != "from the CLI" {
+		t.Errorf("event data = %v, want the caller's data kept", got.Data)
+	}
+
+	if event, err := runEvent(rule, "", nil); err != nil || event.Type != "manual" {
+		t.Errorf("runEvent() without an event = %q, %v; want manual", event.Type, err)
+	}
+	if _, err := runEvent(rule, "daemon_stopped", nil); err == nil || !strings.Contains(err.Error(), "on_events") {
+		t.Errorf("runEvent(event not in on_events) error = %v, want on_events error", err)
+	}
+	if _, err := runEvent(scriptRule("by-hand", "true"), "daemon_started", nil); err == nil || !strings.Contains(err.Error(), "not lifecycle") {
+		t.Errorf("runEvent(non-lifecycle rule) error = %v, want trigger type error", err)
+	}
+}
+
 func TestHandleEvent_DoesNotOverrideExistingTimestamp(t *testing.T) {
 	// FR-1: Scheduled triggers already set timestamp in Data.
 	existingTS := "2026-02-16T12:00:00Z"
@@ -106,21 +162,20 @@ func TestHandleEvent_DoesNotOverrideExistingTimestamp(t *testing.T) {
 // ===== FR-2: mergeClaudeConfig merges all 9 fields =====
 
 func TestMergeClaudeConfig_MergesAllFields(t *testing.T) {
-	d := &Daemon{
-		config: &config.Global{
-			ClaudeDefaults: config.ClaudeConfig{
-				Model:              "sonnet",
-				PermissionMode:     "default",
-				MaxBudgetUSD:       1.0,
-				AllowedTools:       []string{"Bash", "Read"},
-				DisallowedTools:    []string{"WebFetch"},
-				AddDirs:            []string{"/default/dir"},
-				SystemPrompt:       "Default system prompt",
-				AppendSystemPrompt: "Default append prompt",
-				MCPConfig:          []string{"/default/mcp.json"},
-			},
+	d := &Daemon{}
+	d.cfg.Store(&config.Global{
+		ClaudeDefaults: config.ClaudeConfig{
+			Model:              "sonnet",
+			PermissionMode:     "default",
+			MaxBudgetUSD:       1.0,
+			AllowedTools:       []string{"Bash", "Read"},
+			DisallowedTools:    []string{"WebFetch"},
+			AddDirs:            []string{"/default/dir"},
+			SystemPrompt:       "Default system prompt",
+			AppendSystemPrompt: "Default append prompt",
+			MCPConfig:          []string{"/default/mcp.json"},
 		},
-	}
+	})
 
 	// Rule config with empty fields — should fall back to defaults
 	ruleCfg := config.ClaudeConfig{}
@@ -156,18 +211,58 @@ func TestMergeClaudeConfig_MergesAllFields(t *testing.T) {
 	}
 }
 
+func TestMergeClaudeConfig_SystemPromptFilePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"default.md":  "Default from file",
+		"rule.md":     "Rule from file",
+		"config.yaml": "claude_defaults:\n  system_prompt_file: default.md\n  append_system_prompt: Default append\n",
+		"file.yaml":   "name: file\naction:\n  prompt: go\nclaude:\n  system_prompt_file: rule.md\n",
+		"inline.yaml": "name: inline\naction:\n  prompt: go\nclaude:\n  system_prompt: Inline\n  system_prompt_file: rule.md\n",
+		"none.yaml":   "name: none\naction:\n  prompt: go\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	global, err := config.LoadGlobal(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Daemon{}
+	d.cfg.Store(global)
+
+	// inline > rule file > global default
+	for file, want := range map[string]string{
+		"inline.yaml": "Inline",
+		"file.yaml":   "Rule from file",
+		"none.yaml":   "Default from file",
+	} {
+		rule, err := config.LoadRule(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := d.mergeClaudeConfig(rule.Claude)
+		if result.SystemPrompt != want {
+			t.Errorf("%s: SystemPrompt = %q, want %q", file, result.SystemPrompt, want)
+		}
+		if result.AppendSystemPrompt != "Default append" {
+			t.Errorf("%s: AppendSystemPrompt = %q, want global default", file, result.AppendSystemPrompt)
+		}
+	}
+}
+
 func TestMergeClaudeConfig_RuleOverridesDefaults(t *testing.T) {
-	d := &Daemon{
-		config: &config.Global{
-			ClaudeDefaults: config.ClaudeConfig{
-				Model:          "sonnet",
-				PermissionMode: "default",
-				MaxBudgetUSD:   1.0,
-				AllowedTools:   []string{"Bash"},
-				SystemPrompt:   "Default prompt",
-			},
+	d := &Daemon{}
+	d.cfg.Store(&config.Global{
+		ClaudeDefaults: config.ClaudeConfig{
+			Model:          "sonnet",
+			PermissionMode: "default",
+			MaxBudgetUSD:   1.0,
+			AllowedTools:   []string{"Bash"},
+			SystemPrompt:   "Default prompt",
 		},
-	}
+	})
 
 	// Rule config with explicit values — should override defaults
 	ruleCfg := config.ClaudeConfig{
@@ -206,12 +301,12 @@ Volumes over threshold:
 - /Volumes/Media: 85% used
 TRIGGER:storage-cleanup-caches`
 
-	triggered := parseTriggeredRules(output)
+	triggered := parseTriggeredRules(output, "TRIGGER:")
 	if len(triggered) != 1 {
 		t.Fatalf("FR-13: expected 1 triggered rule, got %d", len(triggered))
 	}
-	if triggered[0] != "storage-cleanup-caches" {
-		t.Errorf("FR-13: expected 'storage-cleanup-caches', got %q", triggered[0])
+	if triggered[0].Name != "storage-cleanup-caches" {
+		t.Errorf("FR-13: expected 'storage-cleanup-caches', got %q", triggered[0].Name)
 	}
 }
 
@@ -220,7 +315,7 @@ func TestParseTriggeredRules_NoMarkers(t *testing.T) {
 	output := `Disk usage is at 45%, all volumes are normal.
 No action needed.`
 
-	triggered := parseTriggeredRules(output)
+	triggered := parseTriggeredRules(output, "TRIGGER:")
 	if len(triggered) != 0 {
 		t.Errorf("FR-13: expected 0 triggered rules for no-marker output, got %d", len(triggered))
 	}
@@ -231,19 +326,271 @@ func TestParseTriggeredRules_MultipleMarkers(t *testing.T) {
 TRIGGER:storage-cleanup-caches
 TRIGGER:server-check-services`
 
-	triggered := parseTriggeredRules(output)
+	triggered := parseTriggeredRules(output, "TRIGGER:")
 	if len(triggered) != 2 {
 		t.Fatalf("FR-13: expected 2 triggered rules, got %d", len(triggered))
 	}
 }
 
 func TestParseTriggeredRules_EmptyOutput(t *testing.T) {
-	triggered := parseTriggeredRules("")
+	triggered := parseTriggeredRules("", "TRIGGER:")
 	if len(triggered) != 0 {
 		t.Errorf("FR-13: expected 0 triggered rules for empty output, got %d", len(triggered))
 	}
 }
 
+func TestParseTriggeredRules_JSONPayload(t *testing.T) {
+	output := `Cleanup needed.
+TRIGGER: storage-cleanup-caches {"volume": "/Volumes/Media", "used_pct": 91}
+TRIGGER:server-check-services{not json}`
+
+	triggered := parseTriggeredRules(output, "TRIGGER:")
+	if len(triggered) != 2 {
+		t.Fatalf("expected 2 markers, got %+v", triggered)
+	}
+	m := triggered[0]
+	if m.Name != "storage-cleanup-caches" || m.Err != nil {
+		t.Fatalf("marker = %+v, want storage-cleanup-caches with no error", m)
+	}
+	if m.Data["volume"] != "/Volumes/Media" || m.Data["used_pct"] != float64(91) {
+		t.Errorf("payload = %v", m.Data)
+	}
+	if triggered[1].Name != "server-check-services" || triggered[1].Err == nil {
+		t.Errorf("marker = %+v, want a payload error", triggered[1])
+	}
+}
+
+func TestParseTriggeredRules_CustomMarker(t *testing.T) {
+	output := "TRIGGER:ignored\n@@fire child"
+
+	triggered := parseTriggeredRules(output, "@@fire")
+	if len(triggered) != 1 || triggered[0].Name != "child" {
+		t.Errorf("markers = %+v, want only the custom marker", triggered)
+	}
+}
+
+func TestFireTriggeredRules_PayloadMergedIntoChildEvent(t *testing.T) {
+	parent := scriptRule("parent", "true")
+	parent.Triggers = []string{"child", "other"}
+	d := newTestDaemon(t, parent, scriptRule("child", "true"), scriptRule("other", "true"))
+	d.config().Daemon.TriggerMarker = "NEXT:"
+	d.events = make(chan trigger.Event, 10)
+
+	event := trigger.Event{RuleName: "parent", Data: map[string]any{"file_path": "/tmp/a", "volume": "/"}}
+	d.fireTriggeredRules(context.Background(), parent, event, `NEXT: child {"volume": "/Volumes/Media"}`, nil)
+
+	if len(d.events) != 1 {
+		t.Fatalf("queued %d events, want 1", len(d.events))
+	}
+	child := <-d.events
+	if child.RuleName != "child" || child.Type != "triggered" {
+		t.Fatalf("child event = %+v", child)
+	}
+	if child.Data["volume"] != "/Volumes/Media" || child.Data["file_path"] != "/tmp/a" {
+		t.Errorf("child data = %v, want payload merged over parent data", child.Data)
+	}
+	if event.Data["volume"] != "/" {
+		t.Errorf("parent event data was modified: %v", event.Data)
+	}
+}
+
+func TestFireTriggeredRules_InvalidPayloadNotFired(t *testing.T) {
+	parent := scriptRule("parent", "true")
+	parent.Triggers = []string{"child", "other"}
+	d := newTestDaemon(t, parent, scriptRule("child", "true"), scriptRule("other", "true"))
+	d.events = make(chan trigger.Event, 10)
+
+	d.fireTriggeredRules(context.Background(), parent, trigger.Event{RuleName: "parent"}, "TRIGGER:child{oops", nil)
+
+	if got := drainEvents(d); len(got) != 0 {
+		t.Errorf("fired = %v, want nothing for a malformed marker", got)
+	}
+}
+
+func TestHandleEvent_TriggerMarkersOnlyFromAnswer(t *testing.T) {
+	// A fake claude emitting a marker on stderr and another in its JSON answer
+	bin := t.TempDir()
+	script := `#!/bin/sh
+echo 'TRIGGER: from-stderr' >&2
+printf '%s\n' '{"type":"result","subtype":"success","is_error":false,"result":"Disk at 91%.\nTRIGGER: from-answer"}'
+`
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	rule := &config.Rule{
+		Name:     "monitor",
+		Enabled:  true,
+		Trigger:  config.Trigger{Type: "manual"},
+		Action:   config.Action{Prompt: "check disk"},
+		Triggers: []string{"from-stderr", "from-answer"},
+	}
+	d := newTestDaemon(t, rule, scriptRule("from-stderr", "true"), scriptRule("from-answer", "true"))
+	d.events = make(chan trigger.Event, 10)
+
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "monitor", Type: "manual", Timestamp: time.Now()})
+
+	close(d.events)
+	var fired []string
+	for e := range d.events {
+		fired = append(fired, e.RuleName)
+	}
+	if len(fired) != 1 || fired[0] != "from-answer" {
+		t.Errorf("fired = %v, want only the rule named in the answer", fired)
+	}
+}
+
+func TestHandleEvent_DryRunRecordsPlannedOps(t *testing.T) {
+	bin := t.TempDir()
+	script := `#!/bin/sh
+case "$*" in *"--permission-mode plan"*) ;; *) echo "not plan mode" >&2; exit 1 ;; esac
+cat <<'EOF'
+{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Write","input":{"file_path":"/tmp/out.txt","content":"x"}}]}}
+{"type":"result","subtype":"success","is_error":false,"result":"Would write the report."}
+EOF
+`
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	rule := &config.Rule{
+		Name:    "filer",
+		Enabled: true,
+		DryRun:  true,
+		Trigger: config.Trigger{Type: "manual"},
+		Action:  config.Action{Prompt: "file the report"},
+	}
+	d := newTestDaemon(t, rule)
+
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "filer", Type: "manual", Timestamp: time.Now()})
+
+	records, _ := d.stateDB.GetHistory("filer", "", nil, 10)
+	if len(records) != 1 || records[0].State != "success" {
+		t.Fatalf("records = %+v, want one successful dry run", records)
+	}
+	if records[0].PlannedOps != `[{"op":"write","path":"/tmp/out.txt"}]` {
+		t.Errorf("PlannedOps = %q", records[0].PlannedOps)
+	}
+	if !strings.Contains(records[0].Output, "write  /tmp/out.txt") {
+		t.Errorf("Output = %q, want plan summary", records[0].Output)
+	}
+}
+
+func TestHandleEvent_RecordsStderrSeparately(t *testing.T) {
+	d := newTestDaemon(t, scriptRule("mounter", "echo 'partial'; echo 'mount failed: X-Plex-Token=abc123' >&2; exit 1"))
+
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "mounter", Type: "manual", Timestamp: time.Now()})
+
+	records, _ := d.stateDB.GetHistory("mounter", "", nil, 1)
+	if len(records) != 1 {
+		t.Fatalf("records = %+v, want one", records)
+	}
+	rec := records[0]
+	if strings.TrimSpace(rec.Output) != "partial" {
+		t.Errorf("Output = %q, want stdout only", rec.Output)
+	}
+	if !strings.Contains(rec.Stderr, "mount failed") || strings.Contains(rec.Stderr, "abc123") {
+		t.Errorf("Stderr = %q, want scrubbed stderr", rec.Stderr)
+	}
+}
+
+func TestHandleEvent_RecordsExitCode(t *testing.T) {
+	d := newTestDaemon(t, scriptRule("checker", "exit 42"))
+
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "checker", Type: "manual", Timestamp: time.Now()})
+
+	records, _ := d.stateDB.GetHistory("checker", "", nil, 1)
+	if len(records) != 1 {
+		t.Fatalf("records = %+v, want one", records)
+	}
+	if records[0].State != "failure" || records[0].ExitCode == nil || *records[0].ExitCode != 42 {
+		t.Errorf("record = %+v, want failure with exit code 42", records[0])
+	}
+}
+
+func TestRecordExecution_TruncationLimits(t *testing.T) {
+	output := strings.Repeat("o", 20000)
+	data := map[string]any{"payload": strings.Repeat("d", 4000)}
+	tests := []struct {
+		name               string
+		cfg                config.RuleExecConfig
+		wantOutput, wantEv int
+	}{
+		{"defaults", config.RuleExecConfig{}, config.DefaultMaxOutputBytes, config.DefaultMaxEventDataBytes},
+		{"larger", config.RuleExecConfig{MaxOutputBytes: 16384, MaxEventDataBytes: 2048}, 16384, 2048},
+		{"smaller", config.RuleExecConfig{MaxOutputBytes: 2048, MaxEventDataBytes: 256}, 2048, 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDaemon(t)
+			d.config().RuleExecution = tt.cfg
+			rule := &config.Rule{Name: "verbose"}
+			id := d.recordExecution(rule, trigger.Event{RuleName: "verbose", Type: "manual", Data: data}, "success", time.Now(), output, "")
+
+			rec, err := d.stateDB.GetExecution(id)
+			if err != nil || rec == nil {
+				t.Fatalf("GetExecution(%d) = %v, %v", id, rec, err)
+			}
+			if got := len(rec.Output); got != tt.wantOutput {
+				t.Errorf("output length = %d, want %d", got, tt.wantOutput)
+			}
+			if got := len(rec.EventData); got != tt.wantEv {
+				t.Errorf("event data length = %d, want %d", got, tt.wantEv)
+			}
+		})
+	}
+}
+
+// drainEvents returns the rule names of all queued events.
+func drainEvents(d *Daemon) []string {
+	var names []string
+	for {
+		select {
+		case e := <-d.events:
+			names = append(names, e.RuleName)
+		default:
+			return names
+		}
+	}
+}
+
+func TestFireTriggeredRules_UnknownTargetNotFired(t *testing.T) {
+	parent := scriptRule("parent", "true")
+	parent.Triggers = []string{"child", "chld"}
+	d := newTestDaemon(t, parent, scriptRule("child", "true"))
+	d.events = make(chan trigger.Event, 10)
+
+	d.fireTriggeredRules(context.Background(), parent, trigger.Event{RuleName: "parent"}, "TRIGGER: chld\nTRIGGER: child", nil)
+
+	if got := drainEvents(d); len(got) != 1 || got[0] != "child" {
+		t.Errorf("fired = %v, want only the existing rule", got)
+	}
+	if records, _ := d.stateDB.GetHistory("chld", "", nil, 10); len(records) != 0 {
+		t.Errorf("unknown target should not be recorded, got %+v", records)
+	}
+}
+
+func TestFireTriggeredRules_DisabledTargetSkipped(t *testing.T) {
+	parent := scriptRule("parent", "true")
+	parent.Triggers = []string{"child"}
+	child := scriptRule("child", "true")
+	child.Enabled = false
+	d := newTestDaemon(t, parent, child)
+	d.events = make(chan trigger.Event, 10)
+
+	d.fireTriggeredRules(context.Background(), parent, trigger.Event{RuleName: "parent"}, "", nil)
+
+	if got := drainEvents(d); len(got) != 0 {
+		t.Errorf("fired = %v, want disabled rule not fired", got)
+	}
+	records, _ := d.stateDB.GetHistory("child", "skipped:disabled", nil, 10)
+	if len(records) != 1 || !strings.Contains(records[0].Error, "triggered by parent") {
+		t.Errorf("expected one disabled skip naming the parent, got %+v", records)
+	}
+}
+
 // parseTriggeredRules and expandHome are now defined in daemon.go.
 
 // ===== FR-12: expandHome resolves to run_as_user's home =====
@@ -266,3 +613,937 @@ func TestExpandHome_NoTilde(t *testing.T) {
 		t.Errorf("FR-12: non-tilde path should be unchanged, got %q", result)
 	}
 }
+
+// ===== Shared memory MCP server =====
+
+func TestMemoryEndpoint_PrefersSharedServer(t *testing.T) {
+	d := &Daemon{
+		daemonPath: "/usr/local/bin/srvrmgrd",
+		mcpURL:     "http://127.0.0.1:9877/mcp",
+	}
+	if got := d.memoryEndpoint(); got != "http://127.0.0.1:9877/mcp" {
+		t.Errorf("memoryEndpoint() = %q, want shared server URL", got)
+	}
+}
+
+func TestMemoryEndpoint_FallsBackToStdio(t *testing.T) {
+	d := &Daemon{daemonPath: "/usr/local/bin/srvrmgrd"}
+	if got := d.memoryEndpoint(); got != "/usr/local/bin/srvrmgrd" {
+		t.Errorf("memoryEndpoint() = %q, want daemon path", got)
+	}
+}
+
+func TestStartMemoryServer_PortInUseFallsBackToStdio(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	t.Setenv("SRVRMGR_MCP_PORT", "")
+
+	d := newTestDaemon(t)
+	d.daemonPath = "/usr/local/bin/srvrmgrd"
+	d.config().Memory = config.MemoryConfig{
+		Path:       filepath.Join(t.TempDir(), "memory.db"),
+		ListenPort: taken.Addr().(*net.TCPAddr).Port,
+	}
+	d.startMemoryServer(context.Background())
+
+	if got := d.memoryEndpoint(); got != d.daemonPath {
+		t.Errorf("memoryEndpoint() = %q, want stdio fallback while the port is taken", got)
+	}
+}
+
+func TestHandleHealth_ReportsMemoryEmbedder(t *testing.T) {
+	srv, err := mcp.NewServer(filepath.Join(t.TempDir(), "memory.db"))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer srv.Close()
+
+	d := newTestDaemon(t)
+	health := func() map[string]any {
+		rec := httptest.NewRecorder()
+		d.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decoding /health: %v", err)
+		}
+		return resp
+	}
+	if _, ok := health()["memory_embedder"]; ok {
+		t.Error("memory_embedder reported without a memory server")
+	}
+
+	d.memoryServer = srv
+	if got := health()["memory_embedder"]; got != embedder.StateNotLoaded {
+		t.Errorf("memory_embedder = %v, want %s", got, embedder.StateNotLoaded)
+	}
+}
+
+// ===== Reliability stats API =====
+
+func TestHandleAPIStats(t *testing.T) {
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	for i, st := range []string{"success", "failure", "success", "success"} {
+		started := now.Add(time.Duration(i-4) * time.Hour)
+		db.RecordExecution(state.ExecutionRecord{
+			RuleName: "backup", TriggerType: "scheduled", State: st,
+			StartedAt: started, FinishedAt: started, DurationMs: 1,
+		})
+	}
+
+	d := &Daemon{
+		stateDB: db,
+		rules: map[string]*config.Rule{
+			"backup": {Name: "backup"},
+			"idle":   {Name: "idle"},
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	d.handleAPIStats(rec, httptest.NewRequest(http.MethodGet, "/api/stats?days=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var stats []state.RuleReliability
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(stats) != 2 || stats[0].RuleName != "backup" || stats[1].RuleName != "idle" {
+		t.Fatalf("expected stats for backup and idle in order, got %+v", stats)
+	}
+	if stats[0].TotalRuns != 4 || stats[0].Failures != 1 || stats[0].SuccessRate != 0.75 {
+		t.Errorf("backup stats = %+v, want 4 runs, 1 failure, 0.75 success rate", stats[0])
+	}
+	if stats[1].TotalRuns != 0 {
+		t.Errorf("idle stats = %+v, want zero runs", stats[1])
+	}
+}
+
+func TestHandleAPIStats_RejectsBadDays(t *testing.T) {
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	d := &Daemon{stateDB: db}
+	rec := httptest.NewRecorder()
+	d.handleAPIStats(rec, httptest.NewRequest(http.MethodGet, "/api/stats?days=-3", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleAPIHistory_GroupByRule(t *testing.T) {
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	for i, st := range []string{"success", "failure", "success"} {
+		started := now.Add(time.Duration(i-3) * time.Minute)
+		db.RecordExecution(state.ExecutionRecord{
+			RuleName: "backup", TriggerType: "scheduled", State: st,
+			StartedAt: started, FinishedAt: started,
+		})
+	}
+
+	d := &Daemon{stateDB: db}
+	rec := httptest.NewRecorder()
+	d.handleAPIHistory(rec, httptest.NewRequest(http.MethodGet, "/api/history?group_by=rule", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var sums []state.RuleHistorySummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &sums); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(sums) != 1 || sums[0].Total != 3 || sums[0].States["success"] != 2 || sums[0].LastState != "success" {
+		t.Errorf("summaries = %+v, want backup with 3 runs, 2 successes, last success", sums)
+	}
+
+	rec = httptest.NewRecorder()
+	d.handleAPIHistory(rec, httptest.NewRequest(http.MethodGet, "/api/history?group_by=state", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("group_by=state: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestParseTriggerTypes(t *testing.T) {
+	got := parseTriggerTypes(" scheduled, -manual,,webhook ")
+	want := []string{"scheduled", "-manual", "webhook"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("parseTriggerTypes() = %v, want %v", got, want)
+	}
+	if parseTriggerTypes("") != nil {
+		t.Error("parseTriggerTypes(\"\") should be nil")
+	}
+}
+
+// ===== Script actions =====
+
+func TestNew_PathsFollowConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("daemon:\n  log_level: info\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := New(configPath, filepath.Join(dir, "rules"))
+	d.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	if err := d.loadConfig(); err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	w, err := d.initLogWriter()
+	if err != nil {
+		t.Fatalf("initLogWriter() error = %v", err)
+	}
+	w.Close()
+	if err := d.initStateDB(); err != nil {
+		t.Fatalf("initStateDB() error = %v", err)
+	}
+	d.stateDB.Close()
+
+	for _, path := range []string{
+		filepath.Join(dir, "logs", "srvrmgrd.log"),
+		filepath.Join(dir, "state", "history.db"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s beside the config: %v", path, err)
+		}
+	}
+	if got := d.auditLogPath(); got != filepath.Join(dir, "logs", "audit.log") {
+		t.Errorf("auditLogPath() = %q, want it in the derived log dir", got)
+	}
+}
+
+// newTestDaemon returns a daemon with a temp state DB, discarded logs and the given rules.
+func newTestDaemon(t *testing.T, rules ...*config.Rule) *Daemon {
+	t.Helper()
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	d := &Daemon{
+		rules:        make(map[string]*config.Rule),
+		lastRunState: make(map[string]string),
+		lastSuccess:  make(map[string]time.Time),
+		depWaiting:   make(map[string]bool),
+		stateDB:      db,
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		startTime:    time.Now(),
+	}
+	// TRIGGER: is the trigger_marker LoadGlobal defaults to
+	d.cfg.Store(&config.Global{Daemon: config.DaemonConfig{TriggerMarker: "TRIGGER:"}})
+	for _, r := range rules {
+		d.rules[r.Name] = r
+	}
+	return d
+}
+
+func scriptRule(name, script string) *config.Rule {
+	return &config.Rule{
+		Name:    name,
+		Enabled: true,
+		Trigger: config.Trigger{Type: "manual"},
+		Action:  config.Action{Script: script},
+	}
+}
+
+func TestHandleEvent_ScriptSuccess(t *testing.T) {
+	d := newTestDaemon(t, scriptRule("echo", "echo backing up {{target}}"))
+
+	d.handleEvent(context.Background(), trigger.Event{
+		RuleName: "echo", Type: "manual", Timestamp: time.Now(),
+		Data: map[string]any{"target": "/tmp/a b; false"},
+	})
+
+	records, err := d.stateDB.GetHistory("echo", "", nil, 10)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("GetHistory() = %d records, err = %v; want 1", len(records), err)
+	}
+	if records[0].State != "success" {
+		t.Errorf("State = %q, want success (error: %s)", records[0].State, records[0].Error)
+	}
+	if !strings.Contains(records[0].Output, "backing up /tmp/a b; false") {
+		t.Errorf("Output = %q, want quoted event data echoed literally", records[0].Output)
+	}
+	if d.lastRunState["echo"] != "success" {
+		t.Errorf("lastRunState = %q, want success", d.lastRunState["echo"])
+	}
+}
+
+func TestHandleEvent_ScriptNonZeroExitIsFailure(t *testing.T) {
+	d := newTestDaemon(t, scriptRule("fails", "echo oops; exit 2"))
+
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "fails", Type: "manual", Timestamp: time.Now()})
+
+	records, err := d.stateDB.GetHistory("fails", "", nil, 10)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("GetHistory() = %d records, err = %v; want 1", len(records), err)
+	}
+	if records[0].State != "failure" {
+		t.Errorf("State = %q, want failure", records[0].State)
+	}
+	if !strings.Contains(records[0].Error, "exit status 2") {
+		t.Errorf("Error = %q, want exit status", records[0].Error)
+	}
+}
+
+// A script dry run is recorded like a Claude one: flagged as a dry run, with
+// the script as its planned operation and no output of its own.
+func TestHandleEvent_ScriptDryRunRecordsPlan(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "x")
+	rule := scriptRule("dry", "touch {{path}}")
+	rule.DryRun = true
+	d := newTestDaemon(t, rule)
+
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "dry", Type: "manual", Timestamp: time.Now(), Data: map[string]any{"path": marker}})
+
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("dry run ran the script")
+	}
+	records, _ := d.stateDB.GetHistory("dry", "", nil, 10)
+	if len(records) != 1 || !records[0].DryRun {
+		t.Fatalf("records = %+v, want one dry run", records)
+	}
+	if want := `[{"op":"run","command":"touch '` + marker + `'"}]`; records[0].PlannedOps != want {
+		t.Errorf("PlannedOps = %q, want %q", records[0].PlannedOps, want)
+	}
+	if records[0].Output != "" {
+		t.Errorf("Output = %q, want none", records[0].Output)
+	}
+}
+
+// ===== Guard conditions =====
+
+func TestHandleEvent_ConditionTrueExecutes(t *testing.T) {
+	rule := scriptRule("guarded", "echo ran")
+	rule.When = `event_type == "manual" && size > 10`
+	d := newTestDaemon(t, rule)
+
+	d.handleEvent(context.Background(), trigger.Event{
+		RuleName: "guarded", Type: "manual", Timestamp: time.Now(),
+		Data: map[string]any{"size": 42},
+	})
+
+	records, _ := d.stateDB.GetHistory("guarded", "", nil, 10)
+	if len(records) != 1 || records[0].State != "success" {
+		t.Fatalf("expected one success record, got %+v", records)
+	}
+}
+
+func TestHandleEvent_ConditionFalseSkips(t *testing.T) {
+	rule := scriptRule("guarded", "echo ran")
+	rule.When = `size > 10`
+	d := newTestDaemon(t, rule)
+
+	d.handleEvent(context.Background(), trigger.Event{
+		RuleName: "guarded", Type: "manual", Timestamp: time.Now(),
+		Data: map[string]any{"size": 3},
+	})
+
+	records, _ := d.stateDB.GetHistory("guarded", "", nil, 10)
+	if len(records) != 1 || records[0].State != "skipped" || records[0].SkipReason != "condition" {
+		t.Fatalf("expected one skipped/condition record, got %+v", records)
+	}
+	if records[0].Output != "" {
+		t.Errorf("Output = %q, want no execution output", records[0].Output)
+	}
+	if _, ok := d.lastRunState["guarded"]; ok {
+		t.Error("skipped execution should not update lastRunState")
+	}
+}
+
+func TestHandleEvent_ConditionErrorSkips(t *testing.T) {
+	rule := scriptRule("guarded", "echo ran")
+	rule.When = `name && true`
+	d := newTestDaemon(t, rule)
+
+	d.handleEvent(context.Background(), trigger.Event{
+		RuleName: "guarded", Type: "manual", Timestamp: time.Now(),
+		Data: map[string]any{"name": "x"},
+	})
+
+	records, _ := d.stateDB.GetHistory("guarded", "", nil, 10)
+	if len(records) != 1 || records[0].State != "skipped" || records[0].SkipReason != "condition" {
+		t.Fatalf("expected one skipped/condition record, got %+v", records)
+	}
+	if !strings.Contains(records[0].Error, "condition error") {
+		t.Errorf("Error = %q, want condition error", records[0].Error)
+	}
+}
+
+// ===== Retry history =====
+
+func TestHandleFailure_RecordsEachRetry(t *testing.T) {
+	defer func(u time.Duration) { retryDelayUnit = u }(retryDelayUnit)
+	retryDelayUnit = time.Millisecond
+
+	// Fails on the first three runs (original + two retries), then succeeds
+	counter := filepath.Join(t.TempDir(), "count")
+	rule := scriptRule("flaky", `n=$(cat `+counter+` 2>/dev/null || echo 0); n=$((n+1)); echo $n > `+counter+`; [ $n -ge 4 ]`)
+	rule.OnFailure = config.OnFailure{Retry: true, RetryAttempts: 5, RetryDelaySeconds: 1}
+	d := newTestDaemon(t, rule)
+
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "flaky", Type: "manual", Timestamp: time.Now()})
+
+	records, err := d.stateDB.GetHistory("flaky", "", nil, 10)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected original + 3 retry records, got %d: %+v", len(records), records)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	original := records[0]
+	if original.RetryAttempt != 0 || original.State != "failure" {
+		t.Errorf("original = attempt %d %s, want attempt 0 failure", original.RetryAttempt, original.State)
+	}
+	wantStates := []string{"failure", "failure", "success"}
+	for i, rec := range records[1:] {
+		if rec.RetryAttempt != i+1 {
+			t.Errorf("retry %d: RetryAttempt = %d", i+1, rec.RetryAttempt)
+		}
+		if rec.State != wantStates[i] {
+			t.Errorf("retry %d: State = %q, want %q", i+1, rec.State, wantStates[i])
+		}
+		if rec.TriggeredByExecutionID != original.ID {
+			t.Errorf("retry %d: TriggeredByExecutionID = %d, want %d", i+1, rec.TriggeredByExecutionID, original.ID)
+		}
+	}
+	if got := d.lastRunState["flaky"]; got != "success" {
+		t.Errorf("lastRunState = %q, want success", got)
+	}
+}
+
+func TestHandleFailure_RetryIf(t *testing.T) {
+	defer func(u time.Duration) { retryDelayUnit = u }(retryDelayUnit)
+	retryDelayUnit = time.Millisecond
+
+	// Fails once with msg, then succeeds
+	flaky := func(name, msg string) *config.Rule {
+		counter := filepath.Join(t.TempDir(), "count")
+		rule := scriptRule(name, `n=$(cat `+counter+` 2>/dev/null || echo 0); n=$((n+1)); echo $n > `+counter+`; [ $n -ge 2 ] || { echo "`+msg+`" >&2; exit 1; }`)
+		rule.OnFailure = config.OnFailure{Retry: true, RetryAttempts: 3, RetryDelaySeconds: 1, RetryIf: []string{`(?i)rate limit`, `connection (refused|reset)`}}
+		if err := config.ValidateRule(rule); err != nil {
+			t.Fatal(err)
+		}
+		return rule
+	}
+	d := newTestDaemon(t, flaky("transient", "429: Rate limit exceeded"), flaky("deterministic", "authentication failed"))
+
+	d.handleEvent(context.Background(), manualEvent("transient"))
+	if got, want := historyStates(t, d, "transient"), []string{"failure", "success"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matching error: history = %v, want %v (retried)", got, want)
+	}
+
+	d.handleEvent(context.Background(), manualEvent("deterministic"))
+	if got, want := historyStates(t, d, "deterministic"), []string{"failure"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("non-matching error: history = %v, want %v (not retried)", got, want)
+	}
+	if got := d.lastRunState["deterministic"]; got != "failure" {
+		t.Errorf("lastRunState = %q, want failure", got)
+	}
+}
+
+// ===== Replay =====
+
+func TestReplayEvent(t *testing.T) {
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	rec := &state.ExecutionRecord{
+		ID: 7, RuleName: "cleanup", TriggerType: "filesystem", StartedAt: started,
+		EventData: `{"event_type":"filesystem","path":"/tmp/a b","size":3}`,
+	}
+
+	event, err := replayEvent(rec)
+	if err != nil {
+		t.Fatalf("replayEvent() error = %v", err)
+	}
+	if event.RuleName != "cleanup" || event.Type != "filesystem" || !event.Timestamp.Equal(started) {
+		t.Errorf("event = %+v", event)
+	}
+	if event.Data["path"] != "/tmp/a b" || event.Data["size"] != float64(3) {
+		t.Errorf("Data = %v", event.Data)
+	}
+
+	rec.EventData = `{"path":"/tmp/trunc`
+	if _, err := replayEvent(rec); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("replayEvent(truncated) error = %v, want it reported as truncated", err)
+	}
+	rec.EventData = `{"path":}`
+	if _, err := replayEvent(rec); err == nil || strings.Contains(err.Error(), "truncated") {
+		t.Errorf("replayEvent(malformed) error = %v, want a decoding error", err)
+	}
+}
+
+func TestReplay_RunsWithOriginalData(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	rulesDir := filepath.Join(dir, "rules")
+	out := filepath.Join(dir, "out")
+	os.MkdirAll(rulesDir, 0755)
+	os.WriteFile(configPath, []byte("daemon:\n  log_level: error\n"), 0644)
+	os.WriteFile(filepath.Join(rulesDir, "echo.yaml"), []byte(`name: echo
+enabled: true
+trigger:
+  type: manual
+action:
+  script: "printf '%s|%s' {{path}} {{event_type}} > `+out+`"
+`), 0644)
+
+	d := New(configPath, rulesDir)
+	d.stateDB = newTestDaemon(t).stateDB
+	id, err := d.stateDB.RecordExecution(state.ExecutionRecord{
+		RuleName: "echo", TriggerType: "webhook", State: "failure",
+		StartedAt: time.Now(), FinishedAt: time.Now(),
+		EventData: `{"event_type":"webhook","path":"/srv/a b"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := d.GetExecution(id)
+	if err != nil {
+		t.Fatalf("GetExecution() error = %v", err)
+	}
+	if err := d.Replay(context.Background(), rec); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("rule did not run: %v", err)
+	}
+	if string(got) != "/srv/a b|webhook" {
+		t.Errorf("rule saw %q, want original event data", got)
+	}
+	records, _ := d.stateDB.GetHistory("echo", "", nil, 1)
+	if len(records) != 1 || records[0].ID == id || records[0].TriggerType != "webhook" {
+		t.Errorf("replayed record = %+v, want the original webhook trigger type", records)
+	}
+
+	if _, err := d.GetExecution(id + 100); err == nil {
+		t.Error("expected error for unknown execution id")
+	}
+}
+
+// ===== Graceful shutdown =====
+
+// startHandler runs handleEvent for rule in the background like the main loop does.
+func startHandler(d *Daemon, ctx context.Context, rule string) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.handleEvent(ctx, trigger.Event{RuleName: rule, Type: "manual", Timestamp: time.Now()})
+	}()
+}
+
+func TestDrain_WaitsForFastHandlers(t *testing.T) {
+	d := newTestDaemon(t, scriptRule("quick", "sleep 0.1"))
+	d.draining = make(chan struct{})
+	execCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startHandler(d, execCtx, "quick")
+	d.drain(5*time.Second, cancel)
+
+	if execCtx.Err() != nil {
+		t.Error("executions finishing within the grace period should not be cancelled")
+	}
+	if got := historyStates(t, d, "quick"); len(got) != 1 || got[0] != "success" {
+		t.Errorf("history states = %v, want [success]", got)
+	}
+}
+
+func TestDrain_CancelsHungExecutions(t *testing.T) {
+	d := newTestDaemon(t, scriptRule("hung", "sleep 30"))
+	d.draining = make(chan struct{})
+	execCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startHandler(d, execCtx, "hung")
+	time.Sleep(100 * time.Millisecond) // let the script start
+
+	grace := 200 * time.Millisecond
+	start := time.Now()
+	d.drain(grace, cancel)
+	if elapsed := time.Since(start); elapsed > grace+forceStopWait {
+		t.Fatalf("drain took %v, want at most %v", elapsed, grace+forceStopWait)
+	}
+
+	if got := historyStates(t, d, "hung"); len(got) != 1 || got[0] != "cancelled" {
+		t.Errorf("history states = %v, want [cancelled]", got)
+	}
+}
+
+func TestHandleFailure_AbandonsRetryWhenDraining(t *testing.T) {
+	rule := scriptRule("flaky", "exit 1")
+	rule.OnFailure = config.OnFailure{Retry: true, RetryAttempts: 3, RetryDelaySeconds: 60}
+	d := newTestDaemon(t, rule)
+	d.draining = make(chan struct{})
+	close(d.draining)
+
+	done := make(chan struct{})
+	go func() {
+		d.handleEvent(context.Background(), trigger.Event{RuleName: "flaky", Type: "manual", Timestamp: time.Now()})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("retry delay should be abandoned once shutdown starts")
+	}
+	if got := historyStates(t, d, "flaky"); len(got) != 1 {
+		t.Errorf("history states = %v, want only the original failure", got)
+	}
+}
+
+// hangingExecutor runs until its context ends, like a rule that hangs.
+type hangingExecutor struct {
+	mu      sync.Mutex
+	started []string
+}
+
+func (h *hangingExecutor) Execute(ctx context.Context, rule *config.Rule, _ trigger.Event) (*executor.Result, error) {
+	h.mu.Lock()
+	h.started = append(h.started, rule.Name)
+	h.mu.Unlock()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestHandleLifecycleShutdown_BoundsTotalTime(t *testing.T) {
+	prevBudget, prevTimeout := shutdownRulesBudget, shutdownRuleMaxTimeout
+	shutdownRulesBudget, shutdownRuleMaxTimeout = 500*time.Millisecond, 200*time.Millisecond
+	t.Cleanup(func() { shutdownRulesBudget, shutdownRuleMaxTimeout = prevBudget, prevTimeout })
+
+	// Rules start in waves of lifecycleRulesConcurrency every 200ms, so three
+	// waves fit in the budget and the rest are skipped
+	const n = 3*lifecycleRulesConcurrency + 2
+	d := newTestDaemon(t)
+	d.triggers = make(map[string]trigger.Trigger)
+	for i := 0; i < n; i++ {
+		rule := scriptRule(fmt.Sprintf("stop-%02d", i), "true")
+		rule.Trigger = config.Trigger{Type: "lifecycle", OnEvents: []string{"daemon_stopped"}}
+		rule.MaxTimeoutSeconds = 60 // capped at shutdownRuleMaxTimeout
+		d.rules[rule.Name] = rule
+		lt, err := trigger.NewLifecycle(rule.Name, rule.Trigger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.triggers[rule.Name] = lt
+	}
+	hang := &hangingExecutor{}
+	d.SetExecutor(hang)
+
+	start := time.Now()
+	d.handleLifecycleShutdown(context.Background())
+	if elapsed := time.Since(start); elapsed > shutdownRulesBudget+forceStopWait {
+		t.Errorf("shutdown rules took %v, want at most about %v", elapsed, shutdownRulesBudget)
+	}
+
+	if got, want := len(hang.started), 3*lifecycleRulesConcurrency; got != want {
+		t.Errorf("%d shutdown rules ran, want %d within the budget", got, want)
+	}
+	for _, name := range hang.started {
+		if name >= fmt.Sprintf("stop-%02d", 3*lifecycleRulesConcurrency) {
+			t.Errorf("rule %s ran; the last rules in name order should be skipped", name)
+		}
+	}
+}
+
+func TestRunStartupRules_CompleteBeforeReturning(t *testing.T) {
+	lifecycleRule := func(name string, events ...string) *config.Rule {
+		rule := scriptRule(name, "true")
+		rule.Trigger = config.Trigger{Type: "lifecycle", OnEvents: events}
+		return rule
+	}
+	disabled := lifecycleRule("disabled-setup", "daemon_started")
+	disabled.Enabled = false
+	scheduled := scriptRule("hourly", "true")
+	scheduled.Trigger = config.Trigger{Type: "scheduled", RunEvery: "1h"}
+	d := newTestDaemon(t,
+		lifecycleRule("mount-volumes", "daemon_started"),
+		lifecycleRule("warm-cache", "daemon_started", "daemon_stopped"),
+		lifecycleRule("unmount", "daemon_stopped"),
+		disabled,
+		scriptRule("by-hand", "true"),
+		scheduled,
+	)
+	d.triggers = make(map[string]trigger.Trigger)
+	d.events = make(chan trigger.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var steps []string
+	d.SetExecutor(&fakeExecutor{respond: func(_ int, rule *config.Rule) (*executor.Result, error) {
+		time.Sleep(50 * time.Millisecond) // slow setup
+		d.mu.RLock()
+		started := len(d.triggers)
+		d.mu.RUnlock()
+		mu.Lock()
+		steps = append(steps, fmt.Sprintf("%s done, %d triggers started", rule.Name, started))
+		mu.Unlock()
+		return &executor.Result{State: "success"}, nil
+	}})
+
+	if err := d.startTriggers(ctx, true); err != nil {
+		t.Fatalf("startTriggers() error = %v", err)
+	}
+
+	sort.Strings(steps)
+	want := []string{"mount-volumes done, 0 triggers started", "warm-cache done, 0 triggers started"}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("steps = %q, want %q", steps, want)
+	}
+	d.mu.RLock()
+	_, ok := d.triggers["hourly"]
+	d.mu.RUnlock()
+	if !ok {
+		t.Error("startTriggers() did not start the scheduled rule's trigger")
+	}
+	for _, name := range []string{"mount-volumes", "warm-cache"} {
+		if got := historyStates(t, d, name); len(got) != 1 || got[0] != "success" {
+			t.Errorf("%s history = %v, want [success]", name, got)
+		}
+	}
+}
+
+func TestRunStartupRules_Bounded(t *testing.T) {
+	prev := startupRulesBudget
+	startupRulesBudget = 200 * time.Millisecond
+	t.Cleanup(func() { startupRulesBudget = prev })
+
+	rule := scriptRule("hangs", "true")
+	rule.Trigger = config.Trigger{Type: "lifecycle", OnEvents: []string{"daemon_started"}}
+	d := newTestDaemon(t, rule)
+	d.SetExecutor(&hangingExecutor{})
+
+	start := time.Now()
+	d.runStartupRules(context.Background())
+	if elapsed := time.Since(start); elapsed > startupRulesBudget+forceStopWait {
+		t.Errorf("runStartupRules() took %v with a hung rule, want about %v", elapsed, startupRulesBudget)
+	}
+}
+
+// ===== /ready =====
+
+func TestHandleReady(t *testing.T) {
+	d := newTestDaemon(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mux := d.newMux(ctx)
+
+	get := func() (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decoding /ready: %v", err)
+		}
+		return rec.Code, body
+	}
+
+	if code, body := get(); code != http.StatusServiceUnavailable || body["reason"] != "starting" {
+		t.Errorf("before event loop: %d %v, want 503 starting", code, body)
+	}
+
+	d.mu.Lock()
+	d.startupRules = true
+	d.mu.Unlock()
+	if code, body := get(); code != http.StatusServiceUnavailable || body["reason"] != "running startup rules" {
+		t.Errorf("during startup rules: %d %v, want 503 running startup rules", code, body)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/health during startup rules: status %d, want 200", rec.Code)
+	}
+	d.mu.Lock()
+	d.startupRules = false
+	d.mu.Unlock()
+
+	d.setReady(true)
+	if code, body := get(); code != http.StatusOK || body["ready"] != true {
+		t.Errorf("running: %d %v, want 200 ready", code, body)
+	}
+
+	cancel()
+	if code, body := get(); code != http.StatusServiceUnavailable || body["reason"] != "shutting down" {
+		t.Errorf("after cancel: %d %v, want 503 shutting down", code, body)
+	}
+}
+
+func TestWebhook_RejectsBodyNotMatchingSchema(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	writeTestFile(t, schemaPath, `{"type":"object","required":["service"]}`)
+	rule := scriptRule("deploy", "echo deployed")
+	rule.Trigger = config.Trigger{Type: "webhook", ListenPath: "/hooks/deploy", BodySchema: schemaPath}
+	wh, err := trigger.NewWebhook(rule.Name, rule.Trigger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := newTestDaemon(t, rule)
+	d.webhooks = map[string]*trigger.Webhook{"/hooks/deploy": wh}
+	d.events = make(chan trigger.Event, 10)
+	mux := d.newMux(context.Background())
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hooks/deploy", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	if code := post(`{"replicas":3}`); code != http.StatusBadRequest {
+		t.Errorf("invalid body: status %d, want 400", code)
+	}
+	if len(d.events) != 0 {
+		t.Error("invalid body fired the rule")
+	}
+	records, err := d.stateDB.GetHistory("deploy", "", nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].State != state.StateSkipped || records[0].SkipReason != state.SkipInvalidBody {
+		t.Errorf("history = %+v, want one skipped invalid_body record", records)
+	}
+
+	if code := post(`{"service":"api"}`); code != http.StatusOK {
+		t.Errorf("valid body: status %d, want 200", code)
+	}
+	if len(d.events) != 1 {
+		t.Errorf("valid body sent %d events, want 1", len(d.events))
+	}
+}
+
+func TestHandleAPIRunRule(t *testing.T) {
+	starter := scriptRule("starter", "true")
+	starter.Trigger = config.Trigger{Type: "lifecycle", OnEvents: []string{"daemon_started"}}
+	d := newTestDaemon(t, scriptRule("job", "true"), starter)
+	d.events = make(chan trigger.Event, 1)
+	mux := d.newMux(context.Background())
+	do := func(method, target string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, nil)
+		req.RemoteAddr = "127.0.0.1:50000"
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := do(http.MethodPost, "/api/rules/job/run?no_deps=true"); code != http.StatusAccepted {
+		t.Fatalf("run job: status %d, want 202", code)
+	}
+	if ev := <-d.events; ev.RuleName != "job" || ev.Type != "manual" || !ev.NoDeps {
+		t.Errorf("queued event = %+v, want a manual no-deps event for job", ev)
+	}
+
+	if code := do(http.MethodPost, "/api/rules/starter/run?event=daemon_started"); code != http.StatusAccepted {
+		t.Fatalf("run starter: status %d, want 202", code)
+	}
+	if ev := <-d.events; ev.Type != "daemon_started" || ev.NoDeps {
+		t.Errorf("queued event = %+v, want a daemon_started event", ev)
+	}
+
+	for _, tt := range []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodGet, "/api/rules/job/run", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/rules/missing/run", http.StatusNotFound},
+		{http.MethodPost, "/api/rules/job/run?event=daemon_started", http.StatusBadRequest},
+	} {
+		if code := do(tt.method, tt.target); code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.target, code, tt.want)
+		}
+	}
+
+	// Remote clients can't run rules, with or without no_deps
+	for _, target := range []string{"/api/rules/job/run", "/api/rules/job/run?no_deps=true"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		req.RemoteAddr = "192.0.2.10:50000"
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("remote POST %s = %d, want 403", target, rec.Code)
+		}
+	}
+	if len(d.events) != 0 {
+		t.Errorf("remote requests queued %d events", len(d.events))
+	}
+
+	// A full queue is reported rather than blocking
+	d.events <- manualEvent("job")
+	if code := do(http.MethodPost, "/api/rules/job/run"); code != http.StatusServiceUnavailable {
+		t.Errorf("run with a full queue: status %d, want 503", code)
+	}
+}
+
+func TestHandleAPITriggers(t *testing.T) {
+	nightly := scriptRule("nightly", "true")
+	nightly.Trigger = config.Trigger{Type: "scheduled", CronExpression: "0 3 * * *"}
+	hook := scriptRule("deploy", "true")
+	hook.Trigger = config.Trigger{Type: "webhook", ListenPath: "/hooks/deploy"}
+	d := newTestDaemon(t, nightly, hook)
+	d.triggers = make(map[string]trigger.Trigger)
+	for _, rule := range []*config.Rule{nightly, hook} {
+		tr, err := trigger.New(rule.Name, rule.Trigger, "")
+		if err != nil {
+			t.Fatalf("trigger.New(%s) error = %v", rule.Name, err)
+		}
+		d.triggers[rule.Name] = tr
+	}
+
+	rec := httptest.NewRecorder()
+	d.newMux(context.Background()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/triggers", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("triggers = %v, want two", got)
+	}
+	if want := map[string]any{"rule": "deploy", "type": "webhook", "webhook_path": "/hooks/deploy"}; !reflect.DeepEqual(got[0], want) {
+		t.Errorf("triggers[0] = %v, want %v", got[0], want)
+	}
+	if got[1]["rule"] != "nightly" || got[1]["type"] != "scheduled" {
+		t.Errorf("triggers[1] = %v, want the nightly scheduled trigger", got[1])
+	}
+	next, err := time.Parse(time.RFC3339, fmt.Sprint(got[1]["next_run"]))
+	if err != nil {
+		t.Fatalf("next_run = %v: %v", got[1]["next_run"], err)
+	}
+	if !next.After(time.Now()) || time.Until(next) > 24*time.Hour || next.Hour() != 3 || next.Minute() != 0 {
+		t.Errorf("next_run = %v, want the next 03:00", next)
+	}
+}
+
+func TestMaxConcurrent_OverrideWins(t *testing.T) {
+	d := newTestDaemon(t)
+	d.config().RuleExecution.MaxConcurrent = 10
+	if got := d.maxConcurrent(); got != 10 {
+		t.Errorf("maxConcurrent() = %d, want the configured 10", got)
+	}
+	d.SetMaxConcurrent(2)
+	if got := d.maxConcurrent(); got != 2 {
+		t.Errorf("maxConcurrent() = %d, want the override 2", got)
+	}
+}
@@ -0,0 +1,104 @@
+// internal/daemon/depwait.go
+package daemon
+
+import (
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/logging"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// defaultDependsOnWaitTimeout is how long depends_on_wait holds an event when
+// depends_on_wait_timeout is unset.
+const defaultDependsOnWaitTimeout = 30 * time.Minute
+
+// Backoff between dependency checks of a held event; tests shorten them.
+var (
+	depWaitInitialDelay = 5 * time.Second
+	depWaitMaxDelay     = time.Minute
+)
+
+// holdForDependencies holds event, whose dependency gate g failed, until the
+// rule's dependencies are met, if the rule has depends_on_wait. It reports
+// whether the event was held; if not, the caller skips it as usual. Only one
+// event per rule is held at a time, and events are only held while the
+// daemon's event loop runs, since that is where they are requeued.
+func (d *Daemon) holdForDependencies(rule *config.Rule, event trigger.Event, g Gate) bool {
+	if !rule.DependsOnWait || d.draining == nil {
+		return false
+	}
+	timeout := defaultDependsOnWaitTimeout
+	if rule.DependsOnWaitTimeout != "" {
+		timeout, _ = time.ParseDuration(rule.DependsOnWaitTimeout) // validated by ValidateRule
+	}
+
+	d.mu.Lock()
+	if d.depWaiting[rule.Name] {
+		d.mu.Unlock()
+		return false
+	}
+	d.depWaiting[rule.Name] = true
+	d.mu.Unlock()
+
+	logging.WithRule(d.logger, rule.Name).Info("dependencies not met, holding event",
+		"detail", g.Detail, "timeout", timeout)
+	go d.waitForDependencies(rule.Name, event, timeout)
+	return true
+}
+
+// waitForDependencies rechecks a held event's dependencies with exponential
+// backoff. Once they are met the event is requeued to run normally; if
+// timeout passes first it is recorded as skipped. Held events are abandoned
+// at shutdown.
+func (d *Daemon) waitForDependencies(ruleName string, event trigger.Event, timeout time.Duration) {
+	logger := logging.WithRule(d.logger, ruleName)
+	heldAt := time.Now()
+	deadline := heldAt.Add(timeout)
+	waited := func() time.Duration { return time.Since(heldAt).Round(time.Second) }
+	delay := depWaitInitialDelay
+
+	for {
+		select {
+		case <-time.After(min(delay, time.Until(deadline))):
+		case <-d.draining:
+			d.stopWaiting(ruleName)
+			logger.Info("held event abandoned (shutdown)")
+			return
+		}
+
+		d.mu.RLock()
+		rule, ok := d.rules[ruleName]
+		d.mu.RUnlock()
+		if !ok {
+			d.stopWaiting(ruleName)
+			logger.Warn("rule removed while its event was held, dropping event")
+			return
+		}
+
+		met, reason, detail := d.checkDependencies(rule, time.Now())
+		if met {
+			d.stopWaiting(ruleName)
+			logger.Info("dependencies met, requeueing held event", "waited", waited())
+			select {
+			case d.events <- event:
+			default:
+				d.recordDropped(event)
+			}
+			return
+		}
+		if !time.Now().Before(deadline) {
+			d.stopWaiting(ruleName)
+			logger.Warn("skipping rule, dependencies still not met", "detail", detail, "waited", waited())
+			d.recordSkip(rule, event, reason, detail+" (waited "+waited().String()+")")
+			return
+		}
+		delay = min(delay*2, depWaitMaxDelay)
+	}
+}
+
+func (d *Daemon) stopWaiting(ruleName string) {
+	d.mu.Lock()
+	delete(d.depWaiting, ruleName)
+	d.mu.Unlock()
+}
@@ -0,0 +1,121 @@
+// internal/daemon/depwait_test.go
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/state"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// waitingDaemon returns a daemon whose "report" rule waits on "backup", with
+// the event loop's channels set up and dependency checks every few ms.
+func waitingDaemon(t *testing.T, timeout string) (*Daemon, *fakeExecutor) {
+	t.Helper()
+	prevInitial, prevMax := depWaitInitialDelay, depWaitMaxDelay
+	depWaitInitialDelay, depWaitMaxDelay = 5*time.Millisecond, 20*time.Millisecond
+	t.Cleanup(func() { depWaitInitialDelay, depWaitMaxDelay = prevInitial, prevMax })
+
+	report := scriptRule("report", "true")
+	report.DependsOn = []string{"backup"}
+	report.DependsOnWait = true
+	report.DependsOnWaitTimeout = timeout
+	d := newTestDaemon(t, scriptRule("backup", "true"), report)
+	d.events = make(chan trigger.Event, 10)
+	d.draining = make(chan struct{})
+	fake := &fakeExecutor{}
+	d.SetExecutor(fake)
+	return d, fake
+}
+
+func nextEvent(t *testing.T, d *Daemon) trigger.Event {
+	t.Helper()
+	select {
+	case event := <-d.events:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("held event was not requeued")
+		return trigger.Event{}
+	}
+}
+
+func TestDependsOnWait_RunsOnceDependencySucceeds(t *testing.T) {
+	d, fake := waitingDaemon(t, "")
+
+	outcome := d.handleEvent(context.Background(), manualEvent("report"))
+	if outcome.State != "waiting" || len(fake.calls) != 0 {
+		t.Fatalf("outcome = %+v with %d calls, want the event held", outcome, len(fake.calls))
+	}
+	if records := historyFor(t, d, "report"); len(records) != 0 {
+		t.Errorf("history = %+v, want nothing recorded while held", records)
+	}
+
+	// A second event while one is held is skipped rather than queued up
+	if outcome := d.handleEvent(context.Background(), manualEvent("report")); outcome.State != state.StateSkipped {
+		t.Errorf("second event outcome = %+v, want skipped", outcome)
+	}
+
+	d.handleEvent(context.Background(), manualEvent("backup"))
+	event := nextEvent(t, d)
+	if event.RuleName != "report" {
+		t.Fatalf("requeued event for %q, want report", event.RuleName)
+	}
+	if outcome := d.handleEvent(context.Background(), event); outcome.State != "success" {
+		t.Errorf("requeued event outcome = %+v, want success", outcome)
+	}
+	if len(fake.calls) != 2 || fake.calls[1].Rule.Name != "report" {
+		t.Errorf("executor calls = %+v, want backup then report", fake.calls)
+	}
+}
+
+func TestDependsOnWait_SkipsAfterTimeout(t *testing.T) {
+	d, fake := waitingDaemon(t, "30ms")
+
+	d.handleEvent(context.Background(), manualEvent("report"))
+
+	var records []state.ExecutionRecord
+	waitUntil(t, func() bool {
+		records = historyFor(t, d, "report")
+		return len(records) > 0
+	})
+	if len(records) != 1 || records[0].SkipReason != state.SkipDependency {
+		t.Fatalf("history = %+v, want one dependency skip after the timeout", records)
+	}
+	if len(fake.calls) != 0 || len(d.events) != 0 {
+		t.Errorf("report ran or was requeued after timing out")
+	}
+
+	// The rule can hold a new event once the old one timed out
+	if outcome := d.handleEvent(context.Background(), manualEvent("report")); outcome.State != "waiting" {
+		t.Errorf("outcome = %+v, want the new event held", outcome)
+	}
+	close(d.draining)
+	waitUntil(t, func() bool {
+		d.mu.RLock()
+		defer d.mu.RUnlock()
+		return !d.depWaiting["report"]
+	})
+}
+
+func TestDependsOnWait_NotHeldOutsideEventLoop(t *testing.T) {
+	d, _ := waitingDaemon(t, "")
+	d.draining = nil // as in srvrmgr run
+
+	if outcome := d.handleEvent(context.Background(), manualEvent("report")); outcome.State != state.StateSkipped {
+		t.Errorf("outcome = %+v, want skipped", outcome)
+	}
+}
+
+// waitUntil polls cond until it holds, failing the test after two seconds.
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,59 @@
+// internal/daemon/executor.go
+package daemon
+
+import (
+	"context"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/executor"
+	"github.com/colebrumley/srvrmgr/internal/template"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// Executor runs a rule's action for an event. The rule passed in is the
+// effective one: its Claude config is merged with the global defaults, set to
+// plan mode for dry runs, and its add_dirs have ~ expanded. ctx carries the
+// rule's timeout.
+type Executor interface {
+	Execute(ctx context.Context, rule *config.Rule, event trigger.Event) (*executor.Result, error)
+}
+
+// SetExecutor replaces the Executor rule actions run with. Call it before Run.
+func (d *Daemon) SetExecutor(e Executor) {
+	d.executor = e
+}
+
+// ruleExecutor returns the configured Executor, defaulting to claudeExecutor.
+func (d *Daemon) ruleExecutor() Executor {
+	if d.executor != nil {
+		return d.executor
+	}
+	return claudeExecutor{d: d}
+}
+
+// claudeExecutor is the default Executor: action.script runs through the
+// shell, action.prompt through the claude CLI.
+type claudeExecutor struct {
+	d *Daemon
+}
+
+func (c claudeExecutor) Execute(ctx context.Context, rule *config.Rule, event trigger.Event) (*executor.Result, error) {
+	workDir := ""
+	if len(rule.Claude.AddDirs) > 0 {
+		workDir = rule.Claude.AddDirs[0]
+	}
+
+	if rule.Action.Script != "" {
+		// Event data is shell-quoted so it can't inject commands into the script
+		script := template.ExpandShell(rule.Action.Script, event.Data)
+		if rule.DryRun {
+			// Nothing runs; the history shows the script as the dry run's plan
+			return &executor.Result{State: "success", PlannedOps: []executor.PlannedOp{{Op: "run", Command: script}}, ExitCode: -1}, nil
+		}
+		return executor.ExecuteScript(ctx, script, rule.Claude.EnvVars, rule.RunAsUser, workDir)
+	}
+
+	prompt := template.Expand(rule.Action.Prompt, event.Data)
+	memoryEnabled := c.d.isMemoryEnabled(rule)
+	return executor.ExecuteWithMemory(ctx, prompt, rule.Claude, rule.RunAsUser, c.d.config().Logging.Debug, workDir, memoryEnabled, c.d.memoryEndpoint(), rule.Name)
+}
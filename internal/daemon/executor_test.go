@@ -0,0 +1,111 @@
+// internal/daemon/executor_test.go
+package daemon
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/executor"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// executorCall is one call to fakeExecutor.
+type executorCall struct {
+	Rule     config.Rule
+	Event    trigger.Event
+	Deadline time.Time
+}
+
+// fakeExecutor records calls and returns respond's result for the nth call
+// (from 0), or success when respond is nil.
+type fakeExecutor struct {
+	mu      sync.Mutex
+	calls   []executorCall
+	respond func(n int, rule *config.Rule) (*executor.Result, error)
+}
+
+func (f *fakeExecutor) Execute(ctx context.Context, rule *config.Rule, event trigger.Event) (*executor.Result, error) {
+	f.mu.Lock()
+	n := len(f.calls)
+	deadline, _ := ctx.Deadline()
+	f.calls = append(f.calls, executorCall{Rule: *rule, Event: event, Deadline: deadline})
+	f.mu.Unlock()
+	if f.respond == nil {
+		return &executor.Result{State: "success"}, nil
+	}
+	return f.respond(n, rule)
+}
+
+func TestExecuteRule_PassesEffectiveRule(t *testing.T) {
+	rule := promptRule("tidy", "tidy up")
+	rule.DryRun = true
+	rule.MaxTimeoutSeconds = 30
+	rule.Claude.AllowedTools = []string{"Read"}
+	d := newTestDaemon(t, rule)
+	d.config().ClaudeDefaults = config.ClaudeConfig{
+		Model:          "opus",
+		PermissionMode: "acceptEdits",
+		AddDirs:        []string{"/srv/data"},
+		AllowedTools:   []string{"Bash"},
+	}
+	fake := &fakeExecutor{}
+	d.SetExecutor(fake)
+
+	if _, err := d.executeRule(context.Background(), rule, manualEvent("tidy"), 0); err != nil {
+		t.Fatalf("executeRule() error = %v", err)
+	}
+	if len(fake.calls) != 1 {
+		t.Fatalf("executor called %d times, want 1", len(fake.calls))
+	}
+	got := fake.calls[0]
+	if got.Rule.Claude.Model != "opus" || got.Rule.Claude.AddDirs[0] != "/srv/data" {
+		t.Errorf("claude config = %+v, want the global defaults merged in", got.Rule.Claude)
+	}
+	if got.Rule.Claude.AllowedTools[0] != "Read" {
+		t.Errorf("allowed_tools = %v, want the rule's own list", got.Rule.Claude.AllowedTools)
+	}
+	if got.Rule.Claude.PermissionMode != "plan" {
+		t.Errorf("permission_mode = %q, want plan for a dry run", got.Rule.Claude.PermissionMode)
+	}
+	if left := time.Until(got.Deadline); left <= 0 || left > 30*time.Second {
+		t.Errorf("deadline in %v, want the rule's 30s timeout", left)
+	}
+	// The loaded rule is left untouched
+	if rule.Claude.Model != "" || rule.Claude.PermissionMode != "" {
+		t.Errorf("executeRule modified the rule: %+v", rule.Claude)
+	}
+}
+
+func TestExecuteRule_DefaultsToClaudeExecutor(t *testing.T) {
+	d := newTestDaemon(t)
+	if _, ok := d.ruleExecutor().(claudeExecutor); !ok {
+		t.Errorf("ruleExecutor() = %T, want claudeExecutor", d.ruleExecutor())
+	}
+}
+
+func TestClaudeExecutor_Script(t *testing.T) {
+	d := newTestDaemon(t)
+	rule := scriptRule("greet", "echo hello {{name}}")
+
+	result, err := claudeExecutor{d: d}.Execute(context.Background(), rule, trigger.Event{
+		Data: map[string]any{"name": "world; false"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.State != "success" || strings.TrimSpace(result.Output) != "hello world; false" {
+		t.Errorf("result = %+v, want the quoted event data echoed", result)
+	}
+
+	rule.DryRun = true
+	result, err = claudeExecutor{d: d}.Execute(context.Background(), rule, trigger.Event{
+		Data: map[string]any{"name": "world"},
+	})
+	if err != nil || result.Output != "" || len(result.PlannedOps) != 1 || !strings.HasPrefix(result.PlannedOps[0].Command, "echo hello") {
+		t.Errorf("dry run = %+v, %v, want the script planned, not run", result, err)
+	}
+}
@@ -0,0 +1,180 @@
+// internal/daemon/feed.go
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/state"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// feedBuffer is how many values a slow stream client may fall behind by
+// before values are dropped for it.
+const feedBuffer = 64
+
+// feedKeepalive is how often an idle stream sends a comment, so clients and
+// proxies don't time the connection out.
+var feedKeepalive = 15 * time.Second
+
+// feed fans values out to stream clients without blocking the publisher.
+// The zero value has no subscribers.
+type feed[T any] struct {
+	mu   sync.Mutex
+	subs map[chan T]struct{}
+}
+
+// subscribe returns a channel receiving each value published from now on,
+// and a function that unsubscribes it.
+func (f *feed[T]) subscribe() (<-chan T, func()) {
+	ch := make(chan T, feedBuffer)
+	f.mu.Lock()
+	if f.subs == nil {
+		f.subs = make(map[chan T]struct{})
+	}
+	f.subs[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch, func() {
+		f.mu.Lock()
+		delete(f.subs, ch)
+		f.mu.Unlock()
+	}
+}
+
+// publish sends v to every subscriber without blocking; a subscriber whose
+// buffer is full misses it.
+func (f *feed[T]) publish(v T) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- v:
+		default:
+		}
+	}
+}
+
+// serveFeed streams the values published to f that keep accepts as
+// server-sent events, one JSON value per data line, until the client
+// disconnects or ctx (the daemon's) is done.
+func serveFeed[T any](ctx context.Context, w http.ResponseWriter, r *http.Request, f *feed[T], keep func(T) bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	values, unsubscribe := f.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(feedKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case v := <-values:
+			if !keep(v) {
+				continue
+			}
+			data, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-ctx.Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// handleAPIHistoryStream streams new history records (as in /api/history).
+// The optional rule parameter limits it to one rule.
+func (d *Daemon) handleAPIHistoryStream(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ruleName := r.URL.Query().Get("rule")
+		serveFeed(ctx, w, r, &d.historyFeed, func(rec state.ExecutionRecord) bool {
+			return ruleName == "" || rec.RuleName == ruleName
+		})
+	}
+}
+
+// Kinds of ExecutionEvent.
+const (
+	execStarted  = "started"
+	execFinished = "finished"
+	execSkipped  = "skipped"
+)
+
+// ExecutionEvent is one step of an execution's lifecycle, as streamed by
+// /api/events. Finished and skipped events are sent once the execution is
+// in the history, and carry its ID.
+type ExecutionEvent struct {
+	Type         string    `json:"type"` // started, finished or skipped
+	Rule         string    `json:"rule"`
+	TriggerType  string    `json:"trigger_type"`
+	Time         time.Time `json:"time"`
+	ExecutionID  int64     `json:"execution_id,omitempty"`
+	RetryAttempt int       `json:"retry_attempt,omitempty"`
+	State        string    `json:"state,omitempty"`  // finished: success, failure, timeout or cancelled
+	Reason       string    `json:"reason,omitempty"` // skipped: why, as in history's skip_reason
+	Detail       string    `json:"detail,omitempty"` // the error, or more on the skip
+}
+
+// handleAPIEvents streams execution lifecycle events. The optional rule
+// parameter limits it to one rule.
+func (d *Daemon) handleAPIEvents(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ruleName := r.URL.Query().Get("rule")
+		serveFeed(ctx, w, r, &d.execFeed, func(ev ExecutionEvent) bool {
+			return ruleName == "" || ev.Rule == ruleName
+		})
+	}
+}
+
+// publishStarted announces that rule is about to execute for event, as retry
+// attempt number attempt (0 for the original run).
+func (d *Daemon) publishStarted(rule *config.Rule, event trigger.Event, attempt int) {
+	d.execFeed.publish(ExecutionEvent{Type: execStarted, Rule: rule.Name, TriggerType: event.Type, Time: time.Now(), RetryAttempt: attempt})
+}
+
+// publishRecord sends a newly recorded execution to the history stream and,
+// unless it marks a circuit breaker opening, to the events stream.
+func (d *Daemon) publishRecord(rec state.ExecutionRecord) {
+	d.historyFeed.publish(rec)
+
+	ev := ExecutionEvent{
+		Rule:         rec.RuleName,
+		TriggerType:  rec.TriggerType,
+		Time:         rec.FinishedAt,
+		ExecutionID:  rec.ID,
+		RetryAttempt: rec.RetryAttempt,
+	}
+	switch rec.State {
+	case state.StateCircuitOpen:
+		return
+	case state.StateSkipped:
+		ev.Type, ev.Reason = execSkipped, rec.SkipReason
+	default:
+		ev.Type, ev.State = execFinished, rec.State
+	}
+	ev.Detail = rec.Error
+	d.execFeed.publish(ev)
+}
@@ -0,0 +1,191 @@
+// internal/daemon/feed_test.go
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/executor"
+	"github.com/colebrumley/srvrmgr/internal/state"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// streamLines returns the lines of an event stream body as they arrive.
+func streamLines(resp *http.Response) <-chan string {
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	return lines
+}
+
+// nextData waits for the next data line and decodes it into v.
+func nextData(t *testing.T, lines <-chan string, v any) {
+	t.Helper()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream ended")
+			}
+			data, isData := strings.CutPrefix(line, "data: ")
+			if !isData {
+				continue
+			}
+			if err := json.Unmarshal([]byte(data), v); err != nil {
+				t.Fatalf("decoding %q: %v", data, err)
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for a streamed value")
+		}
+	}
+}
+
+func TestHandleAPIHistoryStream(t *testing.T) {
+	d := newTestDaemon(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := httptest.NewServer(d.newMux(ctx))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/history/stream?rule=backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, Content-Type %q; want an event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// The headers arrive once the handler has subscribed
+	d.recordExecution(&config.Rule{Name: "cleanup"}, manualEvent("cleanup"), "success", time.Now(), "", "")
+	id := d.recordExecution(&config.Rule{Name: "backup"}, manualEvent("backup"), "failure", time.Now(), "partial", "disk full")
+
+	lines := streamLines(resp)
+	var rec state.ExecutionRecord
+	nextData(t, lines, &rec)
+	if rec.ID != id || rec.RuleName != "backup" || rec.State != "failure" || rec.Error != "disk full" || rec.Output != "partial" {
+		t.Errorf("streamed record = %+v, want backup's failure %d", rec, id)
+	}
+
+	// Stopping the daemon ends the stream
+	cancel()
+	for range lines {
+	}
+}
+
+func TestHistoryFeed_SlowSubscriberDoesNotBlock(t *testing.T) {
+	var f feed[state.ExecutionRecord]
+	_, unsubscribe := f.subscribe()
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < feedBuffer*2; i++ {
+			f.publish(state.ExecutionRecord{ID: int64(i)})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publish blocked on a subscriber that isn't reading")
+	}
+}
+
+func TestHandleAPIEvents(t *testing.T) {
+	d := newTestDaemon(t, scriptRule("backup", "true"), scriptRule("cleanup", "true"))
+	d.executor = &fakeExecutor{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := httptest.NewServer(d.newMux(ctx))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/events?rule=backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, Content-Type %q; want an event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	d.handleEvent(ctx, trigger.Event{RuleName: "cleanup", Type: "manual", Timestamp: time.Now()})
+	d.handleEvent(ctx, trigger.Event{RuleName: "backup", Type: "manual", Timestamp: time.Now()})
+	d.recordSkip(d.rules["backup"], manualEvent("backup"), state.SkipPaused, "paused via API")
+
+	lines := streamLines(resp)
+	var started, finished, skipped ExecutionEvent
+	nextData(t, lines, &started)
+	nextData(t, lines, &finished)
+	nextData(t, lines, &skipped)
+
+	if started.Type != execStarted || started.Rule != "backup" || started.TriggerType != "manual" {
+		t.Errorf("first event = %+v, want backup started", started)
+	}
+	if finished.Type != execFinished || finished.Rule != "backup" || finished.State != "success" || finished.ExecutionID == 0 {
+		t.Errorf("second event = %+v, want backup finished with success and an ID", finished)
+	}
+	if skipped.Type != execSkipped || skipped.Reason != state.SkipPaused || skipped.Detail != "paused via API" {
+		t.Errorf("third event = %+v, want backup skipped while paused", skipped)
+	}
+
+	// Closing the client unsubscribes it
+	resp.Body.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d.execFeed.mu.Lock()
+		n := len(d.execFeed.subs)
+		d.execFeed.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber not removed after the client disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExecFeed_RetryAttempts(t *testing.T) {
+	defer func(u time.Duration) { retryDelayUnit = u }(retryDelayUnit)
+	retryDelayUnit = time.Millisecond
+
+	rule := scriptRule("flaky", "true")
+	rule.OnFailure = config.OnFailure{Retry: true, RetryAttempts: 2, RetryDelaySeconds: 1}
+	d := newTestDaemon(t, rule)
+	d.executor = &fakeExecutor{respond: func(n int, _ *config.Rule) (*executor.Result, error) {
+		if n == 0 {
+			return &executor.Result{State: "failure", Error: "exit status 1"}, nil
+		}
+		return &executor.Result{State: "success"}, nil
+	}}
+	events, unsubscribe := d.execFeed.subscribe()
+	defer unsubscribe()
+
+	d.handleEvent(context.Background(), manualEvent("flaky"))
+
+	var got []string
+	for len(events) > 0 {
+		ev := <-events
+		got = append(got, fmt.Sprintf("%s %d", ev.Type, ev.RetryAttempt))
+	}
+	want := []string{"started 0", "finished 0", "started 1", "finished 1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+// internal/daemon/gates.go
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/condition"
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/state"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// Gate is the result of one pre-execution check. handleEvent stops at the
+// first gate that fails; --explain reports every gate.
+type Gate struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason"` // skip reason code recorded in history (state.Skip*)
+	Detail string `json:"detail"`
+}
+
+// Gate names, in evaluation order.
+const (
+	gateEnabled      = "enabled"
+	gateAllowlist    = "allowlist"
+	gatePaused       = "paused"
+	gateDependencies = "dependencies"
+	gateCondition    = "condition"
+)
+
+// checkGates runs the pre-execution checks for rule against event. When all is
+// false it returns as soon as a gate fails, so later (possibly costly) gates
+// such as the when condition are not evaluated.
+func (d *Daemon) checkGates(rule *config.Rule, event trigger.Event, all bool) []Gate {
+	checks := []func(*config.Rule, trigger.Event) Gate{
+		d.gateEnabled,
+		d.gateAllowlist,
+		d.gatePaused,
+		d.gateDependencies,
+		d.gateCondition,
+	}
+
+	var gates []Gate
+	for _, check := range checks {
+		g := check(rule, event)
+		gates = append(gates, g)
+		if !g.Passed && !all {
+			break
+		}
+	}
+	return gates
+}
+
+// firstFailedGate returns the first failing gate, if any.
+func firstFailedGate(gates []Gate) (Gate, bool) {
+	for _, g := range gates {
+		if !g.Passed {
+			return g, true
+		}
+	}
+	return Gate{}, false
+}
+
+// gateEnabled mirrors trigger setup: a disabled rule's trigger is never started
+// and triggers_rules chains don't fire it, but manual runs still execute it.
+func (d *Daemon) gateEnabled(rule *config.Rule, event trigger.Event) Gate {
+	g := Gate{Name: gateEnabled, Reason: state.SkipDisabled, Passed: true, Detail: "rule is enabled"}
+	if !rule.Enabled {
+		switch event.Type {
+		case "manual":
+			g.Detail = "rule is disabled, but manual runs still execute"
+		default:
+			g.Passed = false
+			g.Detail = "rule is disabled; its trigger is not started"
+		}
+	}
+	return g
+}
+
+// gateAllowlist applies the FR-15 run_as_user allowlist.
+func (d *Daemon) gateAllowlist(rule *config.Rule, _ trigger.Event) Gate {
+	g := Gate{Name: gateAllowlist, Reason: state.SkipAllowlist, Passed: true}
+	switch {
+	case rule.RunAsUser == "":
+		g.Detail = "no run_as_user set"
+	case d.userMode:
+		g.Passed = false
+		g.Detail = fmt.Sprintf("run_as_user %q needs a root daemon; this one runs as the current user", rule.RunAsUser)
+	case d.runAsUserAllowed(rule):
+		g.Detail = fmt.Sprintf("run_as_user %q is allowed", rule.RunAsUser)
+	default:
+		g.Passed = false
+		g.Detail = fmt.Sprintf("run_as_user %q is not in allowed_run_as_users", rule.RunAsUser)
+	}
+	return g
+}
+
+// gatePaused applies the global kill switch.
+func (d *Daemon) gatePaused(_ *config.Rule, _ trigger.Event) Gate {
+	g := Gate{Name: gatePaused, Reason: state.SkipPaused, Passed: true, Detail: "executions are not paused"}
+	if d.isPaused() {
+		g.Passed = false
+		g.Detail = "executions are paused"
+	}
+	return g
+}
+
+// gateDependencies applies depends_on_rules (see checkDependencies). An
+// event with NoDeps passes whatever the dependencies' state.
+func (d *Daemon) gateDependencies(rule *config.Rule, event trigger.Event) Gate {
+	ok, reason, detail := d.checkDependencies(rule, time.Now())
+	g := Gate{Name: gateDependencies, Reason: state.SkipDependency, Passed: ok, Detail: detail}
+	if !ok {
+		g.Reason = reason
+		if event.NoDeps {
+			g.Passed = true
+			g.Detail = "bypassed by --no-deps: " + detail
+		}
+	}
+	return g
+}
+
+// gateCondition evaluates the rule's when expression against the event data.
+func (d *Daemon) gateCondition(rule *config.Rule, event trigger.Event) Gate {
+	g := Gate{Name: gateCondition, Reason: state.SkipCondition, Passed: true}
+	if rule.When == "" {
+		g.Detail = "no when condition"
+		return g
+	}
+	ok, err := condition.Evaluate(rule.When, event.Data)
+	switch {
+	case err != nil:
+		g.Passed = false
+		g.Detail = fmt.Sprintf("condition error: %v", err)
+	case !ok:
+		g.Passed = false
+		g.Detail = fmt.Sprintf("condition is false: %s", rule.When)
+	default:
+		g.Detail = fmt.Sprintf("condition is true: %s", rule.When)
+	}
+	return g
+}
@@ -0,0 +1,396 @@
+// internal/daemon/gates_test.go
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/state"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+func manualEvent(rule string) trigger.Event {
+	e := trigger.Event{RuleName: rule, Type: "manual", Timestamp: time.Now()}
+	injectEventDefaults(&e)
+	return e
+}
+
+// assertSkippedBy checks that gate name is the first failure and its detail mentions want.
+func assertSkippedBy(t *testing.T, gates []Gate, name, want string) {
+	t.Helper()
+	g, failed := firstFailedGate(gates)
+	if !failed {
+		t.Fatalf("expected gate %q to fail, all passed: %+v", name, gates)
+	}
+	if g.Name != name {
+		t.Fatalf("first failed gate = %q (%s), want %q", g.Name, g.Detail, name)
+	}
+	if !strings.Contains(g.Detail, want) {
+		t.Errorf("gate %q detail = %q, want containing %q", name, g.Detail, want)
+	}
+}
+
+func TestCheckGates_AllPass(t *testing.T) {
+	rule := scriptRule("ok", "true")
+	d := newTestDaemon(t, rule)
+
+	gates := d.checkGates(rule, manualEvent("ok"), true)
+	if len(gates) != 5 {
+		t.Fatalf("expected 5 gates, got %d", len(gates))
+	}
+	if g, failed := firstFailedGate(gates); failed {
+		t.Errorf("unexpected failing gate %+v", g)
+	}
+}
+
+func TestCheckGates_Disabled(t *testing.T) {
+	rule := scriptRule("off", "true")
+	rule.Enabled = false
+	d := newTestDaemon(t, rule)
+
+	// Manual runs of disabled rules still execute
+	if _, failed := firstFailedGate(d.checkGates(rule, manualEvent("off"), true)); failed {
+		t.Error("manual run of a disabled rule should pass the enabled gate")
+	}
+
+	event := trigger.Event{RuleName: "off", Type: "scheduled", Timestamp: time.Now()}
+	assertSkippedBy(t, d.checkGates(rule, event, true), gateEnabled, "disabled")
+}
+
+// An event that reaches a disabled rule is dropped without a history record;
+// its trigger isn't running, so there is nothing to explain there.
+func TestHandleEvent_DisabledNotRecorded(t *testing.T) {
+	rule := scriptRule("off", "true")
+	rule.Enabled = false
+	d := newTestDaemon(t, rule)
+
+	if out := d.handleEvent(context.Background(), trigger.Event{RuleName: "off", Type: "scheduled", Timestamp: time.Now()}); out.State != state.StateSkipped {
+		t.Errorf("outcome = %+v, want skipped", out)
+	}
+	if records, _ := d.stateDB.GetHistory("off", "", nil, 10); len(records) != 0 {
+		t.Errorf("records = %+v, want none", records)
+	}
+}
+
+func TestCheckGates_Allowlist(t *testing.T) {
+	rule := scriptRule("as-bob", "true")
+	rule.RunAsUser = "bob"
+	d := newTestDaemon(t, rule)
+	d.config().Daemon.AllowedRunAsUsers = []string{"alice"}
+
+	assertSkippedBy(t, d.checkGates(rule, manualEvent("as-bob"), true), gateAllowlist, `"bob" is not in allowed_run_as_users`)
+}
+
+func TestCheckGates_UserModeRejectsRunAsUser(t *testing.T) {
+	rule := scriptRule("as-bob", "true")
+	rule.RunAsUser = "bob"
+	d := newTestDaemon(t, rule)
+	d.userMode = true
+
+	assertSkippedBy(t, d.checkGates(rule, manualEvent("as-bob"), true), gateAllowlist, "needs a root daemon")
+}
+
+func TestLoadRules_UserModeSkipsRunAsUser(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "bob.yaml"), []byte(`name: as-bob
+enabled: true
+run_as_user: bob
+trigger:
+  type: manual
+action:
+  script: "true"
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "mine.yaml"), []byte(`name: mine
+enabled: true
+trigger:
+  type: manual
+action:
+  script: "true"
+`), 0644)
+
+	d := New(filepath.Join(dir, "config.yaml"), dir)
+	d.SetUserMode(filepath.Join(dir, "state.db"), filepath.Join(dir, "logs"))
+	if err := d.loadRules(); err != nil {
+		t.Fatalf("loadRules() error = %v", err)
+	}
+	if _, ok := d.rules["as-bob"]; ok {
+		t.Error("run_as_user rule should be rejected in user mode")
+	}
+	if _, ok := d.rules["mine"]; !ok {
+		t.Error("rule without run_as_user should load in user mode")
+	}
+}
+
+func TestCheckGates_Paused(t *testing.T) {
+	rule := scriptRule("job", "true")
+	d := newTestDaemon(t, rule)
+	d.setPaused(true)
+
+	assertSkippedBy(t, d.checkGates(rule, manualEvent("job"), true), gatePaused, "paused")
+}
+
+func TestCheckGates_Dependencies(t *testing.T) {
+	rule := scriptRule("child", "true")
+	rule.DependsOn = []string{"parent"}
+	d := newTestDaemon(t, rule)
+
+	assertSkippedBy(t, d.checkGates(rule, manualEvent("child"), true), gateDependencies, `"parent" has not run yet`)
+
+	d.recordExecutionState("parent", "failure")
+	assertSkippedBy(t, d.checkGates(rule, manualEvent("child"), true), gateDependencies, `"parent" last finished with failure`)
+
+	d.recordExecutionState("parent", "success")
+	if g, failed := firstFailedGate(d.checkGates(rule, manualEvent("child"), true)); failed {
+		t.Errorf("unexpected failing gate after dependency succeeded: %+v", g)
+	}
+}
+
+func TestHandleEvent_NoDepsRunsDespiteUnmetDependencies(t *testing.T) {
+	rule := scriptRule("child", "echo ran")
+	rule.DependsOn = []string{"parent"}
+	d := newTestDaemon(t, rule)
+
+	if got := d.handleEvent(context.Background(), manualEvent("child")); got.State != state.StateSkipped {
+		t.Fatalf("without --no-deps: outcome %+v, want skipped", got)
+	}
+
+	event := manualEvent("child")
+	event.NoDeps = true
+	if got := d.handleEvent(context.Background(), event); got.State != "success" {
+		t.Errorf("with --no-deps: outcome %+v, want success", got)
+	}
+	if got, want := historyStates(t, d, "child"), []string{state.StateSkipped, "success"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+
+	for _, g := range d.checkGates(rule, event, true) {
+		if g.Name == gateDependencies && (!g.Passed || !strings.Contains(g.Detail, "bypassed")) {
+			t.Errorf("dependency gate = %+v, want passed as bypassed", g)
+		}
+	}
+}
+
+func TestCheckGates_DependencyMaxAge(t *testing.T) {
+	rule := scriptRule("report", "true")
+	rule.DependsOn = []string{"fetch", "index"}
+	rule.DependsOnMaxAge = "6h"
+	d := newTestDaemon(t, rule)
+
+	// fetch succeeded two days ago, index an hour ago; both were recorded
+	// before the daemon started, so the times come from the state DB.
+	now := time.Now()
+	for name, at := range map[string]time.Time{"fetch": now.Add(-48 * time.Hour), "index": now.Add(-time.Hour)} {
+		d.stateDB.RecordExecution(state.ExecutionRecord{RuleName: name, TriggerType: "scheduled", State: "success", StartedAt: at, FinishedAt: at})
+	}
+	d.initLastRunStateFromDB()
+
+	gates := d.checkGates(rule, manualEvent("report"), true)
+	assertSkippedBy(t, gates, gateDependencies, `"fetch" last succeeded 48h0m`)
+	if g, _ := firstFailedGate(gates); g.Reason != state.SkipDependencyStale {
+		t.Errorf("reason = %q, want %q", g.Reason, state.SkipDependencyStale)
+	}
+
+	// A fresh success satisfies the window
+	d.recordExecutionState("fetch", "success")
+	if g, failed := firstFailedGate(d.checkGates(rule, manualEvent("report"), true)); failed {
+		t.Errorf("unexpected failing gate with fresh dependencies: %+v", g)
+	}
+
+	// Without a window the old success is enough
+	rule.DependsOnMaxAge = ""
+	d.lastSuccess["fetch"] = now.Add(-48 * time.Hour)
+	if g, failed := firstFailedGate(d.checkGates(rule, manualEvent("report"), true)); failed {
+		t.Errorf("unexpected failing gate without depends_on_max_age: %+v", g)
+	}
+}
+
+func TestCheckDependencies_Modes(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		mode       string
+		maxAge     string
+		states     map[string]string // dependency -> last state; absent means never ran
+		wantOK     bool
+		wantReason string
+		wantDetail string
+	}{
+		{"all, all succeeded", "", "", map[string]string{"a": "success", "b": "success"}, true, "", "all dependencies succeeded"},
+		{"all, one failed", config.DependsOnAll, "", map[string]string{"a": "success", "b": "failure"}, false, state.SkipDependency, `"b" last finished with failure`},
+		{"all, one never ran", "", "", map[string]string{"a": "success"}, false, state.SkipDependency, `"b" has not run yet`},
+		{"any, one succeeded", config.DependsOnAny, "", map[string]string{"a": "failure", "b": "success"}, true, "", `"b" succeeded`},
+		{"any, none succeeded", config.DependsOnAny, "", map[string]string{"a": "failure"}, false, state.SkipDependency, `"a" last finished with failure; dependency "b" has not run yet`},
+		{"any, only success is stale", config.DependsOnAny, "1h", map[string]string{"a": "success", "b": "timeout"}, false, state.SkipDependencyStale, `"a" last succeeded`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := scriptRule("fan-in", "true")
+			rule.DependsOn = []string{"a", "b"}
+			rule.DependsOnMode = tt.mode
+			rule.DependsOnMaxAge = tt.maxAge
+			d := newTestDaemon(t, rule)
+			for dep, st := range tt.states {
+				d.lastRunState[dep] = st
+				if st == "success" {
+					d.lastSuccess[dep] = now.Add(-2 * time.Hour)
+				}
+			}
+
+			ok, reason, detail := d.checkDependencies(rule, now)
+			if ok != tt.wantOK || reason != tt.wantReason || !strings.Contains(detail, tt.wantDetail) {
+				t.Errorf("checkDependencies() = %v, %q, %q; want %v, %q, containing %q",
+					ok, reason, detail, tt.wantOK, tt.wantReason, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestHandleEvent_StaleDependencyRecordsSkip(t *testing.T) {
+	rule := scriptRule("report", "true")
+	rule.Enabled = true
+	rule.DependsOn = []string{"fetch"}
+	rule.DependsOnMaxAge = "1h"
+	d := newTestDaemon(t, rule)
+	d.recordExecutionState("fetch", "success")
+	d.lastSuccess["fetch"] = time.Now().Add(-2 * time.Hour)
+
+	d.handleEvent(context.Background(), manualEvent("report"))
+
+	records := historyFor(t, d, "report")
+	if len(records) != 1 || records[0].State != state.StateSkipped || records[0].SkipReason != state.SkipDependencyStale {
+		t.Fatalf("history = %+v, want one dependency_stale skip", records)
+	}
+}
+
+func TestCheckGates_Condition(t *testing.T) {
+	rule := scriptRule("guarded", "true")
+	rule.When = `event_type == "webhook"`
+	d := newTestDaemon(t, rule)
+
+	assertSkippedBy(t, d.checkGates(rule, manualEvent("guarded"), true), gateCondition, "condition is false")
+}
+
+func TestCheckGates_StopsAtFirstFailure(t *testing.T) {
+	rule := scriptRule("child", "true")
+	rule.DependsOn = []string{"parent"}
+	rule.When = `bogus && true`
+	d := newTestDaemon(t, rule)
+
+	gates := d.checkGates(rule, manualEvent("child"), false)
+	if last := gates[len(gates)-1]; last.Name != gateDependencies || last.Passed {
+		t.Errorf("expected evaluation to stop at dependencies, last gate = %+v", last)
+	}
+
+	// Explain mode reports every gate, including the later condition error
+	all := d.checkGates(rule, manualEvent("child"), true)
+	if last := all[len(all)-1]; last.Name != gateCondition || !strings.Contains(last.Detail, "condition error") {
+		t.Errorf("expected condition error in explain mode, got %+v", last)
+	}
+}
+
+func TestExplainRule_FindsAllowlistDroppedRule(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	rulesDir := filepath.Join(dir, "rules")
+	os.MkdirAll(rulesDir, 0755)
+	os.WriteFile(configPath, []byte("daemon:\n  allowed_run_as_users: [alice]\n"), 0644)
+	os.WriteFile(filepath.Join(rulesDir, "bob.yaml"), []byte(`name: as-bob
+enabled: true
+run_as_user: bob
+trigger:
+  type: manual
+action:
+  script: "true"
+`), 0644)
+
+	d := New(configPath, rulesDir)
+	gates, err := d.ExplainRule("as-bob", "", nil)
+	if err != nil {
+		t.Fatalf("ExplainRule() error = %v", err)
+	}
+	assertSkippedBy(t, gates, gateAllowlist, "bob")
+
+	if _, err := d.ExplainRule("missing", "", nil); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+}
+
+func TestHandleEvent_RecordsSkipReason(t *testing.T) {
+	rule := scriptRule("child", "echo ran")
+	rule.DependsOn = []string{"parent"}
+	d := newTestDaemon(t, rule)
+
+	d.handleEvent(context.Background(), manualEvent("child"))
+
+	records, err := d.stateDB.GetHistory("child", "skipped:dependency", nil, 10)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected one skipped:dependency record, got %+v", records)
+	}
+	if !strings.Contains(records[0].Error, `"parent" has not run yet`) {
+		t.Errorf("Error = %q, want the gate detail", records[0].Error)
+	}
+	if _, ok := d.lastRunState["child"]; ok {
+		t.Error("skipped execution should not update lastRunState")
+	}
+}
+
+func TestFireTriggeredRules_DroppedWhenChannelFull(t *testing.T) {
+	parent := scriptRule("parent", "true")
+	parent.Triggers = []string{"child"}
+	d := newTestDaemon(t, parent, scriptRule("child", "true"))
+	d.events = make(chan trigger.Event) // unbuffered and unread: always full
+
+	d.fireTriggeredRules(context.Background(), parent, manualEvent("parent"), "", nil)
+
+	records, _ := d.stateDB.GetHistory("", "skipped", nil, 10)
+	if len(records) != 1 || records[0].RuleName != "child" || records[0].SkipReason != "dropped" {
+		t.Fatalf("expected one dropped record for child, got %+v", records)
+	}
+	if records[0].TriggerType != "triggered" {
+		t.Errorf("TriggerType = %q, want triggered", records[0].TriggerType)
+	}
+}
+
+func TestTriggerDrop_RecordedInHistory(t *testing.T) {
+	rule := &config.Rule{
+		Name:    "on-start",
+		Enabled: true,
+		Trigger: config.Trigger{Type: "lifecycle", OnEvents: []string{"daemon_started"}},
+		Action:  config.Action{Script: "true"},
+	}
+	d := newTestDaemon(t, rule)
+	d.triggers = make(map[string]trigger.Trigger)
+	d.events = make(chan trigger.Event, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.startTrigger(ctx, rule); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first event fills the channel, the next two overflow it
+	for range 3 {
+		d.fireLifecycleEvent("daemon_started")
+	}
+
+	var records []state.ExecutionRecord
+	deadline := time.Now().Add(2 * time.Second)
+	for len(records) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		records, _ = d.stateDB.GetHistory("on-start", "skipped:dropped", nil, 10)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected two dropped records, got %+v", records)
+	}
+	if records[0].TriggerType != "daemon_started" || records[0].Error != "event channel full" {
+		t.Errorf("record = %+v, want the lifecycle event type and reason", records[0])
+	}
+}
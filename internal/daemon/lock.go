@@ -0,0 +1,56 @@
+// internal/daemon/lock.go
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// lockFileName is the file srvrmgrd holds locked in its config directory while
+// it runs, so a second daemon on the same config (say, a manual launch next
+// to launchd's) can't share its databases, port and logs.
+const lockFileName = "srvrmgrd.lock"
+
+// ErrAlreadyRunning is returned by Run when another daemon holds the lock on
+// the same config directory.
+var ErrAlreadyRunning = errors.New("another srvrmgrd is already running on this config")
+
+// errLocked is returned by lockFile when another process holds the lock.
+var errLocked = errors.New("lock held")
+
+// acquireLock locks the daemon lock file in dir and writes our PID to it.
+// Closing the returned file releases the lock, as does the process exiting,
+// so a lock left by a crashed daemon never blocks a restart.
+func acquireLock(dir string) (*os.File, error) {
+	path := filepath.Join(dir, lockFileName)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		holder := lockHolder(f)
+		f.Close()
+		if errors.Is(err, errLocked) {
+			return nil, fmt.Errorf("%w (pid %s holds %s); stop it first", ErrAlreadyRunning, holder, path)
+		}
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return f, nil
+}
+
+// lockHolder returns the PID recorded in a lock file, or "unknown".
+func lockHolder(f *os.File) string {
+	buf := make([]byte, 32)
+	n, _ := f.ReadAt(buf, 0)
+	if pid := strings.TrimSpace(string(buf[:n])); pid != "" {
+		return pid
+	}
+	return "unknown"
+}
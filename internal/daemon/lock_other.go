@@ -0,0 +1,11 @@
+//go:build !unix
+
+package daemon
+
+import "os"
+
+// lockFile is a no-op where flock isn't available; a second daemon isn't
+// detected.
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_RefusesSecondDaemon(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("daemon:\n  log_level: info\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	held, err := acquireLock(dir)
+	if err != nil {
+		t.Fatalf("acquireLock() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	err = New(configPath, filepath.Join(dir, "rules")).Run(ctx)
+	if !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("Run() error = %v, want ErrAlreadyRunning", err)
+	}
+	if !strings.Contains(err.Error(), "pid "+strconv.Itoa(os.Getpid())) {
+		t.Errorf("Run() error = %q, want the holder's pid", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run() took %v to refuse, want it to fail fast", elapsed)
+	}
+
+	// Released on close, e.g. when the first daemon shuts down
+	held.Close()
+	again, err := acquireLock(dir)
+	if err != nil {
+		t.Fatalf("acquireLock() after release error = %v", err)
+	}
+	again.Close()
+}
@@ -0,0 +1,67 @@
+// internal/daemon/logs.go
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/colebrumley/srvrmgr/internal/logging"
+)
+
+// Line limits for /api/logs.
+const (
+	defaultLogLines = 50
+	maxLogLines     = 5000
+)
+
+// handleAPILogs returns the last matching lines of the daemon log as plain
+// text. Filtering happens here so clients don't have to pull the whole log.
+// Query parameters: grep (regex or substring), i (case-insensitive grep),
+// level (minimum level), rule, and lines. The log names rules, paths and
+// errors, so only local clients may read it.
+func (d *Daemon) handleAPILogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireLocal(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	ignoreCase, _ := strconv.ParseBool(q.Get("i"))
+	filter, err := logging.NewLineFilter(q.Get("grep"), q.Get("level"), ignoreCase)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rule := q.Get("rule"); rule != "" {
+		filter.SetRule(rule)
+	}
+
+	lines := defaultLogLines
+	if l := q.Get("lines"); l != "" {
+		if lines, err = strconv.Atoi(l); err != nil || lines <= 0 {
+			http.Error(w, fmt.Sprintf("invalid lines %q", l), http.StatusBadRequest)
+			return
+		}
+	}
+	if lines > maxLogLines {
+		lines = maxLogLines
+	}
+
+	f, err := os.Open(filepath.Join(d.logDir, "srvrmgrd.log"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("opening log: %v", err), http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := filter.Filter(f, w, lines); err != nil {
+		d.logger.Warn("filtering log for API", "error", err)
+	}
+}
@@ -0,0 +1,101 @@
+// internal/daemon/logs_test.go
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleDaemonLog = `time=2026-01-01T00:00:00Z level=INFO msg="handling event" rule=backup type=scheduled
+time=2026-01-01T00:00:01Z level=DEBUG msg="template expanded" rule=backup
+time=2026-01-01T00:00:02Z level=WARN msg="skipping rule" rule=cleanup reason=paused
+time=2026-01-01T00:00:03Z level=ERROR msg="Execution error" rule=backup error="exit status 1"
+`
+
+func logsDaemon(t *testing.T) *Daemon {
+	t.Helper()
+	d := newTestDaemon(t)
+	d.logDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(d.logDir, "srvrmgrd.log"), []byte(sampleDaemonLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func getLogs(d *Daemon, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	d.handleAPILogs(rec, localRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestHandleAPILogs_Filters(t *testing.T) {
+	d := logsDaemon(t)
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"/api/logs", []string{"handling event", "template expanded", "skipping rule", "Execution error"}},
+		{"/api/logs?grep=execution", nil},
+		{"/api/logs?grep=execution&i=1", []string{"Execution error"}},
+		{"/api/logs?level=warn", []string{"skipping rule", "Execution error"}},
+		{"/api/logs?rule=backup&level=info", []string{"handling event", "Execution error"}},
+		{"/api/logs?lines=1", []string{"Execution error"}},
+	}
+	for _, tt := range tests {
+		rec := getLogs(d, tt.target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", tt.target, rec.Code, rec.Body.String())
+		}
+		body := strings.TrimSuffix(rec.Body.String(), "\n")
+		var got []string
+		if body != "" {
+			got = strings.Split(body, "\n")
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("GET %s = %d lines, want %d:\n%s", tt.target, len(got), len(tt.want), body)
+			continue
+		}
+		for i, msg := range tt.want {
+			if !strings.Contains(got[i], msg) {
+				t.Errorf("GET %s line %d = %q, want %q", tt.target, i, got[i], msg)
+			}
+		}
+	}
+}
+
+func TestHandleAPILogs_Errors(t *testing.T) {
+	d := logsDaemon(t)
+
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodPost, "/api/logs", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/logs?grep=(", http.StatusBadRequest},
+		{http.MethodGet, "/api/logs?level=loud", http.StatusBadRequest},
+		{http.MethodGet, "/api/logs?lines=0", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		d.handleAPILogs(rec, localRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.target, rec.Code, tt.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	d.handleAPILogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("remote GET /api/logs = %d, want 403", rec.Code)
+	}
+
+	d.logDir = t.TempDir()
+	if rec := getLogs(d, "/api/logs"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing log = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,176 @@
+// internal/daemon/maintenance.go
+package daemon
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// repeatedFailureThreshold is the failure streak at which a rule is reported as failing.
+	repeatedFailureThreshold = 3
+	// staleMissedRuns is how many expected scheduled firings may be missed before a rule is stale.
+	staleMissedRuns = 2
+	// staleGrace absorbs scheduler jitter and slow executions when checking staleness.
+	staleGrace = 5 * time.Minute
+)
+
+// healthDigest lists rules whose execution history needs attention.
+type healthDigest struct {
+	RulesChecked   int
+	NeverSucceeded []string
+	Failing        []string
+	Stale          []string
+}
+
+func (h healthDigest) healthy() bool {
+	return len(h.NeverSucceeded) == 0 && len(h.Failing) == 0 && len(h.Stale) == 0
+}
+
+// startMaintenance periodically applies the NFR-1 history limits and logs a
+// history health digest until ctx is cancelled, so problems surface without an
+// external monitor.
+func (d *Daemon) startMaintenance(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.cleanupHistory()
+			d.logHealthDigest(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// cleanupHistory applies the configured history limits: age (each rule's
+// retention_days, else history_retention_days), then rows per rule, then total
+// rows. A non-positive limit is skipped.
+func (d *Daemon) cleanupHistory() {
+	if d.stateDB == nil {
+		return
+	}
+	d.mu.RLock()
+	cfg := d.config().Daemon
+	ruleRetention := make(map[string]int)
+	for name, rule := range d.rules {
+		if rule.RetentionDays > 0 {
+			ruleRetention[name] = rule.RetentionDays
+		}
+	}
+	d.mu.RUnlock()
+
+	limits := []struct {
+		name    string
+		enabled bool
+		run     func() (int64, error)
+	}{
+		{"retention_days", cfg.HistoryRetentionDays > 0 || len(ruleRetention) > 0, func() (int64, error) {
+			return d.stateDB.Cleanup(cfg.HistoryRetentionDays, ruleRetention)
+		}},
+		{"max_rows_per_rule", cfg.HistoryMaxRowsPerRule > 0, func() (int64, error) {
+			return d.stateDB.CleanupByCount(cfg.HistoryMaxRowsPerRule)
+		}},
+		{"max_rows", cfg.HistoryMaxRows > 0, func() (int64, error) {
+			return d.stateDB.CleanupTotal(cfg.HistoryMaxRows)
+		}},
+	}
+	for _, l := range limits {
+		if !l.enabled {
+			continue
+		}
+		if deleted, err := l.run(); err != nil {
+			d.logger.Warn("state cleanup failed", "limit", l.name, "error", err)
+		} else if deleted > 0 {
+			d.logger.Info("cleaned up execution records", "limit", l.name, "deleted", deleted)
+		}
+	}
+}
+
+// logHealthDigest builds the digest and logs it, warning when any rule needs attention.
+func (d *Daemon) logHealthDigest(now time.Time) {
+	digest := d.buildHealthDigest(now)
+	if digest.healthy() {
+		d.logger.Info("maintenance digest: all rules healthy", "rules_checked", digest.RulesChecked)
+		return
+	}
+	d.logger.Warn("maintenance digest: rules need attention",
+		"rules_checked", digest.RulesChecked,
+		"never_succeeded", digest.NeverSucceeded,
+		"failing", digest.Failing,
+		"stale", digest.Stale,
+	)
+}
+
+// buildHealthDigest inspects the execution history of every enabled rule.
+func (d *Daemon) buildHealthDigest(now time.Time) healthDigest {
+	var digest healthDigest
+	if d.stateDB == nil {
+		return digest
+	}
+
+	d.mu.RLock()
+	rules := make([]string, 0, len(d.rules))
+	schedules := make(map[string]cron.Schedule)
+	for name, rule := range d.rules {
+		if !rule.Enabled {
+			continue
+		}
+		rules = append(rules, name)
+		if rule.Trigger.Type == "scheduled" {
+			if sched, err := trigger.ParseSchedule(rule.Trigger); err == nil {
+				schedules[name] = sched
+			}
+		}
+	}
+	d.mu.RUnlock()
+	sort.Strings(rules)
+
+	for _, name := range rules {
+		act, err := d.stateDB.RuleActivity(name)
+		if err != nil {
+			d.logger.Warn("maintenance: could not read rule activity", "rule", name, "error", err)
+			continue
+		}
+		digest.RulesChecked++
+
+		if act.TotalRuns > 0 && act.LastSuccessAt.IsZero() {
+			digest.NeverSucceeded = append(digest.NeverSucceeded, name)
+		} else if act.ConsecutiveFailures >= repeatedFailureThreshold {
+			digest.Failing = append(digest.Failing, name)
+		}
+
+		if sched, ok := schedules[name]; ok {
+			// A rule that has never run is measured from daemon start.
+			since := act.LastRunAt
+			if since.IsZero() {
+				since = d.startTime
+			}
+			if isStale(sched, since, now) {
+				digest.Stale = append(digest.Stale, name)
+			}
+		}
+	}
+
+	return digest
+}
+
+// isStale reports whether a schedule that last fired at lastRun has missed at
+// least staleMissedRuns expected firings as of now.
+func isStale(sched cron.Schedule, lastRun, now time.Time) bool {
+	expected := lastRun
+	for i := 0; i < staleMissedRuns; i++ {
+		expected = sched.Next(expected)
+		if expected.IsZero() {
+			// Schedule never fires again
+			return false
+		}
+	}
+	return now.After(expected.Add(staleGrace))
+}
@@ -0,0 +1,151 @@
+// internal/daemon/maintenance_test.go
+package daemon
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/state"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+func TestIsStale_HourlySchedule(t *testing.T) {
+	sched, err := trigger.ParseSchedule(config.Trigger{Type: "scheduled", RunEvery: "1h"})
+	if err != nil {
+		t.Fatalf("ParseSchedule() error = %v", err)
+	}
+	lastRun := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	if isStale(sched, lastRun, lastRun.Add(90*time.Minute)) {
+		t.Error("one missed hourly run should not be stale")
+	}
+	if !isStale(sched, lastRun, lastRun.Add(3*time.Hour)) {
+		t.Error("three hours without an hourly run should be stale")
+	}
+}
+
+func TestIsStale_DailySchedule(t *testing.T) {
+	sched, err := trigger.ParseSchedule(config.Trigger{Type: "scheduled", RunAt: "03:00"})
+	if err != nil {
+		t.Fatalf("ParseSchedule() error = %v", err)
+	}
+	lastRun := time.Date(2025, 1, 1, 3, 0, 0, 0, time.Local)
+
+	if isStale(sched, lastRun, lastRun.Add(30*time.Hour)) {
+		t.Error("a daily rule 30h after its last run should not be stale")
+	}
+	if !isStale(sched, lastRun, lastRun.Add(49*time.Hour)) {
+		t.Error("a daily rule 49h after its last run should be stale")
+	}
+}
+
+func TestIsStale_WithinGrace(t *testing.T) {
+	sched, err := trigger.ParseSchedule(config.Trigger{Type: "scheduled", CronExpression: "*/10 * * * *"})
+	if err != nil {
+		t.Fatalf("ParseSchedule() error = %v", err)
+	}
+	lastRun := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// Second expected run is 10:20; grace extends to 10:25
+	if isStale(sched, lastRun, lastRun.Add(24*time.Minute)) {
+		t.Error("run within grace period should not be stale")
+	}
+	if !isStale(sched, lastRun, lastRun.Add(26*time.Minute)) {
+		t.Error("run past grace period should be stale")
+	}
+}
+
+func TestBuildHealthDigest(t *testing.T) {
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	record := func(rule, st string, at time.Time) {
+		db.RecordExecution(state.ExecutionRecord{
+			RuleName: rule, TriggerType: "scheduled", State: st,
+			StartedAt: at, FinishedAt: at, DurationMs: 1,
+		})
+	}
+	record("broken", "failure", now.Add(-3*time.Minute))
+	for i := 0; i < 3; i++ {
+		record("failing", "success", now.Add(-time.Hour))
+		record("failing", "failure", now.Add(time.Duration(i-3)*time.Minute))
+	}
+	record("stale", "success", now.Add(-5*time.Hour))
+	record("healthy", "success", now.Add(-10*time.Minute))
+
+	hourly := config.Trigger{Type: "scheduled", RunEvery: "1h"}
+	d := &Daemon{
+		stateDB:   db,
+		startTime: now.Add(-24 * time.Hour),
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		rules: map[string]*config.Rule{
+			"broken":   {Name: "broken", Enabled: true, Trigger: config.Trigger{Type: "manual"}},
+			"failing":  {Name: "failing", Enabled: true, Trigger: config.Trigger{Type: "manual"}},
+			"stale":    {Name: "stale", Enabled: true, Trigger: hourly},
+			"healthy":  {Name: "healthy", Enabled: true, Trigger: hourly},
+			"disabled": {Name: "disabled", Enabled: false, Trigger: hourly},
+		},
+	}
+
+	digest := d.buildHealthDigest(now)
+	if digest.RulesChecked != 4 {
+		t.Errorf("RulesChecked = %d, want 4", digest.RulesChecked)
+	}
+	if len(digest.NeverSucceeded) != 1 || digest.NeverSucceeded[0] != "broken" {
+		t.Errorf("NeverSucceeded = %v, want [broken]", digest.NeverSucceeded)
+	}
+	if len(digest.Failing) != 1 || digest.Failing[0] != "failing" {
+		t.Errorf("Failing = %v, want [failing]", digest.Failing)
+	}
+	if len(digest.Stale) != 1 || digest.Stale[0] != "stale" {
+		t.Errorf("Stale = %v, want [stale]", digest.Stale)
+	}
+}
+
+func TestCleanupHistory_AppliesLimits(t *testing.T) {
+	d := newTestDaemon(t)
+	d.config().Daemon.HistoryRetentionDays = 30
+	d.config().Daemon.HistoryMaxRowsPerRule = 2
+	d.config().Daemon.HistoryMaxRows = -1
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -40)
+	for _, at := range []time.Time{old, now.Add(-3 * time.Minute), now.Add(-2 * time.Minute), now.Add(-time.Minute)} {
+		d.stateDB.RecordExecution(state.ExecutionRecord{RuleName: "r", TriggerType: "manual", State: "success", StartedAt: at, FinishedAt: at})
+	}
+
+	d.cleanupHistory()
+
+	records, _ := d.stateDB.GetHistory("r", "", nil, 10)
+	if len(records) != 2 || !records[1].StartedAt.Equal(now.Add(-2*time.Minute)) {
+		t.Errorf("kept %+v, want the 2 newest records", records)
+	}
+}
+
+func TestCleanupHistory_PerRuleRetention(t *testing.T) {
+	keep := scriptRule("keep", "true")
+	keep.RetentionDays = 365
+	d := newTestDaemon(t, keep, scriptRule("default", "true"))
+	d.config().Daemon.HistoryRetentionDays = 30
+
+	old := time.Now().AddDate(0, 0, -60)
+	for _, rule := range []string{"keep", "default", "deleted-rule"} {
+		d.stateDB.RecordExecution(state.ExecutionRecord{RuleName: rule, TriggerType: "manual", State: "success", StartedAt: old, FinishedAt: old})
+	}
+
+	d.cleanupHistory()
+
+	for rule, want := range map[string]int{"keep": 1, "default": 0, "deleted-rule": 0} {
+		if got := len(historyFor(t, d, rule)); got != want {
+			t.Errorf("%s: kept %d records, want %d", rule, got, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// internal/daemon/onsuccess.go
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/logging"
+	"github.com/colebrumley/srvrmgr/internal/security"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// onSuccessTimeout bounds the POST to an on_success webhook.
+var onSuccessTimeout = 10 * time.Second
+
+// maxSuccessSummary caps the output excerpt sent to on_success webhooks.
+const maxSuccessSummary = 500
+
+// successNotice is the JSON body POSTed to an on_success webhook. Text is a
+// one-line summary, so it also works as a Slack incoming webhook payload.
+type successNotice struct {
+	Text         string    `json:"text"`
+	Rule         string    `json:"rule"`
+	TriggerType  string    `json:"trigger_type"`
+	ExecutionID  int64     `json:"execution_id,omitempty"`
+	RetryAttempt int       `json:"retry_attempt,omitempty"`
+	DryRun       bool      `json:"dry_run,omitempty"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Summary      string    `json:"summary"`
+}
+
+// notifySuccess POSTs a successful execution of rule to its on_success
+// webhook in the background; see waitNotifications. execID is the execution
+// (the original one, if retry attempt attempt succeeded). Failures are
+// logged, never retried.
+func (d *Daemon) notifySuccess(rule *config.Rule, event trigger.Event, execID int64, attempt int, output string) {
+	hook := rule.OnSuccess.Webhook
+	if hook == "" {
+		return
+	}
+	summary := successSummary(output)
+	notice := successNotice{
+		Text:         fmt.Sprintf("srvrmgr: rule %s succeeded", rule.Name),
+		Rule:         rule.Name,
+		TriggerType:  event.Type,
+		ExecutionID:  execID,
+		RetryAttempt: attempt,
+		DryRun:       rule.DryRun,
+		FinishedAt:   time.Now(),
+		Summary:      summary,
+	}
+	if summary != "" {
+		notice.Text += ": " + strings.SplitN(summary, "\n", 2)[0]
+	}
+
+	logger := logging.WithRule(d.logger, rule.Name)
+	d.notifyWG.Add(1)
+	go func() {
+		defer d.notifyWG.Done()
+		if err := postJSON(hook, notice); err != nil {
+			logger.Warn("on_success webhook failed", "error", err)
+		}
+	}()
+}
+
+// waitNotifications waits for in-flight on_success webhooks, so a CLI run
+// or shutdown doesn't exit before they are sent. Each POST gives up after
+// onSuccessTimeout, and so does the wait.
+func (d *Daemon) waitNotifications() {
+	done := make(chan struct{})
+	go func() {
+		d.notifyWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(onSuccessTimeout):
+		d.logger.Warn("on_success webhooks still pending, not waiting for them")
+	}
+}
+
+// successSummary is the start of output, scrubbed of secrets, for an
+// on_success notice.
+func successSummary(output string) string {
+	summary := strings.TrimSpace(security.ScrubOutput(output))
+	if len(summary) > maxSuccessSummary {
+		summary = summary[:maxSuccessSummary]
+	}
+	return summary
+}
+
+// postJSON POSTs v as JSON to url, failing on a non-2xx response.
+func postJSON(url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), onSuccessTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// internal/daemon/onsuccess_test.go
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+)
+
+// successHook is an on_success webhook receiver that passes each notice on.
+func successHook(t *testing.T) (string, <-chan successNotice) {
+	t.Helper()
+	notices := make(chan successNotice, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n successNotice
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("decoding notice: %v", err)
+		}
+		notices <- n
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, notices
+}
+
+func waitNotice(t *testing.T, notices <-chan successNotice) successNotice {
+	t.Helper()
+	select {
+	case n := <-notices:
+		return n
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for on_success webhook")
+		return successNotice{}
+	}
+}
+
+func TestOnSuccess_PostsNotice(t *testing.T) {
+	url, notices := successHook(t)
+	backup := scriptRule("backup", "echo 'backed up 42 files'; echo 'X-Plex-Token=abc123'")
+	backup.OnSuccess.Webhook = url
+	failing := scriptRule("broken", "exit 1")
+	failing.OnSuccess.Webhook = url
+	d := newTestDaemon(t, backup, failing)
+
+	d.handleEvent(context.Background(), manualEvent("broken"))
+	d.handleEvent(context.Background(), manualEvent("backup"))
+
+	n := waitNotice(t, notices)
+	if n.Rule != "backup" || n.TriggerType != "manual" || n.ExecutionID == 0 || n.RetryAttempt != 0 {
+		t.Errorf("notice = %+v, want backup's manual execution", n)
+	}
+	if n.Text != "srvrmgr: rule backup succeeded: backed up 42 files" {
+		t.Errorf("Text = %q", n.Text)
+	}
+	if !strings.HasPrefix(n.Summary, "backed up 42 files\n") || strings.Contains(n.Summary, "abc123") {
+		t.Errorf("Summary = %q, want scrubbed output", n.Summary)
+	}
+	select {
+	case n := <-notices:
+		t.Errorf("unexpected notice %+v, the failed rule shouldn't notify", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestOnSuccess_PostsAfterRetry(t *testing.T) {
+	defer func(u time.Duration) { retryDelayUnit = u }(retryDelayUnit)
+	retryDelayUnit = time.Millisecond
+
+	url, notices := successHook(t)
+	counter := filepath.Join(t.TempDir(), "count")
+	rule := scriptRule("flaky", `n=$(cat `+counter+` 2>/dev/null || echo 0); n=$((n+1)); echo $n > `+counter+`; [ $n -ge 2 ]`)
+	rule.OnFailure = config.OnFailure{Retry: true, RetryAttempts: 2, RetryDelaySeconds: 1}
+	rule.OnSuccess.Webhook = url
+	d := newTestDaemon(t, rule)
+
+	d.handleEvent(context.Background(), manualEvent("flaky"))
+
+	if n := waitNotice(t, notices); n.Rule != "flaky" || n.RetryAttempt != 1 || n.ExecutionID == 0 {
+		t.Errorf("notice = %+v, want the first retry of flaky's execution", n)
+	}
+}
+
+func TestRunRule_WaitsForOnSuccess(t *testing.T) {
+	var received atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond) // a slow receiver
+		received.Store(true)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	rulesDir := filepath.Join(dir, "rules")
+	if err := os.Mkdir(rulesDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, configPath, "logging:\n  format: text\n")
+	writeTestFile(t, filepath.Join(rulesDir, "backup.yaml"),
+		"name: backup\nenabled: true\naction:\n  script: \"true\"\non_success:\n  webhook: "+srv.URL+"\n")
+
+	// The CLI exits as soon as RunRule returns
+	if err := New(configPath, rulesDir).RunRule(context.Background(), "backup", "", nil, false); err != nil {
+		t.Fatalf("RunRule() error = %v", err)
+	}
+	if !received.Load() {
+		t.Error("RunRule() returned before the on_success webhook was sent")
+	}
+}
@@ -0,0 +1,152 @@
+// internal/daemon/outputparser.go
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/executor"
+	"github.com/colebrumley/srvrmgr/internal/logging"
+	"github.com/colebrumley/srvrmgr/internal/security"
+)
+
+// parseOutput extracts structured data from output with action's
+// output_parser. The data is merged into triggered rules' event data, so it
+// is always an object:
+//   - json: the JSON object in the output, which may be surrounded by other
+//     text; any other JSON value is stored under "result"
+//   - lines: the non-blank lines, trimmed, under "lines"
+//   - regex: the named groups of output_pattern's first match
+//
+// It returns nil if the action has no parser.
+func parseOutput(action config.Action, output string) (map[string]any, error) {
+	switch action.OutputParser {
+	case "":
+		return nil, nil
+	case config.OutputParserJSON:
+		return parseJSONOutput(output)
+	case config.OutputParserLines:
+		lines := []string{}
+		for _, line := range strings.Split(output, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return map[string]any{"lines": lines}, nil
+	case config.OutputParserRegex:
+		re, err := regexp.Compile(action.OutputPattern) // validated when the rule was loaded
+		if err != nil {
+			return nil, err
+		}
+		match := re.FindStringSubmatch(output)
+		if match == nil {
+			return nil, errors.New("output_pattern matched nothing")
+		}
+		data := make(map[string]any)
+		for i, name := range re.SubexpNames() {
+			if name != "" {
+				data[name] = match[i]
+			}
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown output_parser %q", action.OutputParser)
+}
+
+// parseJSONOutput decodes the output as JSON, or failing that the span from
+// its first { or [ to the matching last } or ], for output that wraps the
+// JSON in prose.
+func parseJSONOutput(output string) (map[string]any, error) {
+	candidates := []string{strings.TrimSpace(output)}
+	for _, delims := range []string{"{}", "[]"} {
+		start, end := strings.IndexByte(output, delims[0]), strings.LastIndexByte(output, delims[1])
+		if start >= 0 && end > start {
+			candidates = append(candidates, output[start:end+1])
+		}
+	}
+
+	var firstErr error
+	for _, text := range candidates {
+		var v any
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.UseNumber()
+		err := dec.Decode(&v)
+		if err == nil && dec.More() {
+			err = errors.New("trailing data after JSON value")
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if obj, ok := v.(map[string]any); ok {
+			return obj, nil
+		}
+		return map[string]any{"result": v}, nil
+	}
+	return nil, fmt.Errorf("no JSON in output: %w", firstErr)
+}
+
+// parseResult runs rule's output parser on a successful result. The parsed
+// strings are scrubbed like the output, so secrets don't reach the history
+// or triggered rules' event data through them. A parse failure is logged and
+// yields nil; the execution still succeeded.
+func (d *Daemon) parseResult(rule *config.Rule, result *executor.Result) map[string]any {
+	if result.State != "success" || rule.Action.OutputParser == "" {
+		return nil
+	}
+	parsed, err := parseOutput(rule.Action, result.Output)
+	if err != nil {
+		logging.WithRule(d.logger, rule.Name).Warn("could not parse output", "output_parser", rule.Action.OutputParser, "error", err)
+		return nil
+	}
+	return scrubParsed(parsed).(map[string]any)
+}
+
+// scrubParsed scrubs every string in v, a value decoded by parseOutput.
+// Scrubbing the output before parsing it could break its structure, as the
+// patterns don't stop at JSON delimiters.
+func scrubParsed(v any) any {
+	switch v := v.(type) {
+	case string:
+		return security.ScrubOutput(v)
+	case map[string]any:
+		// JSON keys are output too, so they are scrubbed along with the values
+		scrubbed := make(map[string]any, len(v))
+		for k, elem := range v {
+			scrubbed[security.ScrubOutput(k)] = scrubParsed(elem)
+		}
+		return scrubbed
+	case []any:
+		for i, elem := range v {
+			v[i] = scrubParsed(elem)
+		}
+	case []string:
+		for i, elem := range v {
+			v[i] = security.ScrubOutput(elem)
+		}
+	}
+	return v
+}
+
+// parsedOutputJSON serializes parsed data for the history. Data larger than
+// the output limit is replaced by a marker object rather than cut, which
+// would leave invalid JSON; the output it came from is kept, truncated.
+func (d *Daemon) parsedOutputJSON(parsed map[string]any) string {
+	if parsed == nil {
+		return ""
+	}
+	data, err := json.Marshal(parsed)
+	if err != nil {
+		return ""
+	}
+	if limit := d.ruleExecConfig().OutputLimit(); len(data) > limit {
+		data, _ = json.Marshal(map[string]any{"truncated": true, "size": len(data)})
+	}
+	return string(data)
+}
@@ -0,0 +1,162 @@
+// internal/daemon/outputparser_test.go
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		action config.Action
+		output string
+		want   map[string]any
+	}{
+		{"none", config.Action{}, `{"a":1}`, nil},
+		{
+			"json object",
+			config.Action{OutputParser: "json"},
+			`{"volume": "/Volumes/Media", "used_pct": 91, "ok": false}`,
+			map[string]any{"volume": "/Volumes/Media", "used_pct": json.Number("91"), "ok": false},
+		},
+		{
+			"json in prose",
+			config.Action{OutputParser: "json"},
+			"Checked 3 volumes.\n{\"over\": [\"/Volumes/Media\"]}\nDone.",
+			map[string]any{"over": []any{"/Volumes/Media"}},
+		},
+		{
+			"json array",
+			config.Action{OutputParser: "json"},
+			"Volumes over threshold: [\"/Volumes/Media\", \"/Volumes/Backup\"]",
+			map[string]any{"result": []any{"/Volumes/Media", "/Volumes/Backup"}},
+		},
+		{
+			"lines",
+			config.Action{OutputParser: "lines"},
+			"/Volumes/Media\n\n  /Volumes/Backup  \n",
+			map[string]any{"lines": []string{"/Volumes/Media", "/Volumes/Backup"}},
+		},
+		{
+			"regex",
+			config.Action{OutputParser: "regex", OutputPattern: `(?P<volume>/Volumes/\w+) is (?P<pct>\d+)% full`},
+			"Disk report\n/Volumes/Media is 91% full\n/Volumes/Backup is 95% full",
+			map[string]any{"volume": "/Volumes/Media", "pct": "91"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOutput(tt.action, tt.output)
+			if err != nil {
+				t.Fatalf("parseOutput() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOutput() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		action config.Action
+		output string
+	}{
+		{config.Action{OutputParser: "json"}, "no json here"},
+		{config.Action{OutputParser: "json"}, "{broken"},
+		{config.Action{OutputParser: "regex", OutputPattern: `(?P<pct>\d+)%`}, "no percentages"},
+	} {
+		if got, err := parseOutput(tt.action, tt.output); err == nil {
+			t.Errorf("parseOutput(%s, %q) = %v, want an error", tt.action.OutputParser, tt.output, got)
+		}
+	}
+}
+
+func TestHandleEvent_ParsedOutputPassedToChildren(t *testing.T) {
+	parent := scriptRule("disk-check", `echo 'Report follows'; echo '{"volume": "/Volumes/Media", "used_pct": 91}'`)
+	parent.Action.OutputParser = "json"
+	parent.Triggers = []string{"cleanup"}
+	d := newTestDaemon(t, parent, scriptRule("cleanup", "true"))
+	d.events = make(chan trigger.Event, 1)
+
+	d.handleEvent(context.Background(), manualEvent("disk-check"))
+
+	child := <-d.events
+	if child.RuleName != "cleanup" || child.Data["volume"] != "/Volumes/Media" || child.Data["used_pct"] != json.Number("91") {
+		t.Errorf("child event = %+v, want the parsed volume and used_pct", child)
+	}
+	if child.Data["event_type"] != "manual" {
+		t.Errorf("child event data = %v, want the parent's event data kept", child.Data)
+	}
+
+	records, _ := d.stateDB.GetHistory("disk-check", "", nil, 1)
+	if len(records) != 1 {
+		t.Fatalf("records = %+v, want one", records)
+	}
+	rec, err := d.stateDB.GetExecution(records[0].ID)
+	if err != nil || rec == nil {
+		t.Fatalf("GetExecution() = %v, %v", rec, err)
+	}
+	if rec.ParsedOutput != `{"used_pct":91,"volume":"/Volumes/Media"}` {
+		t.Errorf("ParsedOutput = %q", rec.ParsedOutput)
+	}
+}
+
+// Secrets the scrubber removes from history don't reach children through
+// the parsed values either.
+func TestHandleEvent_ParsedOutputScrubbed(t *testing.T) {
+	parent := scriptRule("plex-check", `echo '{"url": "http://localhost:32400?X-Plex-Token=abc123def456"}'`)
+	parent.Action.OutputParser = "json"
+	parent.Triggers = []string{"rescan"}
+	d := newTestDaemon(t, parent, scriptRule("rescan", "true"))
+	d.events = make(chan trigger.Event, 1)
+
+	d.handleEvent(context.Background(), manualEvent("plex-check"))
+
+	child := <-d.events
+	url, _ := child.Data["url"].(string)
+	if strings.Contains(url, "abc123def456") || !strings.Contains(url, "[REDACTED]") {
+		t.Errorf("child event url = %q, want the token redacted", url)
+	}
+}
+
+// Keys are scrubbed like values, and parsed output over the output limit is
+// stored as a marker rather than in full.
+func TestParsedOutputJSON(t *testing.T) {
+	d := newTestDaemon(t)
+	parsed := scrubParsed(map[string]any{"X-Plex-Token=abc123def456": "ok"}).(map[string]any)
+	if got := d.parsedOutputJSON(parsed); strings.Contains(got, "abc123def456") {
+		t.Errorf("parsedOutputJSON() = %s, want the key redacted", got)
+	}
+
+	lines := make([]string, 2000)
+	for i := range lines {
+		lines[i] = "a line of output"
+	}
+	got := d.parsedOutputJSON(map[string]any{"lines": lines})
+	if len(got) > config.DefaultMaxOutputBytes || !json.Valid([]byte(got)) || !strings.Contains(got, `"truncated":true`) {
+		t.Errorf("parsedOutputJSON(large) = %.100s (%d bytes), want a small truncation marker", got, len(got))
+	}
+}
+
+// A parse failure is logged; the execution still counts as a success and
+// children fire with the parent's event data alone.
+func TestHandleEvent_UnparseableOutputStillSucceeds(t *testing.T) {
+	parent := scriptRule("disk-check", "echo 'all good'")
+	parent.Action.OutputParser = "json"
+	parent.Triggers = []string{"cleanup"}
+	d := newTestDaemon(t, parent, scriptRule("cleanup", "true"))
+	d.events = make(chan trigger.Event, 1)
+
+	if out := d.handleEvent(context.Background(), manualEvent("disk-check")); out.State != "success" {
+		t.Errorf("outcome = %+v, want success", out)
+	}
+	if child := <-d.events; child.RuleName != "cleanup" {
+		t.Errorf("child event = %+v, want cleanup", child)
+	}
+}
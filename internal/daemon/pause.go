@@ -0,0 +1,89 @@
+// internal/daemon/pause.go
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// pauseSentinel is the file in the config directory that pauses all executions
+// while it exists, so operators can pause without the API (and across restarts).
+const pauseSentinel = "PAUSED"
+
+// pauseFile returns the path of the pause sentinel file, or "" if the config
+// directory is unknown.
+func (d *Daemon) pauseFile() string {
+	if d.configPath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(d.configPath), pauseSentinel)
+}
+
+// isPaused reports whether executions are paused via the API or the sentinel file.
+func (d *Daemon) isPaused() bool {
+	d.mu.RLock()
+	paused := d.paused
+	d.mu.RUnlock()
+	if paused {
+		return true
+	}
+	if path := d.pauseFile(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// setPaused sets the in-memory pause flag. The sentinel file is managed by the
+// operator and is not touched, so resume cannot override it.
+func (d *Daemon) setPaused(paused bool) {
+	d.mu.Lock()
+	d.paused = paused
+	d.mu.Unlock()
+}
+
+// handleAPIPause pauses all rule executions. Events are still accepted and
+// logged, and recorded as skipped with reason paused. Only local clients may
+// pause, even when webhook_listen_address exposes the API.
+func (d *Daemon) handleAPIPause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireLocal(w, r) {
+		return
+	}
+	d.setPaused(true)
+	d.logger.Warn("rule executions paused via API")
+	d.audit(auditPause, apiActor(r))
+	d.writePauseStatus(w)
+}
+
+// handleAPIResume clears the API pause flag. Like pause, it is local-only.
+func (d *Daemon) handleAPIResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireLocal(w, r) {
+		return
+	}
+	d.setPaused(false)
+	d.logger.Info("rule executions resumed via API")
+	d.audit(auditResume, apiActor(r))
+	d.writePauseStatus(w)
+}
+
+func (d *Daemon) writePauseStatus(w http.ResponseWriter) {
+	resp := map[string]any{"paused": d.isPaused()}
+	if path := d.pauseFile(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			resp["pause_file"] = path
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,124 @@
+// internal/daemon/pause_test.go
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+func historyStates(t *testing.T, d *Daemon, rule string) []string {
+	t.Helper()
+	records, err := d.stateDB.GetHistory(rule, "", nil, 100)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	// Oldest first
+	states := make([]string, len(records))
+	for i, r := range records {
+		states[len(records)-1-i] = r.State
+	}
+	return states
+}
+
+func TestPauseResume_SkipsWhilePaused(t *testing.T) {
+	d := newTestDaemon(t, scriptRule("job", "echo ran"))
+	fire := func() {
+		d.handleEvent(context.Background(), trigger.Event{RuleName: "job", Type: "manual", Timestamp: time.Now()})
+		time.Sleep(5 * time.Millisecond) // keep started_at ordering distinct
+	}
+
+	rec := httptest.NewRecorder()
+	d.handleAPIPause(rec, localRequest(http.MethodPost, "/api/pause", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"paused":true`) {
+		t.Fatalf("pause response = %d %s", rec.Code, rec.Body.String())
+	}
+	fire()
+
+	rec = httptest.NewRecorder()
+	d.handleAPIResume(rec, localRequest(http.MethodPost, "/api/resume", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"paused":false`) {
+		t.Fatalf("resume response = %d %s", rec.Code, rec.Body.String())
+	}
+	fire()
+
+	got := strings.Join(historyStates(t, d, "job"), ",")
+	if got != "skipped,success" {
+		t.Errorf("history states = %s, want skipped,success", got)
+	}
+}
+
+func TestPause_SentinelFile(t *testing.T) {
+	d := newTestDaemon(t, scriptRule("job", "echo ran"))
+	dir := t.TempDir()
+	d.configPath = filepath.Join(dir, "config.yaml")
+
+	if d.isPaused() {
+		t.Fatal("should not be paused without sentinel")
+	}
+	sentinel := filepath.Join(dir, "PAUSED")
+	if err := os.WriteFile(sentinel, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !d.isPaused() {
+		t.Fatal("sentinel file should pause executions")
+	}
+
+	// API resume cannot override the sentinel
+	rec := httptest.NewRecorder()
+	d.handleAPIResume(rec, localRequest(http.MethodPost, "/api/resume", nil))
+	if !strings.Contains(rec.Body.String(), `"paused":true`) || !strings.Contains(rec.Body.String(), "PAUSED") {
+		t.Errorf("resume with sentinel present = %s, want still paused with pause_file", rec.Body.String())
+	}
+
+	d.handleEvent(context.Background(), trigger.Event{RuleName: "job", Type: "manual", Timestamp: time.Now()})
+	if got := historyStates(t, d, "job"); len(got) != 1 || got[0] != "skipped" {
+		t.Errorf("history states = %v, want [skipped]", got)
+	}
+
+	os.Remove(sentinel)
+	if d.isPaused() {
+		t.Error("removing sentinel should resume")
+	}
+}
+
+func TestPause_RequiresPost(t *testing.T) {
+	d := newTestDaemon(t)
+	rec := httptest.NewRecorder()
+	d.handleAPIPause(rec, httptest.NewRequest(http.MethodGet, "/api/pause", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", rec.Code)
+	}
+	if d.isPaused() {
+		t.Error("GET should not pause")
+	}
+}
+
+func TestPauseResume_LocalOnly(t *testing.T) {
+	d := newTestDaemon(t)
+	for _, tt := range []struct {
+		target  string
+		handler http.HandlerFunc
+	}{
+		{"/api/pause", d.handleAPIPause},
+		{"/api/resume", d.handleAPIResume},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, tt.target, nil)
+		req.RemoteAddr = "192.0.2.10:50000"
+		tt.handler(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("remote POST %s = %d, want 403", tt.target, rec.Code)
+		}
+	}
+	if d.isPaused() {
+		t.Error("a remote client paused executions")
+	}
+}
@@ -0,0 +1,154 @@
+// internal/daemon/pipeline_test.go
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/executor"
+	"github.com/colebrumley/srvrmgr/internal/state"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+func promptRule(name, prompt string) *config.Rule {
+	return &config.Rule{
+		Name:    name,
+		Enabled: true,
+		Trigger: config.Trigger{Type: "manual"},
+		Action:  config.Action{Prompt: prompt},
+	}
+}
+
+func historyFor(t *testing.T, d *Daemon, rule string) []state.ExecutionRecord {
+	t.Helper()
+	records, err := d.stateDB.GetHistory(rule, "", nil, 50)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records
+}
+
+func TestPipeline_ClaudeRule(t *testing.T) {
+	d := newTestDaemon(t, promptRule("organize", "Organize {{file_path}}"))
+	d.config().ClaudeDefaults.Model = "sonnet"
+	fake := &fakeExecutor{respond: func(int, *config.Rule) (*executor.Result, error) {
+		return &executor.Result{State: "success", Output: "moved 3 files", Duration: time.Second}, nil
+	}}
+	d.SetExecutor(fake)
+
+	d.handleEvent(context.Background(), trigger.Event{
+		RuleName: "organize", Type: "filesystem", Timestamp: time.Now(),
+		Data: map[string]any{"file_path": "/Users/me/Downloads/a.zip"},
+	})
+
+	if len(fake.calls) != 1 {
+		t.Fatalf("executor called %d times, want 1", len(fake.calls))
+	}
+	if got := fake.calls[0]; got.Rule.Claude.Model != "sonnet" || got.Event.Data["file_path"] != "/Users/me/Downloads/a.zip" {
+		t.Errorf("executor call = %+v, want the default model and the event data", got)
+	}
+	records := historyFor(t, d, "organize")
+	if len(records) != 1 || records[0].State != "success" || records[0].Output != "moved 3 files" {
+		t.Fatalf("history = %+v, want one success with the output", records)
+	}
+	if records[0].TriggerType != "filesystem" {
+		t.Errorf("TriggerType = %q, want filesystem", records[0].TriggerType)
+	}
+	if d.lastRunState["organize"] != "success" {
+		t.Errorf("lastRunState = %q, want success", d.lastRunState["organize"])
+	}
+}
+
+func TestPipeline_ExecutorError(t *testing.T) {
+	d := newTestDaemon(t, promptRule("broken", "do it"))
+	d.SetExecutor(&fakeExecutor{respond: func(int, *config.Rule) (*executor.Result, error) {
+		return nil, errors.New("claude not found")
+	}})
+
+	d.handleEvent(context.Background(), manualEvent("broken"))
+
+	records := historyFor(t, d, "broken")
+	if len(records) != 1 || records[0].State != "failure" || records[0].Error != "claude not found" {
+		t.Fatalf("history = %+v, want one failure with the executor error", records)
+	}
+}
+
+func TestPipeline_RetryUntilSuccess(t *testing.T) {
+	defer func(u time.Duration) { retryDelayUnit = u }(retryDelayUnit)
+	retryDelayUnit = time.Millisecond
+
+	rule := promptRule("flaky", "do it")
+	rule.OnFailure = config.OnFailure{Retry: true, RetryAttempts: 3, RetryDelaySeconds: 1}
+	d := newTestDaemon(t, rule)
+	fake := &fakeExecutor{respond: func(n int, _ *config.Rule) (*executor.Result, error) {
+		if n < 2 {
+			return &executor.Result{State: "failure", Error: "rate limited"}, nil
+		}
+		return &executor.Result{State: "success"}, nil
+	}}
+	d.SetExecutor(fake)
+
+	d.handleEvent(context.Background(), manualEvent("flaky"))
+
+	if len(fake.calls) != 3 {
+		t.Errorf("executor called %d times, want 3", len(fake.calls))
+	}
+	var states []string
+	for _, rec := range historyFor(t, d, "flaky") {
+		states = append(states, rec.State)
+	}
+	if strings.Join(states, ",") != "failure,failure,success" {
+		t.Errorf("history states = %v, want two failures then success", states)
+	}
+	if d.lastRunState["flaky"] != "success" {
+		t.Errorf("lastRunState = %q, want success", d.lastRunState["flaky"])
+	}
+}
+
+func TestPipeline_TriggeredChainWithDependency(t *testing.T) {
+	parent := promptRule("parent", "parent")
+	parent.Triggers = []string{"child"}
+	child := promptRule("child", "child for {{volume}}")
+	child.DependsOn = []string{"parent"}
+	d := newTestDaemon(t, parent, child)
+	d.events = make(chan trigger.Event, 10)
+	d.SetExecutor(&fakeExecutor{respond: func(_ int, rule *config.Rule) (*executor.Result, error) {
+		if rule.Name == "parent" {
+			return &executor.Result{State: "success", Output: "done\nTRIGGER:child{\"volume\":\"/Volumes/Backup\"}"}, nil
+		}
+		return &executor.Result{State: "success", Output: "child ran"}, nil
+	}})
+
+	// The dependency gate holds the child until the parent has succeeded
+	d.handleEvent(context.Background(), manualEvent("child"))
+	if records := historyFor(t, d, "child"); len(records) != 1 || records[0].SkipReason != state.SkipDependency {
+		t.Fatalf("child history = %+v, want one dependency skip", records)
+	}
+
+	d.handleEvent(context.Background(), manualEvent("parent"))
+	var event trigger.Event
+	select {
+	case event = <-d.events:
+	default:
+		t.Fatal("parent success did not fire the child")
+	}
+	if event.RuleName != "child" || event.Type != "triggered" || event.Data["volume"] != "/Volumes/Backup" {
+		t.Fatalf("fired event = %+v, want a triggered event for child with the payload", event)
+	}
+	d.handleEvent(context.Background(), event)
+
+	records := historyFor(t, d, "child")
+	last := records[len(records)-1]
+	if last.State != "success" || last.TriggerType != "triggered" || last.Output != "child ran" {
+		t.Errorf("child record = %+v, want a triggered success", last)
+	}
+	if d.lastRunState["parent"] != "success" || d.lastRunState["child"] != "success" {
+		t.Errorf("lastRunState = %v, want both succeeded", d.lastRunState)
+	}
+}
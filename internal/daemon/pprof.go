@@ -0,0 +1,38 @@
+// internal/daemon/pprof.go
+package daemon
+
+import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// mountPprof adds the net/http/pprof handlers under /debug/pprof/ when
+// daemon.enable_pprof is set. Profiles expose internals (goroutine stacks,
+// command lines), so they are only served on a loopback listen address.
+func (d *Daemon) mountPprof(mux *http.ServeMux) {
+	if !d.config().Daemon.EnablePprof {
+		return
+	}
+	if !isLoopback(d.config().Daemon.WebhookListenAddress) {
+		d.logger.Warn("enable_pprof ignored: webhook_listen_address is not a loopback address",
+			"address", d.config().Daemon.WebhookListenAddress)
+		return
+	}
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	d.logger.Warn("pprof debugging endpoints enabled at /debug/pprof/")
+}
+
+// isLoopback reports whether addr is localhost or a loopback IP.
+func isLoopback(addr string) bool {
+	if addr == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsLoopback()
+}
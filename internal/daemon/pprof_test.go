@@ -0,0 +1,60 @@
+// internal/daemon/pprof_test.go
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func pprofStatus(t *testing.T, d *Daemon) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	d.newMux(context.Background()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	return rec.Code
+}
+
+func TestPprof_OffByDefault(t *testing.T) {
+	d := newTestDaemon(t)
+	d.config().Daemon.WebhookListenAddress = "127.0.0.1"
+
+	if code := pprofStatus(t, d); code != http.StatusNotFound {
+		t.Errorf("/debug/pprof/ = %d, want 404 when enable_pprof is unset", code)
+	}
+}
+
+func TestPprof_Enabled(t *testing.T) {
+	d := newTestDaemon(t)
+	d.config().Daemon.WebhookListenAddress = "127.0.0.1"
+	d.config().Daemon.EnablePprof = true
+
+	if code := pprofStatus(t, d); code != http.StatusOK {
+		t.Errorf("/debug/pprof/ = %d, want 200 when enable_pprof is set", code)
+	}
+}
+
+func TestPprof_RequiresLoopback(t *testing.T) {
+	d := newTestDaemon(t)
+	d.config().Daemon.WebhookListenAddress = "0.0.0.0"
+	d.config().Daemon.EnablePprof = true
+
+	if code := pprofStatus(t, d); code != http.StatusNotFound {
+		t.Errorf("/debug/pprof/ = %d, want 404 on a non-loopback address", code)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1": true,
+		"::1":       true,
+		"localhost": true,
+		"0.0.0.0":   false,
+		"10.0.0.5":  false,
+		"":          false,
+	} {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// internal/daemon/ratelimit.go
+package daemon
+
+import (
+	"container/list"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxRateLimitClients bounds how many client buckets one endpoint keeps; the
+// least recently seen client is forgotten first.
+const maxRateLimitClients = 1024
+
+// rateLimitHandler wraps an HTTP handler with a token-bucket rate limiter
+// (FR-7). Each client IP gets its own bucket, so one noisy source can't
+// throttle everyone else.
+func rateLimitHandler(requestsPerMinute int, handler http.HandlerFunc) http.HandlerFunc {
+	limiter := newRateLimiter(requestsPerMinute, maxRateLimitClients)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !limiter.allow(clientIP(r), time.Now()) {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// rateLimited wraps the handler of route with the limit configured for it in
+// daemon.rate_limits.
+func (d *Daemon) rateLimited(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return rateLimitHandler(d.config().Daemon.RateLimits.Limit(route), handler)
+}
+
+// clientIP is the host part of the request's remote address. Forwarding
+// headers are ignored: they are set by the client and can't be trusted.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// isLocalRequest reports whether r came from this host: over loopback, or
+// from the address the request was received on, as when the CLI connects to
+// a non-loopback webhook_listen_address.
+func isLocalRequest(r *http.Request) bool {
+	ip := clientIP(r)
+	if isLoopback(ip) {
+		return true
+	}
+	local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return false
+	}
+	host, _, err := net.SplitHostPort(local.String())
+	return err == nil && host == ip
+}
+
+// requireLocal rejects r with 403 unless it came from this host (see
+// isLocalRequest), for endpoints that change daemon state or expose its logs
+// and have no authentication of their own. It reports whether r may proceed.
+func requireLocal(w http.ResponseWriter, r *http.Request) bool {
+	if isLocalRequest(r) {
+		return true
+	}
+	http.Error(w, "only allowed from the local host", http.StatusForbidden)
+	return false
+}
+
+// rateLimiter holds a token bucket per client, in an LRU of bounded size.
+type rateLimiter struct {
+	mu         sync.Mutex
+	perMinute  int
+	maxClients int
+	buckets    map[string]*list.Element
+	lru        *list.List // of *tokenBucket, most recently used first
+}
+
+type tokenBucket struct {
+	client     string
+	tokens     int
+	lastRefill time.Time
+}
+
+func newRateLimiter(perMinute, maxClients int) *rateLimiter {
+	return &rateLimiter{
+		perMinute:  perMinute,
+		maxClients: maxClients,
+		buckets:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// allow takes a token from client's bucket, reporting false if it is empty.
+func (l *rateLimiter) allow(client string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var b *tokenBucket
+	if el, ok := l.buckets[client]; ok {
+		l.lru.MoveToFront(el)
+		b = el.Value.(*tokenBucket)
+	} else {
+		b = &tokenBucket{client: client, tokens: l.perMinute, lastRefill: now}
+		l.buckets[client] = l.lru.PushFront(b)
+		if l.lru.Len() > l.maxClients {
+			oldest := l.lru.Back()
+			l.lru.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*tokenBucket).client)
+		}
+	}
+
+	refill := int(now.Sub(b.lastRefill).Minutes() * float64(l.perMinute))
+	if refill > 0 {
+		b.tokens = min(b.tokens+refill, l.perMinute)
+		b.lastRefill = now
+	}
+
+	if b.tokens <= 0 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,123 @@
+// internal/daemon/ratelimit_test.go
+package daemon
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func requestFrom(h http.HandlerFunc, remoteAddr string) int {
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec.Code
+}
+
+func TestRateLimitHandler_PerClientBuckets(t *testing.T) {
+	h := rateLimitHandler(2, func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 2; i++ {
+		if code := requestFrom(h, "10.0.0.1:5000"); code != http.StatusOK {
+			t.Fatalf("request %d from first client = %d, want 200", i+1, code)
+		}
+	}
+	if code := requestFrom(h, "10.0.0.1:5001"); code != http.StatusTooManyRequests {
+		t.Errorf("third request from first client = %d, want 429 (port must not matter)", code)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := requestFrom(h, "10.0.0.2:6000"); code != http.StatusOK {
+			t.Errorf("request %d from second client = %d, want 200 despite first client's limit", i+1, code)
+		}
+	}
+}
+
+func TestRateLimiter_Refills(t *testing.T) {
+	l := newRateLimiter(60, 10)
+	now := time.Now()
+	for i := 0; i < 60; i++ {
+		l.allow("a", now)
+	}
+	if l.allow("a", now) {
+		t.Fatal("allow() = true with an empty bucket")
+	}
+	if !l.allow("a", now.Add(time.Second)) {
+		t.Error("allow() = false after a second at 60/min, want a refilled token")
+	}
+}
+
+func TestRateLimiter_EvictsLeastRecentlyUsed(t *testing.T) {
+	l := newRateLimiter(1, 2)
+	now := time.Now()
+	l.allow("a", now)
+	l.allow("b", now)
+	l.allow("a", now) // a is now the most recently used
+	l.allow("c", now) // evicts b
+
+	if len(l.buckets) != 2 || l.lru.Len() != 2 {
+		t.Fatalf("kept %d buckets, want 2", len(l.buckets))
+	}
+	if _, ok := l.buckets["b"]; ok {
+		t.Error("least recently used client b was not evicted")
+	}
+	if !l.allow("b", now) {
+		t.Error("evicted client b should start with a fresh bucket")
+	}
+	if l.allow("c", now) {
+		t.Error("client c kept its bucket but was allowed past its limit")
+	}
+}
+
+func TestNewMux_ConfiguredRateLimit(t *testing.T) {
+	d := newTestDaemon(t)
+	d.config().Daemon.RateLimits.Routes = map[string]int{"/health": 2}
+	mux := d.newMux(context.Background())
+
+	codes := make([]int, 3)
+	for i := range codes {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		codes[i] = rec.Code
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("/health status codes = %v, want 200, 200, 429 with a limit of 2", codes)
+	}
+}
+
+func TestIsLocalRequest(t *testing.T) {
+	listen := &net.TCPAddr{IP: net.ParseIP("192.168.1.5"), Port: 9876}
+	for _, tt := range []struct {
+		remote string
+		local  net.Addr
+		want   bool
+	}{
+		{"127.0.0.1:5000", nil, true},
+		{"[::1]:5000", nil, true},
+		{"192.168.1.5:5000", listen, true}, // this host, via the listen address
+		{"192.168.1.9:5000", listen, false},
+		{"192.168.1.5:5000", nil, false},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/rules/job/run", nil)
+		req.RemoteAddr = tt.remote
+		if tt.local != nil {
+			req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, tt.local))
+		}
+		if got := isLocalRequest(req); got != tt.want {
+			t.Errorf("isLocalRequest(from %s, on %v) = %v, want %v", tt.remote, tt.local, got, tt.want)
+		}
+	}
+}
+
+// localRequest is httptest.NewRequest from a loopback client, as the CLI
+// connects, for the endpoints only local clients may use.
+func localRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.RemoteAddr = "127.0.0.1:50000"
+	return req
+}
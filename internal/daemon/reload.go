@@ -0,0 +1,153 @@
+// internal/daemon/reload.go
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+)
+
+// Reload asks the running daemon to reload config.yaml and the rules
+// directory, as on SIGHUP. It doesn't block; a request made while another is
+// pending is merged into it.
+func (d *Daemon) Reload() {
+	select {
+	case d.reloadCh <- struct{}{}:
+	default:
+	}
+}
+
+// reload applies a Reload request: the global config first, so the rules are
+// re-validated against the new allowlist.
+func (d *Daemon) reload(ctx context.Context) {
+	d.logger.Info("reloading config and rules")
+	d.reloadConfig()
+	d.reloadRules(ctx)
+}
+
+// reloadConfig re-reads config.yaml. An invalid file is logged and the
+// current config kept. Settings only read at startup keep their current
+// values and are logged as needing a restart.
+func (d *Daemon) reloadConfig() {
+	next, err := config.LoadGlobal(d.configPath)
+	if err != nil {
+		d.logger.Error("failed to reload config, keeping current config", "error", err)
+		return
+	}
+
+	pending := keepStartupSettings(d.config(), next)
+	d.cfg.Store(next)
+
+	if d.stateDB != nil {
+		d.stateDB.SetCompression(next.Daemon.CompressHistory)
+	}
+	for _, key := range pending {
+		d.logger.Warn("config setting changed, restart the daemon to apply it", "setting", key)
+	}
+	d.logger.Info("config reloaded", "path", d.configPath)
+	d.audit(auditConfigReload, "signal", "restart_required", pending)
+}
+
+// keepStartupSettings copies the settings that only take effect at startup
+// from cur into next, so the running daemon's config keeps describing what
+// is in effect. It returns the keys whose value differed.
+func keepStartupSettings(cur, next *config.Global) []string {
+	settings := []struct {
+		key       string
+		cur, next any // pointers to the field in each config
+	}{
+		{"daemon.log_level", &cur.Daemon.LogLevel, &next.Daemon.LogLevel},
+		{"daemon.webhook_listen_address", &cur.Daemon.WebhookListenAddress, &next.Daemon.WebhookListenAddress},
+		{"daemon.webhook_listen_port", &cur.Daemon.WebhookListenPort, &next.Daemon.WebhookListenPort},
+		{"daemon.enable_pprof", &cur.Daemon.EnablePprof, &next.Daemon.EnablePprof},
+		{"daemon.maintenance_interval_minutes", &cur.Daemon.MaintenanceIntervalMinutes, &next.Daemon.MaintenanceIntervalMinutes},
+		{"daemon.audit_log_path", &cur.Daemon.AuditLogPath, &next.Daemon.AuditLogPath},
+		{"logging.format", &cur.Logging.Format, &next.Logging.Format},
+		{"rule_execution.max_concurrent", &cur.RuleExecution.MaxConcurrent, &next.RuleExecution.MaxConcurrent},
+		{"memory", &cur.Memory, &next.Memory},
+	}
+
+	var changed []string
+	for _, s := range settings {
+		curVal, nextVal := reflect.ValueOf(s.cur).Elem(), reflect.ValueOf(s.next).Elem()
+		if !reflect.DeepEqual(curVal.Interface(), nextVal.Interface()) {
+			changed = append(changed, s.key)
+		}
+		nextVal.Set(curVal)
+	}
+	return changed
+}
+
+// Kinds of RuleChange.
+const (
+	ruleAdded          = "added"
+	ruleRemoved        = "removed"
+	ruleEnabled        = "enabled"
+	ruleDisabled       = "disabled"
+	ruleTriggerChanged = "trigger_changed"
+	ruleModified       = "modified" // changed, but not in enabled or trigger
+)
+
+// RuleChange is one difference between the rules before and after a reload.
+type RuleChange struct {
+	Rule   string `json:"rule"`
+	Change string `json:"change"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ruleChanges lists how next differs from prev, sorted by rule name. A rule
+// can change in more than one way, e.g. be disabled and get a new trigger.
+func ruleChanges(prev, next map[string]*config.Rule) []RuleChange {
+	var changes []RuleChange
+	for name, rule := range next {
+		old, ok := prev[name]
+		if !ok {
+			changes = append(changes, RuleChange{Rule: name, Change: ruleAdded, Detail: triggerSummary(rule.Trigger)})
+			continue
+		}
+		if reflect.DeepEqual(old, rule) {
+			continue
+		}
+		n := len(changes)
+		switch {
+		case !old.Enabled && rule.Enabled:
+			changes = append(changes, RuleChange{Rule: name, Change: ruleEnabled})
+		case old.Enabled && !rule.Enabled:
+			changes = append(changes, RuleChange{Rule: name, Change: ruleDisabled})
+		}
+		if !reflect.DeepEqual(old.Trigger, rule.Trigger) {
+			changes = append(changes, RuleChange{Rule: name, Change: ruleTriggerChanged,
+				Detail: fmt.Sprintf("%s -> %s", triggerSummary(old.Trigger), triggerSummary(rule.Trigger))})
+		}
+		if len(changes) == n {
+			changes = append(changes, RuleChange{Rule: name, Change: ruleModified})
+		}
+	}
+	for name := range prev {
+		if _, ok := next[name]; !ok {
+			changes = append(changes, RuleChange{Rule: name, Change: ruleRemoved})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Rule < changes[j].Rule })
+	return changes
+}
+
+// triggerSummary describes a trigger in a few words for reload logs.
+func triggerSummary(t config.Trigger) string {
+	switch {
+	case t.CronExpression != "":
+		return fmt.Sprintf("%s (cron %s)", t.Type, t.CronExpression)
+	case t.RunEvery != "":
+		return fmt.Sprintf("%s (every %s)", t.Type, t.RunEvery)
+	case t.RunAt != "":
+		return fmt.Sprintf("%s (at %s)", t.Type, t.RunAt)
+	case t.ListenPath != "":
+		return fmt.Sprintf("%s (%s)", t.Type, t.ListenPath)
+	case len(t.WatchPaths) > 0:
+		return fmt.Sprintf("%s (%d paths)", t.Type, len(t.WatchPaths))
+	}
+	return t.Type
+}
@@ -0,0 +1,372 @@
+// internal/daemon/reload_test.go
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+	"github.com/fsnotify/fsnotify"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload_ConfigAndRules(t *testing.T) {
+	dir := t.TempDir()
+	rulesDir := filepath.Join(dir, "rules")
+	if err := os.Mkdir(rulesDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "config.yaml")
+	writeTestFile(t, configPath, "daemon:\n  webhook_listen_port: 9876\n  allowed_run_as_users: [alice]\n")
+	writeTestFile(t, filepath.Join(rulesDir, "backup.yaml"), `name: backup
+enabled: true
+run_as_user: bob
+trigger:
+  type: manual
+action:
+  script: echo hi
+`)
+
+	d := newTestDaemon(t)
+	d.configPath, d.rulesDir = configPath, rulesDir
+	d.triggers = make(map[string]trigger.Trigger)
+	d.webhooks = make(map[string]*trigger.Webhook)
+	if err := d.loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d.reload(ctx)
+	if _, ok := d.rules["backup"]; ok {
+		t.Fatal("rule running as bob loaded before bob was allowed")
+	}
+
+	// Allow bob and move the listen port, which needs a restart
+	writeTestFile(t, configPath, "daemon:\n  webhook_listen_port: 9999\n  allowed_run_as_users: [alice, bob]\n")
+	d.reload(ctx)
+
+	if !slices.Equal(d.config().Daemon.AllowedRunAsUsers, []string{"alice", "bob"}) {
+		t.Errorf("allowed_run_as_users = %v after reload", d.config().Daemon.AllowedRunAsUsers)
+	}
+	if d.config().Daemon.WebhookListenPort != 9876 {
+		t.Errorf("webhook_listen_port = %d, want the running 9876 until restart", d.config().Daemon.WebhookListenPort)
+	}
+	if _, ok := d.rules["backup"]; !ok {
+		t.Error("rule not loaded after its run_as_user was allowed")
+	}
+
+	// An invalid config is rejected and the current one kept
+	writeTestFile(t, configPath, "daemon: [not, a, map]\n")
+	d.reload(ctx)
+	if !slices.Contains(d.config().Daemon.AllowedRunAsUsers, "bob") {
+		t.Error("invalid config replaced the running config")
+	}
+}
+
+// Run with -race: settings read while executions run must not race a reload.
+func TestReloadConfig_ConcurrentReaders(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestFile(t, configPath, "daemon:\n  trigger_marker: \"NEXT:\"\n  compress_history: true\n")
+	d := newTestDaemon(t)
+	d.configPath = configPath
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = d.config().Daemon.TriggerMarker
+			d.ruleExecConfig()
+			d.mergeClaudeConfig(config.ClaudeConfig{})
+			d.recordExecution(&config.Rule{Name: "r"}, manualEvent("r"), "success", time.Now(), "out", "")
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		d.reloadConfig()
+	}
+	<-done
+
+	if got := d.config().Daemon.TriggerMarker; got != "NEXT:" {
+		t.Errorf("trigger_marker = %q after reload, want NEXT:", got)
+	}
+}
+
+func TestReload_MergesPendingRequests(t *testing.T) {
+	d := New("config.yaml", "rules")
+	d.Reload()
+	d.Reload() // must not block while the first request is pending
+	if len(d.reloadCh) != 1 {
+		t.Errorf("pending reloads = %d, want 1", len(d.reloadCh))
+	}
+}
+
+func TestKeepStartupSettings(t *testing.T) {
+	cur := &config.Global{}
+	cur.Daemon.WebhookListenPort = 9876
+	cur.Memory.Enabled = true
+	cur.Daemon.AllowedRunAsUsers = []string{"alice"}
+
+	next := &config.Global{}
+	next.Daemon.WebhookListenPort = 9999
+	next.Memory.Enabled = true
+	next.Daemon.AllowedRunAsUsers = []string{"bob"}
+
+	changed := keepStartupSettings(cur, next)
+	if !slices.Equal(changed, []string{"daemon.webhook_listen_port"}) {
+		t.Errorf("changed = %v, want only the listen port", changed)
+	}
+	if next.Daemon.WebhookListenPort != 9876 {
+		t.Errorf("listen port = %d, want the current 9876", next.Daemon.WebhookListenPort)
+	}
+	if !slices.Equal(next.Daemon.AllowedRunAsUsers, []string{"bob"}) {
+		t.Errorf("allowed_run_as_users = %v, want the reloaded value", next.Daemon.AllowedRunAsUsers)
+	}
+}
+
+func TestRuleChanges(t *testing.T) {
+	rule := func(name string, enabled bool, runEvery string) *config.Rule {
+		return &config.Rule{Name: name, Enabled: enabled,
+			Trigger: config.Trigger{Type: "scheduled", RunEvery: runEvery}, Action: config.Action{Script: "true"}}
+	}
+	prev := map[string]*config.Rule{
+		"same":      rule("same", true, "1h"),
+		"gone":      rule("gone", true, "1h"),
+		"off":       rule("off", true, "1h"),
+		"on":        rule("on", false, "1h"),
+		"retimed":   rule("retimed", true, "1h"),
+		"reworded":  rule("reworded", true, "1h"),
+		"off-moved": rule("off-moved", true, "1h"),
+	}
+	next := map[string]*config.Rule{
+		"same":      rule("same", true, "1h"),
+		"new":       rule("new", true, "5m"),
+		"off":       rule("off", false, "1h"),
+		"on":        rule("on", true, "1h"),
+		"retimed":   rule("retimed", true, "2h"),
+		"reworded":  rule("reworded", true, "1h"),
+		"off-moved": rule("off-moved", false, "2h"),
+	}
+	next["reworded"].Action.Script = "false"
+
+	want := []RuleChange{
+		{Rule: "gone", Change: ruleRemoved},
+		{Rule: "new", Change: ruleAdded, Detail: "scheduled (every 5m)"},
+		{Rule: "off", Change: ruleDisabled},
+		{Rule: "off-moved", Change: ruleDisabled},
+		{Rule: "off-moved", Change: ruleTriggerChanged, Detail: "scheduled (every 1h) -> scheduled (every 2h)"},
+		{Rule: "on", Change: ruleEnabled},
+		{Rule: "retimed", Change: ruleTriggerChanged, Detail: "scheduled (every 1h) -> scheduled (every 2h)"},
+		{Rule: "reworded", Change: ruleModified},
+	}
+	if got := ruleChanges(prev, next); !reflect.DeepEqual(got, want) {
+		t.Errorf("ruleChanges() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReloadRules_LogsChanges(t *testing.T) {
+	rulesDir := t.TempDir()
+	if err := os.Chmod(rulesDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(rulesDir, "backup.yaml"), "name: backup\nenabled: true\naction:\n  script: echo hi\n")
+	writeTestFile(t, filepath.Join(rulesDir, "cleanup.yaml"), "name: cleanup\nenabled: true\naction:\n  script: echo hi\n")
+
+	var logs bytes.Buffer
+	d, auditPath := auditDaemon(t)
+	d.rulesDir = rulesDir
+	d.triggers = make(map[string]trigger.Trigger)
+	d.webhooks = make(map[string]*trigger.Webhook)
+	d.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.reloadRules(ctx)
+
+	writeTestFile(t, filepath.Join(rulesDir, "backup.yaml"), "name: backup\nenabled: false\naction:\n  script: echo hi\n")
+	os.Remove(filepath.Join(rulesDir, "cleanup.yaml"))
+	logs.Reset()
+	d.reloadRules(ctx)
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry struct{ Msg, Rule, Change string }
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		if entry.Msg == "rule changed on reload" {
+			got = append(got, entry.Rule+" "+entry.Change)
+		}
+	}
+	if want := []string{"backup disabled", "cleanup removed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("change log entries = %v, want %v", got, want)
+	}
+
+	// The audit log notes each reload without repeating the changes
+	entries := readAudit(t, auditPath)
+	if len(entries) != 2 {
+		t.Fatalf("audit entries = %v, want one per reload", entries)
+	}
+	if e := entries[1]; e["msg"] != auditRulesReloaded || e["changes"] != float64(2) || e["removed"] != nil {
+		t.Errorf("reload audit entry = %v, want a change count only", e)
+	}
+}
+
+func TestWatchRules_RecoversFromRemovedDirectory(t *testing.T) {
+	prev := rulesDirPollInterval
+	rulesDirPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { rulesDirPollInterval = prev })
+
+	rulesDir := filepath.Join(t.TempDir(), "rules")
+	mkRules := func(rule string) {
+		if err := os.Mkdir(rulesDir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		writeTestFile(t, filepath.Join(rulesDir, rule+".yaml"), "name: "+rule+"\nenabled: true\ntrigger:\n  type: scheduled\n  run_every: 1h\naction:\n  script: echo hi\n")
+	}
+	mkRules("backup")
+
+	d := newTestDaemon(t)
+	d.rulesDir = rulesDir
+	d.triggers = make(map[string]trigger.Trigger)
+	d.webhooks = make(map[string]*trigger.Webhook)
+	d.events = make(chan trigger.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.reloadRules(ctx)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(rulesDir); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		d.watchRules(ctx, watcher)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	loaded := func(names ...string) func() bool {
+		return func() bool {
+			d.mu.RLock()
+			defer d.mu.RUnlock()
+			if len(d.rules) != len(names) || len(d.triggers) != len(names) {
+				return false
+			}
+			for _, name := range names {
+				if _, ok := d.triggers[name]; !ok {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	if !loaded("backup")() {
+		t.Fatal("backup not loaded before the directory was removed")
+	}
+
+	if err := os.RemoveAll(rulesDir); err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(t, loaded())
+
+	mkRules("cleanup")
+	waitUntil(t, loaded("cleanup"))
+}
+
+func TestReloadRules_SkipsUnchangedRules(t *testing.T) {
+	rulesDir := t.TempDir()
+	if err := os.Chmod(rulesDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	rulePath := filepath.Join(rulesDir, "backup.yaml")
+	writeRule := func(prompt string) {
+		writeTestFile(t, rulePath, "name: backup\nenabled: true\ntrigger:\n  type: scheduled\n  run_every: 1h\naction:\n  prompt: "+prompt+"\n")
+	}
+	writeRule("Back up the database")
+
+	var logs bytes.Buffer
+	d := newTestDaemon(t)
+	d.rulesDir = rulesDir
+	d.triggers = make(map[string]trigger.Trigger)
+	d.webhooks = make(map[string]*trigger.Webhook)
+	d.events = make(chan trigger.Event, 10)
+	d.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.reloadRules(ctx)
+	defer func() {
+		for _, tr := range d.triggers {
+			tr.Stop()
+		}
+	}()
+
+	messages := func() []string {
+		var msgs []string
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var entry struct{ Msg string }
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("log line %q: %v", line, err)
+			}
+			msgs = append(msgs, entry.Msg)
+		}
+		logs.Reset()
+		return msgs
+	}
+	messages()
+
+	d.mu.RLock()
+	rule, trig := d.rules["backup"], d.triggers["backup"]
+	d.mu.RUnlock()
+	if rule.Hash == "" || trig == nil {
+		t.Fatalf("rule not loaded: hash %q, trigger %v", rule.Hash, trig)
+	}
+
+	// Reloading an untouched file changes nothing
+	d.reloadRules(ctx)
+	if got, want := messages(), []string{"rules reload was a no-op"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unchanged reload logged %v, want %v", got, want)
+	}
+	d.mu.RLock()
+	if d.rules["backup"] != rule {
+		t.Error("unchanged reload replaced the rule")
+	}
+	d.mu.RUnlock()
+
+	// A new prompt updates the rule and its hash, but the trigger keeps running
+	writeRule("Back up the database and verify it")
+	d.reloadRules(ctx)
+	msgs := messages()
+	if slices.Contains(msgs, "reloaded trigger") || !slices.Contains(msgs, "rules reloaded") {
+		t.Errorf("prompt change logged %v, want a reload without a trigger restart", msgs)
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if got := d.rules["backup"]; got.Action.Prompt != "Back up the database and verify it" || got.Hash == rule.Hash {
+		t.Errorf("rule after prompt change = prompt %q, hash %q (was %q)", got.Action.Prompt, got.Hash, rule.Hash)
+	}
+	if d.triggers["backup"] != trig {
+		t.Error("prompt change restarted the trigger")
+	}
+}
@@ -0,0 +1,111 @@
+// internal/daemon/runall.go
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/trigger"
+)
+
+// RunRules manually runs every enabled rule with the given trigger type (for
+// CLI use) and returns how each run ended, in the order the rules ran.
+func (d *Daemon) RunRules(ctx context.Context, triggerType string) ([]RunOutcome, error) {
+	if !slices.Contains(config.TriggerTypes, triggerType) {
+		return nil, fmt.Errorf("invalid trigger type %q: must be one of %s", triggerType, strings.Join(config.TriggerTypes, ", "))
+	}
+	if err := d.initManualRun(); err != nil {
+		return nil, err
+	}
+	defer d.closeAuditLog()
+
+	outcomes := d.runAll(ctx, d.enabledRulesOfType(triggerType))
+	d.waitNotifications()
+	return outcomes, nil
+}
+
+// enabledRulesOfType returns the enabled rules with the given trigger type,
+// sorted by name.
+func (d *Daemon) enabledRulesOfType(triggerType string) []*config.Rule {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var rules []*config.Rule
+	for _, rule := range d.rules {
+		if rule.Enabled && rule.Trigger.Type == triggerType {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules
+}
+
+// runAll runs a manual event for each rule, up to rule_execution.max_concurrent
+// at once. A rule waits for the rules it depends on in the same batch to
+// finish first, so its dependency gate sees their results; rules in a
+// dependency cycle run last and are left to the gate.
+func (d *Daemon) runAll(ctx context.Context, rules []*config.Rule) []RunOutcome {
+	limit := d.maxConcurrent()
+	if limit <= 0 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+
+	inBatch := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		inBatch[rule.Name] = true
+	}
+	done := make(map[string]bool, len(rules))
+	ready := func(rule *config.Rule) bool {
+		for _, dep := range rule.DependsOn {
+			if inBatch[dep] && !done[dep] {
+				return false
+			}
+		}
+		return true
+	}
+
+	var outcomes []RunOutcome
+	pending := rules
+	for len(pending) > 0 {
+		var wave, waiting []*config.Rule
+		for _, rule := range pending {
+			if ready(rule) {
+				wave = append(wave, rule)
+			} else {
+				waiting = append(waiting, rule)
+			}
+		}
+		if len(wave) == 0 {
+			wave, waiting = waiting, nil
+		}
+
+		results := make([]RunOutcome, len(wave))
+		var wg sync.WaitGroup
+		for i, rule := range wave {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				results[i] = d.handleEvent(ctx, trigger.Event{RuleName: rule.Name, Type: "manual", Timestamp: time.Now()})
+			}()
+		}
+		wg.Wait()
+
+		for _, rule := range wave {
+			done[rule.Name] = true
+		}
+		outcomes = append(outcomes, results...)
+		pending = waiting
+	}
+	return outcomes
+}
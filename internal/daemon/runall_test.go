@@ -0,0 +1,79 @@
+// internal/daemon/runall_test.go
+package daemon
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/executor"
+	"github.com/colebrumley/srvrmgr/internal/state"
+)
+
+func scheduledRule(name string, dependsOn ...string) *config.Rule {
+	rule := scriptRule(name, "true")
+	rule.Trigger = config.Trigger{Type: "scheduled"}
+	rule.DependsOn = dependsOn
+	return rule
+}
+
+func TestRunAll_RunsEnabledRulesOfType(t *testing.T) {
+	disabled := scheduledRule("disabled")
+	disabled.Enabled = false
+	d := newTestDaemon(t,
+		scheduledRule("report", "backup"), // listed first, but waits for backup
+		scheduledRule("backup"),
+		scheduledRule("cleanup"),
+		disabled,
+		scriptRule("by-hand", "true"),
+	)
+	d.config().RuleExecution.MaxConcurrent = 4
+	fake := &fakeExecutor{}
+	d.SetExecutor(fake)
+
+	outcomes := d.runAll(context.Background(), d.enabledRulesOfType("scheduled"))
+
+	var ran []string
+	for _, o := range outcomes {
+		ran = append(ran, o.Rule)
+		if o.State != "success" {
+			t.Errorf("%s: state = %q, want success", o.Rule, o.State)
+		}
+	}
+	if want := []string{"backup", "cleanup", "report"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if len(fake.calls) != 3 {
+		t.Errorf("executor called %d times, want 3", len(fake.calls))
+	}
+	for _, c := range fake.calls {
+		if c.Event.Type != "manual" {
+			t.Errorf("%s ran with event type %q, want manual", c.Rule.Name, c.Event.Type)
+		}
+	}
+}
+
+func TestRunAll_DependentSeesFailure(t *testing.T) {
+	d := newTestDaemon(t, scheduledRule("backup"), scheduledRule("report", "backup"))
+	d.SetExecutor(&fakeExecutor{respond: func(_ int, rule *config.Rule) (*executor.Result, error) {
+		return &executor.Result{State: "failure", Error: "disk full"}, nil
+	}})
+
+	outcomes := d.runAll(context.Background(), d.enabledRulesOfType("scheduled"))
+
+	want := []RunOutcome{
+		{Rule: "backup", State: "failure", Detail: "disk full"},
+		{Rule: "report", State: state.StateSkipped, Detail: state.SkipDependency},
+	}
+	if !reflect.DeepEqual(outcomes, want) {
+		t.Errorf("outcomes = %+v, want %+v", outcomes, want)
+	}
+}
+
+func TestRunRules_RejectsUnknownType(t *testing.T) {
+	d := newTestDaemon(t)
+	if _, err := d.RunRules(context.Background(), "cron"); err == nil {
+		t.Error("expected error for unknown trigger type")
+	}
+}
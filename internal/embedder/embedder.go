@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 
 	"github.com/knights-analytics/hugot"
 	"github.com/knights-analytics/hugot/pipelines"
@@ -20,8 +21,17 @@ type Embedder struct {
 	mu       sync.Mutex
 	initOnce sync.Once
 	initErr  error
+	state    atomic.Value // string; see State
 }
 
+// Model load states reported by State
+const (
+	StateNotLoaded = "not_loaded"
+	StateLoading   = "loading"
+	StateReady     = "ready"
+	StateFailed    = "failed"
+)
+
 // New creates a new Embedder with lazy initialization
 // The model is not loaded until the first Embed call
 func New() (*Embedder, error) {
@@ -37,9 +47,32 @@ func NewEager() (*Embedder, error) {
 	return e, nil
 }
 
+// Warm loads the model now, if it isn't loaded yet, so the first Embed call
+// doesn't have to wait for it
+func (e *Embedder) Warm() error {
+	return e.init()
+}
+
+// State reports whether the model has been loaded, without loading it
+func (e *Embedder) State() string {
+	if state, ok := e.state.Load().(string); ok {
+		return state
+	}
+	return StateNotLoaded
+}
+
 // init performs the actual model initialization
 func (e *Embedder) init() error {
 	e.initOnce.Do(func() {
+		e.state.Store(StateLoading)
+		defer func() {
+			if e.initErr != nil {
+				e.state.Store(StateFailed)
+			} else {
+				e.state.Store(StateReady)
+			}
+		}()
+
 		// Create temp directory for model files
 		modelDir, err := os.MkdirTemp("", "srvrmgr-embedder-*")
 		if err != nil {
@@ -2,15 +2,18 @@
 package executor
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	osuser "os/user"
 	"strings"
 	"time"
 
 	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/mcp"
 )
 
 // MCPConfig represents the MCP configuration file format
@@ -18,26 +21,38 @@ type MCPConfig struct {
 	MCPServers map[string]MCPServerConfig `json:"mcpServers"`
 }
 
-// MCPServerConfig represents a single MCP server configuration
+// MCPServerConfig represents a single MCP server configuration.
+// Stdio servers set Command/Args; HTTP servers set Type and URL.
 type MCPServerConfig struct {
-	Command string   `json:"command"`
-	Args    []string `json:"args"`
+	Type    string            `json:"type,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
 }
 
 // Result represents the outcome of a Claude Code execution
 type Result struct {
-	State    string
-	Output   string
-	Error    string
-	Duration time.Duration
+	State      string
+	Output     string // stdout; for Claude runs in JSON mode, only the final answer text
+	Error      string
+	Stderr     string // captured separately so diagnostics don't mix with Output
+	ExitCode   int    // process exit status; -1 if it was killed by a signal or never started
+	Duration   time.Duration
+	PlannedOps []PlannedOp // file operations proposed by a plan-mode (dry run) execution
 }
 
 // BuildArgs constructs the command-line arguments for claude
 func BuildArgs(cfg config.ClaudeConfig, prompt string, debug bool) []string {
 	args := []string{"--print"}
 
-	if debug {
+	if debug || cfg.PermissionMode == "plan" {
+		// Plan mode streams so proposed tool calls can be summarized
 		args = append(args, "--verbose", "--output-format", "stream-json")
+	} else {
+		// A single JSON result lets runClaude separate the answer from diagnostics
+		args = append(args, "--output-format", "json")
 	}
 
 	if cfg.Model != "" {
@@ -72,21 +87,37 @@ func BuildArgs(cfg config.ClaudeConfig, prompt string, debug bool) []string {
 	return args
 }
 
+// memoryServerConfig returns the MCP server entry for the memory server.
+// An http(s) URL points at the daemon's shared HTTP server; anything else is
+// treated as the daemon executable path and spawned over stdio. ruleName, if
+// set, is passed along so the server can tag and scope memories.
+func memoryServerConfig(mcpURL, ruleName string) MCPServerConfig {
+	if strings.HasPrefix(mcpURL, "http://") || strings.HasPrefix(mcpURL, "https://") {
+		cfg := MCPServerConfig{Type: "http", URL: mcpURL}
+		if ruleName != "" {
+			cfg.Headers = map[string]string{mcp.RuleHeader: ruleName}
+		}
+		return cfg
+	}
+	cfg := MCPServerConfig{Command: mcpURL, Args: []string{"mcp-server"}}
+	if ruleName != "" {
+		cfg.Env = map[string]string{mcp.RuleEnv: ruleName}
+	}
+	return cfg
+}
+
 // BuildArgsWithMemory constructs command-line arguments with optional memory MCP injection
-// If mcpURL is provided, uses HTTP transport; otherwise falls back to stdio with daemonPath
+// If mcpURL is an HTTP URL, uses HTTP transport; otherwise it is the daemon path for stdio
+// ruleName identifies the calling rule to the memory server
 // Returns the args slice, a cleanup function to remove temp files, and any error
-func BuildArgsWithMemory(cfg config.ClaudeConfig, prompt string, debug bool, memoryEnabled bool, mcpURL string) ([]string, func(), error) {
+func BuildArgsWithMemory(cfg config.ClaudeConfig, prompt string, debug bool, memoryEnabled bool, mcpURL, ruleName string) ([]string, func(), error) {
 	args := BuildArgs(cfg, prompt, debug)
 	cleanup := func() {}
 
 	if memoryEnabled && mcpURL != "" {
-		// mcpURL is actually the daemon path for stdio transport
 		mcpCfg := MCPConfig{
 			MCPServers: map[string]MCPServerConfig{
-				"srvrmgr-memory": {
-					Command: mcpURL,
-					Args:    []string{"mcp-server"},
-				},
+				"srvrmgr-memory": memoryServerConfig(mcpURL, ruleName),
 			},
 		}
 
@@ -117,25 +148,100 @@ func BuildArgsWithMemory(cfg config.ClaudeConfig, prompt string, debug bool, mem
 
 // Execute runs Claude Code with the given configuration
 func Execute(ctx context.Context, prompt string, cfg config.ClaudeConfig, user string, debug bool, workDir string) (*Result, error) {
-	return ExecuteWithMemory(ctx, prompt, cfg, user, debug, workDir, false, "")
+	return ExecuteWithMemory(ctx, prompt, cfg, user, debug, workDir, false, "", "")
 }
 
 // ExecuteWithMemory runs Claude Code with optional memory MCP injection
-// mcpURL should be the HTTP URL of the MCP server (e.g., "http://127.0.0.1:9877")
-func ExecuteWithMemory(ctx context.Context, prompt string, cfg config.ClaudeConfig, user string, debug bool, workDir string, memoryEnabled bool, mcpURL string) (*Result, error) {
-	args, cleanup, err := BuildArgsWithMemory(cfg, prompt, debug, memoryEnabled, mcpURL)
+// mcpURL is either the HTTP URL of the shared MCP server (e.g., "http://127.0.0.1:9877/mcp")
+// or the daemon executable path for the stdio fallback; ruleName is the rule being run
+func ExecuteWithMemory(ctx context.Context, prompt string, cfg config.ClaudeConfig, user string, debug bool, workDir string, memoryEnabled bool, mcpURL, ruleName string) (*Result, error) {
+	args, cleanup, err := BuildArgsWithMemory(cfg, prompt, debug, memoryEnabled, mcpURL, ruleName)
 	if err != nil {
 		return nil, err
 	}
 	defer cleanup()
 
+	cmd := buildCommand(ctx, user, cfg.EnvVars, workDir, "claude", args...)
+	if debug {
+		// stream-json transcripts are kept whole in Output for debugging
+		return runCommand(ctx, cmd), nil
+	}
+	if cfg.PermissionMode == "plan" {
+		return runClaudePlan(ctx, cmd), nil
+	}
+	return runClaude(ctx, cmd), nil
+}
+
+// claudeResult is the object printed by claude --print --output-format json.
+type claudeResult struct {
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+	IsError bool   `json:"is_error"`
+	Result  string `json:"result"`
+}
+
+// runClaude runs a JSON-mode claude command. Output is the final answer text,
+// so callers such as TRIGGER: marker parsing only see the assistant's answer.
+// If stdout isn't a JSON result, it is used as Output unchanged.
+func runClaude(ctx context.Context, cmd *exec.Cmd) *Result {
+	result := runCommand(ctx, cmd)
+	if answer, ok := parseClaudeResult([]byte(result.Output)); ok {
+		result.Output = answer.Result
+		if answer.IsError && result.State == "success" {
+			result.State = "failure"
+			result.Error = fmt.Sprintf("claude reported an error (%s)", answer.Subtype)
+		}
+	}
+	return result
+}
+
+// runClaudePlan runs a plan-mode (dry run) claude command in stream-json mode.
+// Like runClaude, Output is the final answer, followed by a summary of the
+// file operations Claude proposed, which are also returned as PlannedOps.
+func runClaudePlan(ctx context.Context, cmd *exec.Cmd) *Result {
+	result := runCommand(ctx, cmd)
+	if answer, ops, ok := parsePlanStream([]byte(result.Output)); ok {
+		result.PlannedOps = ops
+		result.Output = strings.TrimSpace(answer.Result + "\n\n" + FormatPlan(ops))
+		if answer.IsError && result.State == "success" {
+			result.State = "failure"
+			result.Error = fmt.Sprintf("claude reported an error (%s)", answer.Subtype)
+		}
+	}
+	return result
+}
+
+// parseClaudeResult decodes the result object from claude's JSON output.
+func parseClaudeResult(stdout []byte) (claudeResult, bool) {
+	var res claudeResult
+	if err := json.Unmarshal(bytes.TrimSpace(stdout), &res); err != nil || res.Type != "result" {
+		return claudeResult{}, false
+	}
+	return res, true
+}
+
+// killWaitDelay bounds how long a killed command's output is drained.
+const killWaitDelay = 2 * time.Second
+
+// buildCommand creates the subprocess for name/args, running it as user via sudo
+// when set and passing env_vars through in either case. Under run_as_user, HOME,
+// USER and LOGNAME point at that user and the working directory defaults to
+// their home, so tools don't pick up the daemon's (root's) config.
+func buildCommand(ctx context.Context, user string, envVars map[string]string, workDir string, name string, args ...string) *exec.Cmd {
 	// FR-18: Resolve env var references.
 	// Sourced from architect (os.ExpandEnv) for robustness — handles $VAR, ${VAR}, and more.
 	// Combined with convention's sudo env passthrough pattern.
-	resolved := resolveEnvVars(cfg.EnvVars)
+	resolved := resolveEnvVars(envVars)
 
 	var cmd *exec.Cmd
 	if user != "" {
+		if u, err := osuser.Lookup(user); err == nil {
+			resolved = withUserEnv(resolved, u)
+			if workDir == "" {
+				workDir = u.HomeDir
+			}
+		}
+
 		sudoArgs := []string{"-u", user}
 		// FR-18: Pass env_vars through sudo using env command.
 		// Sourced from convention — sudo's env_reset would strip env vars otherwise.
@@ -145,11 +251,11 @@ func ExecuteWithMemory(ctx context.Context, prompt string, cfg config.ClaudeConf
 				sudoArgs = append(sudoArgs, k+"="+v)
 			}
 		}
-		sudoArgs = append(sudoArgs, "claude")
+		sudoArgs = append(sudoArgs, name)
 		sudoArgs = append(sudoArgs, args...)
 		cmd = exec.CommandContext(ctx, "sudo", sudoArgs...)
 	} else {
-		cmd = exec.CommandContext(ctx, "claude", args...)
+		cmd = exec.CommandContext(ctx, name, args...)
 		// FR-18: Pass env_vars directly when not using sudo
 		if len(resolved) > 0 {
 			cmd.Env = os.Environ()
@@ -162,43 +268,81 @@ func ExecuteWithMemory(ctx context.Context, prompt string, cfg config.ClaudeConf
 	if workDir != "" {
 		cmd.Dir = workDir
 	}
+	// Kill the whole process tree on timeout or cancellation, and don't wait
+	// indefinitely for any process still holding the output pipe.
+	setProcessGroup(cmd)
+	cmd.WaitDelay = killWaitDelay
+	return cmd
+}
+
+// withUserEnv adds HOME, USER and LOGNAME for u to env without overriding
+// values the rule set explicitly in env_vars.
+func withUserEnv(env map[string]string, u *osuser.User) map[string]string {
+	out := make(map[string]string, len(env)+3)
+	for k, v := range env {
+		out[k] = v
+	}
+	defaults := map[string]string{"HOME": u.HomeDir, "USER": u.Username, "LOGNAME": u.Username}
+	for k, v := range defaults {
+		if _, ok := out[k]; !ok && v != "" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// runCommand runs cmd to completion with stdout in Output and stderr in Stderr.
+func runCommand(ctx context.Context, cmd *exec.Cmd) *Result {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
 	start := time.Now()
-	output, err := cmd.CombinedOutput()
-	duration := time.Since(start)
+	err := cmd.Run()
+	result := newResult(ctx, stdout.String(), err, time.Since(start))
+	result.Stderr = stderr.String()
+	result.ExitCode = -1
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	return result
+}
 
+// newResult maps a finished command's error to a result state, distinguishing
+// timeouts and cancellations via ctx.
+func newResult(ctx context.Context, output string, err error, duration time.Duration) *Result {
 	if err != nil {
 		// Check if it was a context cancellation (timeout or shutdown)
 		if ctx.Err() == context.DeadlineExceeded {
 			return &Result{
 				State:    "timeout",
 				Error:    "execution timed out",
-				Output:   string(output),
+				Output:   output,
 				Duration: duration,
-			}, nil
+			}
 		}
 		if ctx.Err() == context.Canceled {
 			return &Result{
 				State:    "cancelled",
 				Error:    "execution cancelled",
-				Output:   string(output),
+				Output:   output,
 				Duration: duration,
-			}, nil
+			}
 		}
 
 		return &Result{
 			State:    "failure",
 			Error:    err.Error(),
-			Output:   string(output),
+			Output:   output,
 			Duration: duration,
-		}, nil
+		}
 	}
 
 	return &Result{
 		State:    "success",
-		Output:   string(output),
+		Output:   output,
 		Duration: duration,
-	}, nil
+	}
 }
 
 // FR-18: resolveEnvVars expands environment variable references in values.
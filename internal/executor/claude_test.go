@@ -2,9 +2,17 @@
 package executor
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/mcp"
 )
 
 func TestBuildArgs(t *testing.T) {
@@ -77,7 +85,7 @@ func TestBuildArgsWithMemory(t *testing.T) {
 		Model: "sonnet",
 	}
 
-	args, cleanup, err := BuildArgsWithMemory(cfg, "Do something", false, true, "/usr/local/bin/srvrmgrd")
+	args, cleanup, err := BuildArgsWithMemory(cfg, "Do something", false, true, "/usr/local/bin/srvrmgrd", "")
 	if err != nil {
 		t.Fatalf("BuildArgsWithMemory() error = %v", err)
 	}
@@ -91,12 +99,96 @@ func TestBuildArgsWithMemory(t *testing.T) {
 	}
 }
 
+// readMemoryMCPConfig returns the srvrmgr-memory entry from the injected --mcp-config file
+func readMemoryMCPConfig(t *testing.T, args []string) MCPServerConfig {
+	t.Helper()
+	for i, arg := range args {
+		if arg != "--mcp-config" || i+1 >= len(args) {
+			continue
+		}
+		data, err := os.ReadFile(args[i+1])
+		if err != nil {
+			t.Fatalf("reading MCP config: %v", err)
+		}
+		var cfg MCPConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			t.Fatalf("parsing MCP config: %v", err)
+		}
+		if srv, ok := cfg.MCPServers["srvrmgr-memory"]; ok {
+			return srv
+		}
+	}
+	t.Fatalf("no srvrmgr-memory MCP config injected in %v", args)
+	return MCPServerConfig{}
+}
+
+func TestBuildArgsWithMemoryStdio(t *testing.T) {
+	cfg := config.ClaudeConfig{Model: "sonnet"}
+
+	args, cleanup, err := BuildArgsWithMemory(cfg, "Do something", false, true, "/usr/local/bin/srvrmgrd", "")
+	if err != nil {
+		t.Fatalf("BuildArgsWithMemory() error = %v", err)
+	}
+	defer cleanup()
+
+	srv := readMemoryMCPConfig(t, args)
+	if srv.Command != "/usr/local/bin/srvrmgrd" || len(srv.Args) != 1 || srv.Args[0] != "mcp-server" {
+		t.Errorf("expected stdio config for daemon path, got %+v", srv)
+	}
+	if srv.Type != "" || srv.URL != "" {
+		t.Errorf("stdio config should not set type/url, got %+v", srv)
+	}
+}
+
+func TestBuildArgsWithMemoryHTTP(t *testing.T) {
+	cfg := config.ClaudeConfig{Model: "sonnet"}
+
+	args, cleanup, err := BuildArgsWithMemory(cfg, "Do something", false, true, "http://127.0.0.1:9877/mcp", "")
+	if err != nil {
+		t.Fatalf("BuildArgsWithMemory() error = %v", err)
+	}
+	defer cleanup()
+
+	srv := readMemoryMCPConfig(t, args)
+	if srv.Type != "http" || srv.URL != "http://127.0.0.1:9877/mcp" {
+		t.Errorf("expected http config pointing at shared server, got %+v", srv)
+	}
+	if srv.Command != "" {
+		t.Errorf("http config should not spawn a command, got %q", srv.Command)
+	}
+	if args[len(args)-1] != "Do something" {
+		t.Errorf("expected prompt as last arg, got %s", args[len(args)-1])
+	}
+}
+
+func TestBuildArgsWithMemoryPassesRuleName(t *testing.T) {
+	cfg := config.ClaudeConfig{Model: "sonnet"}
+
+	args, cleanup, err := BuildArgsWithMemory(cfg, "Do something", false, true, "http://127.0.0.1:9877/mcp", "backup")
+	if err != nil {
+		t.Fatalf("BuildArgsWithMemory() error = %v", err)
+	}
+	defer cleanup()
+	if srv := readMemoryMCPConfig(t, args); srv.Headers[mcp.RuleHeader] != "backup" {
+		t.Errorf("http config headers = %v, want %s: backup", srv.Headers, mcp.RuleHeader)
+	}
+
+	args, cleanup, err = BuildArgsWithMemory(cfg, "Do something", false, true, "/usr/local/bin/srvrmgrd", "backup")
+	if err != nil {
+		t.Fatalf("BuildArgsWithMemory() error = %v", err)
+	}
+	defer cleanup()
+	if srv := readMemoryMCPConfig(t, args); srv.Env[mcp.RuleEnv] != "backup" {
+		t.Errorf("stdio config env = %v, want %s=backup", srv.Env, mcp.RuleEnv)
+	}
+}
+
 func TestBuildArgsWithMemoryDisabled(t *testing.T) {
 	cfg := config.ClaudeConfig{
 		Model: "sonnet",
 	}
 
-	args, cleanup, err := BuildArgsWithMemory(cfg, "Do something", false, false, "/usr/local/bin/srvrmgrd")
+	args, cleanup, err := BuildArgsWithMemory(cfg, "Do something", false, false, "/usr/local/bin/srvrmgrd", "")
 	if err != nil {
 		t.Fatalf("BuildArgsWithMemory() error = %v", err)
 	}
@@ -120,7 +212,7 @@ func TestBuildArgsWithMemoryEmptyDaemonPath(t *testing.T) {
 		Model: "sonnet",
 	}
 
-	args, cleanup, err := BuildArgsWithMemory(cfg, "Do something", false, true, "")
+	args, cleanup, err := BuildArgsWithMemory(cfg, "Do something", false, true, "", "")
 	if err != nil {
 		t.Fatalf("BuildArgsWithMemory() error = %v", err)
 	}
@@ -171,3 +263,123 @@ func TestBuildArgs_EnvVarsField(t *testing.T) {
 		t.Errorf("FR-18: expected PLEX_TOKEN=test-token, got %q", cfg.EnvVars["PLEX_TOKEN"])
 	}
 }
+
+func TestBuildCommand_RunAsUserSetsHome(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("cannot determine current user: %v", err)
+	}
+
+	cmd := buildCommand(context.Background(), current.Username, map[string]string{"FOO": "bar"}, "", "claude", "--print")
+
+	if !slices.Contains(cmd.Args, "HOME="+current.HomeDir) {
+		t.Errorf("expected HOME=%s in sudo env args, got %v", current.HomeDir, cmd.Args)
+	}
+	if !slices.Contains(cmd.Args, "USER="+current.Username) {
+		t.Errorf("expected USER=%s in sudo env args, got %v", current.Username, cmd.Args)
+	}
+	if !slices.Contains(cmd.Args, "FOO=bar") {
+		t.Errorf("expected env_vars to be preserved, got %v", cmd.Args)
+	}
+	if cmd.Dir != current.HomeDir {
+		t.Errorf("Dir = %q, want user's home %q when no add_dir is set", cmd.Dir, current.HomeDir)
+	}
+}
+
+func TestBuildCommand_RunAsUserKeepsExplicitEnvAndWorkDir(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("cannot determine current user: %v", err)
+	}
+
+	workDir := t.TempDir()
+	cmd := buildCommand(context.Background(), current.Username, map[string]string{"HOME": "/custom/home"}, workDir, "claude")
+
+	if !slices.Contains(cmd.Args, "HOME=/custom/home") || slices.Contains(cmd.Args, "HOME="+current.HomeDir) {
+		t.Errorf("explicit HOME in env_vars should win, got %v", cmd.Args)
+	}
+	if cmd.Dir != workDir {
+		t.Errorf("Dir = %q, want add_dir %q", cmd.Dir, workDir)
+	}
+}
+
+func TestBuildCommand_NoUserLeavesEnvAlone(t *testing.T) {
+	cmd := buildCommand(context.Background(), "", nil, "", "claude")
+	if cmd.Env != nil {
+		t.Errorf("expected inherited environment, got %v", cmd.Env)
+	}
+	if cmd.Dir != "" {
+		t.Errorf("Dir = %q, want unset", cmd.Dir)
+	}
+}
+
+// fakeClaude puts a claude executable running script first on PATH.
+func fakeClaude(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+const resultFixture = `{"type":"result","subtype":"success","is_error":false,"duration_ms":1200,"num_turns":2,"result":"Cleaned 3 caches.\nTRIGGER: notify","session_id":"abc","total_cost_usd":0.01}`
+
+func TestBuildArgs_JSONOutputUnlessDebug(t *testing.T) {
+	args := BuildArgs(config.ClaudeConfig{}, "test", false)
+	if !slices.Contains(args, "json") || slices.Contains(args, "stream-json") {
+		t.Errorf("non-debug args = %v, want --output-format json", args)
+	}
+}
+
+func TestParseClaudeResult(t *testing.T) {
+	res, ok := parseClaudeResult([]byte(resultFixture + "\n"))
+	if !ok {
+		t.Fatal("parseClaudeResult() failed on fixture")
+	}
+	if res.Result != "Cleaned 3 caches.\nTRIGGER: notify" || res.IsError {
+		t.Errorf("parsed = %+v", res)
+	}
+
+	for _, bad := range []string{"", "plain text answer", `{"type":"assistant"}`} {
+		if _, ok := parseClaudeResult([]byte(bad)); ok {
+			t.Errorf("parseClaudeResult(%q) should not parse", bad)
+		}
+	}
+}
+
+func TestExecute_JSONOutputSeparatesStderr(t *testing.T) {
+	fakeClaude(t, "echo 'TRIGGER: from-stderr' >&2\ncat <<'EOF'\n"+resultFixture+"\nEOF\n")
+
+	result, err := Execute(context.Background(), "clean", config.ClaudeConfig{}, "", false, "")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.State != "success" {
+		t.Fatalf("State = %q (%s)", result.State, result.Error)
+	}
+	if result.Output != "Cleaned 3 caches.\nTRIGGER: notify" {
+		t.Errorf("Output = %q, want only the answer text", result.Output)
+	}
+	if !strings.Contains(result.Stderr, "from-stderr") {
+		t.Errorf("Stderr = %q, want stderr", result.Stderr)
+	}
+}
+
+func TestExecute_JSONIsErrorFails(t *testing.T) {
+	fakeClaude(t, `echo '{"type":"result","subtype":"error_max_turns","is_error":true,"result":""}'`)
+
+	result, _ := Execute(context.Background(), "clean", config.ClaudeConfig{}, "", false, "")
+	if result.State != "failure" || !strings.Contains(result.Error, "error_max_turns") {
+		t.Errorf("result = %+v, want failure naming the subtype", result)
+	}
+}
+
+func TestExecute_NonJSONOutputKept(t *testing.T) {
+	fakeClaude(t, "echo 'old claude plain output'")
+
+	result, _ := Execute(context.Background(), "clean", config.ClaudeConfig{}, "", false, "")
+	if result.State != "success" || strings.TrimSpace(result.Output) != "old claude plain output" {
+		t.Errorf("result = %+v, want raw stdout as Output", result)
+	}
+}
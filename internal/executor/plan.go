@@ -0,0 +1,168 @@
+// internal/executor/plan.go
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PlannedOp is a file operation Claude proposed during a plan-mode (dry run)
+// execution. Op is "write", "edit", "delete" or "run"; run ops carry the shell
+// command instead of a path.
+type PlannedOp struct {
+	Op      string `json:"op"`
+	Path    string `json:"path,omitempty"`
+	Command string `json:"command,omitempty"`
+}
+
+// streamEvent is one line of claude --output-format stream-json. Assistant
+// messages carry tool_use blocks; the final line is the same result object as
+// --output-format json.
+type streamEvent struct {
+	claudeResult
+	Message struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+	} `json:"message"`
+}
+
+// toolInput holds the tool_use input fields that name files or commands.
+type toolInput struct {
+	FilePath     string `json:"file_path"`
+	NotebookPath string `json:"notebook_path"`
+	Command      string `json:"command"`
+}
+
+// parsePlanStream extracts the final result and the proposed file operations
+// from a stream-json transcript. ok is false if there is no result line.
+func parsePlanStream(stdout []byte) (res claudeResult, ops []PlannedOp, ok bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var ev streamEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		switch ev.Type {
+		case "assistant":
+			for _, block := range ev.Message.Content {
+				if block.Type == "tool_use" {
+					ops = append(ops, plannedOps(block.Name, block.Input)...)
+				}
+			}
+		case "result":
+			res, ok = ev.claudeResult, true
+		}
+	}
+	return res, ops, ok
+}
+
+// plannedOps maps one proposed tool call to the file operations it implies.
+// Read-only tools yield none.
+func plannedOps(tool string, raw json.RawMessage) []PlannedOp {
+	var in toolInput
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil
+	}
+	switch tool {
+	case "Write":
+		return []PlannedOp{{Op: "write", Path: in.FilePath}}
+	case "Edit", "MultiEdit":
+		return []PlannedOp{{Op: "edit", Path: in.FilePath}}
+	case "NotebookEdit":
+		return []PlannedOp{{Op: "edit", Path: in.NotebookPath}}
+	case "Bash":
+		if paths := removedPaths(in.Command); len(paths) > 0 {
+			ops := make([]PlannedOp, len(paths))
+			for i, p := range paths {
+				ops[i] = PlannedOp{Op: "delete", Path: p}
+			}
+			return ops
+		}
+		if in.Command != "" {
+			return []PlannedOp{{Op: "run", Command: in.Command}}
+		}
+	}
+	return nil
+}
+
+// removedPaths returns the operands of a simple `rm` command, or nil if
+// command is anything else (including pipelines and compound commands).
+func removedPaths(command string) []string {
+	if strings.ContainsAny(command, ";&|`$()<>\\") {
+		return nil
+	}
+	fields, ok := splitWords(command)
+	if !ok || len(fields) < 2 || fields[0] != "rm" {
+		return nil
+	}
+	var paths []string
+	for _, f := range fields[1:] {
+		if strings.HasPrefix(f, "-") {
+			continue
+		}
+		paths = append(paths, f)
+	}
+	return paths
+}
+
+// splitWords splits s on whitespace, honouring single and double quotes. It
+// reports false for an unterminated quote.
+func splitWords(s string) ([]string, bool) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, false
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, true
+}
+
+// FormatPlan renders planned operations as the summary appended to a dry
+// run's output.
+func FormatPlan(ops []PlannedOp) string {
+	if len(ops) == 0 {
+		return "Planned file operations (dry run): none"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Planned file operations (dry run): %d", len(ops))
+	for _, op := range ops {
+		target := op.Path
+		if op.Op == "run" {
+			target = op.Command
+		}
+		fmt.Fprintf(&b, "\n  %-6s %s", op.Op, target)
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+// internal/executor/plan_test.go
+package executor
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+)
+
+// planStreamFixture is a trimmed claude --permission-mode plan --output-format
+// stream-json transcript.
+const planStreamFixture = `{"type":"system","subtype":"init","session_id":"abc","tools":["Bash","Read","Write","Edit"]}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Let me look at the downloads folder."},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/Users/me/Downloads/report.pdf"}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"..."}]}}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Write","input":{"file_path":"/Users/me/Documents/Reports/report.pdf","content":"..."}},{"type":"tool_use","id":"t3","name":"Edit","input":{"file_path":"/Users/me/Documents/index.md","old_string":"a","new_string":"b"}}]}}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t4","name":"Bash","input":{"command":"rm -f /Users/me/Downloads/report.pdf '/Users/me/Downloads/old copy.tmp'"}},{"type":"tool_use","id":"t5","name":"Bash","input":{"command":"mv a b && ls"}}]}}
+{"type":"result","subtype":"success","is_error":false,"result":"Plan: file the report and clean up.","session_id":"abc"}
+`
+
+func TestParsePlanStream(t *testing.T) {
+	res, ops, ok := parsePlanStream([]byte(planStreamFixture))
+	if !ok {
+		t.Fatal("parsePlanStream() found no result line")
+	}
+	if res.Result != "Plan: file the report and clean up." {
+		t.Errorf("result = %q", res.Result)
+	}
+
+	want := []PlannedOp{
+		{Op: "write", Path: "/Users/me/Documents/Reports/report.pdf"},
+		{Op: "edit", Path: "/Users/me/Documents/index.md"},
+		{Op: "delete", Path: "/Users/me/Downloads/report.pdf"},
+		{Op: "delete", Path: "/Users/me/Downloads/old copy.tmp"},
+		{Op: "run", Command: "mv a b && ls"},
+	}
+	if !slices.Equal(ops, want) {
+		t.Errorf("ops = %+v\nwant %+v", ops, want)
+	}
+}
+
+func TestParsePlanStream_NoResult(t *testing.T) {
+	if _, _, ok := parsePlanStream([]byte("not json\n")); ok {
+		t.Error("parsePlanStream() should fail without a result line")
+	}
+}
+
+func TestFormatPlan(t *testing.T) {
+	got := FormatPlan([]PlannedOp{{Op: "write", Path: "/tmp/a"}, {Op: "run", Command: "make"}})
+	for _, want := range []string{"dry run): 2", "write  /tmp/a", "run    make"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatPlan() = %q, missing %q", got, want)
+		}
+	}
+	if got := FormatPlan(nil); !strings.HasSuffix(got, "none") {
+		t.Errorf("FormatPlan(nil) = %q", got)
+	}
+}
+
+func TestBuildArgs_PlanModeStreams(t *testing.T) {
+	args := BuildArgs(config.ClaudeConfig{PermissionMode: "plan"}, "test", false)
+	if !slices.Contains(args, "stream-json") || !slices.Contains(args, "--verbose") {
+		t.Errorf("plan-mode args = %v, want --verbose --output-format stream-json", args)
+	}
+}
+
+func TestExecute_PlanModeSummarizesOperations(t *testing.T) {
+	fakeClaude(t, "cat <<'EOF'\n"+planStreamFixture+"EOF\n")
+
+	result, err := Execute(context.Background(), "file reports", config.ClaudeConfig{PermissionMode: "plan"}, "", false, "")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.State != "success" {
+		t.Fatalf("State = %q (%s)", result.State, result.Error)
+	}
+	if len(result.PlannedOps) == 0 || result.PlannedOps[0].Op != "write" {
+		t.Errorf("PlannedOps = %+v", result.PlannedOps)
+	}
+	if !strings.HasPrefix(result.Output, "Plan: file the report") ||
+		!strings.Contains(result.Output, "write  /Users/me/Documents/Reports/report.pdf") {
+		t.Errorf("Output = %q, want answer followed by plan summary", result.Output)
+	}
+}
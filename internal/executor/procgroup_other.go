@@ -0,0 +1,9 @@
+//go:build !unix
+
+package executor
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups aren't available; only the
+// direct child is killed on cancellation.
+func setProcessGroup(cmd *exec.Cmd) {}
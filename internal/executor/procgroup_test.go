@@ -0,0 +1,58 @@
+//go:build darwin || linux
+
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// processAlive reports whether pid is running. Orphans reparented to a PID 1
+// that doesn't reap them linger as zombies, which count as exited.
+func processAlive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	return err != nil || !strings.Contains(string(stat), ") Z ")
+}
+
+func TestExecuteScript_TimeoutKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	// The backgrounded sleep outlives the shell unless the whole group is killed
+	script := "sleep 30 & echo $! > " + pidFile + "; wait"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	result, err := ExecuteScript(ctx, script, nil, "", "")
+	if err != nil {
+		t.Fatalf("ExecuteScript() error = %v", err)
+	}
+	if result.State != "timeout" {
+		t.Errorf("State = %q, want timeout", result.State)
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("child pid not written: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { syscall.Kill(pid, syscall.SIGKILL) })
+
+	deadline := time.Now().Add(groupKillDelay + 2*time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("child process %d survived the timeout", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package executor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// groupKillDelay is how long a cancelled process group gets to exit after
+// SIGTERM before it is sent SIGKILL.
+const groupKillDelay = 1 * time.Second
+
+// setProcessGroup starts cmd in its own process group and makes cancellation
+// (timeout or shutdown) signal the whole group, so MCP servers and tool
+// subprocesses don't survive as orphans.
+//
+// The group gets SIGTERM first because sudo, when it runs the command on its
+// own pty, starts it in a separate session that a group signal can't reach;
+// sudo relays SIGTERM to it but cannot relay SIGKILL. Whatever is left is
+// killed after groupKillDelay.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := -cmd.Process.Pid
+		if err := syscall.Kill(pgid, syscall.SIGTERM); err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return os.ErrProcessDone
+			}
+			return err
+		}
+		time.AfterFunc(groupKillDelay, func() {
+			syscall.Kill(pgid, syscall.SIGKILL)
+		})
+		return nil
+	}
+}
@@ -0,0 +1,13 @@
+// internal/executor/script.go
+package executor
+
+import "context"
+
+// ExecuteScript runs a shell script for rules that use action.script instead of
+// a Claude prompt. It shares user switching, env_vars and result mapping with
+// ExecuteWithMemory, so a non-zero exit is a "failure" and the context deadline
+// is a "timeout".
+func ExecuteScript(ctx context.Context, script string, envVars map[string]string, user string, workDir string) (*Result, error) {
+	cmd := buildCommand(ctx, user, envVars, workDir, "/bin/sh", "-c", script)
+	return runCommand(ctx, cmd), nil
+}
@@ -0,0 +1,72 @@
+// internal/executor/script_test.go
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteScript_Success(t *testing.T) {
+	dir := t.TempDir()
+	result, err := ExecuteScript(context.Background(), `echo "hello $GREETING_NAME"; pwd`,
+		map[string]string{"GREETING_NAME": "world"}, "", dir)
+	if err != nil {
+		t.Fatalf("ExecuteScript() error = %v", err)
+	}
+	if result.State != "success" {
+		t.Fatalf("State = %q, want success (error: %s)", result.State, result.Error)
+	}
+	if !strings.Contains(result.Output, "hello world") {
+		t.Errorf("Output = %q, want env var expanded", result.Output)
+	}
+	if !strings.Contains(result.Output, dir) {
+		t.Errorf("Output = %q, want working directory %s", result.Output, dir)
+	}
+}
+
+func TestExecuteScript_NonZeroExitIsFailure(t *testing.T) {
+	result, err := ExecuteScript(context.Background(), "echo partial; exit 3", nil, "", "")
+	if err != nil {
+		t.Fatalf("ExecuteScript() error = %v", err)
+	}
+	if result.State != "failure" {
+		t.Errorf("State = %q, want failure", result.State)
+	}
+	if !strings.Contains(result.Error, "exit status 3") {
+		t.Errorf("Error = %q, want exit status", result.Error)
+	}
+	if !strings.Contains(result.Output, "partial") {
+		t.Errorf("Output = %q, want output captured on failure", result.Output)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", result.ExitCode)
+	}
+}
+
+func TestExecuteScript_SeparatesStderr(t *testing.T) {
+	result, err := ExecuteScript(context.Background(), "echo answer; echo 'disk not mounted' >&2; exit 1", nil, "", "")
+	if err != nil {
+		t.Fatalf("ExecuteScript() error = %v", err)
+	}
+	if strings.TrimSpace(result.Output) != "answer" {
+		t.Errorf("Output = %q, want only stdout", result.Output)
+	}
+	if strings.TrimSpace(result.Stderr) != "disk not mounted" {
+		t.Errorf("Stderr = %q, want only stderr", result.Stderr)
+	}
+}
+
+func TestExecuteScript_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := ExecuteScript(ctx, "sleep 2", nil, "", "")
+	if err != nil {
+		t.Fatalf("ExecuteScript() error = %v", err)
+	}
+	if result.State != "timeout" {
+		t.Errorf("State = %q, want timeout", result.State)
+	}
+}
@@ -0,0 +1,123 @@
+// internal/logging/filter.go
+package logging
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+)
+
+// levelPatterns find the level attribute in text and JSON slog lines.
+var levelPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\blevel=(\S+)`),
+	regexp.MustCompile(`"level":"([^"]+)"`),
+}
+
+// LineFilter selects log lines matching a pattern and at or above a minimum
+// level. The zero value matches every line.
+type LineFilter struct {
+	pattern  *regexp.Regexp
+	rule     *regexp.Regexp
+	minLevel *slog.Level
+}
+
+// NewLineFilter builds a filter from a regular expression (a plain substring
+// works too) and a minimum level name ("debug", "info", "warn", "error").
+// Either may be empty to skip that check.
+func NewLineFilter(pattern, level string, ignoreCase bool) (*LineFilter, error) {
+	f := &LineFilter{}
+	if pattern != "" {
+		if ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		f.pattern = re
+	}
+	if level != "" {
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid level %q: must be debug, info, warn or error", level)
+		}
+		f.minLevel = &lvl
+	}
+	return f, nil
+}
+
+// SetRule restricts the filter to lines logged with the rule attribute set to
+// name (see WithRule).
+func (f *LineFilter) SetRule(name string) {
+	q := regexp.QuoteMeta(name)
+	f.rule = regexp.MustCompile(`\brule=` + q + `(\s|$)|"rule":"` + q + `"`)
+}
+
+// Active reports whether the filter drops any lines.
+func (f *LineFilter) Active() bool {
+	return f.pattern != nil || f.rule != nil || f.minLevel != nil
+}
+
+// Match reports whether line passes the filter. With a minimum level set,
+// lines without a recognizable level are dropped.
+func (f *LineFilter) Match(line string) bool {
+	if f.minLevel != nil {
+		lvl, ok := lineLevel(line)
+		if !ok || lvl < *f.minLevel {
+			return false
+		}
+	}
+	if f.rule != nil && !f.rule.MatchString(line) {
+		return false
+	}
+	return f.pattern == nil || f.pattern.MatchString(line)
+}
+
+// Filter copies the lines of r that pass the filter to w. If tail is positive,
+// only the last tail matching lines are written, after r is fully read.
+func (f *LineFilter) Filter(r io.Reader, w io.Writer, tail int) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var last []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !f.Match(line) {
+			continue
+		}
+		if tail <= 0 {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(last) == tail {
+			last = last[1:]
+		}
+		last = append(last, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading log: %w", err)
+	}
+	for _, line := range last {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineLevel extracts the slog level of a text or JSON log line.
+func lineLevel(line string) (slog.Level, bool) {
+	for _, re := range levelPatterns {
+		if m := re.FindStringSubmatch(line); m != nil {
+			var lvl slog.Level
+			if err := lvl.UnmarshalText([]byte(m[1])); err == nil {
+				return lvl, true
+			}
+		}
+	}
+	return 0, false
+}
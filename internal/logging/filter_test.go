@@ -0,0 +1,99 @@
+// internal/logging/filter_test.go
+package logging
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleLog = `time=2026-01-01T00:00:00Z level=INFO msg="handling event" rule=backup type=scheduled
+time=2026-01-01T00:00:01Z level=DEBUG msg="template expanded" rule=backup
+time=2026-01-01T00:00:02Z level=WARN msg="skipping rule" rule=cleanup reason=paused
+time=2026-01-01T00:00:03Z level=ERROR msg="execution error" rule=Backup error="exit status 1"
+{"time":"2026-01-01T00:00:04Z","level":"ERROR","msg":"rule failed after all retries","rule":"cleanup"}
+panic: continuation line without a level
+`
+
+func filterSample(t *testing.T, pattern, level string, ignoreCase bool, tail int) []string {
+	t.Helper()
+	f, err := NewLineFilter(pattern, level, ignoreCase)
+	if err != nil {
+		t.Fatalf("NewLineFilter() error = %v", err)
+	}
+	var b strings.Builder
+	if err := f.Filter(strings.NewReader(sampleLog), &b, tail); err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+}
+
+func TestLineFilter_Pattern(t *testing.T) {
+	got := filterSample(t, "rule=backup", "", false, 0)
+	if len(got) != 2 || !strings.Contains(got[1], "template expanded") {
+		t.Errorf("Filter(rule=backup) = %q", got)
+	}
+
+	got = filterSample(t, "rule=backup", "", true, 0)
+	if len(got) != 3 || !strings.Contains(got[2], "rule=Backup") {
+		t.Errorf("Filter(rule=backup, ignore case) = %q", got)
+	}
+
+	got = filterSample(t, `"rule":"cleanup"|reason=\w+`, "", false, 0)
+	if len(got) != 2 {
+		t.Errorf("Filter(regex) = %q, want 2 lines", got)
+	}
+}
+
+func TestLineFilter_Level(t *testing.T) {
+	got := filterSample(t, "", "warn", false, 0)
+	if len(got) != 3 {
+		t.Fatalf("Filter(level=warn) = %q, want WARN, ERROR and JSON ERROR lines", got)
+	}
+	for _, line := range got {
+		if strings.Contains(line, "DEBUG") || strings.Contains(line, "INFO") || strings.HasPrefix(line, "panic") {
+			t.Errorf("Filter(level=warn) kept %q", line)
+		}
+	}
+
+	got = filterSample(t, "cleanup", "error", false, 0)
+	if len(got) != 1 || !strings.Contains(got[0], "all retries") {
+		t.Errorf("Filter(cleanup, level=error) = %q", got)
+	}
+}
+
+func TestLineFilter_Rule(t *testing.T) {
+	f, _ := NewLineFilter("", "", false)
+	f.SetRule("cleanup")
+	var b strings.Builder
+	f.Filter(strings.NewReader(sampleLog), &b, 0)
+	got := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(got) != 2 || !strings.Contains(got[0], "skipping rule") || !strings.Contains(got[1], "all retries") {
+		t.Errorf("Filter(rule=cleanup) = %q", got)
+	}
+}
+
+func TestLineFilter_Tail(t *testing.T) {
+	got := filterSample(t, "", "info", false, 2)
+	if len(got) != 2 || !strings.Contains(got[0], "execution error") || !strings.Contains(got[1], "all retries") {
+		t.Errorf("Filter(tail=2) = %q, want the last two matches", got)
+	}
+}
+
+func TestLineFilter_ZeroValueMatchesAll(t *testing.T) {
+	f, _ := NewLineFilter("", "", false)
+	if f.Active() {
+		t.Error("empty filter should not be active")
+	}
+	if !f.Match("panic: anything") {
+		t.Error("empty filter should match every line")
+	}
+}
+
+func TestNewLineFilter_Invalid(t *testing.T) {
+	if _, err := NewLineFilter("(", "", false); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	if _, err := NewLineFilter("", "loud", false); err == nil {
+		t.Error("expected error for invalid level")
+	}
+}
@@ -5,24 +5,70 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
+	"github.com/colebrumley/srvrmgr/internal/config"
 	"github.com/colebrumley/srvrmgr/internal/embedder"
 	"github.com/colebrumley/srvrmgr/internal/memory"
+	"github.com/colebrumley/srvrmgr/internal/security"
+	"github.com/colebrumley/srvrmgr/internal/state"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // Server wraps the MCP server with memory tools
 type Server struct {
 	db       *memory.DB
-	embedder *embedder.Embedder
+	embedder textEmbedder
 	server   *mcp.Server
+	history  HistoryReader // nil until SetHistory; backs recall_executions
+	rule     string        // calling rule when requests don't name one; see SetRule
+
+	unknownCategory string // config.UnknownCategory*; see SetUnknownCategory
+}
+
+// textEmbedder is the part of embedder.Embedder the server uses.
+type textEmbedder interface {
+	Embed(text string) ([]float32, error)
+	EmbedBatch(texts []string) ([][]float32, error)
+	Warm() error
+	State() string
+	Close() error
+}
+
+// newEmbedder creates the server's embedder; tests replace it to simulate
+// the model being unavailable.
+var newEmbedder = embedder.New
+
+// failedEmbedder stands in for an embedder that couldn't be created. Every
+// call returns err, so memories are stored without embeddings and recall
+// falls back to keyword search.
+type failedEmbedder struct {
+	err error
+}
+
+func (f failedEmbedder) Embed(string) ([]float32, error)          { return nil, f.err }
+func (f failedEmbedder) EmbedBatch([]string) ([][]float32, error) { return nil, f.err }
+func (f failedEmbedder) Warm() error                              { return f.err }
+func (f failedEmbedder) State() string                            { return embedder.StateFailed }
+func (f failedEmbedder) Close() error                             { return nil }
+
+// HistoryReader is the read-only slice of the execution history database
+// (state.DB) that the recall_executions tool uses.
+type HistoryReader interface {
+	GetHistory(ruleName, state string, triggerTypes []string, limit int) ([]state.ExecutionRecord, error)
 }
 
 // RememberInput is the input schema for the remember tool
 type RememberInput struct {
 	Content  string `json:"content" jsonschema:"The knowledge to store"`
 	Category string `json:"category,omitempty" jsonschema:"Optional category: file-patterns, api-behaviors, system-quirks, naming-conventions"`
+	Shared   bool   `json:"shared,omitempty" jsonschema:"Store for every rule instead of only the current one"`
 }
 
 // RememberOutput is the output schema for the remember tool
@@ -31,18 +77,38 @@ type RememberOutput struct {
 	Message string `json:"message"`
 }
 
+// RememberManyInput is the input schema for the remember_many tool
+type RememberManyInput struct {
+	Memories []RememberInput `json:"memories" jsonschema:"The memories to store"`
+}
+
+// RememberManyOutput is the output schema for the remember_many tool
+type RememberManyOutput struct {
+	IDs     []int64 `json:"ids"`
+	Message string  `json:"message"`
+}
+
 // RecallInput is the input schema for the recall tool
 type RecallInput struct {
 	Query    string `json:"query" jsonschema:"Search terms"`
-	Category string `json:"category,omitempty" jsonschema:"Optional category filter"`
+	Category string `json:"category,omitempty" jsonschema:"Optional category filter; only categories memories have been stored under match"`
 	Mode     string `json:"mode,omitempty" jsonschema:"Search mode: semantic (default) or keyword"`
-	Limit    int    `json:"limit,omitempty" jsonschema:"Max results (default 10)"`
+	Limit    int    `json:"limit,omitempty" jsonschema:"Max results (default 10, max 50)"`
+	Scope    string `json:"scope,omitempty" jsonschema:"rule (default): the current rule's memories plus shared ones; all: every rule's memories"`
 }
 
+// Recall scopes
+const (
+	ScopeRule = "rule"
+	ScopeAll  = "all"
+)
+
 // RecallOutput is the output schema for the recall tool
 type RecallOutput struct {
 	Memories []MemoryResult `json:"memories"`
 	Count    int            `json:"count"`
+	Warning  string         `json:"warning,omitempty"`
+	Note     string         `json:"note,omitempty"` // e.g. why an unknown category matched nothing
 }
 
 // MemoryResult is a single memory in recall results
@@ -50,6 +116,7 @@ type MemoryResult struct {
 	ID       int64   `json:"id"`
 	Content  string  `json:"content"`
 	Category string  `json:"category,omitempty"`
+	Rule     string  `json:"rule,omitempty"`
 	Score    float32 `json:"score,omitempty"`
 }
 
@@ -63,6 +130,59 @@ type ForgetOutput struct {
 	Message string `json:"message"`
 }
 
+// ForgetWhereInput is the input schema for the forget_where tool
+type ForgetWhereInput struct {
+	Category      string `json:"category,omitempty" jsonschema:"Only remove memories in this category"`
+	OlderThanDays int    `json:"older_than_days,omitempty" jsonschema:"Only remove memories created more than this many days ago"`
+}
+
+// ForgetWhereOutput is the output schema for the forget_where tool
+type ForgetWhereOutput struct {
+	Deleted int64  `json:"deleted"`
+	Message string `json:"message"`
+}
+
+// RecallExecutionsInput is the input schema for the recall_executions tool
+type RecallExecutionsInput struct {
+	Rule  string `json:"rule,omitempty" jsonschema:"Rule name whose past executions to return; defaults to, and must be, the calling rule when it is known"`
+	State string `json:"state,omitempty" jsonschema:"Optional state filter: success, failure, timeout, cancelled or skipped"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Max records, newest first (default 10, max 50)"`
+}
+
+// RecallExecutionsOutput is the output schema for the recall_executions tool
+type RecallExecutionsOutput struct {
+	Executions []ExecutionResult `json:"executions"`
+	Count      int               `json:"count"`
+}
+
+// ExecutionResult is a single execution in recall_executions results
+type ExecutionResult struct {
+	ID           int64     `json:"id"`
+	State        string    `json:"state"`
+	SkipReason   string    `json:"skip_reason,omitempty"`
+	TriggerType  string    `json:"trigger_type"`
+	StartedAt    time.Time `json:"started_at"`
+	DurationMs   int64     `json:"duration_ms"`
+	RetryAttempt int       `json:"retry_attempt,omitempty"`
+	DryRun       bool      `json:"dry_run,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Output       string    `json:"output,omitempty"`
+}
+
+// recall limits: results per call
+const (
+	defaultRecallLimit = 10
+	maxRecallLimit     = 50
+)
+
+// recall_executions limits: records per call and characters per output/error,
+// so a few calls can't flood the agent's context.
+const (
+	defaultExecutionsLimit = 10
+	maxExecutionsLimit     = 50
+	maxExecutionTextLen    = 500
+)
+
 // NewServer creates a new MCP server with memory tools
 func NewServer(dbPath string) (*Server, error) {
 	db, err := memory.Open(dbPath)
@@ -70,10 +190,13 @@ func NewServer(dbPath string) (*Server, error) {
 		return nil, fmt.Errorf("opening memory database: %w", err)
 	}
 
-	emb, err := embedder.New()
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("creating embedder: %w", err)
+	// Without an embedder memory still works in keyword-only mode, rather
+	// than not at all
+	var emb textEmbedder
+	if e, err := newEmbedder(); err != nil {
+		emb = failedEmbedder{err: fmt.Errorf("creating embedder: %w", err)}
+	} else {
+		emb = e
 	}
 
 	s := &Server{db: db, embedder: emb}
@@ -89,10 +212,16 @@ func NewServer(dbPath string) (*Server, error) {
 		Description: "Store domain knowledge you've learned that would help future rule executions. Use for: file patterns/conventions, API behaviors, system quirks, naming conventions. Be specific and factual. Don't store: execution logs, temporary state, or procedural instructions.",
 	}, s.handleRemember)
 
+	// Register remember_many tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "remember_many",
+		Description: "Store several pieces of domain knowledge at once, e.g. when seeding memory from a document. Same guidance as remember applies to each entry.",
+	}, s.handleRememberMany)
+
 	// Register recall tool
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "recall",
-		Description: "Search stored memories for relevant domain knowledge. Use before making assumptions about files, APIs, or system behaviors you've encountered before.",
+		Description: "Search stored memories for relevant domain knowledge. Use before making assumptions about files, APIs, or system behaviors you've encountered before. By default only this rule's memories and shared ones are searched.",
 	}, s.handleRecall)
 
 	// Register forget tool
@@ -101,19 +230,85 @@ func NewServer(dbPath string) (*Server, error) {
 		Description: "Remove a memory that's no longer accurate or relevant. Use when you learn something that contradicts a stored memory.",
 	}, s.handleForget)
 
+	// Register forget_where tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "forget_where",
+		Description: "Bulk-remove memories by category and/or age, e.g. everything in a temporary category older than 30 days. At least one of category or older_than_days is required.",
+	}, s.handleForgetWhere)
+
 	s.server = server
 	return s, nil
 }
 
+// SetHistory gives the server read access to execution history and registers
+// the recall_executions tool. Servers without history (e.g. the stdio
+// fallback) don't offer the tool.
+func (s *Server) SetHistory(h HistoryReader) {
+	if h == nil || s.history != nil {
+		return
+	}
+	s.history = h
+
+	// Register recall_executions tool
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:        "recall_executions",
+		Description: "Look up this rule's recent executions (newest first) to learn from past outcomes, e.g. why the last run failed. Read-only; output and errors are truncated.",
+	}, s.handleRecallExecutions)
+}
+
+// SetRule sets the rule that requests come from when they don't carry the
+// rule header, e.g. a stdio server spawned for a single rule execution.
+func (s *Server) SetRule(name string) {
+	s.rule = name
+}
+
+// SetUnknownCategory sets what recall does when asked for a category no
+// memory is stored under: config.UnknownCategoryEmpty (the default) returns
+// nothing, config.UnknownCategoryAll searches every category. Either way the
+// result notes the known categories.
+func (s *Server) SetUnknownCategory(mode string) {
+	s.unknownCategory = mode
+}
+
+// The server learns which rule is calling it from this header (HTTP) or
+// environment variable (stdio), which the executor sets when it configures
+// the memory server for a rule, so memories can be scoped per rule.
+const (
+	RuleHeader = "X-Srvrmgr-Rule"
+	RuleEnv    = "SRVRMGR_MEMORY_RULE"
+)
+
+// callerRule returns the name of the rule making req, or "" if unknown.
+func (s *Server) callerRule(req *mcp.CallToolRequest) string {
+	if req != nil && req.Extra != nil {
+		if name := req.Extra.Header.Get(RuleHeader); name != "" {
+			return name
+		}
+	}
+	return s.rule
+}
+
+// ruleFor returns the rule name to store a memory under: none if it is
+// shared, otherwise the caller's.
+func (s *Server) ruleFor(req *mcp.CallToolRequest, shared bool) string {
+	if shared {
+		return ""
+	}
+	return s.callerRule(req)
+}
+
 func (s *Server) handleRemember(ctx context.Context, req *mcp.CallToolRequest, input RememberInput) (*mcp.CallToolResult, RememberOutput, error) {
+	// Scrub secrets first so the embedding is computed from what is actually stored
+	content := security.ScrubOutput(input.Content)
+
 	// Generate embedding
-	embedding, err := s.embedder.Embed(input.Content)
+	embedding, err := s.embedder.Embed(content)
 	if err != nil {
 		// Log warning but continue without embedding
 		embedding = nil
 	}
 
-	id, err := s.db.RememberWithEmbedding(input.Content, input.Category, "", embedding)
+	id, err := s.db.RememberWithEmbedding(content, input.Category, s.ruleFor(req, input.Shared), embedding)
 	if err != nil {
 		return nil, RememberOutput{}, fmt.Errorf("failed to store memory: %w", err)
 	}
@@ -123,10 +318,43 @@ func (s *Server) handleRemember(ctx context.Context, req *mcp.CallToolRequest, i
 	}, nil
 }
 
+func (s *Server) handleRememberMany(ctx context.Context, req *mcp.CallToolRequest, input RememberManyInput) (*mcp.CallToolResult, RememberManyOutput, error) {
+	if len(input.Memories) == 0 {
+		return nil, RememberManyOutput{}, fmt.Errorf("memories must contain at least one entry")
+	}
+
+	memories := make([]memory.Memory, len(input.Memories))
+	contents := make([]string, len(input.Memories))
+	for i, m := range input.Memories {
+		// Scrub secrets first so embeddings are computed from what is actually stored
+		contents[i] = security.ScrubOutput(m.Content)
+		memories[i] = memory.Memory{Content: contents[i], Category: m.Category, RuleName: s.ruleFor(req, m.Shared)}
+	}
+
+	// Embed everything in one pipeline call
+	embeddings, err := s.embedder.EmbedBatch(contents)
+	if err != nil || len(embeddings) != len(contents) {
+		// Continue without embeddings; they can be backfilled later
+		embeddings = nil
+	}
+
+	ids, err := s.db.RememberManyWithEmbedding(memories, embeddings)
+	if err != nil {
+		return nil, RememberManyOutput{}, fmt.Errorf("failed to store memories: %w", err)
+	}
+	return nil, RememberManyOutput{
+		IDs:     ids,
+		Message: fmt.Sprintf("Stored %d memories", len(ids)),
+	}, nil
+}
+
 func (s *Server) handleRecall(ctx context.Context, req *mcp.CallToolRequest, input RecallInput) (*mcp.CallToolResult, RecallOutput, error) {
 	limit := input.Limit
 	if limit <= 0 {
-		limit = 10
+		limit = defaultRecallLimit
+	}
+	if limit > maxRecallLimit {
+		limit = maxRecallLimit
 	}
 
 	mode := input.Mode
@@ -134,51 +362,117 @@ func (s *Server) handleRecall(ctx context.Context, req *mcp.CallToolRequest, inp
 		mode = "semantic"
 	}
 
-	results := []MemoryResult{}
+	var ruleName string
+	switch input.Scope {
+	case "", ScopeRule:
+		ruleName = s.callerRule(req)
+	case ScopeAll:
+	default:
+		return nil, RecallOutput{}, fmt.Errorf("scope must be %q or %q, got %q", ScopeRule, ScopeAll, input.Scope)
+	}
+
+	category, search, note, err := s.checkCategory(input.Category)
+	if err != nil {
+		return nil, RecallOutput{}, err
+	}
+	if !search {
+		return nil, RecallOutput{Memories: []MemoryResult{}, Note: note}, nil
+	}
 
 	if mode == "keyword" {
-		// Use FTS5 keyword search
-		memories, err := s.db.Recall(input.Query, input.Category)
-		if err != nil {
-			return nil, RecallOutput{}, fmt.Errorf("failed to search memories: %w", err)
-		}
-		for _, m := range memories {
-			if len(results) >= limit {
-				break
-			}
-			results = append(results, MemoryResult{
-				ID:       m.ID,
-				Content:  m.Content,
-				Category: m.Category,
-			})
-		}
-	} else {
-		// Use semantic search
-		queryEmbedding, err := s.embedder.Embed(input.Query)
+		results, err := s.keywordRecall(input.Query, category, ruleName, limit)
 		if err != nil {
-			return nil, RecallOutput{}, fmt.Errorf("failed to embed query: %w", err)
+			return nil, RecallOutput{}, err
 		}
+		return nil, RecallOutput{Memories: results, Count: len(results), Note: note}, nil
+	}
 
-		memories, err := s.db.RecallSemantic(queryEmbedding, input.Category, limit)
-		if err != nil {
-			return nil, RecallOutput{}, fmt.Errorf("failed to search memories: %w", err)
-		}
-		for _, m := range memories {
-			results = append(results, MemoryResult{
-				ID:       m.ID,
-				Content:  m.Content,
-				Category: m.Category,
-				Score:    m.Score,
-			})
+	// Use semantic search, or keyword search if the embedding model is
+	// unavailable
+	queryEmbedding, err := s.embedder.Embed(input.Query)
+	if err != nil {
+		results, kerr := s.keywordRecall(input.Query, category, ruleName, limit)
+		if kerr != nil {
+			return nil, RecallOutput{}, kerr
 		}
+		return nil, RecallOutput{
+			Memories: results,
+			Count:    len(results),
+			Warning:  fmt.Sprintf("semantic search unavailable (%v); showing keyword matches instead", err),
+			Note:     note,
+		}, nil
+	}
+
+	memories, err := s.db.RecallSemantic(queryEmbedding, category, ruleName, limit)
+	if err != nil {
+		return nil, RecallOutput{}, fmt.Errorf("failed to search memories: %w", err)
+	}
+	results := []MemoryResult{}
+	for _, m := range memories {
+		results = append(results, MemoryResult{
+			ID:       m.ID,
+			Content:  m.Content,
+			Category: m.Category,
+			Rule:     m.RuleName,
+			Score:    m.Score,
+		})
 	}
 
 	return nil, RecallOutput{
 		Memories: results,
 		Count:    len(results),
+		Note:     note,
 	}, nil
 }
 
+// checkCategory resolves the category filter for a recall. A category no
+// memory is stored under (often a guess or a typo) either matches nothing,
+// so search is false, or with config.UnknownCategoryAll is dropped; note
+// then says so and lists the known categories.
+func (s *Server) checkCategory(category string) (filter string, search bool, note string, err error) {
+	if category == "" {
+		return "", true, "", nil
+	}
+	known, err := s.db.Categories()
+	if err != nil {
+		return "", false, "", fmt.Errorf("failed to list categories: %w", err)
+	}
+	if slices.Contains(known, category) {
+		return category, true, "", nil
+	}
+
+	knownList := "none"
+	if len(known) > 0 {
+		knownList = strings.Join(known, ", ")
+	}
+	if s.unknownCategory == config.UnknownCategoryAll {
+		return "", true, fmt.Sprintf("no memories are stored under category %q, so all categories were searched; known categories: %s", category, knownList), nil
+	}
+	return "", false, fmt.Sprintf("no memories are stored under category %q; known categories: %s", category, knownList), nil
+}
+
+// keywordRecall runs a full-text search for query, returning up to limit
+// results.
+func (s *Server) keywordRecall(query, category, ruleName string, limit int) ([]MemoryResult, error) {
+	memories, err := s.db.Recall(query, category, ruleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memories: %w", err)
+	}
+	results := []MemoryResult{}
+	for _, m := range memories {
+		if len(results) >= limit {
+			break
+		}
+		results = append(results, MemoryResult{
+			ID:       m.ID,
+			Content:  m.Content,
+			Category: m.Category,
+			Rule:     m.RuleName,
+		})
+	}
+	return results, nil
+}
+
 func (s *Server) handleForget(ctx context.Context, req *mcp.CallToolRequest, input ForgetInput) (*mcp.CallToolResult, ForgetOutput, error) {
 	err := s.db.Forget(input.ID)
 	if err != nil {
@@ -192,26 +486,133 @@ func (s *Server) handleForget(ctx context.Context, req *mcp.CallToolRequest, inp
 	}, nil
 }
 
+func (s *Server) handleForgetWhere(ctx context.Context, req *mcp.CallToolRequest, input ForgetWhereInput) (*mcp.CallToolResult, ForgetWhereOutput, error) {
+	if input.OlderThanDays < 0 {
+		return nil, ForgetWhereOutput{}, fmt.Errorf("older_than_days must be >= 0, got %d", input.OlderThanDays)
+	}
+
+	var olderThan time.Time
+	if input.OlderThanDays > 0 {
+		olderThan = time.Now().AddDate(0, 0, -input.OlderThanDays)
+	}
+
+	deleted, err := s.db.ForgetWhere(input.Category, olderThan)
+	if err != nil {
+		if errors.Is(err, memory.ErrNoFilter) {
+			return nil, ForgetWhereOutput{}, fmt.Errorf("refusing to delete all memories: specify category and/or older_than_days")
+		}
+		return nil, ForgetWhereOutput{}, fmt.Errorf("failed to delete memories: %w", err)
+	}
+	return nil, ForgetWhereOutput{
+		Deleted: deleted,
+		Message: fmt.Sprintf("Deleted %d memories", deleted),
+	}, nil
+}
+
+func (s *Server) handleRecallExecutions(ctx context.Context, req *mcp.CallToolRequest, input RecallExecutionsInput) (*mcp.CallToolResult, RecallExecutionsOutput, error) {
+	if s.history == nil {
+		return nil, RecallExecutionsOutput{}, fmt.Errorf("execution history is not available")
+	}
+	// A rule may only read its own history: other rules' output and errors
+	// can hold paths and secrets it has no business seeing
+	rule := input.Rule
+	caller := s.callerRule(req)
+	switch {
+	case rule == "":
+		rule = caller
+	case caller != "" && rule != caller:
+		return nil, RecallExecutionsOutput{}, fmt.Errorf("rule %q can only recall its own executions, not %q's", caller, rule)
+	}
+	if rule == "" {
+		return nil, RecallExecutionsOutput{}, fmt.Errorf("rule is required")
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultExecutionsLimit
+	}
+	if limit > maxExecutionsLimit {
+		limit = maxExecutionsLimit
+	}
+
+	records, err := s.history.GetHistory(rule, input.State, nil, limit)
+	if err != nil {
+		return nil, RecallExecutionsOutput{}, fmt.Errorf("failed to read execution history: %w", err)
+	}
+
+	results := []ExecutionResult{}
+	for _, r := range records {
+		results = append(results, ExecutionResult{
+			ID:           r.ID,
+			State:        r.State,
+			SkipReason:   r.SkipReason,
+			TriggerType:  r.TriggerType,
+			StartedAt:    r.StartedAt,
+			DurationMs:   r.DurationMs,
+			RetryAttempt: r.RetryAttempt,
+			DryRun:       r.DryRun,
+			Error:        truncateText(security.ScrubOutput(r.Error), maxExecutionTextLen),
+			Output:       truncateText(security.ScrubOutput(r.Output), maxExecutionTextLen),
+		})
+	}
+	return nil, RecallExecutionsOutput{
+		Executions: results,
+		Count:      len(results),
+	}, nil
+}
+
+// truncateText shortens s to at most n bytes without splitting a UTF-8 rune.
+func truncateText(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "... [truncated]"
+}
+
+// WarmUp loads the embedding model so the first remember or recall call
+// doesn't wait for it.
+func (s *Server) WarmUp() error {
+	return s.embedder.Warm()
+}
+
+// EmbedderState reports whether the embedding model is loaded; see
+// embedder.State.
+func (s *Server) EmbedderState() string {
+	return s.embedder.State()
+}
+
+// BackfillEmbeddings embeds any stored memories that are missing an embedding.
+// The model is only loaded if there is at least one such memory.
+func (s *Server) BackfillEmbeddings() (int, error) {
+	return s.db.BackfillEmbeddings(s.embedder.Embed)
+}
+
 // Run starts the MCP server on stdio
 func (s *Server) Run(ctx context.Context) error {
 	return s.server.Run(ctx, &mcp.StdioTransport{})
 }
 
-// RunHTTP starts the MCP server as an HTTP server on the given address
-// Uses SSE transport with endpoint at /sse for compatibility with Claude Code
-func (s *Server) RunHTTP(ctx context.Context, addr string) error {
-	sseHandler := mcp.NewSSEHandler(func(r *http.Request) *mcp.Server {
-		return s.server
-	}, nil)
-
-	mux := http.NewServeMux()
-	// Serve SSE at both root and /sse path for compatibility
-	mux.Handle("/", sseHandler)
-	mux.Handle("/sse", sseHandler)
+// Listen opens the TCP listener for RunHTTP. An address that is already in
+// use gets an error saying so rather than the bare bind error.
+func Listen(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return nil, fmt.Errorf("%s is already in use (is another srvrmgrd running? set memory.listen_port or SRVRMGR_MCP_PORT to use another port): %w", addr, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	return ln, nil
+}
 
+// RunHTTP serves the MCP server over HTTP on ln until ctx is cancelled.
+// See Handler for the routes that are served.
+func (s *Server) RunHTTP(ctx context.Context, ln net.Listener) error {
 	httpServer := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+		Handler: s.Handler(),
 	}
 
 	// Shutdown gracefully on context cancellation
@@ -220,13 +621,58 @@ func (s *Server) RunHTTP(ctx context.Context, addr string) error {
 		httpServer.Shutdown(context.Background())
 	}()
 
-	err := httpServer.ListenAndServe()
+	err := httpServer.Serve(ln)
 	if err == http.ErrServerClosed {
 		return nil
 	}
 	return err
 }
 
+// Handler returns the HTTP handler for the MCP server. Both transports are
+// served concurrently so clients can migrate without reconfiguring the daemon:
+//   - /mcp: streamable HTTP transport (preferred by newer clients)
+//   - / and /sse: SSE transport, kept for compatibility with existing configs
+func (s *Server) Handler() http.Handler {
+	getServer := func(r *http.Request) *mcp.Server {
+		return s.server
+	}
+	sseHandler := mcp.NewSSEHandler(getServer, nil)
+	streamableHandler := mcp.NewStreamableHTTPHandler(getServer, nil)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", sseHandler)
+	mux.Handle("/sse", sseHandler)
+	mux.Handle("/mcp", streamableHandler)
+	return mux
+}
+
+// Tools returns the registered tools as a client sees them when listing
+// tools, including their input schemas.
+func (s *Server) Tools(ctx context.Context) ([]*mcp.Tool, error) {
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := s.server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to MCP server: %w", err)
+	}
+	defer serverSession.Close()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "srvrmgr-tools", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connecting MCP client: %w", err)
+	}
+	defer session.Close()
+
+	var tools []*mcp.Tool
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("listing tools: %w", err)
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
 // MCPServer returns the underlying MCP server for direct use
 func (s *Server) MCPServer() *mcp.Server {
 	return s.server
@@ -3,8 +3,22 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/colebrumley/srvrmgr/internal/embedder"
+	"github.com/colebrumley/srvrmgr/internal/state"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func TestNewServer(t *testing.T) {
@@ -166,3 +180,498 @@ func TestSemanticRecall(t *testing.T) {
 		t.Error("Expected non-zero similarity score")
 	}
 }
+
+func TestForgetWhere(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	server, err := NewServer(dbPath)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+
+	ctx := context.Background()
+
+	server.db.Remember("scratch note", "temp", "")
+	server.db.Remember("invoices are PDFs", "file-patterns", "")
+
+	t.Run("requires a filter", func(t *testing.T) {
+		_, _, err := server.handleForgetWhere(ctx, nil, ForgetWhereInput{})
+		if err == nil {
+			t.Error("handleForgetWhere() should return error when no filter is given")
+		}
+	})
+
+	t.Run("by category", func(t *testing.T) {
+		_, output, err := server.handleForgetWhere(ctx, nil, ForgetWhereInput{Category: "temp"})
+		if err != nil {
+			t.Fatalf("handleForgetWhere() error = %v", err)
+		}
+		if output.Deleted != 1 {
+			t.Errorf("handleForgetWhere() deleted = %d, want 1", output.Deleted)
+		}
+	})
+}
+
+func TestRememberScrubsSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	server, err := NewServer(dbPath)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+
+	ctx := context.Background()
+
+	_, output, err := server.handleRemember(ctx, nil, RememberInput{
+		Content:  "Plex library refresh URL is http://plex:32400/library/sections/1/refresh?X-Plex-Token=abc123secret",
+		Category: "api-behaviors",
+	})
+	if err != nil {
+		t.Fatalf("handleRemember() error = %v", err)
+	}
+
+	rows, err := server.db.Recall("Plex", "", "")
+	if err != nil || len(rows) != 1 || rows[0].ID != output.ID {
+		t.Fatalf("reading stored memory: rows=%v err=%v", rows, err)
+	}
+	stored := rows[0].Content
+	if strings.Contains(stored, "abc123secret") {
+		t.Errorf("stored memory contains secret: %q", stored)
+	}
+	if !strings.Contains(stored, "X-Plex-Token=[REDACTED]") {
+		t.Errorf("stored memory not redacted: %q", stored)
+	}
+
+	_, recallOut, err := server.handleRecall(ctx, nil, RecallInput{Query: "Plex", Mode: "keyword"})
+	if err != nil {
+		t.Fatalf("handleRecall() error = %v", err)
+	}
+	if recallOut.Count != 1 {
+		t.Fatalf("handleRecall() count = %d, want 1", recallOut.Count)
+	}
+	if strings.Contains(recallOut.Memories[0].Content, "abc123secret") {
+		t.Errorf("recalled memory contains secret: %q", recallOut.Memories[0].Content)
+	}
+}
+
+func TestRememberTagsCallingRule(t *testing.T) {
+	server, err := NewServer(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+	ctx := context.Background()
+
+	fromRule := func(name string) *mcp.CallToolRequest {
+		return &mcp.CallToolRequest{Extra: &mcp.RequestExtra{Header: http.Header{RuleHeader: {name}}}}
+	}
+	remember := func(req *mcp.CallToolRequest, input RememberInput) {
+		t.Helper()
+		if _, _, err := server.handleRemember(ctx, req, input); err != nil {
+			t.Fatalf("handleRemember() error = %v", err)
+		}
+	}
+	remember(fromRule("backup"), RememberInput{Content: "nas share is mounted at /Volumes/nas"})
+	remember(fromRule("photos"), RememberInput{Content: "nas photos live under /Volumes/nas/photos"})
+	remember(fromRule("photos"), RememberInput{Content: "nas is asleep before 6am", Shared: true})
+	server.SetRule("cleanup") // stdio servers learn the rule from the environment
+	remember(nil, RememberInput{Content: "nas trash is emptied weekly"})
+
+	rules := func(out RecallOutput) []string {
+		var got []string
+		for _, m := range out.Memories {
+			got = append(got, m.Rule)
+		}
+		sort.Strings(got)
+		return got
+	}
+	tests := []struct {
+		name string
+		req  *mcp.CallToolRequest
+		in   RecallInput
+		want []string
+	}{
+		{"own and shared", fromRule("backup"), RecallInput{Query: "nas", Mode: "keyword"}, []string{"", "backup"}},
+		{"explicit rule scope", fromRule("photos"), RecallInput{Query: "nas", Mode: "keyword", Scope: ScopeRule}, []string{"", "photos"}},
+		{"rule from SetRule", nil, RecallInput{Query: "nas", Mode: "keyword"}, []string{"", "cleanup"}},
+		{"all rules", fromRule("backup"), RecallInput{Query: "nas", Mode: "keyword", Scope: ScopeAll}, []string{"", "backup", "cleanup", "photos"}},
+	}
+	for _, tt := range tests {
+		_, out, err := server.handleRecall(ctx, tt.req, tt.in)
+		if err != nil {
+			t.Fatalf("%s: handleRecall() error = %v", tt.name, err)
+		}
+		if got := rules(out); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: recalled rules = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, _, err := server.handleRecall(ctx, nil, RecallInput{Query: "nas", Mode: "keyword", Scope: "mine"}); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+}
+
+func TestStreamableHTTPTransport(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	server, err := NewServer(dbPath)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+
+	if _, err := server.db.Remember("Backups run nightly at 02:00", "system-quirks", ""); err != nil {
+		t.Fatalf("Remember() error = %v", err)
+	}
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{Endpoint: ts.URL + "/mcp"}, nil)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer session.Close()
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "recall",
+		Arguments: map[string]any{"query": "Backups", "mode": "keyword"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("recall returned tool error: %+v", result.Content)
+	}
+
+	data, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		t.Fatalf("marshaling structured content: %v", err)
+	}
+	var output RecallOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatalf("unmarshaling recall output: %v", err)
+	}
+	if output.Count != 1 {
+		t.Errorf("recall over streamable HTTP count = %d, want 1", output.Count)
+	}
+}
+
+func TestRememberMany(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	server, err := NewServer(dbPath)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+
+	ctx := context.Background()
+
+	_, output, err := server.handleRememberMany(ctx, nil, RememberManyInput{
+		Memories: []RememberInput{
+			{Content: "Invoices from Acme are PDFs", Category: "file-patterns"},
+			{Content: "Invoices from Globex are CSVs", Category: "file-patterns"},
+			{Content: "The router reboots nightly", Category: "system-quirks"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("handleRememberMany() error = %v", err)
+	}
+	if len(output.IDs) != 3 {
+		t.Fatalf("handleRememberMany() stored %d memories, want 3", len(output.IDs))
+	}
+
+	_, recallOut, err := server.handleRecall(ctx, nil, RecallInput{Query: "Invoices", Mode: "keyword"})
+	if err != nil {
+		t.Fatalf("handleRecall() error = %v", err)
+	}
+	if recallOut.Count != 2 {
+		t.Errorf("handleRecall() count = %d, want 2", recallOut.Count)
+	}
+
+	if _, _, err := server.handleRememberMany(ctx, nil, RememberManyInput{}); err == nil {
+		t.Error("handleRememberMany() should reject an empty list")
+	}
+}
+
+func TestRecallExecutions(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	server, err := NewServer(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+
+	ctx := context.Background()
+
+	if _, _, err := server.handleRecallExecutions(ctx, nil, RecallExecutionsInput{Rule: "backup"}); err == nil {
+		t.Error("handleRecallExecutions() should fail without history")
+	}
+
+	history, err := state.Open(filepath.Join(tmpDir, "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	defer history.Close()
+
+	started := time.Now().Add(-time.Hour)
+	seed := []state.ExecutionRecord{
+		{RuleName: "backup", TriggerType: "scheduled", State: "failure", Error: "disk full: " + strings.Repeat("x", 1000)},
+		{RuleName: "backup", TriggerType: "scheduled", State: "success", Output: "copied 12 files"},
+		{RuleName: "other", TriggerType: "manual", State: "success"},
+	}
+	for i, rec := range seed {
+		rec.StartedAt = started.Add(time.Duration(i) * time.Minute)
+		rec.FinishedAt = rec.StartedAt
+		if _, err := history.RecordExecution(rec); err != nil {
+			t.Fatalf("RecordExecution() error = %v", err)
+		}
+	}
+	server.SetHistory(history)
+
+	_, output, err := server.handleRecallExecutions(ctx, nil, RecallExecutionsInput{Rule: "backup"})
+	if err != nil {
+		t.Fatalf("handleRecallExecutions() error = %v", err)
+	}
+	if output.Count != 2 {
+		t.Fatalf("handleRecallExecutions() count = %d, want 2", output.Count)
+	}
+	if output.Executions[0].State != "success" || output.Executions[0].Output != "copied 12 files" {
+		t.Errorf("newest execution = %+v", output.Executions[0])
+	}
+	failed := output.Executions[1]
+	if !strings.HasPrefix(failed.Error, "disk full") || len(failed.Error) > maxExecutionTextLen+len("... [truncated]") {
+		t.Errorf("error not truncated: %d bytes", len(failed.Error))
+	}
+
+	_, output, err = server.handleRecallExecutions(ctx, nil, RecallExecutionsInput{Rule: "backup", State: "failure"})
+	if err != nil || output.Count != 1 {
+		t.Errorf("state filter: count = %d, err = %v; want 1", output.Count, err)
+	}
+
+	if _, _, err := server.handleRecallExecutions(ctx, nil, RecallExecutionsInput{}); err == nil {
+		t.Error("handleRecallExecutions() should require a rule")
+	}
+
+	// A rule's own history by default, and never another rule's
+	fromBackup := &mcp.CallToolRequest{Extra: &mcp.RequestExtra{Header: http.Header{RuleHeader: {"backup"}}}}
+	_, output, err = server.handleRecallExecutions(ctx, fromBackup, RecallExecutionsInput{})
+	if err != nil || output.Count != 2 {
+		t.Errorf("caller's own history: count = %d, err = %v; want 2", output.Count, err)
+	}
+	if _, _, err := server.handleRecallExecutions(ctx, fromBackup, RecallExecutionsInput{Rule: "other"}); err == nil {
+		t.Error("handleRecallExecutions() should reject another rule's history")
+	}
+	server.SetRule("other") // stdio servers learn the rule from the environment
+	if _, _, err := server.handleRecallExecutions(ctx, nil, RecallExecutionsInput{Rule: "backup"}); err == nil {
+		t.Error("handleRecallExecutions() should reject another rule's history over stdio")
+	}
+}
+
+func TestRecallExecutionsRegisteredWithHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	server, err := NewServer(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+
+	history, err := state.Open(filepath.Join(tmpDir, "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error = %v", err)
+	}
+	defer history.Close()
+	now := time.Now()
+	if _, err := history.RecordExecution(state.ExecutionRecord{
+		RuleName: "backup", TriggerType: "manual", State: "success", StartedAt: now, FinishedAt: now,
+	}); err != nil {
+		t.Fatalf("RecordExecution() error = %v", err)
+	}
+	server.SetHistory(history)
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{Endpoint: ts.URL + "/mcp"}, nil)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer session.Close()
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "recall_executions",
+		Arguments: map[string]any{"rule": "backup"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("recall_executions returned tool error: %+v", result.Content)
+	}
+
+	data, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		t.Fatalf("marshaling structured content: %v", err)
+	}
+	var output RecallExecutionsOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatalf("unmarshaling recall_executions output: %v", err)
+	}
+	if output.Count != 1 || output.Executions[0].State != "success" {
+		t.Errorf("recall_executions over streamable HTTP = %+v, want the seeded record", output)
+	}
+}
+
+func TestListen_AddressInUse(t *testing.T) {
+	taken, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer taken.Close()
+
+	_, err = Listen(taken.Addr().String())
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Errorf("Listen() on a taken port error = %v, want already in use", err)
+	}
+}
+
+func TestWarmUpLoadsEmbedderBeforeFirstCall(t *testing.T) {
+	server, err := NewServer(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+
+	if got := server.EmbedderState(); got != embedder.StateNotLoaded {
+		t.Fatalf("EmbedderState() before warm-up = %q, want %q", got, embedder.StateNotLoaded)
+	}
+	want := embedder.StateReady
+	if err := server.WarmUp(); err != nil {
+		want = embedder.StateFailed
+	}
+	if got := server.EmbedderState(); got != want {
+		t.Errorf("EmbedderState() after warm-up = %q, want %q", got, want)
+	}
+}
+
+func TestKeywordOnlyWhenEmbedderFails(t *testing.T) {
+	prev := newEmbedder
+	newEmbedder = func() (*embedder.Embedder, error) {
+		return nil, errors.New("extracting model files: no space left on device")
+	}
+	t.Cleanup(func() { newEmbedder = prev })
+
+	server, err := NewServer(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v, want keyword-only server", err)
+	}
+	defer server.Close()
+	ctx := context.Background()
+
+	if got := server.EmbedderState(); got != embedder.StateFailed {
+		t.Errorf("EmbedderState() = %q, want %q", got, embedder.StateFailed)
+	}
+	if err := server.WarmUp(); err == nil {
+		t.Error("WarmUp() error = nil, want the embedder's error")
+	}
+
+	if _, _, err := server.handleRemember(ctx, nil, RememberInput{Content: "backups go to the nas share"}); err != nil {
+		t.Fatalf("handleRemember() error = %v", err)
+	}
+	if _, _, err := server.handleRememberMany(ctx, nil, RememberManyInput{Memories: []RememberInput{{Content: "the nas sleeps at night"}}}); err != nil {
+		t.Fatalf("handleRememberMany() error = %v", err)
+	}
+
+	_, out, err := server.handleRecall(ctx, nil, RecallInput{Query: "nas"})
+	if err != nil {
+		t.Fatalf("semantic handleRecall() error = %v", err)
+	}
+	if out.Count != 2 {
+		t.Errorf("semantic recall found %d memories, want 2 keyword matches", out.Count)
+	}
+	if !strings.Contains(out.Warning, "no space left on device") {
+		t.Errorf("Warning = %q, want the embedder error", out.Warning)
+	}
+
+	_, out, err = server.handleRecall(ctx, nil, RecallInput{Query: "nas", Mode: "keyword"})
+	if err != nil {
+		t.Fatalf("keyword handleRecall() error = %v", err)
+	}
+	if out.Count != 2 || out.Warning != "" {
+		t.Errorf("keyword recall = %d memories, warning %q; want 2 and none", out.Count, out.Warning)
+	}
+}
+
+func TestRecallUnknownCategory(t *testing.T) {
+	server, err := NewServer(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+	ctx := context.Background()
+	server.db.Remember("nas invoices are PDFs", "file-patterns", "")
+	server.db.Remember("nas sleeps at night", "system-quirks", "")
+
+	recall := func(category string) RecallOutput {
+		t.Helper()
+		_, out, err := server.handleRecall(ctx, nil, RecallInput{Query: "nas", Category: category, Mode: "keyword"})
+		if err != nil {
+			t.Fatalf("handleRecall(%q) error = %v", category, err)
+		}
+		return out
+	}
+
+	if out := recall("file-patterns"); out.Count != 1 || out.Note != "" {
+		t.Errorf("known category: %d memories, note %q; want 1 and no note", out.Count, out.Note)
+	}
+
+	out := recall("file-pattern")
+	if out.Count != 0 || out.Memories == nil {
+		t.Errorf("unknown category: memories = %v, want empty list", out.Memories)
+	}
+	if !strings.Contains(out.Note, `"file-pattern"`) || !strings.Contains(out.Note, "file-patterns, system-quirks") {
+		t.Errorf("unknown category note = %q, want the category and known categories", out.Note)
+	}
+
+	server.SetUnknownCategory(config.UnknownCategoryAll)
+	out = recall("file-pattern")
+	if out.Count != 2 || !strings.Contains(out.Note, "all categories were searched") {
+		t.Errorf("unknown category with fallback: %d memories, note %q; want 2 and a note", out.Count, out.Note)
+	}
+}
+
+func TestRecallClampsLimit(t *testing.T) {
+	server, err := NewServer(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+	for i := 0; i < maxRecallLimit+10; i++ {
+		server.db.Remember(fmt.Sprintf("nas fact %d", i), "", "")
+	}
+
+	_, out, err := server.handleRecall(context.Background(), nil, RecallInput{Query: "nas", Mode: "keyword", Limit: 5000})
+	if err != nil {
+		t.Fatalf("handleRecall() error = %v", err)
+	}
+	if out.Count != maxRecallLimit {
+		t.Errorf("recall with limit 5000 returned %d memories, want %d", out.Count, maxRecallLimit)
+	}
+}
@@ -0,0 +1,330 @@
+// internal/memory/ann.go
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Approximate nearest neighbor search for RecallSemantic on large stores. The
+// index is an IVF (inverted file): stored embeddings are clustered with
+// k-means, and a query is only scored against the members of the clusters
+// whose centroids are closest to it.
+
+// Defaults for SetANN
+const (
+	DefaultANNThreshold = 2000
+	DefaultANNProbes    = 8
+)
+
+const (
+	annTrainIterations = 8
+	// annTrainSamplePerList caps the embeddings k-means trains on, per cluster
+	annTrainSamplePerList = 40
+)
+
+// annEntry is a stored embedding and the fields RecallSemantic filters on
+type annEntry struct {
+	id        int64
+	embedding []float32
+	category  string
+	ruleName  string
+}
+
+// annHit is a search result: a memory ID and its similarity to the query
+type annHit struct {
+	id    int64
+	score float32
+}
+
+type ivfIndex struct {
+	centroids [][]float32
+	lists     [][]annEntry
+	where     map[int64]int // entry ID -> index into lists
+	maxID     int64
+	builtSize int // entries the centroids were trained on
+}
+
+// buildIVF clusters entries into about sqrt(n) lists. Embeddings must be
+// normalized, as they are in the database.
+func buildIVF(entries []annEntry) *ivfIndex {
+	nlist := max(1, int(math.Sqrt(float64(len(entries)))))
+	x := &ivfIndex{
+		centroids: trainCentroids(entries, nlist),
+		where:     make(map[int64]int, len(entries)),
+		builtSize: len(entries),
+	}
+	x.lists = make([][]annEntry, len(x.centroids))
+	for _, e := range entries {
+		x.add(e)
+	}
+	return x
+}
+
+// trainCentroids runs spherical k-means on an evenly spaced sample of
+// entries. Starting points are also evenly spaced, so builds are repeatable.
+func trainCentroids(entries []annEntry, k int) [][]float32 {
+	sample := entries
+	if limit := k * annTrainSamplePerList; len(sample) > limit {
+		sample = make([]annEntry, limit)
+		for i := range sample {
+			sample[i] = entries[i*len(entries)/limit]
+		}
+	}
+	k = min(k, len(sample))
+	if k == 0 {
+		return nil
+	}
+
+	centroids := make([][]float32, k)
+	for i := range centroids {
+		centroids[i] = sample[i*len(sample)/k].embedding
+	}
+	dims := len(centroids[0])
+	for iter := 0; iter < annTrainIterations; iter++ {
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for _, e := range sample {
+			c := nearestCentroid(centroids, e.embedding)
+			if sums[c] == nil {
+				sums[c] = make([]float64, dims)
+			}
+			for i, v := range e.embedding {
+				sums[c][i] += float64(v)
+			}
+			counts[c]++
+		}
+		for c := range centroids {
+			if counts[c] == 0 {
+				continue // keep the old centroid rather than lose the cluster
+			}
+			mean := make([]float32, dims)
+			for i, v := range sums[c] {
+				mean[i] = float32(v / float64(counts[c]))
+			}
+			centroids[c] = normalize(mean)
+		}
+	}
+	return centroids
+}
+
+func nearestCentroid(centroids [][]float32, v []float32) int {
+	best, bestScore := 0, float32(math.Inf(-1))
+	for i, c := range centroids {
+		if s := dotProduct(c, v); s > bestScore {
+			best, bestScore = i, s
+		}
+	}
+	return best
+}
+
+func (x *ivfIndex) size() int {
+	return len(x.where)
+}
+
+// add files e under its nearest centroid. The centroids aren't retrained, so
+// the index is rebuilt once it has grown well past builtSize.
+func (x *ivfIndex) add(e annEntry) {
+	if _, ok := x.where[e.id]; ok || len(x.centroids) == 0 {
+		return
+	}
+	c := nearestCentroid(x.centroids, e.embedding)
+	x.lists[c] = append(x.lists[c], e)
+	x.where[e.id] = c
+	x.maxID = max(x.maxID, e.id)
+}
+
+func (x *ivfIndex) remove(id int64) {
+	c, ok := x.where[id]
+	if !ok {
+		return
+	}
+	list := x.lists[c]
+	for i := range list {
+		if list[i].id == id {
+			x.lists[c] = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	delete(x.where, id)
+}
+
+// search scores the entries accepted by match in the probes lists nearest to
+// query and returns up to limit of them, best first.
+func (x *ivfIndex) search(query []float32, probes int, match func(*annEntry) bool, limit int) []annHit {
+	order := make([]int, len(x.centroids))
+	scores := make([]float32, len(x.centroids))
+	for i, c := range x.centroids {
+		order[i] = i
+		scores[i] = dotProduct(query, c)
+	}
+	sort.Slice(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	if probes < len(order) {
+		order = order[:max(probes, 1)]
+	}
+
+	var hits []annHit
+	for _, c := range order {
+		for i := range x.lists[c] {
+			e := &x.lists[c][i]
+			if match(e) {
+				hits = append(hits, annHit{id: e.id, score: dotProduct(query, e.embedding)})
+			}
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// SetANN tunes approximate search. RecallSemantic uses the index once at
+// least threshold memories have embeddings (0 turns it off), and scores the
+// members of the probes clusters nearest the query. More probes are slower
+// and closer to an exact search.
+func (d *DB) SetANN(threshold, probes int) {
+	d.annMu.Lock()
+	defer d.annMu.Unlock()
+	d.annThreshold = threshold
+	d.annProbes = probes
+	d.ann = nil
+}
+
+// recallANN searches the index. ok is false if the caller should scan
+// instead: the store is below the threshold, or the probed clusters held fewer
+// than limit matches, as happens with narrow filters.
+func (d *DB) recallANN(query []float32, category, ruleName string, limit int) (results []MemoryWithScore, ok bool, err error) {
+	d.annMu.Lock()
+	if d.annThreshold <= 0 {
+		d.annMu.Unlock()
+		return nil, false, nil
+	}
+	idx, err := d.syncANN()
+	if err != nil || idx == nil {
+		d.annMu.Unlock()
+		return nil, false, err
+	}
+	hits := idx.search(query, d.annProbes, func(e *annEntry) bool {
+		return (category == "" || e.category == category) &&
+			(ruleName == "" || e.ruleName == "" || e.ruleName == ruleName)
+	}, limit)
+	d.annMu.Unlock()
+
+	if len(hits) < limit {
+		return nil, false, nil
+	}
+	results, err = d.memoriesByID(hits)
+	return results, err == nil, err
+}
+
+// syncANN brings the index up to date with the database, which other
+// processes may also write to, and returns it; nil if the store is below the
+// threshold. New rows are added to the index; deletions or embeddings added
+// to existing rows elsewhere trigger a rebuild, as does doubling in size.
+// Callers hold annMu.
+func (d *DB) syncANN() (*ivfIndex, error) {
+	var count int
+	var maxID sql.NullInt64
+	if err := d.db.QueryRow("SELECT COUNT(*), MAX(id) FROM memories WHERE embedding IS NOT NULL").Scan(&count, &maxID); err != nil {
+		return nil, fmt.Errorf("counting embeddings: %w", err)
+	}
+	if count < d.annThreshold {
+		d.ann = nil
+		return nil, nil
+	}
+
+	if d.ann != nil && maxID.Int64 > d.ann.maxID {
+		entries, err := d.loadANNEntries(d.ann.maxID)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			d.ann.add(e)
+		}
+	}
+	if d.ann == nil || d.ann.size() != count || d.ann.size() > 2*d.ann.builtSize {
+		entries, err := d.loadANNEntries(0)
+		if err != nil {
+			return nil, err
+		}
+		d.ann = buildIVF(entries)
+	}
+	return d.ann, nil
+}
+
+// loadANNEntries reads the embeddings of memories with an ID above afterID.
+func (d *DB) loadANNEntries(afterID int64) ([]annEntry, error) {
+	rows, err := d.db.Query(`
+		SELECT id, embedding, category, rule_name
+		FROM memories
+		WHERE embedding IS NOT NULL AND id > ?
+		ORDER BY id
+	`, afterID)
+	if err != nil {
+		return nil, fmt.Errorf("loading embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []annEntry
+	for rows.Next() {
+		var e annEntry
+		var embeddingBytes []byte
+		var cat, ruleName sql.NullString
+		if err := rows.Scan(&e.id, &embeddingBytes, &cat, &ruleName); err != nil {
+			return nil, fmt.Errorf("scanning embedding: %w", err)
+		}
+		if e.embedding = bytesToFloat32Slice(embeddingBytes); len(e.embedding) == 0 {
+			continue // corrupted embedding data
+		}
+		e.category = cat.String
+		e.ruleName = ruleName.String
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// memoriesByID reads the memories for hits, keeping their order. Memories
+// deleted since the search are left out.
+func (d *DB) memoriesByID(hits []annHit) ([]MemoryWithScore, error) {
+	placeholders := make([]string, len(hits))
+	args := make([]any, len(hits))
+	for i, h := range hits {
+		placeholders[i] = "?"
+		args[i] = h.id
+	}
+	rows, err := d.db.Query(`
+		SELECT id, content, category, rule_name, created_at, updated_at
+		FROM memories
+		WHERE id IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying memories: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[int64]Memory, len(hits))
+	for rows.Next() {
+		var m Memory
+		var cat, ruleName sql.NullString
+		if err := rows.Scan(&m.ID, &m.Content, &cat, &ruleName, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning memory: %w", err)
+		}
+		m.Category = cat.String
+		m.RuleName = ruleName.String
+		byID[m.ID] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]MemoryWithScore, 0, len(hits))
+	for _, h := range hits {
+		if m, ok := byID[h.id]; ok {
+			results = append(results, MemoryWithScore{Memory: m, Score: h.score})
+		}
+	}
+	return results, nil
+}
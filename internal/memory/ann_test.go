@@ -0,0 +1,155 @@
+// internal/memory/ann_test.go
+package memory
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// clusteredEmbeddings returns n embeddings scattered around a few centers,
+// which is roughly how embeddings of related memories are distributed.
+func clusteredEmbeddings(rng *rand.Rand, n, dims, clusters int) [][]float32 {
+	centers := make([][]float32, clusters)
+	for i := range centers {
+		centers[i] = randomEmbedding(rng, dims)
+	}
+	out := make([][]float32, n)
+	for i := range out {
+		center := normalize(centers[rng.Intn(clusters)])
+		v := make([]float32, dims)
+		for j := range v {
+			v[j] = center[j] + float32(rng.NormFloat64())*0.05
+		}
+		out[i] = v
+	}
+	return out
+}
+
+func fillTestDB(t testing.TB, db *DB, embeddings [][]float32) {
+	t.Helper()
+	memories := make([]Memory, len(embeddings))
+	for i := range memories {
+		memories[i] = Memory{Content: fmt.Sprintf("memory %d", i)}
+	}
+	if _, err := db.RememberManyWithEmbedding(memories, embeddings); err != nil {
+		t.Fatalf("RememberManyWithEmbedding() error = %v", err)
+	}
+}
+
+func TestRecallSemanticANNMatchesExact(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	rng := rand.New(rand.NewSource(1))
+	fillTestDB(t, db, clusteredEmbeddings(rng, 2000, 64, 40))
+	queries := clusteredEmbeddings(rng, 20, 64, 40)
+
+	const k = 10
+	found, total := 0, 0
+	for _, q := range queries {
+		db.SetANN(0, 0)
+		exact, err := db.RecallSemantic(q, "", "", k)
+		if err != nil {
+			t.Fatalf("exact RecallSemantic() error = %v", err)
+		}
+		db.SetANN(100, DefaultANNProbes)
+		approx, err := db.RecallSemantic(q, "", "", k)
+		if err != nil {
+			t.Fatalf("ANN RecallSemantic() error = %v", err)
+		}
+		if db.ann == nil {
+			t.Fatal("RecallSemantic() above the threshold did not build the index")
+		}
+
+		want := map[int64]bool{}
+		for _, m := range exact {
+			want[m.ID] = true
+		}
+		for _, m := range approx {
+			if want[m.ID] {
+				found++
+			}
+		}
+		total += len(exact)
+	}
+	if recall := float64(found) / float64(total); recall < 0.9 {
+		t.Errorf("ANN recall@%d = %.2f, want >= 0.9", k, recall)
+	}
+}
+
+func TestRecallSemanticANNTracksChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+	db.SetANN(50, 2)
+
+	rng := rand.New(rand.NewSource(2))
+	fillTestDB(t, db, clusteredEmbeddings(rng, 200, 32, 8))
+	target := randomEmbedding(rng, 32)
+	top := func() string {
+		t.Helper()
+		results, err := db.RecallSemantic(target, "", "", 1)
+		if err != nil || len(results) == 0 {
+			t.Fatalf("RecallSemantic() = %v, %v", results, err)
+		}
+		return results[0].Content
+	}
+	top() // build the index
+
+	id, _ := db.RememberWithEmbedding("exact match", "", "", target)
+	if got := top(); got != "exact match" {
+		t.Errorf("after insert, top result = %q, want the new memory", got)
+	}
+
+	db.Forget(id)
+	if got := top(); got == "exact match" {
+		t.Error("forgotten memory still returned")
+	}
+
+	// Another process writing to the same database
+	other, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	other.RememberWithEmbedding("written elsewhere", "", "", target)
+	other.Close()
+	if got := top(); got != "written elsewhere" {
+		t.Errorf("after insert by another handle, top result = %q, want it", got)
+	}
+}
+
+func BenchmarkRecallSemantic(b *testing.B) {
+	db, err := Open(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	rng := rand.New(rand.NewSource(1))
+	fillTestDB(b, db, clusteredEmbeddings(rng, 10000, 384, 100))
+	query := clusteredEmbeddings(rng, 1, 384, 100)[0]
+
+	for _, mode := range []struct {
+		name      string
+		threshold int
+	}{
+		{"exact", 0},
+		{"ann", DefaultANNThreshold},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			db.SetANN(mode.threshold, DefaultANNProbes)
+			db.RecallSemantic(query, "", "", 10) // build the index outside the timer
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := db.RecallSemantic(query, "", "", 10); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
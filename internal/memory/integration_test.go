@@ -22,7 +22,7 @@ func TestMemoryFullFlow(t *testing.T) {
 	}
 
 	// Recall by search
-	memories, err := db.Recall("invoices", "")
+	memories, err := db.Recall("invoices", "", "")
 	if err != nil {
 		t.Fatalf("Recall() error = %v", err)
 	}
@@ -31,7 +31,7 @@ func TestMemoryFullFlow(t *testing.T) {
 	}
 
 	// Recall by category (note: can't use "*" as FTS5 query, need actual term)
-	memories, err = db.Recall("file-patterns", "file-patterns")
+	memories, err = db.Recall("file-patterns", "file-patterns", "")
 	if err != nil {
 		// FTS5 may not like querying category this way, adjust test if needed
 		t.Logf("Recall by category note: %v", err)
@@ -44,7 +44,7 @@ func TestMemoryFullFlow(t *testing.T) {
 	}
 
 	// Verify it's gone
-	memories, err = db.Recall("Acme", "")
+	memories, err = db.Recall("Acme", "", "")
 	if err != nil {
 		t.Fatalf("Recall() error = %v", err)
 	}
@@ -53,7 +53,7 @@ func TestMemoryFullFlow(t *testing.T) {
 	}
 
 	// Other memory still exists
-	memories, err = db.Recall("Reports", "")
+	memories, err = db.Recall("Reports", "", "")
 	if err != nil {
 		t.Fatalf("Recall() error = %v", err)
 	}
@@ -0,0 +1,40 @@
+// internal/state/compress.go
+package state
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// gzipText compresses s for storage in a compressed row.
+func gzipText(s string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeText returns a stored output or event_data value, decompressing it if
+// its row is marked compressed. NULL and empty values decode to "".
+func decodeText(raw []byte, compressed bool) (string, error) {
+	if !compressed || len(raw) == 0 {
+		return string(raw), nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return "", fmt.Errorf("decompressing: %w", err)
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("decompressing: %w", err)
+	}
+	return string(data), nil
+}
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -18,21 +20,87 @@ type ExecutionRecord struct {
 	ID                     int64
 	RuleName               string
 	TriggerType            string
-	State                  string // success, failure, timeout, cancelled
+	State                  string // success, failure, timeout, cancelled, skipped, circuit_open
+	SkipReason             string // why a skipped execution didn't run (see Skip* constants)
 	StartedAt              time.Time
 	FinishedAt             time.Time
 	DurationMs             int64
 	RetryAttempt           int
 	TriggeredByExecutionID int64
-	EventData              string // JSON-serialized, max 1KB
+	EventData              string // JSON-serialized, truncated to rule_execution.max_event_data_bytes
 	Error                  string
-	Output                 string // truncated to 10KB, scrubbed of secrets
+	Output                 string // stdout, truncated to rule_execution.max_output_bytes, scrubbed of secrets
+	Stderr                 string // stderr, truncated like Output
+	ExitCode               *int   // nil when no process ran or it was killed by a signal
 	DryRun                 bool
+	PlannedOps             string // JSON-serialized file operations proposed by a dry run
+	ParsedOutput           string // JSON-serialized data extracted by action.output_parser; only loaded by GetExecution
+}
+
+// StateSkipped marks an event that was accepted but not executed; the record's
+// SkipReason says why.
+const StateSkipped = "skipped"
+
+// StateCircuitOpen marks the point where the daemon's circuit breaker stopped a
+// rule's trigger after repeated failures.
+const StateCircuitOpen = "circuit_open"
+
+// Skip reasons recorded with StateSkipped.
+const (
+	SkipDisabled        = "disabled"         // rule disabled and its trigger not running
+	SkipAllowlist       = "allowlist"        // run_as_user not in allowed_run_as_users
+	SkipPaused          = "paused"           // global kill switch engaged
+	SkipDependency      = "dependency"       // a depends_on_rules entry has not succeeded
+	SkipDependencyStale = "dependency_stale" // a dependency last succeeded longer ago than depends_on_max_age
+	SkipCondition       = "condition"        // when expression false or failed to evaluate
+	SkipDropped         = "dropped"          // event channel full, event discarded
+	SkipInvalidBody     = "invalid_body"     // webhook body didn't match the trigger's body_schema
+)
+
+// StateDropped is accepted as a history state filter, short for
+// "skipped:dropped". Dropped events have no state of their own: they are
+// recorded as StateSkipped with SkipDropped, so stats count them as skips.
+const StateDropped = "dropped"
+
+// SkipReasons lists every valid skip reason.
+var SkipReasons = []string{SkipDisabled, SkipAllowlist, SkipPaused, SkipDependency, SkipDependencyStale, SkipCondition, SkipDropped, SkipInvalidBody}
+
+// RuleReliability summarizes a rule's execution outcomes over a time window.
+// Only completed executions count: cancelled (daemon shutdown) and skipped runs
+// are excluded, and timeouts count as failures.
+type RuleReliability struct {
+	RuleName      string    `json:"rule_name"`
+	TotalRuns     int       `json:"total_runs"`
+	Successes     int       `json:"successes"`
+	Failures      int       `json:"failures"`
+	SuccessRate   float64   `json:"success_rate"`    // 0-1; 0 when there are no runs
+	LastFailureAt time.Time `json:"last_failure_at"` // zero if the rule has not failed
+	MTBFSeconds   float64   `json:"mtbf_seconds"`    // mean time between failures; 0 with fewer than 2 failures
+}
+
+// RuleHistorySummary counts a rule's executions by state, with its most
+// recent execution.
+type RuleHistorySummary struct {
+	RuleName  string         `json:"rule_name"`
+	Total     int            `json:"total"`
+	States    map[string]int `json:"states"` // executions per state
+	LastState string         `json:"last_state"`
+	LastAt    time.Time      `json:"last_at"`
+}
+
+// RuleActivity is a snapshot of a rule's recent execution pattern, used by the
+// daemon's maintenance digest.
+type RuleActivity struct {
+	TotalRuns           int       // completed executions (success, failure, timeout)
+	LastRunAt           time.Time // last time the rule fired, including skips; zero if never
+	LastSuccessAt       time.Time // zero if the rule has never succeeded
+	ConsecutiveFailures int       // failed or timed-out events since the last success; retries aren't counted
 }
 
 // DB wraps the SQLite database connection for execution history.
 type DB struct {
-	db *sql.DB
+	db       *sql.DB
+	compress atomic.Bool // gzip output, parsed_output and event_data of new records
 }
 
 const stateSchema = `
@@ -63,16 +131,23 @@ CREATE INDEX IF NOT EXISTS idx_execution_history_state ON execution_history(stat
 CREATE INDEX IF NOT EXISTS idx_execution_history_started ON execution_history(started_at);
 `
 
+// sqlitePragmas enables WAL journaling so readers don't block the writer, and a
+// busy timeout so concurrent writers retry instead of returning "database is locked".
+const sqlitePragmas = "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
 // Open opens or creates a state database at the given path.
 func Open(path string) (*DB, error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return nil, fmt.Errorf("creating database directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", path)
+	db, err := sql.Open("sqlite", path+sqlitePragmas)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
+	// A single connection serializes writers within this process; busy_timeout
+	// makes writers in other processes wait for the lock instead of failing.
+	db.SetMaxOpenConns(1)
 
 	if err := db.Ping(); err != nil {
 		db.Close()
@@ -91,14 +166,67 @@ func Open(path string) (*DB, error) {
 		db.Exec("INSERT INTO schema_version (version) VALUES (1)")
 	}
 
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating schema: %w", err)
+	}
+
 	return &DB{db: db}, nil
 }
 
+// migrations upgrade the schema one version at a time: migrations[0] moves
+// version 1 to 2, migrations[1] moves 2 to 3, and so on.
+var migrations = []string{
+	`ALTER TABLE execution_history ADD COLUMN skip_reason TEXT`,
+	`ALTER TABLE execution_history ADD COLUMN planned_ops TEXT`,
+	`ALTER TABLE execution_history ADD COLUMN compressed BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE execution_history ADD COLUMN stderr TEXT`,
+	`ALTER TABLE execution_history ADD COLUMN exit_code INTEGER`,
+	`ALTER TABLE execution_history ADD COLUMN parsed_output TEXT`,
+	// Skips recorded before skip reasons existed had the reason in the state
+	`UPDATE execution_history SET state = 'skipped', skip_reason = substr(state, 9)
+		WHERE state IN ('skipped_paused', 'skipped_condition')`,
+}
+
+// migrate applies any migrations newer than the recorded schema version.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 1) FROM schema_version").Scan(&version); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+
+	for v := version; v-1 < len(migrations); v++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[v-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying migration to version %d: %w", v+1, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_version (version) VALUES (?)", v+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("recording schema version %d: %w", v+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close closes the database connection.
 func (d *DB) Close() error {
 	return d.db.Close()
 }
 
+// SetCompression turns gzip compression of output, stderr, parsed_output and
+// event_data on or off for records stored from now on. Reads handle both kinds
+// of rows either way.
+func (d *DB) SetCompression(enabled bool) {
+	d.compress.Store(enabled)
+}
+
 // RecordExecution stores an execution record and returns its ID.
 func (d *DB) RecordExecution(rec ExecutionRecord) (int64, error) {
 	var triggeredBy *int64
@@ -106,14 +234,32 @@ func (d *DB) RecordExecution(rec ExecutionRecord) (int64, error) {
 		triggeredBy = &rec.TriggeredByExecutionID
 	}
 
+	var eventData, output, stderr, parsedOutput any = rec.EventData, rec.Output, rec.Stderr, rec.ParsedOutput
+	compressed := d.compress.Load() && (rec.EventData != "" || rec.Output != "" || rec.Stderr != "" || rec.ParsedOutput != "")
+	if compressed {
+		var err error
+		if eventData, err = gzipText(rec.EventData); err != nil {
+			return 0, fmt.Errorf("compressing event data: %w", err)
+		}
+		if output, err = gzipText(rec.Output); err != nil {
+			return 0, fmt.Errorf("compressing output: %w", err)
+		}
+		if stderr, err = gzipText(rec.Stderr); err != nil {
+			return 0, fmt.Errorf("compressing stderr: %w", err)
+		}
+		if parsedOutput, err = gzipText(rec.ParsedOutput); err != nil {
+			return 0, fmt.Errorf("compressing parsed output: %w", err)
+		}
+	}
+
 	result, err := d.db.Exec(`
 		INSERT INTO execution_history
 		(rule_name, trigger_type, state, started_at, finished_at, duration_ms,
-		 retry_attempt, triggered_by_execution_id, event_data, error, output, dry_run)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		 retry_attempt, triggered_by_execution_id, event_data, error, output, stderr, exit_code, dry_run, skip_reason, planned_ops, parsed_output, compressed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		rec.RuleName, rec.TriggerType, rec.State, rec.StartedAt, rec.FinishedAt,
-		rec.DurationMs, rec.RetryAttempt, triggeredBy, rec.EventData,
-		rec.Error, rec.Output, rec.DryRun,
+		rec.DurationMs, rec.RetryAttempt, triggeredBy, eventData,
+		rec.Error, output, stderr, rec.ExitCode, rec.DryRun, rec.SkipReason, rec.PlannedOps, parsedOutput, compressed,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("recording execution: %w", err)
@@ -121,20 +267,17 @@ func (d *DB) RecordExecution(rec ExecutionRecord) (int64, error) {
 	return result.LastInsertId()
 }
 
-// GetHistory retrieves execution history filtered by rule name and/or state.
-func (d *DB) GetHistory(ruleName, state string, limit int) ([]ExecutionRecord, error) {
-	query := "SELECT id, rule_name, trigger_type, state, started_at, finished_at, duration_ms, retry_attempt, error, output, dry_run FROM execution_history WHERE 1=1"
-	var args []any
-
-	if ruleName != "" {
-		query += " AND rule_name = ?"
-		args = append(args, ruleName)
-	}
-	if state != "" {
-		query += " AND state = ?"
-		args = append(args, state)
-	}
+// GetHistory retrieves execution history filtered by rule name, state and/or trigger types.
+// A state of "skipped:<reason>" matches skipped executions with that skip reason.
+func (d *DB) GetHistory(ruleName, state string, triggerTypes []string, limit int) ([]ExecutionRecord, error) {
+	return d.GetHistorySince(ruleName, state, triggerTypes, time.Time{}, limit)
+}
 
+// GetHistorySince is GetHistory limited to executions started at or after
+// since. A zero since doesn't bound the query.
+func (d *DB) GetHistorySince(ruleName, state string, triggerTypes []string, since time.Time, limit int) ([]ExecutionRecord, error) {
+	clause, args := historyClause(ruleName, state, triggerTypes, since)
+	query := "SELECT id, rule_name, trigger_type, state, started_at, finished_at, duration_ms, retry_attempt, triggered_by_execution_id, error, output, stderr, exit_code, dry_run, skip_reason, planned_ops, compressed FROM execution_history WHERE 1=1" + clause
 	query += " ORDER BY started_at DESC"
 	if limit > 0 {
 		query += " LIMIT ?"
@@ -150,19 +293,175 @@ func (d *DB) GetHistory(ruleName, state string, limit int) ([]ExecutionRecord, e
 	var records []ExecutionRecord
 	for rows.Next() {
 		var r ExecutionRecord
-		var errStr, output sql.NullString
+		var errStr, skipReason, plannedOps sql.NullString
+		var output, stderr []byte
+		var compressed bool
+		var triggeredBy, exitCode sql.NullInt64
 		if err := rows.Scan(&r.ID, &r.RuleName, &r.TriggerType, &r.State,
-			&r.StartedAt, &r.FinishedAt, &r.DurationMs, &r.RetryAttempt,
-			&errStr, &output, &r.DryRun); err != nil {
+			&r.StartedAt, &r.FinishedAt, &r.DurationMs, &r.RetryAttempt, &triggeredBy,
+			&errStr, &output, &stderr, &exitCode, &r.DryRun, &skipReason, &plannedOps, &compressed); err != nil {
 			return nil, fmt.Errorf("scanning record: %w", err)
 		}
+		var err error
+		if r.Output, err = decodeText(output, compressed); err != nil {
+			return nil, fmt.Errorf("reading output of execution %d: %w", r.ID, err)
+		}
+		if r.Stderr, err = decodeText(stderr, compressed); err != nil {
+			return nil, fmt.Errorf("reading stderr of execution %d: %w", r.ID, err)
+		}
 		r.Error = errStr.String
-		r.Output = output.String
+		r.SkipReason = skipReason.String
+		r.PlannedOps = plannedOps.String
+		r.TriggeredByExecutionID = triggeredBy.Int64
+		r.ExitCode = nullInt(exitCode)
 		records = append(records, r)
 	}
 	return records, rows.Err()
 }
 
+// historyClause builds the SQL filter shared by the history queries: an
+// optional rule name, a state ("skipped:<reason>" narrows to one skip reason,
+// and StateDropped is "skipped:dropped"), trigger types (see
+// triggerTypeClause) and earliest start time.
+func historyClause(ruleName, state string, triggerTypes []string, since time.Time) (string, []any) {
+	if state == StateDropped {
+		state = StateSkipped + ":" + SkipDropped
+	}
+	var clause string
+	var args []any
+	if ruleName != "" {
+		clause += " AND rule_name = ?"
+		args = append(args, ruleName)
+	}
+	if !since.IsZero() {
+		clause += " AND started_at >= ?"
+		args = append(args, since)
+	}
+	if reason, ok := strings.CutPrefix(state, StateSkipped+":"); ok {
+		clause += " AND state = ? AND skip_reason = ?"
+		args = append(args, StateSkipped, reason)
+	} else if state != "" {
+		clause += " AND state = ?"
+		args = append(args, state)
+	}
+	typeClause, typeArgs := triggerTypeClause(triggerTypes)
+	return clause + typeClause, append(args, typeArgs...)
+}
+
+// HistoryByRule aggregates the executions matching the same filters as
+// GetHistorySince into one summary per rule, ordered by rule name.
+func (d *DB) HistoryByRule(ruleName, state string, triggerTypes []string, since time.Time) ([]RuleHistorySummary, error) {
+	clause, args := historyClause(ruleName, state, triggerTypes, since)
+
+	rows, err := d.db.Query(`
+		SELECT rule_name, state, COUNT(*) FROM execution_history
+		WHERE 1=1`+clause+`
+		GROUP BY rule_name, state ORDER BY rule_name`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("counting history: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []RuleHistorySummary
+	for rows.Next() {
+		var name, st string
+		var n int
+		if err := rows.Scan(&name, &st, &n); err != nil {
+			return nil, fmt.Errorf("scanning history counts: %w", err)
+		}
+		if len(summaries) == 0 || summaries[len(summaries)-1].RuleName != name {
+			summaries = append(summaries, RuleHistorySummary{RuleName: name, States: make(map[string]int)})
+		}
+		sum := &summaries[len(summaries)-1]
+		sum.States[st] = n
+		sum.Total += n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	byRule := make(map[string]*RuleHistorySummary)
+	for i := range summaries {
+		byRule[summaries[i].RuleName] = &summaries[i]
+	}
+
+	// The latest execution of each rule
+	rows, err = d.db.Query(`
+		SELECT rule_name, state, started_at FROM (
+			SELECT rule_name, state, started_at,
+			       ROW_NUMBER() OVER (PARTITION BY rule_name ORDER BY started_at DESC) AS rn
+			FROM execution_history WHERE 1=1`+clause+`
+		) WHERE rn = 1`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying last executions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, st string
+		var at time.Time
+		if err := rows.Scan(&name, &st, &at); err != nil {
+			return nil, fmt.Errorf("scanning last execution: %w", err)
+		}
+		if sum, ok := byRule[name]; ok {
+			sum.LastState, sum.LastAt = st, at
+		}
+	}
+	return summaries, rows.Err()
+}
+
+// GetExecution returns the execution with the given ID, including its event
+// data, or nil if there is no such execution.
+func (d *DB) GetExecution(id int64) (*ExecutionRecord, error) {
+	var r ExecutionRecord
+	var errStr, skipReason, plannedOps sql.NullString
+	var eventData, output, stderr, parsedOutput []byte
+	var compressed bool
+	var triggeredBy, exitCode sql.NullInt64
+	err := d.db.QueryRow(`
+		SELECT id, rule_name, trigger_type, state, started_at, finished_at, duration_ms,
+		       retry_attempt, triggered_by_execution_id, event_data, error, output, stderr, exit_code, dry_run, skip_reason, planned_ops, parsed_output, compressed
+		FROM execution_history WHERE id = ?`,
+		id,
+	).Scan(&r.ID, &r.RuleName, &r.TriggerType, &r.State, &r.StartedAt, &r.FinishedAt, &r.DurationMs,
+		&r.RetryAttempt, &triggeredBy, &eventData, &errStr, &output, &stderr, &exitCode, &r.DryRun, &skipReason, &plannedOps, &parsedOutput, &compressed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting execution: %w", err)
+	}
+	r.TriggeredByExecutionID = triggeredBy.Int64
+	r.ExitCode = nullInt(exitCode)
+	if r.EventData, err = decodeText(eventData, compressed); err != nil {
+		return nil, fmt.Errorf("reading event data of execution %d: %w", id, err)
+	}
+	if r.Output, err = decodeText(output, compressed); err != nil {
+		return nil, fmt.Errorf("reading output of execution %d: %w", id, err)
+	}
+	if r.Stderr, err = decodeText(stderr, compressed); err != nil {
+		return nil, fmt.Errorf("reading stderr of execution %d: %w", id, err)
+	}
+	if r.ParsedOutput, err = decodeText(parsedOutput, compressed); err != nil {
+		return nil, fmt.Errorf("reading parsed output of execution %d: %w", id, err)
+	}
+	r.Error = errStr.String
+	r.SkipReason = skipReason.String
+	r.PlannedOps = plannedOps.String
+	return &r, nil
+}
+
+// nullInt converts a nullable integer column to an *int, nil for NULL.
+func nullInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
 // GetLastState returns the most recent execution state for a rule.
 func (d *DB) GetLastState(ruleName string) (string, error) {
 	var state sql.NullString
@@ -179,14 +478,242 @@ func (d *DB) GetLastState(ruleName string) (string, error) {
 	return state.String, nil
 }
 
-// Cleanup removes execution records older than the specified number of days.
-func (d *DB) Cleanup(retentionDays int) (int64, error) {
-	cutoff := time.Now().AddDate(0, 0, -retentionDays)
-	result, err := d.db.Exec(
-		"DELETE FROM execution_history WHERE started_at < ?", cutoff,
-	)
+// RuleReliability computes success rate, failure count, last failure time and
+// mean time between failures for a rule from executions started at or after since.
+// triggerTypes narrows the executions considered (see triggerTypeClause).
+// A rule with no runs in the window returns a zero-valued summary, not an error.
+func (d *DB) RuleReliability(ruleName string, since time.Time, triggerTypes []string) (RuleReliability, error) {
+	rel := RuleReliability{RuleName: ruleName}
+	clause, clauseArgs := triggerTypeClause(triggerTypes)
+	args := append([]any{ruleName, since}, clauseArgs...)
+
+	// MTBF is the span between the first and last failure divided by the number of
+	// gaps between failures. substr() trims the timestamp to a form julianday() parses.
+	var mtbf sql.NullFloat64
+	err := d.db.QueryRow(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN state = 'success' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN state IN ('failure', 'timeout') THEN 1 ELSE 0 END), 0),
+			(julianday(MAX(CASE WHEN state IN ('failure', 'timeout') THEN substr(started_at, 1, 19) END)) -
+			 julianday(MIN(CASE WHEN state IN ('failure', 'timeout') THEN substr(started_at, 1, 19) END))) * 86400.0 /
+			NULLIF(SUM(CASE WHEN state IN ('failure', 'timeout') THEN 1 ELSE 0 END) - 1, 0)
+		FROM execution_history
+		WHERE rule_name = ? AND started_at >= ? AND state IN ('success', 'failure', 'timeout')`+clause,
+		args...,
+	).Scan(&rel.TotalRuns, &rel.Successes, &rel.Failures, &mtbf)
+	if err != nil {
+		return rel, fmt.Errorf("computing reliability: %w", err)
+	}
+
+	if rel.TotalRuns > 0 {
+		rel.SuccessRate = float64(rel.Successes) / float64(rel.TotalRuns)
+	}
+	if mtbf.Valid {
+		rel.MTBFSeconds = mtbf.Float64
+	}
+
+	if rel.Failures > 0 {
+		err := d.db.QueryRow(`
+			SELECT started_at FROM execution_history
+			WHERE rule_name = ? AND started_at >= ? AND state IN ('failure', 'timeout')`+clause+`
+			ORDER BY started_at DESC LIMIT 1`,
+			args...,
+		).Scan(&rel.LastFailureAt)
+		if err != nil && err != sql.ErrNoRows {
+			return rel, fmt.Errorf("getting last failure: %w", err)
+		}
+	}
+
+	return rel, nil
+}
+
+// RuleActivity returns run counts, last run and success times, and the current
+// failure streak for a rule. Cancelled executions are ignored.
+func (d *DB) RuleActivity(ruleName string) (RuleActivity, error) {
+	var act RuleActivity
+
+	err := d.db.QueryRow(`
+		SELECT started_at FROM execution_history
+		WHERE rule_name = ? AND state != 'cancelled'
+		ORDER BY started_at DESC LIMIT 1`,
+		ruleName,
+	).Scan(&act.LastRunAt)
+	if err == sql.ErrNoRows {
+		return act, nil
+	}
+	if err != nil {
+		return act, fmt.Errorf("getting last run: %w", err)
+	}
+
+	err = d.db.QueryRow(`
+		SELECT COUNT(*) FROM execution_history
+		WHERE rule_name = ? AND state IN ('success', 'failure', 'timeout')`,
+		ruleName,
+	).Scan(&act.TotalRuns)
+	if err != nil {
+		return act, fmt.Errorf("counting runs: %w", err)
+	}
+
+	err = d.db.QueryRow(`
+		SELECT started_at FROM execution_history
+		WHERE rule_name = ? AND state = 'success'
+		ORDER BY started_at DESC LIMIT 1`,
+		ruleName,
+	).Scan(&act.LastSuccessAt)
+	if err != nil && err != sql.ErrNoRows {
+		return act, fmt.Errorf("getting last success: %w", err)
+	}
+
+	err = d.db.QueryRow(`
+		SELECT COUNT(*) FROM execution_history
+		WHERE rule_name = ? AND state IN ('failure', 'timeout') AND retry_attempt = 0
+		AND started_at > COALESCE(
+			(SELECT MAX(started_at) FROM execution_history WHERE rule_name = ? AND state = 'success'), '')`,
+		ruleName, ruleName,
+	).Scan(&act.ConsecutiveFailures)
+	if err != nil {
+		return act, fmt.Errorf("counting failure streak: %w", err)
+	}
+
+	return act, nil
+}
+
+// LastSuccesses returns when each rule's most recent successful execution
+// finished, keyed by rule name. Rules that never succeeded are absent.
+func (d *DB) LastSuccesses() (map[string]time.Time, error) {
+	rows, err := d.db.Query(`
+		SELECT rule_name, finished_at FROM (
+			SELECT rule_name, finished_at,
+			       ROW_NUMBER() OVER (PARTITION BY rule_name ORDER BY finished_at DESC) AS rn
+			FROM execution_history WHERE state = 'success'
+		) WHERE rn = 1`)
+	if err != nil {
+		return nil, fmt.Errorf("querying last successes: %w", err)
+	}
+	defer rows.Close()
+
+	last := make(map[string]time.Time)
+	for rows.Next() {
+		var name string
+		var at time.Time
+		if err := rows.Scan(&name, &at); err != nil {
+			return nil, fmt.Errorf("scanning last success: %w", err)
+		}
+		last[name] = at
+	}
+	return last, rows.Err()
+}
+
+// triggerTypeClause builds an SQL fragment filtering on trigger_type.
+// Plain values are included (OR'd together); values prefixed with "-" are excluded,
+// e.g. {"-manual", "-triggered"} keeps everything except manual and chained runs.
+// An empty list applies no filter.
+func triggerTypeClause(triggerTypes []string) (string, []any) {
+	var include, exclude []any
+	for _, t := range triggerTypes {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		if strings.HasPrefix(t, "-") {
+			exclude = append(exclude, strings.TrimPrefix(t, "-"))
+		} else {
+			include = append(include, t)
+		}
+	}
+
+	var clause string
+	if len(include) > 0 {
+		clause += " AND trigger_type IN (" + placeholders(len(include)) + ")"
+	}
+	if len(exclude) > 0 {
+		clause += " AND trigger_type NOT IN (" + placeholders(len(exclude)) + ")"
+	}
+	return clause, append(include, exclude...)
+}
+
+// placeholders returns n comma-separated SQL bind placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// Cleanup removes execution records older than retentionDays, or for the
+// rules in ruleRetention, older than that rule's own number of days. A
+// non-positive retentionDays keeps the records of every other rule.
+func (d *DB) Cleanup(retentionDays int, ruleRetention map[string]int) (int64, error) {
+	now := time.Now()
+	tx, err := d.db.Begin()
 	if err != nil {
 		return 0, fmt.Errorf("cleaning up history: %w", err)
 	}
+	defer tx.Rollback()
+
+	var deleted int64
+	del := func(query string, args ...any) error {
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return fmt.Errorf("cleaning up history: %w", err)
+		}
+		n, err := result.RowsAffected()
+		deleted += n
+		return err
+	}
+
+	var overridden []any
+	for name, days := range ruleRetention {
+		if days <= 0 {
+			continue
+		}
+		overridden = append(overridden, name)
+		if err := del("DELETE FROM execution_history WHERE rule_name = ? AND started_at < ?",
+			name, now.AddDate(0, 0, -days)); err != nil {
+			return 0, err
+		}
+	}
+	if retentionDays > 0 {
+		query := "DELETE FROM execution_history WHERE started_at < ?"
+		args := []any{now.AddDate(0, 0, -retentionDays)}
+		if len(overridden) > 0 {
+			query += " AND rule_name NOT IN (" + placeholders(len(overridden)) + ")"
+			args = append(args, overridden...)
+		}
+		if err := del(query, args...); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("cleaning up history: %w", err)
+	}
+	return deleted, nil
+}
+
+// CleanupByCount keeps only the maxRows most recent records of each rule and
+// returns how many were deleted. Unlike Cleanup, this bounds the history of a
+// busy rule within the retention window.
+func (d *DB) CleanupByCount(maxRows int) (int64, error) {
+	result, err := d.db.Exec(`
+		DELETE FROM execution_history WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY rule_name ORDER BY started_at DESC, id DESC) AS n
+				FROM execution_history
+			) WHERE n > ?
+		)`, maxRows)
+	if err != nil {
+		return 0, fmt.Errorf("cleaning up history by count: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// CleanupTotal keeps only the maxRows most recent records across all rules
+// and returns how many were deleted.
+func (d *DB) CleanupTotal(maxRows int) (int64, error) {
+	result, err := d.db.Exec(`
+		DELETE FROM execution_history WHERE id NOT IN (
+			SELECT id FROM execution_history ORDER BY started_at DESC, id DESC LIMIT ?
+		)`, maxRows)
+	if err != nil {
+		return 0, fmt.Errorf("cleaning up history by total: %w", err)
+	}
 	return result.RowsAffected()
 }
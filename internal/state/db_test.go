@@ -2,8 +2,12 @@
 package state
 
 import (
+	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -180,6 +184,15 @@ func TestRecordExecution_WithRetryAndParent(t *testing.T) {
 	if childID <= parentID {
 		t.Errorf("child id (%d) should be > parent id (%d)", childID, parentID)
 	}
+
+	records, err := db.GetHistory("child-rule", "", nil, 10)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("GetHistory() = %d records, err = %v; want 1", len(records), err)
+	}
+	if records[0].RetryAttempt != 2 || records[0].TriggeredByExecutionID != parentID {
+		t.Errorf("read back attempt %d parent %d, want attempt 2 parent %d",
+			records[0].RetryAttempt, records[0].TriggeredByExecutionID, parentID)
+	}
 }
 
 func TestRecordExecution_DryRun(t *testing.T) {
@@ -213,7 +226,7 @@ func TestGetHistory_FilterByRule(t *testing.T) {
 	now := time.Now()
 	insertTestRecords(t, db, now)
 
-	records, err := db.GetHistory("rule-a", "", 100)
+	records, err := db.GetHistory("rule-a", "", nil, 100)
 	if err != nil {
 		t.Fatalf("GetHistory() error = %v", err)
 	}
@@ -235,7 +248,7 @@ func TestGetHistory_FilterByState(t *testing.T) {
 	now := time.Now()
 	insertTestRecords(t, db, now)
 
-	records, err := db.GetHistory("", "failure", 100)
+	records, err := db.GetHistory("", "failure", nil, 100)
 	if err != nil {
 		t.Fatalf("GetHistory() error = %v", err)
 	}
@@ -257,7 +270,7 @@ func TestGetHistory_WithLimit(t *testing.T) {
 	now := time.Now()
 	insertTestRecords(t, db, now)
 
-	records, err := db.GetHistory("", "", 2)
+	records, err := db.GetHistory("", "", nil, 2)
 	if err != nil {
 		t.Fatalf("GetHistory() error = %v", err)
 	}
@@ -267,11 +280,83 @@ func TestGetHistory_WithLimit(t *testing.T) {
 	}
 }
 
+func TestGetHistorySince(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	insertTestRecords(t, db, now)
+	boot := now.Add(-30 * time.Second) // after both rule-a runs
+
+	records, err := db.GetHistorySince("", "", nil, boot, 100)
+	if err != nil {
+		t.Fatalf("GetHistorySince() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("GetHistorySince() returned %d records, want the 2 since boot", len(records))
+	}
+	for _, r := range records {
+		if r.StartedAt.Before(boot) {
+			t.Errorf("record %d started %v, before boot %v", r.ID, r.StartedAt, boot)
+		}
+	}
+
+	sums, err := db.HistoryByRule("", "", nil, boot)
+	if err != nil {
+		t.Fatalf("HistoryByRule() error = %v", err)
+	}
+	if len(sums) != 1 || sums[0].RuleName != "rule-b" || sums[0].Total != 2 {
+		t.Errorf("HistoryByRule() since boot = %+v, want only rule-b's 2 runs", sums)
+	}
+}
+
+func TestHistoryByRule(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	insertTestRecords(t, db, now)
+	if _, err := db.RecordExecution(ExecutionRecord{
+		RuleName: "rule-a", TriggerType: "manual", State: "success",
+		StartedAt: now.Add(-5 * time.Second), FinishedAt: now,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sums, err := db.HistoryByRule("", "", nil, time.Time{})
+	if err != nil {
+		t.Fatalf("HistoryByRule() error = %v", err)
+	}
+	if len(sums) != 2 || sums[0].RuleName != "rule-a" || sums[1].RuleName != "rule-b" {
+		t.Fatalf("HistoryByRule() = %+v, want rule-a and rule-b", sums)
+	}
+
+	a := sums[0]
+	if a.Total != 3 || a.States["success"] != 2 || a.States["failure"] != 1 {
+		t.Errorf("rule-a counts = %d %v, want 3 total with 2 success and 1 failure", a.Total, a.States)
+	}
+	if a.LastState != "success" || !a.LastAt.Equal(now.Add(-5*time.Second)) {
+		t.Errorf("rule-a last = %s at %v, want the manual success", a.LastState, a.LastAt)
+	}
+	if b := sums[1]; b.LastState != "failure" || b.Total != 2 {
+		t.Errorf("rule-b = %+v, want 2 runs ending in failure", b)
+	}
+
+	// Filters match GetHistory
+	sums, err = db.HistoryByRule("", "", []string{"-manual"}, time.Time{})
+	if err != nil {
+		t.Fatalf("HistoryByRule() error = %v", err)
+	}
+	if a := sums[0]; a.Total != 2 || a.LastState != "failure" {
+		t.Errorf("rule-a without manual runs = %+v, want 2 runs ending in failure", a)
+	}
+}
+
 func TestGetHistory_EmptyResults(t *testing.T) {
 	db := openTestDB(t)
 	defer db.Close()
 
-	records, err := db.GetHistory("nonexistent-rule", "", 100)
+	records, err := db.GetHistory("nonexistent-rule", "", nil, 100)
 	if err != nil {
 		t.Fatalf("GetHistory() error = %v", err)
 	}
@@ -341,7 +426,7 @@ func TestCleanup(t *testing.T) {
 	})
 
 	// Cleanup records older than 90 days
-	deleted, err := db.Cleanup(90)
+	deleted, err := db.Cleanup(90, nil)
 	if err != nil {
 		t.Fatalf("Cleanup() error = %v", err)
 	}
@@ -350,18 +435,208 @@ func TestCleanup(t *testing.T) {
 	}
 
 	// Verify old record is gone
-	records, _ := db.GetHistory("old-rule", "", 100)
+	records, _ := db.GetHistory("old-rule", "", nil, 100)
 	if len(records) != 0 {
 		t.Error("Cleanup() did not remove old record")
 	}
 
 	// Verify recent record still exists
-	records, _ = db.GetHistory("recent-rule", "", 100)
+	records, _ = db.GetHistory("recent-rule", "", nil, 100)
 	if len(records) != 1 {
 		t.Error("Cleanup() should not remove recent record")
 	}
 }
 
+func TestCleanup_PerRuleRetention(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	record := func(rule string, daysAgo int) {
+		at := now.AddDate(0, 0, -daysAgo)
+		db.RecordExecution(ExecutionRecord{RuleName: rule, TriggerType: "scheduled", State: "success", StartedAt: at, FinishedAt: at})
+	}
+	// audit keeps a year, noisy a week, other uses the 90-day default
+	for _, days := range []int{400, 200, 10} {
+		record("audit", days)
+	}
+	for _, days := range []int{30, 8, 6} {
+		record("noisy", days)
+	}
+	for _, days := range []int{100, 80} {
+		record("other", days)
+	}
+
+	deleted, err := db.Cleanup(90, map[string]int{"audit": 365, "noisy": 7})
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if deleted != 4 {
+		t.Errorf("Cleanup() deleted %d records, want 4", deleted)
+	}
+	for rule, want := range map[string]int{"audit": 2, "noisy": 1, "other": 1} {
+		records, _ := db.GetHistory(rule, "", nil, 100)
+		if len(records) != want {
+			t.Errorf("%s: kept %d records, want %d", rule, len(records), want)
+		}
+	}
+
+	// Without a global retention only the overrides apply
+	record("other", 1000)
+	if deleted, err := db.Cleanup(-1, map[string]int{"audit": 30}); err != nil || deleted != 1 {
+		t.Errorf("Cleanup(-1) = %d, %v; want 1 audit record deleted", deleted, err)
+	}
+	if records, _ := db.GetHistory("other", "", nil, 100); len(records) != 2 {
+		t.Errorf("other: kept %d records, want 2 with global retention off", len(records))
+	}
+}
+
+func TestLastSuccesses(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i, rec := range []struct {
+		rule, state string
+	}{
+		{"a", "success"}, {"a", "success"}, {"a", "failure"}, {"b", "failure"},
+	} {
+		at := base.Add(time.Duration(i) * time.Minute)
+		db.RecordExecution(ExecutionRecord{RuleName: rec.rule, TriggerType: "manual", State: rec.state, StartedAt: at, FinishedAt: at})
+	}
+
+	last, err := db.LastSuccesses()
+	if err != nil {
+		t.Fatalf("LastSuccesses() error = %v", err)
+	}
+	if len(last) != 1 || !last["a"].Equal(base.Add(time.Minute)) {
+		t.Errorf("LastSuccesses() = %v, want only a at %v", last, base.Add(time.Minute))
+	}
+}
+
+// seedRuns records n executions of rule, one minute apart, newest last.
+func seedRuns(t *testing.T, db *DB, rule string, n int, base time.Time) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		at := base.Add(time.Duration(i) * time.Minute)
+		if _, err := db.RecordExecution(ExecutionRecord{
+			RuleName: rule, TriggerType: "scheduled", State: "success", StartedAt: at, FinishedAt: at,
+		}); err != nil {
+			t.Fatalf("RecordExecution() error = %v", err)
+		}
+	}
+}
+
+func TestCleanupByCount(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	base := time.Now().Add(-time.Hour)
+	seedRuns(t, db, "busy", 8, base)
+	seedRuns(t, db, "quiet", 2, base)
+
+	deleted, err := db.CleanupByCount(3)
+	if err != nil {
+		t.Fatalf("CleanupByCount() error = %v", err)
+	}
+	if deleted != 5 {
+		t.Errorf("CleanupByCount() deleted %d records, want 5", deleted)
+	}
+
+	busy, _ := db.GetHistory("busy", "", nil, 100)
+	if len(busy) != 3 {
+		t.Fatalf("busy rule kept %d records, want 3", len(busy))
+	}
+	for i, rec := range busy {
+		if want := base.Add(time.Duration(7-i) * time.Minute); !rec.StartedAt.Equal(want) {
+			t.Errorf("busy[%d] started %v, want newest %v", i, rec.StartedAt, want)
+		}
+	}
+	if quiet, _ := db.GetHistory("quiet", "", nil, 100); len(quiet) != 2 {
+		t.Errorf("quiet rule kept %d records, want both", len(quiet))
+	}
+}
+
+func TestCleanupTotal(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	base := time.Now().Add(-time.Hour)
+	seedRuns(t, db, "a", 3, base)
+	seedRuns(t, db, "b", 3, base.Add(10*time.Minute))
+
+	deleted, err := db.CleanupTotal(4)
+	if err != nil {
+		t.Fatalf("CleanupTotal() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("CleanupTotal() deleted %d records, want 2", deleted)
+	}
+	if a, _ := db.GetHistory("a", "", nil, 100); len(a) != 1 || !a[0].StartedAt.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("rule a kept %+v, want only its newest record", a)
+	}
+}
+
+// ===== Concurrency =====
+
+func TestOpen_EnablesWAL(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	var mode string
+	if err := db.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatalf("reading journal_mode: %v", err)
+	}
+	if mode != "wal" {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+}
+
+func TestRecordExecution_Concurrent(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	const workers = 20
+	const perWorker = 25
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				_, err := db.RecordExecution(ExecutionRecord{
+					RuleName: fmt.Sprintf("rule-%d", w), TriggerType: "scheduled", State: "success",
+					StartedAt: now, FinishedAt: now, DurationMs: 1,
+				})
+				if err != nil {
+					errs <- err
+				}
+				// Interleave reads with writes like the daemon's API handlers do
+				if _, err := db.GetHistory("", "", nil, 10); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent access error: %v", err)
+	}
+
+	records, err := db.GetHistory("", "", nil, 0)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(records) != workers*perWorker {
+		t.Errorf("recorded %d executions, want %d", len(records), workers*perWorker)
+	}
+}
+
 // ===== Helpers =====
 
 func openTestDB(t *testing.T) *DB {
@@ -404,3 +679,561 @@ func insertTestRecords(t *testing.T, db *DB, now time.Time) {
 		}
 	}
 }
+
+// ===== Reliability =====
+
+func TestRuleReliability(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	base := time.Now().Add(-10 * time.Hour)
+	seed := []struct {
+		offset time.Duration
+		state  string
+	}{
+		{0, "success"},
+		{1 * time.Hour, "failure"},
+		{2 * time.Hour, "success"},
+		{3 * time.Hour, "timeout"},
+		{4 * time.Hour, "success"},
+		{5 * time.Hour, "failure"},
+		{6 * time.Hour, "cancelled"},
+	}
+	for _, s := range seed {
+		started := base.Add(s.offset)
+		if _, err := db.RecordExecution(ExecutionRecord{
+			RuleName: "flaky", TriggerType: "scheduled", State: s.state,
+			StartedAt: started, FinishedAt: started, DurationMs: 1,
+		}); err != nil {
+			t.Fatalf("RecordExecution() error = %v", err)
+		}
+	}
+
+	rel, err := db.RuleReliability("flaky", base.Add(-time.Minute), nil)
+	if err != nil {
+		t.Fatalf("RuleReliability() error = %v", err)
+	}
+	if rel.TotalRuns != 6 {
+		t.Errorf("TotalRuns = %d, want 6 (cancelled excluded)", rel.TotalRuns)
+	}
+	if rel.Successes != 3 || rel.Failures != 3 {
+		t.Errorf("Successes/Failures = %d/%d, want 3/3", rel.Successes, rel.Failures)
+	}
+	if rel.SuccessRate != 0.5 {
+		t.Errorf("SuccessRate = %v, want 0.5", rel.SuccessRate)
+	}
+	// Failures at +1h, +3h, +5h: two gaps of 2h each
+	if diff := rel.MTBFSeconds - 7200; diff > 1 || diff < -1 {
+		t.Errorf("MTBFSeconds = %v, want 7200", rel.MTBFSeconds)
+	}
+	if rel.LastFailureAt.Sub(base.Add(5*time.Hour)).Abs() > time.Second {
+		t.Errorf("LastFailureAt = %v, want %v", rel.LastFailureAt, base.Add(5*time.Hour))
+	}
+
+	// The since bound excludes older runs
+	rel, err = db.RuleReliability("flaky", base.Add(150*time.Minute), nil)
+	if err != nil {
+		t.Fatalf("RuleReliability() error = %v", err)
+	}
+	if rel.TotalRuns != 3 || rel.Failures != 2 {
+		t.Errorf("windowed TotalRuns/Failures = %d/%d, want 3/2", rel.TotalRuns, rel.Failures)
+	}
+}
+
+func TestRuleReliability_NoRuns(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	rel, err := db.RuleReliability("never-ran", time.Now().Add(-24*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("RuleReliability() error = %v", err)
+	}
+	if rel.TotalRuns != 0 || rel.SuccessRate != 0 || rel.MTBFSeconds != 0 || !rel.LastFailureAt.IsZero() {
+		t.Errorf("expected zero-valued reliability, got %+v", rel)
+	}
+	if rel.RuleName != "never-ran" {
+		t.Errorf("RuleName = %q, want never-ran", rel.RuleName)
+	}
+}
+
+func TestRuleReliability_SingleFailureHasNoMTBF(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	db.RecordExecution(ExecutionRecord{
+		RuleName: "once", TriggerType: "manual", State: "failure",
+		StartedAt: now, FinishedAt: now, DurationMs: 1,
+	})
+
+	rel, err := db.RuleReliability("once", now.Add(-time.Hour), nil)
+	if err != nil {
+		t.Fatalf("RuleReliability() error = %v", err)
+	}
+	if rel.Failures != 1 || rel.MTBFSeconds != 0 {
+		t.Errorf("Failures/MTBFSeconds = %d/%v, want 1/0", rel.Failures, rel.MTBFSeconds)
+	}
+}
+
+// ===== Trigger type filters =====
+
+func seedTriggerTypes(t *testing.T, db *DB) {
+	t.Helper()
+	now := time.Now()
+	for i, tt := range []string{"scheduled", "scheduled", "manual", "webhook", "triggered"} {
+		started := now.Add(time.Duration(-i) * time.Minute)
+		state := "success"
+		if tt == "manual" {
+			state = "failure"
+		}
+		if _, err := db.RecordExecution(ExecutionRecord{
+			RuleName: "mixed", TriggerType: tt, State: state,
+			StartedAt: started, FinishedAt: started, DurationMs: 1,
+		}); err != nil {
+			t.Fatalf("RecordExecution() error = %v", err)
+		}
+	}
+}
+
+func TestGetHistory_SingleTriggerType(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	seedTriggerTypes(t, db)
+
+	records, err := db.GetHistory("", "", []string{"scheduled"}, 100)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("GetHistory() returned %d records, want 2", len(records))
+	}
+	for _, r := range records {
+		if r.TriggerType != "scheduled" {
+			t.Errorf("TriggerType = %q, want scheduled", r.TriggerType)
+		}
+	}
+}
+
+func TestGetHistory_MultipleTriggerTypes(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	seedTriggerTypes(t, db)
+
+	records, err := db.GetHistory("", "", []string{"manual", "webhook"}, 100)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("GetHistory() returned %d records, want 2", len(records))
+	}
+	for _, r := range records {
+		if r.TriggerType != "manual" && r.TriggerType != "webhook" {
+			t.Errorf("unexpected TriggerType %q", r.TriggerType)
+		}
+	}
+}
+
+func TestGetHistory_ExcludedTriggerTypes(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	seedTriggerTypes(t, db)
+
+	records, err := db.GetHistory("", "", []string{"-manual", "-triggered"}, 100)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("GetHistory() returned %d records, want 3", len(records))
+	}
+	for _, r := range records {
+		if r.TriggerType == "manual" || r.TriggerType == "triggered" {
+			t.Errorf("excluded TriggerType %q returned", r.TriggerType)
+		}
+	}
+}
+
+func TestRuleReliability_ExcludesManualRuns(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	seedTriggerTypes(t, db)
+
+	since := time.Now().Add(-time.Hour)
+	all, err := db.RuleReliability("mixed", since, nil)
+	if err != nil {
+		t.Fatalf("RuleReliability() error = %v", err)
+	}
+	if all.Failures != 1 {
+		t.Errorf("unfiltered Failures = %d, want 1", all.Failures)
+	}
+
+	rel, err := db.RuleReliability("mixed", since, []string{"-manual"})
+	if err != nil {
+		t.Fatalf("RuleReliability() error = %v", err)
+	}
+	if rel.TotalRuns != 4 || rel.Failures != 0 || rel.SuccessRate != 1 {
+		t.Errorf("filtered reliability = %+v, want 4 runs, 0 failures, rate 1", rel)
+	}
+}
+
+// ===== Rule activity =====
+
+func TestRuleActivity(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	base := time.Now().Add(-time.Hour)
+	for i, st := range []string{"failure", "success", "failure", "timeout", "cancelled"} {
+		started := base.Add(time.Duration(i) * time.Minute)
+		db.RecordExecution(ExecutionRecord{
+			RuleName: "streaky", TriggerType: "scheduled", State: st,
+			StartedAt: started, FinishedAt: started, DurationMs: 1,
+		})
+	}
+
+	act, err := db.RuleActivity("streaky")
+	if err != nil {
+		t.Fatalf("RuleActivity() error = %v", err)
+	}
+	if act.TotalRuns != 4 {
+		t.Errorf("TotalRuns = %d, want 4", act.TotalRuns)
+	}
+	if act.ConsecutiveFailures != 2 {
+		t.Errorf("ConsecutiveFailures = %d, want 2", act.ConsecutiveFailures)
+	}
+	if act.LastSuccessAt.Sub(base.Add(time.Minute)).Abs() > time.Second {
+		t.Errorf("LastSuccessAt = %v, want %v", act.LastSuccessAt, base.Add(time.Minute))
+	}
+	if act.LastRunAt.Sub(base.Add(3*time.Minute)).Abs() > time.Second {
+		t.Errorf("LastRunAt = %v, want %v", act.LastRunAt, base.Add(3*time.Minute))
+	}
+}
+
+func TestRuleActivity_RetriesNotCountedAsFailures(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	// One event that failed and then failed all four retries
+	now := time.Now()
+	for attempt := 0; attempt <= 4; attempt++ {
+		db.RecordExecution(ExecutionRecord{
+			RuleName: "flaky", TriggerType: "scheduled", State: "failure", RetryAttempt: attempt,
+			StartedAt: now.Add(time.Duration(attempt) * time.Second), FinishedAt: now, DurationMs: 1,
+		})
+	}
+
+	act, err := db.RuleActivity("flaky")
+	if err != nil {
+		t.Fatalf("RuleActivity() error = %v", err)
+	}
+	if act.ConsecutiveFailures != 1 {
+		t.Errorf("ConsecutiveFailures = %d, want 1 (retries belong to the same event)", act.ConsecutiveFailures)
+	}
+}
+
+func TestRuleActivity_NeverSucceeded(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		db.RecordExecution(ExecutionRecord{
+			RuleName: "broken", TriggerType: "scheduled", State: "failure",
+			StartedAt: now.Add(time.Duration(i) * time.Second), FinishedAt: now, DurationMs: 1,
+		})
+	}
+
+	act, err := db.RuleActivity("broken")
+	if err != nil {
+		t.Fatalf("RuleActivity() error = %v", err)
+	}
+	if !act.LastSuccessAt.IsZero() {
+		t.Errorf("LastSuccessAt = %v, want zero", act.LastSuccessAt)
+	}
+	if act.ConsecutiveFailures != 3 {
+		t.Errorf("ConsecutiveFailures = %d, want 3", act.ConsecutiveFailures)
+	}
+
+	none, err := db.RuleActivity("missing")
+	if err != nil {
+		t.Fatalf("RuleActivity() error = %v", err)
+	}
+	if none.TotalRuns != 0 || !none.LastRunAt.IsZero() {
+		t.Errorf("expected empty activity, got %+v", none)
+	}
+}
+
+func TestOpen_MigratesOldSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// A version 1 database, created before skip_reason existed
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := raw.Exec(stateSchema); err != nil {
+		t.Fatal(err)
+	}
+	raw.Exec("INSERT INTO schema_version (version) VALUES (1)")
+	raw.Exec(`INSERT INTO execution_history (rule_name, trigger_type, state, started_at, finished_at, duration_ms)
+		VALUES ('old', 'manual', 'success', ?, ?, 10)`, time.Now(), time.Now())
+	raw.Exec(`INSERT INTO execution_history (rule_name, trigger_type, state, started_at, finished_at, duration_ms)
+		VALUES ('paused', 'manual', 'skipped_paused', ?, ?, 0)`, time.Now(), time.Now())
+	raw.Close()
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	var version int
+	db.db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version)
+	if version != len(migrations)+1 {
+		t.Errorf("schema version = %d, want %d", version, len(migrations)+1)
+	}
+
+	records, err := db.GetHistory("old", "", nil, 10)
+	if err != nil || len(records) != 1 || records[0].SkipReason != "" {
+		t.Fatalf("GetHistory() after migration = %+v, %v", records, err)
+	}
+	// Skips from before skip reasons get the state and reason they'd have now
+	records, err = db.GetHistory("paused", "", nil, 10)
+	if err != nil || len(records) != 1 || records[0].State != StateSkipped || records[0].SkipReason != SkipPaused {
+		t.Errorf("GetHistory(legacy skip) after migration = %+v, %v", records, err)
+	}
+	db.Close()
+
+	// Reopening must not re-apply migrations
+	db, err = Open(dbPath)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	db.Close()
+}
+
+func TestGetHistory_FilterBySkipReason(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	for _, rec := range []ExecutionRecord{
+		{RuleName: "r", TriggerType: "scheduled", State: StateSkipped, SkipReason: SkipDependency, StartedAt: now, FinishedAt: now},
+		{RuleName: "r", TriggerType: "scheduled", State: StateSkipped, SkipReason: SkipPaused, StartedAt: now, FinishedAt: now},
+		{RuleName: "r", TriggerType: "scheduled", State: StateSkipped, SkipReason: SkipDropped, StartedAt: now, FinishedAt: now},
+		{RuleName: "r", TriggerType: "scheduled", State: "success", StartedAt: now, FinishedAt: now},
+	} {
+		if _, err := db.RecordExecution(rec); err != nil {
+			t.Fatalf("RecordExecution() error = %v", err)
+		}
+	}
+
+	records, err := db.GetHistory("r", "skipped", nil, 10)
+	if err != nil || len(records) != 3 {
+		t.Fatalf("GetHistory(skipped) = %d records, %v; want 3", len(records), err)
+	}
+
+	records, err = db.GetHistory("r", "skipped:dependency", nil, 10)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(records) != 1 || records[0].SkipReason != SkipDependency {
+		t.Errorf("GetHistory(skipped:dependency) = %+v, want one dependency skip", records)
+	}
+
+	records, err = db.GetHistory("r", StateDropped, nil, 10)
+	if err != nil || len(records) != 1 || records[0].SkipReason != SkipDropped {
+		t.Errorf("GetHistory(dropped) = %+v, %v; want the dropped event", records, err)
+	}
+}
+
+func TestGetExecution(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id, err := db.RecordExecution(ExecutionRecord{
+		RuleName:    "r",
+		TriggerType: "webhook",
+		State:       "failure",
+		StartedAt:   now,
+		FinishedAt:  now,
+		EventData:   `{"path":"/tmp/x"}`,
+		Error:       "boom",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := db.GetExecution(id)
+	if err != nil {
+		t.Fatalf("GetExecution() error = %v", err)
+	}
+	if rec == nil || rec.ID != id || rec.RuleName != "r" || rec.TriggerType != "webhook" {
+		t.Fatalf("GetExecution() = %+v", rec)
+	}
+	if rec.EventData != `{"path":"/tmp/x"}` || rec.Error != "boom" {
+		t.Errorf("EventData = %q, Error = %q", rec.EventData, rec.Error)
+	}
+
+	rec, err = db.GetExecution(id + 100)
+	if err != nil || rec != nil {
+		t.Errorf("GetExecution(missing) = %+v, %v; want nil, nil", rec, err)
+	}
+}
+
+func TestRecordExecution_ParsedOutput(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	parsed := `{"pct":"91","volume":"/Volumes/Media"}`
+	id, err := db.RecordExecution(ExecutionRecord{
+		RuleName: "disk", TriggerType: "scheduled", State: "success", StartedAt: now, FinishedAt: now,
+		ParsedOutput: parsed,
+	})
+	if err != nil {
+		t.Fatalf("RecordExecution() error = %v", err)
+	}
+	rec, err := db.GetExecution(id)
+	if err != nil || rec == nil || rec.ParsedOutput != parsed {
+		t.Errorf("GetExecution() = %+v, %v; want ParsedOutput %s", rec, err, parsed)
+	}
+}
+
+func TestRecordExecution_PlannedOps(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	ops := `[{"op":"delete","path":"/tmp/old.log"}]`
+	id, err := db.RecordExecution(ExecutionRecord{
+		RuleName: "r", TriggerType: "manual", State: "success", StartedAt: now, FinishedAt: now,
+		DryRun: true, PlannedOps: ops,
+	})
+	if err != nil {
+		t.Fatalf("RecordExecution() error = %v", err)
+	}
+
+	rec, err := db.GetExecution(id)
+	if err != nil || rec == nil || rec.PlannedOps != ops {
+		t.Fatalf("GetExecution() = %+v, %v; want planned ops", rec, err)
+	}
+	records, err := db.GetHistory("r", "", nil, 1)
+	if err != nil || len(records) != 1 || records[0].PlannedOps != ops {
+		t.Fatalf("GetHistory() = %+v, %v; want planned ops", records, err)
+	}
+}
+
+func TestRecordExecution_Stderr(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id, err := db.RecordExecution(ExecutionRecord{
+		RuleName: "r", TriggerType: "manual", State: "failure", StartedAt: now, FinishedAt: now,
+		Output: "partial answer", Stderr: "permission denied",
+	})
+	if err != nil {
+		t.Fatalf("RecordExecution() error = %v", err)
+	}
+
+	rec, err := db.GetExecution(id)
+	if err != nil || rec == nil || rec.Output != "partial answer" || rec.Stderr != "permission denied" {
+		t.Fatalf("GetExecution() = %+v, %v; want output and stderr kept apart", rec, err)
+	}
+	records, err := db.GetHistory("r", "", nil, 1)
+	if err != nil || len(records) != 1 || records[0].Stderr != "permission denied" {
+		t.Fatalf("GetHistory() = %+v, %v; want stderr", records, err)
+	}
+}
+
+func TestRecordExecution_ExitCode(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	code := 42
+	withCode, err := db.RecordExecution(ExecutionRecord{
+		RuleName: "r", TriggerType: "manual", State: "failure", StartedAt: now, FinishedAt: now, ExitCode: &code,
+	})
+	if err != nil {
+		t.Fatalf("RecordExecution() error = %v", err)
+	}
+	skipped, err := db.RecordExecution(ExecutionRecord{
+		RuleName: "r", TriggerType: "manual", State: StateSkipped, StartedAt: now, FinishedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("RecordExecution() error = %v", err)
+	}
+
+	rec, err := db.GetExecution(withCode)
+	if err != nil || rec == nil || rec.ExitCode == nil || *rec.ExitCode != 42 {
+		t.Fatalf("GetExecution() = %+v, %v; want exit code 42", rec, err)
+	}
+	rec, err = db.GetExecution(skipped)
+	if err != nil || rec == nil || rec.ExitCode != nil {
+		t.Fatalf("GetExecution() = %+v, %v; want no exit code for a skip", rec, err)
+	}
+	records, err := db.GetHistory("r", "failure", nil, 1)
+	if err != nil || len(records) != 1 || records[0].ExitCode == nil || *records[0].ExitCode != 42 {
+		t.Fatalf("GetHistory() = %+v, %v; want exit code 42", records, err)
+	}
+}
+
+func TestCompression_RoundTrip(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	output := strings.Repeat("line of verbose output\n", 400)
+	plain := ExecutionRecord{RuleName: "r", TriggerType: "manual", State: "success", StartedAt: now, FinishedAt: now,
+		EventData: `{"file_path":"/tmp/a"}`, Output: output, Stderr: "warning: retrying", ParsedOutput: `{"lines":["a","b"]}`}
+	plainID, err := db.RecordExecution(plain)
+	if err != nil {
+		t.Fatalf("RecordExecution() error = %v", err)
+	}
+
+	db.SetCompression(true)
+	packed := plain
+	packed.StartedAt = now.Add(time.Second)
+	packedID, err := db.RecordExecution(packed)
+	if err != nil {
+		t.Fatalf("RecordExecution(compressed) error = %v", err)
+	}
+
+	var compressed bool
+	var stored []byte
+	db.db.QueryRow("SELECT compressed, output FROM execution_history WHERE id = ?", packedID).Scan(&compressed, &stored)
+	if !compressed || len(stored) >= len(output) {
+		t.Errorf("compressed row: flag = %v, %d bytes stored for %d bytes of output", compressed, len(stored), len(output))
+	}
+	db.db.QueryRow("SELECT parsed_output FROM execution_history WHERE id = ?", packedID).Scan(&stored)
+	if string(stored) == plain.ParsedOutput {
+		t.Error("parsed_output of a compressed row was stored uncompressed")
+	}
+
+	for _, id := range []int64{plainID, packedID} {
+		rec, err := db.GetExecution(id)
+		if err != nil || rec == nil {
+			t.Fatalf("GetExecution(%d) = %v, %v", id, rec, err)
+		}
+		if rec.Output != output || rec.Stderr != plain.Stderr || rec.EventData != plain.EventData || rec.ParsedOutput != plain.ParsedOutput {
+			t.Errorf("GetExecution(%d) did not round-trip output, stderr, event data and parsed output", id)
+		}
+	}
+	records, err := db.GetHistory("r", "", nil, 10)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("GetHistory() = %d records, %v", len(records), err)
+	}
+	for _, rec := range records {
+		if rec.Output != output {
+			t.Errorf("GetHistory() record %d output did not round-trip", rec.ID)
+		}
+	}
+
+	// Rows without output or event data are stored uncompressed
+	emptyID, _ := db.RecordExecution(ExecutionRecord{RuleName: "r", TriggerType: "manual", State: StateSkipped, StartedAt: now, FinishedAt: now})
+	db.db.QueryRow("SELECT compressed FROM execution_history WHERE id = ?", emptyID).Scan(&compressed)
+	if compressed {
+		t.Error("empty row should not be marked compressed")
+	}
+}
@@ -4,6 +4,7 @@ package template
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/colebrumley/srvrmgr/internal/security"
 )
@@ -24,3 +25,21 @@ func Expand(tmpl string, data map[string]any) string {
 		return match // Keep original if not found
 	})
 }
+
+// ExpandShell is like Expand but single-quotes each substituted value so event
+// data interpolated into a shell script is always a single literal word.
+func ExpandShell(tmpl string, data map[string]any) string {
+	return templateVar.ReplaceAllStringFunc(tmpl, func(match string) string {
+		varName := match[2 : len(match)-2]
+
+		if val, ok := data[varName]; ok {
+			return shellQuote(security.SanitizeValue(fmt.Sprintf("%v", val)))
+		}
+		return match
+	})
+}
+
+// shellQuote wraps s in single quotes, escaping embedded single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -132,3 +132,15 @@ func TestExpand_PreservesTabsAndNewlines(t *testing.T) {
 		t.Error("FR-16: newlines should be preserved in sanitized output")
 	}
 }
+
+func TestExpandShell_QuotesValues(t *testing.T) {
+	data := map[string]any{
+		"file_path": "/tmp/it's here; rm -rf /",
+		"count":     3,
+	}
+	got := ExpandShell("echo {{file_path}} {{count}} {{missing}}", data)
+	want := `echo '/tmp/it'\''s here; rm -rf /' '3' {{missing}}`
+	if got != want {
+		t.Errorf("ExpandShell() = %q, want %q", got, want)
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"os"
-	"os/user"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -17,26 +15,24 @@ import (
 	"github.com/fsnotify/fsevents"
 )
 
-// pendingEvent tracks a debounced event, preserving the first event type
-// seen during the debounce window so that file_created isn't silently
-// replaced by a subsequent file_modified.
-type pendingEvent struct {
-	timer     *time.Timer
-	eventType string
-}
-
-// Filesystem watches directories for file events using macOS FSEvents.
-// FSEvents watches path strings (not file descriptors), so it handles
-// volume mount/unmount and non-existent paths natively.
+// Filesystem watches directories, or single files through their parent
+// directory, for file events using macOS FSEvents. FSEvents watches path
+// strings (not file descriptors), so it handles volume mount/unmount and
+// non-existent paths natively.
 type Filesystem struct {
+	dropNotifier
 	ruleName          string
 	watchPaths        []string
 	watchPathPrefixes []string // precomputed wp + "/" for recursive prefix matching
 	cleanedWatchPaths []string // precomputed filepath.Clean(wp) for non-recursive matching
+	watchFiles        map[string]bool
+	streamPaths       []string // directories handed to FSEvents
 	recursive         bool
 	onEvents          map[string]bool
 	ignorePatterns    []string
 	debounceDuration  time.Duration
+	dedupeWindow      time.Duration // collapses bursts for a path when debounce is off
+	latency           time.Duration // FSEvents coalescing window
 	stream            *fsevents.EventStream
 	done              chan struct{}
 	mu                sync.Mutex
@@ -55,26 +51,34 @@ func NewFilesystem(ruleName string, cfg config.Trigger, runAsUser string) (*File
 		onEvents[e] = true
 	}
 
-	var watchPaths, prefixes, cleaned []string
+	var expanded []string
 	for _, p := range cfg.WatchPaths {
-		expanded := expandHomeForUser(p, runAsUser)
-		if resolved, err := filepath.EvalSymlinks(expanded); err == nil {
-			expanded = resolved
+		path := expandHomeForUser(p, runAsUser)
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
 		}
-		watchPaths = append(watchPaths, expanded)
-		prefixes = append(prefixes, expanded+"/")
-		cleaned = append(cleaned, filepath.Clean(expanded))
+		expanded = append(expanded, path)
+	}
+	targets := resolveWatchTargets(expanded)
+
+	var prefixes []string
+	for _, dir := range targets.dirs {
+		prefixes = append(prefixes, dir+"/")
 	}
 
 	return &Filesystem{
 		ruleName:          ruleName,
-		watchPaths:        watchPaths,
+		watchPaths:        targets.dirs,
 		watchPathPrefixes: prefixes,
-		cleanedWatchPaths: cleaned,
+		cleanedWatchPaths: targets.dirs,
+		watchFiles:        targets.files,
+		streamPaths:       targets.streamPaths,
 		recursive:         cfg.Recursive,
 		onEvents:          onEvents,
 		ignorePatterns:    cfg.IgnorePatterns,
 		debounceDuration:  time.Duration(cfg.DebounceSeconds) * time.Second,
+		dedupeWindow:      time.Duration(cfg.DedupeWindowMs) * time.Millisecond,
+		latency:           time.Duration(cfg.CoalesceSeconds * float64(time.Second)),
 		pending:           make(map[string]*pendingEvent),
 	}, nil
 }
@@ -93,16 +97,12 @@ func (f *Filesystem) Start(ctx context.Context, events chan<- Event) error {
 	f.stopped = false
 	f.done = make(chan struct{})
 
-	stream := &fsevents.EventStream{
-		Paths:   f.watchPaths,
-		Latency: 0,
-		Flags:   fsevents.FileEvents | fsevents.WatchRoot | fsevents.NoDefer,
-	}
+	stream := f.newStream()
 	f.stream = stream
 	f.mu.Unlock()
 
 	stream.Start()
-	slog.Info("fsevents stream started", "rule", f.ruleName, "paths", f.watchPaths)
+	slog.Info("fsevents stream started", "rule", f.ruleName, "paths", f.streamPaths)
 
 	for {
 		select {
@@ -118,6 +118,17 @@ func (f *Filesystem) Start(ctx context.Context, events chan<- Event) error {
 	}
 }
 
+// newStream configures the FSEvents stream. With a latency set the kernel
+// coalesces changes for that long before delivering them; NoDefer still
+// delivers the first event of a quiet period immediately.
+func (f *Filesystem) newStream() *fsevents.EventStream {
+	return &fsevents.EventStream{
+		Paths:   f.streamPaths,
+		Latency: f.latency,
+		Flags:   fsevents.FileEvents | fsevents.WatchRoot | fsevents.NoDefer,
+	}
+}
+
 func (f *Filesystem) Stop() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -183,6 +194,13 @@ func (f *Filesystem) handleFSEvent(ev fsevents.Event, events chan<- Event) {
 
 	eventPath := ev.Path
 
+	// A watched single file reports file_modified or file_deleted whatever
+	// the flags, since a replacement arrives as create/rename events.
+	if f.watchFiles[eventPath] {
+		f.handleFileEvent(eventPath, events)
+		return
+	}
+
 	// Map flags to event type first (O(1) bitmask checks), then filter by
 	// onEvents before doing O(n) path matching — avoids wasted work for
 	// unwatched event types.
@@ -225,14 +243,23 @@ func (f *Filesystem) handleFSEvent(ev fsevents.Event, events chan<- Event) {
 		}
 	}
 
-	if f.debounceDuration > 0 {
-		f.debounce(eventPath, filename, eventType, events)
+	if wait := max(f.debounceDuration, f.dedupeWindow); wait > 0 {
+		f.debounce(eventPath, filename, eventType, wait, events)
 		return
 	}
 	f.sendEvent(eventPath, filename, eventType, events)
 }
 
-func (f *Filesystem) debounce(path, filename, eventType string, events chan<- Event) {
+// handleFileEvent emits one event per burst of changes to a watched file.
+func (f *Filesystem) handleFileEvent(path string, events chan<- Event) {
+	eventType := singleFileEventType(path)
+	if !f.onEvents[eventType] {
+		return
+	}
+	f.debounce(path, filepath.Base(path), eventType, max(f.debounceDuration, fileCoalesceWindow), events)
+}
+
+func (f *Filesystem) debounce(path, filename, eventType string, wait time.Duration, events chan<- Event) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
@@ -249,7 +276,7 @@ func (f *Filesystem) debounce(path, filename, eventType string, events chan<- Ev
 
 	f.pending[path] = &pendingEvent{
 		eventType: eventType,
-		timer: time.AfterFunc(f.debounceDuration, func() {
+		timer: time.AfterFunc(wait, func() {
 			f.mu.Lock()
 			stopped := f.stopped
 			delete(f.pending, path)
@@ -262,8 +289,7 @@ func (f *Filesystem) debounce(path, filename, eventType string, events chan<- Ev
 }
 
 func (f *Filesystem) sendEvent(path, filename, eventType string, events chan<- Event) {
-	select {
-	case events <- Event{
+	f.send(events, Event{
 		RuleName:  f.ruleName,
 		Type:      eventType,
 		Timestamp: time.Now(),
@@ -272,31 +298,5 @@ func (f *Filesystem) sendEvent(path, filename, eventType string, events chan<- E
 			"file_name":  filename,
 			"event_type": eventType,
 		},
-	}:
-	default:
-		// channel full, drop event
-	}
-}
-
-// expandHomeForUser resolves ~ or ~/... to the specified user's home directory.
-func expandHomeForUser(path, username string) string {
-	if path == "~" {
-		path = "~/"
-	}
-	if !strings.HasPrefix(path, "~/") {
-		return path
-	}
-
-	if username != "" {
-		u, err := user.Lookup(username)
-		if err == nil {
-			return filepath.Join(u.HomeDir, path[2:])
-		}
-	}
-
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return path
-	}
-	return filepath.Join(home, path[2:])
+	})
 }
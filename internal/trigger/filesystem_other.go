@@ -4,25 +4,376 @@ package trigger
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"runtime"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/fsnotify/fsnotify"
 )
 
-// Filesystem is a stub on non-darwin platforms.
+// maxWatches bounds how many directories one trigger watches. Every directory
+// needs its own inotify watch, and the per-user limit
+// (fs.inotify.max_user_watches) is shared by every process.
+var maxWatches = 8192
+
+// osWatchLimitPath holds the per-user inotify watch limit on Linux.
+const osWatchLimitPath = "/proc/sys/fs/inotify/max_user_watches"
+
+// Filesystem watches directories, or single files through their parent
+// directory, for file events using fsnotify. fsnotify isn't recursive, so with
+// recursive set every subdirectory gets its own watch: the tree is walked on
+// start, new subdirectories are added as they are created and their watches
+// are dropped when they are removed.
 type Filesystem struct {
-	ruleName string
+	dropNotifier
+	ruleName         string
+	dirs             []string
+	files            map[string]bool
+	streamPaths      []string // directories to watch at start
+	recursive        bool
+	onEvents         map[string]bool
+	ignorePatterns   []string
+	debounceDuration time.Duration
+	dedupeWindow     time.Duration // collapses bursts for a path when debounce is off
+	watcher          *fsnotify.Watcher
+	watched          map[string]bool // directories with a watch
+	watchLimit       int             // read once per Start; see watchLimit
+	limitWarned      bool
+	done             chan struct{}
+	mu               sync.Mutex
+	pending          map[string]*pendingEvent
+	stopped          bool
+	running          bool
 }
 
 var _ Trigger = (*Filesystem)(nil)
 
+// NewFilesystem creates a new filesystem trigger using fsnotify.
+// FR-12: runAsUser is used to resolve ~ in watch_paths to the correct user's home.
 func NewFilesystem(ruleName string, cfg config.Trigger, runAsUser string) (*Filesystem, error) {
-	return nil, fmt.Errorf("filesystem triggers require macOS (FSEvents); running on %s", runtime.GOOS)
+	onEvents := make(map[string]bool)
+	for _, e := range cfg.OnEvents {
+		onEvents[e] = true
+	}
+
+	var expanded []string
+	for _, p := range cfg.WatchPaths {
+		path := expandHomeForUser(p, runAsUser)
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		expanded = append(expanded, path)
+	}
+	targets := resolveWatchTargets(expanded)
+
+	return &Filesystem{
+		ruleName:         ruleName,
+		dirs:             targets.dirs,
+		files:            targets.files,
+		streamPaths:      targets.streamPaths,
+		recursive:        cfg.Recursive,
+		onEvents:         onEvents,
+		ignorePatterns:   cfg.IgnorePatterns,
+		debounceDuration: time.Duration(cfg.DebounceSeconds) * time.Second,
+		dedupeWindow:     time.Duration(cfg.DedupeWindowMs) * time.Millisecond,
+		pending:          make(map[string]*pendingEvent),
+	}, nil
+}
+
+func (f *Filesystem) RuleName() string {
+	return f.ruleName
 }
 
-func (f *Filesystem) RuleName() string { return f.ruleName }
 func (f *Filesystem) Start(ctx context.Context, events chan<- Event) error {
-	return fmt.Errorf("filesystem triggers require macOS (FSEvents); running on %s", runtime.GOOS)
+	f.mu.Lock()
+	if f.running {
+		f.mu.Unlock()
+		return fmt.Errorf("filesystem trigger %q already running", f.ruleName)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		f.mu.Unlock()
+		return fmt.Errorf("creating fsnotify watcher: %w", err)
+	}
+	f.running = true
+	f.stopped = false
+	f.done = make(chan struct{})
+	f.watcher = watcher
+	f.watched = make(map[string]bool)
+	f.watchLimit = watchLimit()
+	f.limitWarned = false
+	done := f.done
+
+	for _, dir := range f.streamPaths {
+		if f.recursive && slices.Contains(f.dirs, dir) {
+			f.addTree(dir)
+		} else {
+			f.addWatch(dir)
+		}
+	}
+	watches := len(f.watched)
+	f.mu.Unlock()
+
+	slog.Info("fsnotify watcher started", "rule", f.ruleName, "paths", f.streamPaths, "watches", watches)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-done:
+			return nil
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			f.handleFSEvent(ev, events)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				slog.Warn("fsnotify queue overflow, events may have been lost", "rule", f.ruleName)
+			} else {
+				slog.Warn("fsnotify watcher error", "rule", f.ruleName, "error", err)
+			}
+		}
+	}
+}
+
+func (f *Filesystem) Stop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = true
+	f.running = false
+
+	if f.watcher != nil {
+		f.watcher.Close()
+		f.watcher = nil
+	}
+
+	if f.done != nil {
+		select {
+		case <-f.done:
+		default:
+			close(f.done)
+		}
+	}
+
+	for path, pe := range f.pending {
+		pe.timer.Stop()
+		delete(f.pending, path)
+	}
+
+	return nil
+}
+
+// watchLimit is maxWatches, or the OS's per-user limit if that is lower.
+func watchLimit() int {
+	limit := maxWatches
+	if data, err := os.ReadFile(osWatchLimitPath); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && n > 0 && n < limit {
+			limit = n
+		}
+	}
+	return limit
+}
+
+// addWatch watches dir, reporting false once the watch limit is reached.
+// Callers must hold f.mu.
+func (f *Filesystem) addWatch(dir string) bool {
+	if f.watcher == nil || f.watched[dir] {
+		return true
+	}
+	limit := f.watchLimit
+	if len(f.watched) >= limit {
+		if !f.limitWarned {
+			slog.Error("filesystem watch limit reached, deeper directories are not watched",
+				"rule", f.ruleName, "limit", limit, "dir", dir)
+			f.limitWarned = true
+		}
+		return false
+	}
+	if err := f.watcher.Add(dir); err != nil {
+		slog.Warn("could not watch directory", "rule", f.ruleName, "dir", dir, "error", err)
+		return true
+	}
+	f.watched[dir] = true
+	if len(f.watched) == limit*9/10 {
+		slog.Warn("filesystem trigger is near the watch limit",
+			"rule", f.ruleName, "watches", len(f.watched), "limit", limit)
+	}
+	return true
+}
+
+// addTree watches root and every directory below it. Callers must hold f.mu.
+func (f *Filesystem) addTree(root string) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if !f.addWatch(path) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+}
+
+// removeTree drops the watches on dir and every directory below it. Callers
+// must hold f.mu.
+func (f *Filesystem) removeTree(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for path := range f.watched {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			delete(f.watched, path)
+			if f.watcher != nil {
+				f.watcher.Remove(path) // already gone if the directory was deleted
+			}
+		}
+	}
+}
+
+// isWatchedPath filters events by depth: with recursive=false only direct
+// children of watched directories pass.
+func (f *Filesystem) isWatchedPath(eventPath string) bool {
+	if f.recursive {
+		for _, dir := range f.dirs {
+			if eventPath == dir || strings.HasPrefix(eventPath, dir+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+	return slices.Contains(f.dirs, filepath.Dir(eventPath))
+}
+
+func (f *Filesystem) handleFSEvent(ev fsnotify.Event, events chan<- Event) {
+	eventPath := filepath.Clean(ev.Name)
+
+	// A watched single file reports file_modified or file_deleted whatever
+	// the op, since a replacement arrives as create/rename events.
+	if f.files[eventPath] {
+		if ev.Op != fsnotify.Chmod {
+			f.handleFileEvent(eventPath, events)
+		}
+		return
+	}
+
+	if !f.isWatchedPath(eventPath) {
+		return
+	}
+
+	var eventType string
+	switch {
+	case ev.Has(fsnotify.Create):
+		info, err := os.Lstat(eventPath)
+		if err == nil && info.IsDir() {
+			eventType = "directory_created"
+			if f.recursive {
+				f.mu.Lock()
+				f.addTree(eventPath)
+				f.mu.Unlock()
+			}
+		} else {
+			eventType = "file_created"
+		}
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		f.mu.Lock()
+		wasDir := f.watched[eventPath]
+		if wasDir {
+			f.removeTree(eventPath)
+		}
+		f.mu.Unlock()
+		if ev.Has(fsnotify.Rename) {
+			// Source side of a rename: the destination arrives as Create. Skip.
+			return
+		}
+		if wasDir {
+			eventType = "directory_deleted"
+		} else {
+			eventType = "file_deleted"
+		}
+	case ev.Has(fsnotify.Write):
+		eventType = "file_modified"
+	default:
+		return
+	}
+
+	if !f.onEvents[eventType] {
+		return
+	}
+
+	// Ignore patterns match against the basename only (not the full path).
+	filename := filepath.Base(eventPath)
+	for _, pattern := range f.ignorePatterns {
+		if matched, _ := filepath.Match(pattern, filename); matched {
+			return
+		}
+	}
+
+	if wait := max(f.debounceDuration, f.dedupeWindow); wait > 0 {
+		f.debounce(eventPath, filename, eventType, wait, events)
+		return
+	}
+	f.sendEvent(eventPath, filename, eventType, events)
+}
+
+// handleFileEvent emits one event per burst of changes to a watched file.
+func (f *Filesystem) handleFileEvent(path string, events chan<- Event) {
+	eventType := singleFileEventType(path)
+	if !f.onEvents[eventType] {
+		return
+	}
+	f.debounce(path, filepath.Base(path), eventType, max(f.debounceDuration, fileCoalesceWindow), events)
+}
+
+func (f *Filesystem) debounce(path, filename, eventType string, wait time.Duration, events chan<- Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stopped {
+		return
+	}
+
+	// Keep the first event type seen during the debounce window,
+	// so file_created isn't silently replaced by file_modified.
+	if pe, exists := f.pending[path]; exists {
+		pe.timer.Stop()
+		eventType = pe.eventType
+	}
+
+	f.pending[path] = &pendingEvent{
+		eventType: eventType,
+		timer: time.AfterFunc(wait, func() {
+			f.mu.Lock()
+			stopped := f.stopped
+			delete(f.pending, path)
+			f.mu.Unlock()
+			if !stopped {
+				f.sendEvent(path, filename, eventType, events)
+			}
+		}),
+	}
+}
+
+func (f *Filesystem) sendEvent(path, filename, eventType string, events chan<- Event) {
+	f.send(events, Event{
+		RuleName:  f.ruleName,
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Data: map[string]any{
+			"file_path":  path,
+			"file_name":  filename,
+			"event_type": eventType,
+		},
+	})
 }
-func (f *Filesystem) Stop() error { return nil }
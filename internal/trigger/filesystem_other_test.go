@@ -0,0 +1,247 @@
+//go:build !darwin
+
+package trigger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/colebrumley/srvrmgr/internal/config"
+)
+
+// startFilesystem starts a filesystem trigger for cfg and returns its events.
+func startFilesystem(t *testing.T, cfg config.Trigger) (*Filesystem, <-chan Event) {
+	t.Helper()
+	cfg.Type = "filesystem"
+	trigger, err := NewFilesystem("fs-rule", cfg, "")
+	if err != nil {
+		t.Fatalf("NewFilesystem failed: %v", err)
+	}
+	events := make(chan Event, 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go func() {
+		close(started)
+		trigger.Start(ctx, events)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		trigger.Stop()
+	})
+	<-started
+	// Wait until the initial watches are in place
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		trigger.mu.Lock()
+		ready := trigger.running && trigger.watched != nil
+		trigger.mu.Unlock()
+		if ready || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	return trigger, events
+}
+
+// collectEvents returns the events received within d.
+func collectEvents(events <-chan Event, d time.Duration) []Event {
+	var got []Event
+	deadline := time.After(d)
+	for {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-deadline:
+			return got
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilesystemTrigger_Recursive(t *testing.T) {
+	dir := t.TempDir()
+	deep := filepath.Join(dir, "a", "b", "c")
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	trigger, events := startFilesystem(t, config.Trigger{
+		WatchPaths: []string{dir},
+		OnEvents:   []string{"file_created"},
+		Recursive:  true,
+	})
+	if n := len(trigger.watched); n != 4 {
+		t.Errorf("watches = %d, want 4 (root and three nested directories)", n)
+	}
+
+	// Existing deep directory
+	existing := filepath.Join(deep, "existing.txt")
+	writeFile(t, existing, "x")
+	got := collectEvents(events, 300*time.Millisecond)
+	if len(got) != 1 || got[0].Data["file_path"] != existing {
+		t.Fatalf("events = %+v, want file_created for %s", got, existing)
+	}
+
+	// Directories created after start are watched as they appear
+	newDeep := filepath.Join(dir, "x", "y")
+	if err := os.Mkdir(filepath.Join(dir, "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := os.Mkdir(newDeep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	created := filepath.Join(newDeep, "new.txt")
+	writeFile(t, created, "x")
+	got = collectEvents(events, 300*time.Millisecond)
+	if len(got) != 1 || got[0].Data["file_path"] != created {
+		t.Fatalf("events = %+v, want file_created for %s", got, created)
+	}
+}
+
+func TestFilesystemTrigger_RemovedDirectoryDropsWatches(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	trigger, events := startFilesystem(t, config.Trigger{
+		WatchPaths: []string{dir},
+		OnEvents:   []string{"directory_deleted"},
+		Recursive:  true,
+	})
+
+	if err := os.RemoveAll(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	got := collectEvents(events, 300*time.Millisecond)
+	if len(got) == 0 || got[len(got)-1].Type != "directory_deleted" {
+		t.Errorf("events = %+v, want directory_deleted", got)
+	}
+	trigger.mu.Lock()
+	n := len(trigger.watched)
+	trigger.mu.Unlock()
+	if n != 1 {
+		t.Errorf("watches = %d after removal, want only the root", n)
+	}
+}
+
+func TestFilesystemTrigger_NonRecursiveIgnoresNested(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	_, events := startFilesystem(t, config.Trigger{
+		WatchPaths: []string{dir},
+		OnEvents:   []string{"file_created"},
+	})
+
+	writeFile(t, filepath.Join(sub, "nested.txt"), "x")
+	top := filepath.Join(dir, "top.txt")
+	writeFile(t, top, "x")
+
+	got := collectEvents(events, 300*time.Millisecond)
+	if len(got) != 1 || got[0].Data["file_path"] != top {
+		t.Errorf("events = %+v, want only %s", got, top)
+	}
+}
+
+func TestFilesystemTrigger_WatchLimit(t *testing.T) {
+	defer func(n int) { maxWatches = n }(maxWatches)
+	maxWatches = 3
+
+	dir := t.TempDir()
+	for _, sub := range []string{"a", "b", "c", "d"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trigger, _ := startFilesystem(t, config.Trigger{
+		WatchPaths: []string{dir},
+		OnEvents:   []string{"file_created"},
+		Recursive:  true,
+	})
+	trigger.mu.Lock()
+	defer trigger.mu.Unlock()
+	if n := len(trigger.watched); n != 3 {
+		t.Errorf("watches = %d, want the limit of 3", n)
+	}
+}
+
+func TestFilesystemTrigger_SingleFile(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "app.conf")
+	writeFile(t, conf, "v1")
+
+	_, events := startFilesystem(t, config.Trigger{
+		WatchPaths: []string{conf},
+		OnEvents:   []string{"file_created", "file_modified"},
+	})
+
+	writeFile(t, filepath.Join(dir, "other.conf"), "x")
+	writeFile(t, conf, "v2")
+	got := collectEvents(events, 500*time.Millisecond)
+	if len(got) != 1 || got[0].Type != "file_modified" || got[0].Data["file_path"] != conf {
+		t.Fatalf("in-place write: events = %+v, want one file_modified for %s", got, conf)
+	}
+
+	tmp := filepath.Join(dir, ".app.conf.swp")
+	writeFile(t, tmp, "v3")
+	if err := os.Rename(tmp, conf); err != nil {
+		t.Fatal(err)
+	}
+	got = collectEvents(events, 500*time.Millisecond)
+	if len(got) != 1 || got[0].Type != "file_modified" || got[0].Data["file_path"] != conf {
+		t.Fatalf("write-rename: events = %+v, want one file_modified for %s", got, conf)
+	}
+}
+
+func TestFilesystemTrigger_DedupeWindow(t *testing.T) {
+	for _, tc := range []struct {
+		window int
+		want   []string
+	}{
+		{0, []string{"file_created", "file_modified"}},
+		{100, []string{"file_created"}},
+	} {
+		dir := t.TempDir()
+		_, events := startFilesystem(t, config.Trigger{
+			WatchPaths:     []string{dir},
+			OnEvents:       []string{"file_created", "file_modified"},
+			DedupeWindowMs: tc.window,
+		})
+
+		// Creating a file with content reports a create then a write
+		writeFile(t, filepath.Join(dir, "new.txt"), "x")
+
+		var got []string
+		for _, e := range collectEvents(events, 300*time.Millisecond) {
+			got = append(got, e.Type)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("dedupe_window_ms=%d: events = %v, want %v", tc.window, got, tc.want)
+		}
+	}
+}
+
+func TestFilesystemTrigger_DoubleStartReturnsError(t *testing.T) {
+	trigger, _ := startFilesystem(t, config.Trigger{
+		WatchPaths: []string{t.TempDir()},
+		OnEvents:   []string{"file_created"},
+	})
+	if err := trigger.Start(context.Background(), make(chan Event)); err == nil {
+		t.Error("expected error on double Start(), got nil")
+	}
+}
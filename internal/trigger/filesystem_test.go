@@ -130,6 +130,138 @@ func TestFilesystemTrigger_DirectoryCreated(t *testing.T) {
 	}
 }
 
+// ===== Single-file watch_paths =====
+
+// collectEvents returns the events received within d.
+func collectEvents(events <-chan Event, d time.Duration) []Event {
+	var got []Event
+	deadline := time.After(d)
+	for {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-deadline:
+			return got
+		}
+	}
+}
+
+func TestFilesystemTrigger_DedupeWindow(t *testing.T) {
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+
+	trigger, err := NewFilesystem("dedupe", config.Trigger{
+		Type:           "filesystem",
+		WatchPaths:     []string{dir},
+		OnEvents:       []string{"file_created", "file_modified"},
+		DedupeWindowMs: 200,
+	}, "")
+	if err != nil {
+		t.Fatalf("NewFilesystem failed: %v", err)
+	}
+
+	events := make(chan Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = trigger.Start(ctx, events)
+	}()
+	time.Sleep(fsEventsInitDelay)
+
+	// Create then modify in quick succession, as a single save often does
+	path := filepath.Join(dir, "saved.txt")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := collectEvents(events, time.Second)
+	if len(got) != 1 || got[0].Type != "file_created" {
+		t.Fatalf("events = %+v, want a single file_created", got)
+	}
+}
+
+func TestFilesystemTrigger_SingleFile(t *testing.T) {
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	conf := filepath.Join(dir, "app.conf")
+	if err := os.WriteFile(conf, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	trigger, err := NewFilesystem("conf-rule", config.Trigger{
+		Type:       "filesystem",
+		WatchPaths: []string{conf},
+		OnEvents:   []string{"file_created", "file_modified"},
+	}, "")
+	if err != nil {
+		t.Fatalf("NewFilesystem failed: %v", err)
+	}
+
+	events := make(chan Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := trigger.Start(ctx, events); err != nil && err != context.Canceled {
+			t.Errorf("Start failed: %v", err)
+		}
+	}()
+	time.Sleep(fsEventsInitDelay)
+
+	// A sibling in the same directory is not watched
+	if err := os.WriteFile(filepath.Join(dir, "other.conf"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// In-place write
+	if err := os.WriteFile(conf, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := collectEvents(events, time.Second)
+	if len(got) != 1 || got[0].Type != "file_modified" || got[0].Data["file_path"] != conf {
+		t.Fatalf("in-place write: events = %+v, want one file_modified for %s", got, conf)
+	}
+
+	// Replacement via write-rename, as editors save
+	tmp := filepath.Join(dir, ".app.conf.swp")
+	if err := os.WriteFile(tmp, []byte("v3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, conf); err != nil {
+		t.Fatal(err)
+	}
+
+	got = collectEvents(events, time.Second)
+	if len(got) != 1 || got[0].Type != "file_modified" || got[0].Data["file_path"] != conf {
+		t.Fatalf("write-rename: events = %+v, want one file_modified for %s", got, conf)
+	}
+}
+
+func TestFilesystemTrigger_CoalesceLatency(t *testing.T) {
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+
+	for _, tc := range []struct {
+		coalesce float64
+		want     time.Duration
+	}{
+		{0, 0},
+		{1.5, 1500 * time.Millisecond},
+	} {
+		trigger, err := NewFilesystem("coalesce", config.Trigger{
+			Type:            "filesystem",
+			WatchPaths:      []string{dir},
+			OnEvents:        []string{"file_created"},
+			CoalesceSeconds: tc.coalesce,
+		}, "")
+		if err != nil {
+			t.Fatalf("NewFilesystem failed: %v", err)
+		}
+		if got := trigger.newStream().Latency; got != tc.want {
+			t.Errorf("coalesce_seconds=%g: stream latency = %v, want %v", tc.coalesce, got, tc.want)
+		}
+	}
+}
+
 func TestFilesystemTrigger_DoubleStartReturnsError(t *testing.T) {
 	dir, _ := filepath.EvalSymlinks(t.TempDir())
 
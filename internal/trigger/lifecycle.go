@@ -10,6 +10,7 @@ import (
 
 // Lifecycle fires on daemon start/stop events
 type Lifecycle struct {
+	dropNotifier
 	ruleName string
 	onEvents map[string]bool
 }
@@ -50,15 +51,10 @@ func (l *Lifecycle) Fire(eventType string, events chan<- Event) bool {
 	if !l.ShouldFireOn(eventType) {
 		return false
 	}
-	select {
-	case events <- Event{
+	return l.send(events, Event{
 		RuleName:  l.ruleName,
 		Type:      eventType,
 		Timestamp: time.Now(),
 		Data:      map[string]any{},
-	}:
-		return true
-	default:
-		return false // channel full, avoid blocking
-	}
+	})
 }
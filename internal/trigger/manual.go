@@ -10,6 +10,7 @@ import (
 
 // Manual is a trigger that only fires via CLI
 type Manual struct {
+	dropNotifier
 	ruleName string
 }
 
@@ -34,15 +35,10 @@ func (m *Manual) Stop() error {
 
 // Fire manually triggers this rule. Returns false if the channel is full.
 func (m *Manual) Fire(events chan<- Event, data map[string]any) bool {
-	select {
-	case events <- Event{
+	return m.send(events, Event{
 		RuleName:  m.ruleName,
 		Type:      "manual",
 		Timestamp: time.Now(),
 		Data:      data,
-	}:
-		return true
-	default:
-		return false
-	}
+	})
 }
@@ -17,6 +17,7 @@ import (
 type Scheduled struct {
 	ruleName string
 	cron     *cron.Cron
+	entry    cron.EntryID
 	events   chan<- Event
 	mu       sync.Mutex
 }
@@ -31,22 +32,12 @@ func NewScheduled(ruleName string, cfg config.Trigger) (*Scheduled, error) {
 		cron:     c,
 	}
 
-	// Parse the cron expression
-	cronExpr := cfg.CronExpression
-	if cronExpr == "" {
-		// Convert simple syntax to cron
-		var err error
-		cronExpr, err = convertSimpleToCron(cfg.RunEvery, cfg.RunAt)
-		if err != nil {
-			return nil, fmt.Errorf("invalid schedule: %w", err)
-		}
-	} else {
-		// FR-9: Accept 5-field cron expressions by prepending "0" for seconds.
-		// Sourced from architect — named helper for clarity.
-		cronExpr = normalizeCronExpression(cronExpr)
+	cronExpr, err := cronSpec(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := c.AddFunc(cronExpr, func() {
+	s.entry, err = c.AddFunc(cronExpr, func() {
 		s.mu.Lock()
 		events := s.events
 		s.mu.Unlock()
@@ -89,6 +80,48 @@ func (s *Scheduled) Stop() error {
 	return nil
 }
 
+// NextRun returns when the trigger will next fire, implementing NextRunner.
+// Before Start, when cron hasn't computed it yet, it is the next time the
+// schedule matches from now.
+func (s *Scheduled) NextRun() time.Time {
+	entry := s.cron.Entry(s.entry)
+	if !entry.Valid() {
+		return time.Time{}
+	}
+	if entry.Next.IsZero() {
+		return entry.Schedule.Next(time.Now())
+	}
+	return entry.Next
+}
+
+// ParseSchedule returns the cron schedule a scheduled trigger fires on, so
+// callers can reason about expected run times without starting the trigger.
+func ParseSchedule(cfg config.Trigger) (cron.Schedule, error) {
+	cronExpr, err := cronSpec(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Same field set as cron.WithSeconds()
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	return parser.Parse(cronExpr)
+}
+
+// cronSpec resolves a trigger's cron_expression, run_every or run_at into a
+// 6-field cron expression.
+func cronSpec(cfg config.Trigger) (string, error) {
+	if cfg.CronExpression == "" {
+		// Convert simple syntax to cron
+		cronExpr, err := convertSimpleToCron(cfg.RunEvery, cfg.RunAt)
+		if err != nil {
+			return "", fmt.Errorf("invalid schedule: %w", err)
+		}
+		return cronExpr, nil
+	}
+	// FR-9: Accept 5-field cron expressions by prepending "0" for seconds.
+	// Sourced from architect — named helper for clarity.
+	return normalizeCronExpression(cfg.CronExpression), nil
+}
+
 // normalizeCronExpression converts 5-field cron expressions to 6-field
 // by prepending "0" for the seconds field (FR-9).
 // Sourced from architect — named helper is self-documenting.
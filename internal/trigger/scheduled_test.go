@@ -46,3 +46,87 @@ func TestScheduledTrigger(t *testing.T) {
 
 	trigger.Stop()
 }
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2025, 1, 1, 10, 7, 0, 0, time.UTC)
+
+	tests := []struct {
+		cfg  config.Trigger
+		want time.Time
+	}{
+		{config.Trigger{RunEvery: "30m"}, time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{config.Trigger{CronExpression: "0 12 * * *"}, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		sched, err := ParseSchedule(tt.cfg)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%+v) error = %v", tt.cfg, err)
+		}
+		if got := sched.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next() = %v, want %v", got, tt.want)
+		}
+	}
+
+	if _, err := ParseSchedule(config.Trigger{RunEvery: "5x"}); err == nil {
+		t.Error("expected error for invalid run_every")
+	}
+}
+
+// NextRun agrees with the schedule the trigger was built from, for each way
+// of writing one.
+func TestScheduled_NextRunMatchesSchedule(t *testing.T) {
+	for _, cfg := range []config.Trigger{
+		{Type: "scheduled", CronExpression: "*/15 * * * *"},
+		{Type: "scheduled", CronExpression: "30 0 4 * * *"},
+		{Type: "scheduled", RunEvery: "6h"},
+		{Type: "scheduled", RunAt: "09:30"},
+	} {
+		s, err := NewScheduled("rule", cfg)
+		if err != nil {
+			t.Fatalf("NewScheduled(%+v) error = %v", cfg, err)
+		}
+		sched, err := ParseSchedule(cfg)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%+v) error = %v", cfg, err)
+		}
+		before := time.Now()
+		got := s.NextRun()
+		// The schedule's next match from just before the call, unless the
+		// clock crossed a match in between
+		if want := sched.Next(before); !got.Equal(want) && !got.Equal(sched.Next(want)) {
+			t.Errorf("%+v: NextRun() = %v, want %v", cfg, got, want)
+		}
+		if !got.After(before) {
+			t.Errorf("%+v: NextRun() = %v, want a time in the future", cfg, got)
+		}
+	}
+	var _ NextRunner = (*Scheduled)(nil)
+}
+
+func TestScheduled_NextRun(t *testing.T) {
+	s, err := NewScheduled("hourly", config.Trigger{Type: "scheduled", CronExpression: "0 * * * *"})
+	if err != nil {
+		t.Fatalf("NewScheduled failed: %v", err)
+	}
+	check := func(when string) {
+		t.Helper()
+		next := s.NextRun()
+		if !next.After(time.Now()) || time.Until(next) > time.Hour || next.Minute() != 0 || next.Second() != 0 {
+			t.Errorf("NextRun() %s = %v, want the next full hour", when, next)
+		}
+	}
+	check("before Start")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Start(ctx, make(chan Event, 1))
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+		s.Stop()
+	}()
+	check("after Start")
+}
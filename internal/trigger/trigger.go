@@ -3,6 +3,7 @@ package trigger
 
 import (
 	"context"
+	"sync"
 	"time"
 )
 
@@ -12,6 +13,9 @@ type Event struct {
 	Type      string
 	Timestamp time.Time
 	Data      map[string]any
+	// NoDeps skips the depends_on_rules check, for manual runs with
+	// srvrmgr run --no-deps. Triggers never set it.
+	NoDeps bool
 }
 
 // Trigger is the interface all triggers must implement
@@ -23,3 +27,45 @@ type Trigger interface {
 	// RuleName returns the name of the rule this trigger belongs to
 	RuleName() string
 }
+
+// DropReporter is implemented by triggers that drop events rather than block
+// when the events channel is full. The handler is called for each dropped
+// event and must not block.
+type DropReporter interface {
+	SetDropHandler(fn func(Event))
+}
+
+// NextRunner is implemented by triggers that fire on a schedule. NextRun
+// returns when the trigger next fires, or the zero time if it never will.
+type NextRunner interface {
+	NextRun() time.Time
+}
+
+// dropNotifier sends events without blocking and reports the ones dropped
+// because the channel is full. Triggers embed it to implement DropReporter.
+type dropNotifier struct {
+	mu     sync.Mutex
+	onDrop func(Event)
+}
+
+func (n *dropNotifier) SetDropHandler(fn func(Event)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.onDrop = fn
+}
+
+// send delivers ev, reporting false if events is full and ev was dropped.
+func (n *dropNotifier) send(events chan<- Event, ev Event) bool {
+	select {
+	case events <- ev:
+		return true
+	default:
+	}
+	n.mu.Lock()
+	onDrop := n.onDrop
+	n.mu.Unlock()
+	if onDrop != nil {
+		onDrop(ev)
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+// internal/trigger/watchpaths.go
+package trigger
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// pendingEvent tracks a debounced event, preserving the first event type
+// seen during the debounce window so that file_created isn't silently
+// replaced by a subsequent file_modified.
+type pendingEvent struct {
+	timer     *time.Timer
+	eventType string
+}
+
+// fileCoalesceWindow merges the burst of events a single save produces
+// (e.g. write-rename replacement) into one event for watched single files
+// when the rule sets no debounce of its own.
+const fileCoalesceWindow = 100 * time.Millisecond
+
+// watchTargets splits resolved watch_paths into directories and single files.
+// A file is watched through its parent directory and events are filtered to
+// its path, so editors that save by writing a temp file and renaming it over
+// the original keep triggering the rule.
+type watchTargets struct {
+	dirs        []string        // watched directories, cleaned
+	files       map[string]bool // watched single files, cleaned
+	streamPaths []string        // directories to hand to the watcher, each once
+}
+
+// resolveWatchTargets classifies each path. Paths that don't exist yet are
+// treated as directories.
+func resolveWatchTargets(paths []string) watchTargets {
+	t := watchTargets{files: make(map[string]bool)}
+	add := func(dir string) {
+		if !slices.Contains(t.streamPaths, dir) {
+			t.streamPaths = append(t.streamPaths, dir)
+		}
+	}
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			t.files[p] = true
+			add(filepath.Dir(p))
+			continue
+		}
+		t.dirs = append(t.dirs, p)
+		add(p)
+	}
+	return t
+}
+
+// singleFileEventType maps any event on a watched single file to
+// file_modified while the file exists (writes, attribute changes and
+// write-rename replacements alike), or file_deleted once it is gone.
+func singleFileEventType(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return "file_deleted"
+	}
+	return "file_modified"
+}
+
+// expandHomeForUser resolves ~ or ~/... to the specified user's home directory.
+func expandHomeForUser(path, username string) string {
+	if path == "~" {
+		path = "~/"
+	}
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	if username != "" {
+		u, err := user.Lookup(username)
+		if err == nil {
+			return filepath.Join(u.HomeDir, path[2:])
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
@@ -0,0 +1,48 @@
+// internal/trigger/watchpaths_test.go
+package trigger
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestResolveWatchTargets(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "app.conf")
+	other := filepath.Join(dir, "other.conf")
+	for _, p := range []string{conf, other} {
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := filepath.Join(dir, "not-yet")
+
+	got := resolveWatchTargets([]string{conf, dir + "/", other, missing})
+
+	if !got.files[conf] || !got.files[other] || len(got.files) != 2 {
+		t.Errorf("files = %v, want both config files", got.files)
+	}
+	if !slices.Equal(got.dirs, []string{dir, missing}) {
+		t.Errorf("dirs = %v, want the directory and the missing path", got.dirs)
+	}
+	// The parent of the files is also a watched dir, but is only streamed once
+	if !slices.Equal(got.streamPaths, []string{dir, missing}) {
+		t.Errorf("streamPaths = %v", got.streamPaths)
+	}
+}
+
+func TestSingleFileEventType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := singleFileEventType(path); got != "file_modified" {
+		t.Errorf("existing file = %q, want file_modified", got)
+	}
+	os.Remove(path)
+	if got := singleFileEventType(path); got != "file_deleted" {
+		t.Errorf("removed file = %q, want file_deleted", got)
+	}
+}
@@ -4,22 +4,50 @@ package trigger
 import (
 	"context"
 	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/colebrumley/srvrmgr/internal/config"
+	"github.com/google/jsonschema-go/jsonschema"
 )
 
+// Errors returned by HandleRequest for requests that don't fire the rule.
+var (
+	ErrWebhookForbidden = errors.New("request not allowed")
+	ErrEventDropped     = errors.New("event channel full")
+	ErrInvalidBody      = errors.New("body does not match body_schema")
+)
+
+// InvalidBodyError is returned by HandleRequest when the request body fails
+// the trigger's body_schema. Event is the event that was not sent.
+type InvalidBodyError struct {
+	Event Event
+	Err   error
+}
+
+func (e *InvalidBodyError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrInvalidBody, e.Err)
+}
+
+func (e *InvalidBodyError) Unwrap() []error {
+	return []error{ErrInvalidBody, e.Err}
+}
+
 // Webhook handles HTTP webhook triggers
 type Webhook struct {
+	dropNotifier
 	ruleName       string
 	listenPath     string
 	allowedMethods map[string]bool
 	requireSecret  bool
 	secretHeader   string
 	secret         string
+	bodySchema     *jsonschema.Resolved // nil: any body is accepted
 }
 
 // NewWebhook creates a new webhook trigger
@@ -34,6 +62,15 @@ func NewWebhook(ruleName string, cfg config.Trigger) (*Webhook, error) {
 		secret = os.Getenv(cfg.SecretEnvVar)
 	}
 
+	// Rules from LoadRuleFile come with the schema already read
+	bodySchema := cfg.ResolvedBodySchema
+	if bodySchema == nil && cfg.BodySchema != "" {
+		var err error
+		if bodySchema, err = config.LoadBodySchema(cfg.BodySchema); err != nil {
+			return nil, err
+		}
+	}
+
 	return &Webhook{
 		ruleName:       ruleName,
 		listenPath:     cfg.ListenPath,
@@ -41,9 +78,22 @@ func NewWebhook(ruleName string, cfg config.Trigger) (*Webhook, error) {
 		requireSecret:  cfg.RequireSecret,
 		secretHeader:   cfg.SecretHeader,
 		secret:         secret,
+		bodySchema:     bodySchema,
 	}, nil
 }
 
+// checkBody validates body against the trigger's body_schema, if any.
+func (w *Webhook) checkBody(body []byte) error {
+	if w.bodySchema == nil {
+		return nil
+	}
+	var instance any
+	if err := json.Unmarshal(body, &instance); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return w.bodySchema.Validate(instance)
+}
+
 func (w *Webhook) RuleName() string {
 	return w.ruleName
 }
@@ -63,21 +113,24 @@ func (w *Webhook) Stop() error {
 	return nil
 }
 
-// HandleRequest processes an incoming HTTP request
-func (w *Webhook) HandleRequest(r *http.Request, events chan<- Event) bool {
+// HandleRequest processes an incoming HTTP request, sending an event for it
+// unless it is rejected: ErrWebhookForbidden for a disallowed method or bad
+// secret, an *InvalidBodyError for a body failing body_schema, or
+// ErrEventDropped when the event channel is full.
+func (w *Webhook) HandleRequest(r *http.Request, events chan<- Event) error {
 	// Check method
 	if len(w.allowedMethods) > 0 && !w.allowedMethods[r.Method] {
-		return false
+		return ErrWebhookForbidden
 	}
 
 	// Check secret if required
 	if w.requireSecret {
 		if w.secret == "" {
-			return false // secret env var not set, reject all requests
+			return ErrWebhookForbidden // secret env var not set, reject all requests
 		}
 		headerVal := r.Header.Get(w.secretHeader)
 		if subtle.ConstantTimeCompare([]byte(headerVal), []byte(w.secret)) != 1 {
-			return false
+			return ErrWebhookForbidden
 		}
 	}
 
@@ -92,8 +145,7 @@ func (w *Webhook) HandleRequest(r *http.Request, events chan<- Event) bool {
 		}
 	}
 
-	select {
-	case events <- Event{
+	event := Event{
 		RuleName:  w.ruleName,
 		Type:      "webhook",
 		Timestamp: time.Now(),
@@ -103,9 +155,12 @@ func (w *Webhook) HandleRequest(r *http.Request, events chan<- Event) bool {
 			"http_method":  r.Method,
 			"http_path":    r.URL.Path,
 		},
-	}:
-		return true
-	default:
-		return false // event channel full
 	}
+	if err := w.checkBody(body); err != nil {
+		return &InvalidBodyError{Event: event, Err: err}
+	}
+	if !w.send(events, event) {
+		return ErrEventDropped
+	}
+	return nil
 }
@@ -2,7 +2,10 @@
 package trigger
 
 import (
+	"errors"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -71,3 +74,80 @@ func TestWebhookTriggerMethodNotAllowed(t *testing.T) {
 		// Expected
 	}
 }
+
+func TestWebhookTriggerReportsDroppedEvent(t *testing.T) {
+	trigger, err := NewWebhook("test-rule", config.Trigger{Type: "webhook", ListenPath: "/hooks/test"})
+	if err != nil {
+		t.Fatalf("NewWebhook failed: %v", err)
+	}
+	var dropped []Event
+	trigger.SetDropHandler(func(e Event) { dropped = append(dropped, e) })
+
+	events := make(chan Event) // unbuffered and unread: always full
+	if err := trigger.HandleRequest(httptest.NewRequest("POST", "/hooks/test", nil), events); !errors.Is(err, ErrEventDropped) {
+		t.Errorf("HandleRequest with a full channel = %v, want ErrEventDropped", err)
+	}
+	if len(dropped) != 1 || dropped[0].RuleName != "test-rule" || dropped[0].Type != "webhook" {
+		t.Errorf("dropped = %+v, want the webhook event", dropped)
+	}
+}
+
+// deploySchema requires a string "service" and an integer "replicas".
+const deploySchema = `{
+  "type": "object",
+  "required": ["service"],
+  "properties": {
+    "service": {"type": "string"},
+    "replicas": {"type": "integer", "minimum": 1}
+  }
+}`
+
+func TestWebhookTriggerBodySchema(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "deploy.schema.json")
+	if err := os.WriteFile(schemaPath, []byte(deploySchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	trigger, err := NewWebhook("deploy", config.Trigger{Type: "webhook", ListenPath: "/hooks/deploy", BodySchema: schemaPath})
+	if err != nil {
+		t.Fatalf("NewWebhook failed: %v", err)
+	}
+	events := make(chan Event, 10)
+
+	valid := httptest.NewRequest("POST", "/hooks/deploy", strings.NewReader(`{"service":"api","replicas":2}`))
+	if err := trigger.HandleRequest(valid, events); err != nil {
+		t.Fatalf("valid body rejected: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("valid body sent %d events, want 1", len(events))
+	}
+	<-events
+
+	for _, body := range []string{`{"replicas":2}`, `{"service":"api","replicas":0}`, `not json`} {
+		err := trigger.HandleRequest(httptest.NewRequest("POST", "/hooks/deploy", strings.NewReader(body)), events)
+		var bodyErr *InvalidBodyError
+		if !errors.As(err, &bodyErr) || !errors.Is(err, ErrInvalidBody) {
+			t.Errorf("body %s: HandleRequest() = %v, want an InvalidBodyError", body, err)
+			continue
+		}
+		if bodyErr.Event.RuleName != "deploy" || bodyErr.Event.Data["http_body"] != body {
+			t.Errorf("body %s: rejected event = %+v", body, bodyErr.Event)
+		}
+	}
+	if len(events) != 0 {
+		t.Errorf("invalid bodies sent %d events, want none", len(events))
+	}
+}
+
+func TestNewWebhook_BadBodySchema(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewWebhook("deploy", config.Trigger{Type: "webhook", ListenPath: "/hooks/deploy", BodySchema: filepath.Join(dir, "missing.json")}); err == nil {
+		t.Error("NewWebhook accepted a missing body_schema")
+	}
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"type": 5}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWebhook("deploy", config.Trigger{Type: "webhook", ListenPath: "/hooks/deploy", BodySchema: bad}); err == nil {
+		t.Error("NewWebhook accepted an invalid body_schema")
+	}
+}